@@ -1,20 +1,53 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"github.com/ocowchun/baddb/ddb"
 	"github.com/ocowchun/baddb/server"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
 	var port = flag.Int("port", 9527, "ddb server port")
+	var gsiStronglyConsistent = flag.Bool("gsiStronglyConsistent", false, "ignore gsiDelaySeconds, GSI reads reflect base table writes immediately")
+	var inMemory = flag.Bool("inMemory", true, "keep tables in memory, with dbPath they are dumped to dbPath on shutdown")
+	var dbPath = flag.String("dbPath", "", "sqlite file to store tables in when inMemory=false")
+	var strict = flag.Bool("strict", false, "reject empty GSI and LSI key attributes and lists and maps nested deeper than 32 levels the way DynamoDB does")
+	var tableCreationDelay = flag.Duration("tableCreationDelay", 0, "how long a new table stays CREATING before it turns ACTIVE")
+	var indexCreationDelay = flag.Duration("indexCreationDelay", 0, "how long a GSI added by update-table stays CREATING before it turns ACTIVE")
+	var maxBatchGetItemResponseBytes = flag.Int("maxBatchGetItemResponseBytes", ddb.DEFAULT_MAX_BATCH_GET_ITEM_RESPONSE_BYTES, "cap on the size of items returned by BatchGetItem, the rest are returned as UnprocessedKeys")
+	var schema = flag.String("schema", "", "JSON file containing an array of CreateTableInput, the tables are created on startup")
 
 	flag.Parse()
 
-	svr := server.NewDdbServer()
+	if !*inMemory && *dbPath == "" {
+		log.Fatalf("dbPath is required when inMemory=false")
+	}
+
+	svr, err := server.NewDdbServerWithConfig(ddb.Config{
+		GsiStronglyConsistent:        *gsiStronglyConsistent,
+		DbPath:                       *dbPath,
+		FileBacked:                   !*inMemory,
+		Strict:                       *strict,
+		TableCreationDelay:           *tableCreationDelay,
+		IndexCreationDelay:           *indexCreationDelay,
+		MaxBatchGetItemResponseBytes: *maxBatchGetItemResponseBytes,
+	})
+	if err != nil {
+		log.Fatalf("Failed to start baddb: %v", err)
+	}
+	if *schema != "" {
+		if err := svr.CreateTablesFromSchemaFile(*schema); err != nil {
+			log.Fatalf("Failed to create tables: %v", err)
+		}
+	}
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", svr.Handler)
 
@@ -23,8 +56,26 @@ func main() {
 		Handler: mux,
 	}
 
+	// ListenAndServe returns as soon as Shutdown starts, shutdownDone is closed once in-flight requests are drained
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		if err := server.Shutdown(context.Background()); err != nil {
+			log.Printf("Server shutdown error: %v", err)
+		}
+	}()
+
 	log.Printf("baddb server is running on port %d...", *port)
+	log.Printf("baddb makes no outbound network connections, there is no telemetry")
 	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("Server error: %v", err)
 	}
+	<-shutdownDone
+
+	if err := svr.Close(); err != nil {
+		log.Fatalf("Failed to close baddb: %v", err)
+	}
 }
@@ -0,0 +1,64 @@
+package ddb
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// CLIENT_REQUEST_TOKEN_TTL is how long a TransactWriteItems call stays idempotent by its ClientRequestToken
+	CLIENT_REQUEST_TOKEN_TTL = 10 * time.Minute
+	// MAX_CLIENT_REQUEST_TOKEN_LENGTH is the longest ClientRequestToken DynamoDB accepts
+	MAX_CLIENT_REQUEST_TOKEN_LENGTH = 36
+)
+
+var idempotentParameterMismatchExceptionMessage = "The request uses the same client token as a previous, but non-identical request."
+
+type clientRequestTokenResult struct {
+	transactItems []types.TransactWriteItem
+	output        *dynamodb.TransactWriteItemsOutput
+	createdAt     time.Time
+}
+
+// clientRequestTokens remembers the successful TransactWriteItems calls by their ClientRequestToken, holding
+// the lock through a call keeps a replay from running concurrently with the call it replays
+type clientRequestTokens struct {
+	sync.Mutex
+	results map[string]clientRequestTokenResult
+}
+
+// lookup returns the output of the previous call with token, ok is false when the token is unknown or expired.
+// A previous call with different TransactItems fails with IdempotentParameterMismatchException
+func (t *clientRequestTokens) lookup(token string, transactItems []types.TransactWriteItem, now time.Time) (*dynamodb.TransactWriteItemsOutput, bool, error) {
+	result, ok := t.results[token]
+	if !ok || now.Sub(result.createdAt) > CLIENT_REQUEST_TOKEN_TTL {
+		return nil, false, nil
+	}
+	if !reflect.DeepEqual(result.transactItems, transactItems) {
+		return nil, false, &types.IdempotentParameterMismatchException{
+			Message: &idempotentParameterMismatchExceptionMessage,
+		}
+	}
+	return result.output, true, nil
+}
+
+// store remembers the output of a successful call with token and forgets the expired tokens
+func (t *clientRequestTokens) store(token string, transactItems []types.TransactWriteItem, output *dynamodb.TransactWriteItemsOutput, now time.Time) {
+	if t.results == nil {
+		t.results = make(map[string]clientRequestTokenResult)
+	}
+	for existingToken, result := range t.results {
+		if now.Sub(result.createdAt) > CLIENT_REQUEST_TOKEN_TTL {
+			delete(t.results, existingToken)
+		}
+	}
+	t.results[token] = clientRequestTokenResult{
+		transactItems: transactItems,
+		output:        output,
+		createdAt:     now,
+	}
+}
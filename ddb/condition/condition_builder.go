@@ -1,7 +1,10 @@
 package condition
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -45,6 +48,20 @@ func NewCondition(f func(entry *core.Entry) (bool, error)) *Condition {
 	}
 }
 
+// newLeafCondition is false when a path of a comparison or function doesn't resolve to an attribute,
+// e.g. `info.status = :v` of an item without info, the way DynamoDB evaluates conditions on missing attributes
+func newLeafCondition(f func(entry *core.Entry) (bool, error)) *Condition {
+	return &Condition{
+		f: func(entry *core.Entry) (bool, error) {
+			matched, err := f(entry)
+			if errors.Is(err, core.ErrPathNotFound) {
+				return false, nil
+			}
+			return matched, err
+		},
+	}
+}
+
 func (c *Condition) Check(entry *core.Entry) (bool, error) {
 	return c.f(entry)
 }
@@ -231,13 +248,12 @@ func (b *ConditionBuilder) BuildOrCondition(exp *ast.OrConditionExpression) (*Co
 		if err != nil {
 			return false, err
 		}
-
-		rightResult, err := right.Check(entry)
-		if err != nil {
-			return false, err
+		// short-circuit, so `attribute_not_exists(pk) OR version < :v` doesn't read version of a missing item
+		if leftResult {
+			return true, nil
 		}
 
-		return leftResult || rightResult, nil
+		return right.Check(entry)
 	}
 
 	return &Condition{
@@ -261,13 +277,11 @@ func (b *ConditionBuilder) BuildAndCondition(exp *ast.AndConditionExpression) (*
 		if err != nil {
 			return false, err
 		}
-
-		rightResult, err := right.Check(entry)
-		if err != nil {
-			return false, err
+		if !leftResult {
+			return false, nil
 		}
 
-		return leftResult && rightResult, nil
+		return right.Check(entry)
 	}
 
 	return &Condition{
@@ -320,6 +334,13 @@ func (b *ConditionBuilder) BuildContainsFunction(exp *ast.ContainsFunctionExpres
 				}
 			}
 			return false, nil
+		} else if leftVal.BS != nil && rightVal.B != nil {
+			for _, b := range *leftVal.BS {
+				if bytes.Equal(b, *rightVal.B) {
+					return true, nil
+				}
+			}
+			return false, nil
 		} else if leftVal.NS != nil && rightVal.N != nil {
 			for _, n := range *leftVal.NS {
 				if n == *rightVal.N {
@@ -342,9 +363,7 @@ func (b *ConditionBuilder) BuildContainsFunction(exp *ast.ContainsFunctionExpres
 		return false, fmt.Errorf("left operand must be a list of strings and right operand must be a string")
 	}
 
-	return &Condition{
-		f: f,
-	}, nil
+	return newLeafCondition(f), nil
 
 }
 
@@ -368,19 +387,20 @@ func (b *ConditionBuilder) BuildBeginsWithFunction(exp *ast.BeginsWithFunctionEx
 		if err != nil {
 			return false, err
 		}
-		if leftVal.S != nil && rightVal.S != nil {
-			return strings.HasPrefix(*leftVal.S, *rightVal.S), nil
+		if (leftVal.S != nil && rightVal.S != nil) || (leftVal.B != nil && rightVal.B != nil) {
+			return leftVal.BeginsWith(rightVal)
 		} else {
-			return false, fmt.Errorf("both values must be string")
+			return false, fmt.Errorf("both values must be string or binary")
 		}
 	}
 
-	return &Condition{
-		f: f,
-	}, nil
+	return newLeafCondition(f), nil
 
 }
 
+// attributeTypeNames are the types attribute_type compares against, in the order DynamoDB lists them
+var attributeTypeNames = []string{"B", "N", "S", "BOOL", "NULL", "L", "M", "BS", "NS", "SS"}
+
 func (b *ConditionBuilder) BuildAttributeTypeFunction(exp *ast.AttributeTypeFunctionExpression) (*Condition, error) {
 	operand, err := b.buildOperand(exp.Path)
 	if err != nil {
@@ -398,6 +418,9 @@ func (b *ConditionBuilder) BuildAttributeTypeFunction(exp *ast.AttributeTypeFunc
 	} else {
 		return nil, fmt.Errorf("attribute type must be a string, but got %T", dataType)
 	}
+	if !slices.Contains(attributeTypeNames, dataTypeName) {
+		return nil, fmt.Errorf("Invalid attribute type name found; type: %s, valid types: { %s }", dataTypeName, strings.Join(attributeTypeNames, ","))
+	}
 
 	f := func(entry *core.Entry) (bool, error) {
 		val, err := getValue(entry, operand)
@@ -408,9 +431,7 @@ func (b *ConditionBuilder) BuildAttributeTypeFunction(exp *ast.AttributeTypeFunc
 		return val.Type() == dataTypeName, nil
 	}
 
-	return &Condition{
-		f: f,
-	}, nil
+	return newLeafCondition(f), nil
 }
 
 func (b *ConditionBuilder) BuildAttributeNotExistsFunction(exp *ast.AttributeNotExistsFunctionExpression) (*Condition, error) {
@@ -485,9 +506,7 @@ func (b *ConditionBuilder) BuildInCondition(exp *ast.InConditionExpression) (*Co
 		return false, nil
 	}
 
-	return &Condition{
-		f: f,
-	}, nil
+	return newLeafCondition(f), nil
 }
 
 func (b *ConditionBuilder) BuildBetweenCondition(exp *ast.BetweenConditionExpression) (*Condition, error) {
@@ -529,9 +548,7 @@ func (b *ConditionBuilder) BuildBetweenCondition(exp *ast.BetweenConditionExpres
 		}
 		return lowerCompared && upperCompared, nil
 	}
-	return &Condition{
-		f: f,
-	}, nil
+	return newLeafCondition(f), nil
 }
 
 func (b *ConditionBuilder) BuildComparatorCondition(exp *ast.ComparatorConditionExpression) (*Condition, error) {
@@ -557,22 +574,24 @@ func (b *ConditionBuilder) BuildComparatorCondition(exp *ast.ComparatorCondition
 		return compareValue(leftVal, rightVal, exp.Operator)
 	}
 
-	return &Condition{
-		f: f,
-	}, nil
+	return newLeafCondition(f), nil
 }
 
 func compareValue(leftVal core.AttributeValue, rightVal core.AttributeValue, operator string) (bool, error) {
+	// = and <> work on every type, lists, maps and sets are compared deeply
+	switch operator {
+	case "=":
+		return leftVal.Equal(rightVal), nil
+	case "<>":
+		return !leftVal.Equal(rightVal), nil
+	}
+
 	compared, err := leftVal.Compare(rightVal)
 	if err != nil {
 		return false, err
 	}
 
 	switch operator {
-	case "=":
-		return compared == 0, nil
-	case "<>":
-		return compared != 0, nil
 	case "<":
 		return compared < 0, nil
 	case "<=":
@@ -604,6 +623,9 @@ func getValue(entry *core.Entry, operand Operand) (core.AttributeValue, error) {
 		} else if val.B != nil {
 			l := strconv.Itoa(len(*val.B))
 			return core.AttributeValue{N: &l}, nil
+		} else if val.BS != nil {
+			l := strconv.Itoa(len(*val.BS))
+			return core.AttributeValue{N: &l}, nil
 		} else if val.NS != nil {
 			l := strconv.Itoa(len(*val.NS))
 			return core.AttributeValue{N: &l}, nil
@@ -2,9 +2,11 @@ package condition
 
 import (
 	"errors"
+	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/ocowchun/baddb/ddb/core"
 	"github.com/ocowchun/baddb/ddb/expression/parser"
+	"strings"
 	"testing"
 )
 
@@ -200,6 +202,397 @@ func TestConditionBuilder_BuildInCondition(t *testing.T) {
 	}
 }
 
+func TestConditionBuilder_CompareNumbersWithDifferentPrecision(t *testing.T) {
+	entry := &core.Entry{
+		Body: map[string]core.AttributeValue{
+			"a": {N: aws.String("1.0")},
+			"b": {N: aws.String("1.00")},
+			"c": {N: aws.String("1.5")},
+		},
+	}
+
+	tests := []struct {
+		exp      string
+		expected bool
+	}{
+		{exp: "a = b", expected: true},
+		{exp: "a <> b", expected: false},
+		{exp: "a >= b", expected: true},
+		{exp: "a < c", expected: true},
+		{exp: "a > c", expected: false},
+		{exp: "c > :val", expected: true},
+		{exp: "a = :val", expected: true},
+		{exp: "a IN (:val)", expected: true},
+		{exp: "b IN (:val)", expected: true},
+	}
+
+	for _, tt := range tests {
+		condition, err := BuildCondition(
+			tt.exp,
+			make(map[string]string),
+			map[string]core.AttributeValue{
+				":val": {N: aws.String("1")},
+			})
+		if err != nil {
+			t.Fatalf("unexpected error: %v when building condition %s", err, tt.exp)
+		}
+
+		result, err := condition.Check(entry)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != tt.expected {
+			t.Fatalf("expected %v but got %v for condition %s", tt.expected, result, tt.exp)
+		}
+	}
+}
+
+func TestConditionBuilder_CompareNestedNumbersWithDifferentFormats(t *testing.T) {
+	tests := []struct {
+		stored   string
+		rating   string
+		exp      string
+		expected bool
+	}{
+		{stored: "9.0", rating: "9.00", exp: "info.rating = :rating", expected: true},
+		{stored: "9.00", rating: "9.0", exp: "info.rating = :rating", expected: true},
+		{stored: "9", rating: "9.0", exp: "info.rating = :rating", expected: true},
+		{stored: "9.0", rating: "9", exp: "info.rating = :rating", expected: true},
+		{stored: "9.0", rating: "9", exp: "info.rating <> :rating", expected: false},
+		{stored: "9.3", rating: "9.30", exp: "info.rating = :rating", expected: true},
+		{stored: "9.3", rating: "9.03", exp: "info.rating = :rating", expected: false},
+		{stored: "90", rating: "9.0", exp: "info.rating = :rating", expected: false},
+	}
+
+	for _, tt := range tests {
+		info := map[string]core.AttributeValue{
+			"rating": {N: aws.String(tt.stored)},
+		}
+		entry := &core.Entry{
+			Body: map[string]core.AttributeValue{
+				"info": {M: &info},
+			},
+		}
+		condition, err := BuildCondition(
+			tt.exp,
+			make(map[string]string),
+			map[string]core.AttributeValue{
+				":rating": {N: aws.String(tt.rating)},
+			})
+		if err != nil {
+			t.Fatalf("unexpected error: %v when building condition %s", err, tt.exp)
+		}
+
+		result, err := condition.Check(entry)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != tt.expected {
+			t.Fatalf("expected %v but got %v for condition %s with stored %s and :rating %s", tt.expected, result, tt.exp, tt.stored, tt.rating)
+		}
+	}
+}
+
+func TestConditionBuilder_CompareStringsByUTF8Bytes(t *testing.T) {
+	tests := []struct {
+		stored   string
+		name     string
+		exp      string
+		expected bool
+	}{
+		{stored: "apple", name: "banana", exp: "#name < :name", expected: true},
+		{stored: "apple", name: "banana", exp: "#name > :name", expected: false},
+		{stored: "banana", name: "apple", exp: "#name > :name", expected: true},
+		{stored: "apple", name: "apple", exp: "#name >= :name", expected: true},
+		{stored: "apple", name: "applesauce", exp: "#name < :name", expected: true},
+		{stored: "Zebra", name: "apple", exp: "#name < :name", expected: true},
+		{stored: "zebra", name: "élan", exp: "#name < :name", expected: true},
+		// U+FF5E is 0xEF 0xBD 0x9E in UTF-8 and U+1F600 is 0xF0 0x9F 0x98 0x80, UTF-16 would order them the other way
+		{stored: "\uFF5E", name: "\U0001F600", exp: "#name < :name", expected: true},
+		{stored: "\U0001F600", name: "\uFF5E", exp: "#name > :name", expected: true},
+	}
+
+	for _, tt := range tests {
+		entry := &core.Entry{
+			Body: map[string]core.AttributeValue{
+				"name": {S: aws.String(tt.stored)},
+			},
+		}
+		condition, err := BuildCondition(
+			tt.exp,
+			map[string]string{"#name": "name"},
+			map[string]core.AttributeValue{
+				":name": {S: aws.String(tt.name)},
+			})
+		if err != nil {
+			t.Fatalf("unexpected error: %v when building condition %s", err, tt.exp)
+		}
+
+		result, err := condition.Check(entry)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != tt.expected {
+			t.Fatalf("expected %v but got %v for condition %s with stored %q and :name %q", tt.expected, result, tt.exp, tt.stored, tt.name)
+		}
+	}
+}
+
+func TestConditionBuilder_NotEqualsNestedAndSetAttributes(t *testing.T) {
+	info := map[string]core.AttributeValue{
+		"rating": {N: aws.String("4.5")},
+	}
+	entry := &core.Entry{
+		Body: map[string]core.AttributeValue{
+			"info": {M: &info},
+			"tags": {SS: &[]string{"action", "drama", "sci-fi"}},
+			"ids":  {NS: &[]string{"1", "2.0", "3"}},
+		},
+	}
+
+	tests := []struct {
+		exp      string
+		val      core.AttributeValue
+		expected bool
+	}{
+		{exp: "info.rating <> :val", val: core.AttributeValue{N: aws.String("4.5")}, expected: false},
+		{exp: "info.rating <> :val", val: core.AttributeValue{N: aws.String("5")}, expected: true},
+		{exp: "info.rating <> :val", val: core.AttributeValue{S: aws.String("4.5")}, expected: true},
+		// sets don't keep an order, so the same elements in another order are equal
+		{exp: "tags <> :val", val: core.AttributeValue{SS: &[]string{"sci-fi", "action", "drama"}}, expected: false},
+		{exp: "tags = :val", val: core.AttributeValue{SS: &[]string{"sci-fi", "action", "drama"}}, expected: true},
+		{exp: "tags <> :val", val: core.AttributeValue{SS: &[]string{"action", "drama"}}, expected: true},
+		{exp: "tags <> :val", val: core.AttributeValue{SS: &[]string{"action", "drama", "comedy"}}, expected: true},
+		{exp: "ids <> :val", val: core.AttributeValue{NS: &[]string{"3", "1.0", "2"}}, expected: false},
+		{exp: "ids <> :val", val: core.AttributeValue{NS: &[]string{"3", "1", "4"}}, expected: true},
+	}
+
+	for _, tt := range tests {
+		condition, err := BuildCondition(
+			tt.exp,
+			make(map[string]string),
+			map[string]core.AttributeValue{
+				":val": tt.val,
+			})
+		if err != nil {
+			t.Fatalf("unexpected error: %v when building condition %s", err, tt.exp)
+		}
+
+		result, err := condition.Check(entry)
+		if err != nil {
+			t.Fatalf("unexpected error: %v for condition %s", err, tt.exp)
+		}
+		if result != tt.expected {
+			t.Fatalf("expected %v but got %v for condition %s with %v", tt.expected, result, tt.exp, tt.val)
+		}
+	}
+}
+
+func TestConditionBuilder_CompareSizes(t *testing.T) {
+	list := func(n int) core.AttributeValue {
+		l := make([]core.AttributeValue, n)
+		for i := range l {
+			l[i] = core.AttributeValue{N: aws.String("1")}
+		}
+		return core.AttributeValue{L: &l}
+	}
+	entries := []*core.Entry{
+		{
+			Body: map[string]core.AttributeValue{
+				"list1": list(3),
+				"list2": list(1),
+			},
+		},
+		{
+			Body: map[string]core.AttributeValue{
+				"list1": list(2),
+				"list2": list(2),
+			},
+		},
+		{
+			Body: map[string]core.AttributeValue{
+				"list1": list(0),
+				"list2": list(2),
+			},
+		},
+	}
+
+	tests := []struct {
+		exp      string
+		expected []bool
+	}{
+		{
+			exp:      "size(list1) > size(list2)",
+			expected: []bool{true, false, false},
+		},
+		{
+			exp:      "size(list1) = size(list2)",
+			expected: []bool{false, true, false},
+		},
+		{
+			exp:      "size(list1) < size(list2)",
+			expected: []bool{false, false, true},
+		},
+		{
+			exp:      "size(list1) >= size(list2) AND size(list2) > :one",
+			expected: []bool{false, true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		condition, err := BuildCondition(
+			tt.exp,
+			make(map[string]string),
+			map[string]core.AttributeValue{
+				":one": {N: aws.String("1")},
+			})
+		if err != nil {
+			t.Fatalf("unexpected error: %v when building condition %s", err, tt.exp)
+		}
+
+		for i, entry := range entries {
+			result, err := condition.Check(entry)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result != tt.expected[i] {
+				t.Fatalf("expected %v but got %v for condition %s", tt.expected[i], result, tt.exp)
+			}
+		}
+	}
+}
+
+func TestConditionBuilder_EmptyListAndMap(t *testing.T) {
+	emptyList := make([]core.AttributeValue, 0)
+	emptyMap := make(map[string]core.AttributeValue)
+	entries := []*core.Entry{
+		{
+			Body: map[string]core.AttributeValue{
+				"tags":    {L: &emptyList},
+				"profile": {M: &emptyMap},
+			},
+		},
+		{
+			Body: map[string]core.AttributeValue{
+				"tags":    {L: &[]core.AttributeValue{{N: aws.String("1")}}},
+				"profile": {M: &map[string]core.AttributeValue{"k": {S: aws.String("v")}}},
+			},
+		},
+	}
+
+	tests := []struct {
+		exp      string
+		expected []bool
+	}{
+		{
+			exp:      "size(tags) = :zero",
+			expected: []bool{true, false},
+		},
+		{
+			exp:      "size(profile) = :zero",
+			expected: []bool{true, false},
+		},
+		{
+			exp:      "attribute_exists(tags) AND attribute_exists(profile)",
+			expected: []bool{true, true},
+		},
+		{
+			exp:      "attribute_not_exists(profile)",
+			expected: []bool{false, false},
+		},
+		{
+			exp:      "attribute_type(tags, :list) AND attribute_type(profile, :map)",
+			expected: []bool{true, true},
+		},
+		{
+			exp:      "tags = :emptyList AND profile = :emptyMap",
+			expected: []bool{true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		condition, err := BuildCondition(
+			tt.exp,
+			make(map[string]string),
+			map[string]core.AttributeValue{
+				":zero":      {N: aws.String("0")},
+				":list":      {S: aws.String("L")},
+				":map":       {S: aws.String("M")},
+				":emptyList": {L: &emptyList},
+				":emptyMap":  {M: &emptyMap},
+			})
+		if err != nil {
+			t.Fatalf("unexpected error: %v when building condition %s", err, tt.exp)
+		}
+
+		for i, entry := range entries {
+			result, err := condition.Check(entry)
+			if err != nil {
+				t.Fatalf("unexpected error: %v when checking condition %s", err, tt.exp)
+			}
+
+			if result != tt.expected[i] {
+				t.Fatalf("expected %v but got %v for condition %s", tt.expected[i], result, tt.exp)
+			}
+		}
+	}
+}
+
+func TestConditionBuilder_AttributeNameWithDot(t *testing.T) {
+	entries := []*core.Entry{
+		{
+			Body: map[string]core.AttributeValue{
+				"a.b": {S: aws.String("literal")},
+			},
+		},
+		{
+			Body: map[string]core.AttributeValue{
+				"a": {M: &map[string]core.AttributeValue{
+					"b": {S: aws.String("nested")},
+				}},
+			},
+		},
+	}
+
+	tests := []struct {
+		exp      string
+		expected []bool
+	}{
+		{
+			exp:      "attribute_exists(#n)",
+			expected: []bool{true, false},
+		},
+		{
+			exp:      "attribute_exists(a.b)",
+			expected: []bool{false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		condition, err := BuildCondition(
+			tt.exp,
+			map[string]string{
+				"#n": "a.b",
+			},
+			make(map[string]core.AttributeValue))
+		if err != nil {
+			t.Fatalf("unexpected error: %v when building condition %s", err, tt.exp)
+		}
+
+		for i, entry := range entries {
+			result, err := condition.Check(entry)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result != tt.expected[i] {
+				t.Fatalf("expected %v but got %v for condition %s", tt.expected[i], result, tt.exp)
+			}
+		}
+	}
+}
+
 func TestConditionBuilder_BuildFunctionCondition(t *testing.T) {
 	entries := []*core.Entry{
 		{
@@ -273,6 +666,111 @@ func TestConditionBuilder_BuildFunctionCondition(t *testing.T) {
 	}
 }
 
+func TestConditionBuilder_MultipleFunctions(t *testing.T) {
+	tags := []core.AttributeValue{{S: aws.String("sci-fi")}, {S: aws.String("drama")}}
+	entries := []*core.Entry{
+		{
+			Body: map[string]core.AttributeValue{
+				"a": {N: aws.String("1")},
+				"b": {S: aws.String("Alice")},
+				"c": {S: aws.String("prefix-value")},
+				"d": {L: &tags},
+			},
+		},
+		// a is missing
+		{
+			Body: map[string]core.AttributeValue{
+				"b": {S: aws.String("Alice")},
+				"c": {S: aws.String("prefix-value")},
+				"d": {L: &tags},
+			},
+		},
+		// b is a number
+		{
+			Body: map[string]core.AttributeValue{
+				"a": {N: aws.String("1")},
+				"b": {N: aws.String("1")},
+				"c": {S: aws.String("prefix-value")},
+				"d": {L: &tags},
+			},
+		},
+		// c doesn't begin with the prefix
+		{
+			Body: map[string]core.AttributeValue{
+				"a": {N: aws.String("1")},
+				"b": {S: aws.String("Alice")},
+				"c": {S: aws.String("value")},
+				"d": {L: &tags},
+			},
+		},
+		// d doesn't contain sci-fi
+		{
+			Body: map[string]core.AttributeValue{
+				"a": {N: aws.String("1")},
+				"b": {S: aws.String("Alice")},
+				"c": {S: aws.String("prefix-value")},
+				"d": {L: &[]core.AttributeValue{{S: aws.String("comedy")}}},
+			},
+		},
+	}
+	expressionAttributeValues := map[string]core.AttributeValue{
+		":t": {S: aws.String("S")},
+		":p": {S: aws.String("prefix-")},
+		":e": {S: aws.String("sci-fi")},
+		":n": {N: aws.String("0")},
+	}
+
+	tests := []struct {
+		exp      string
+		expected []bool
+	}{
+		{
+			exp:      "attribute_exists(a) AND attribute_type(b, :t) AND begins_with(c, :p) AND contains(d, :e)",
+			expected: []bool{true, false, false, false, false},
+		},
+		{
+			// AND binds tighter than OR
+			exp:      "attribute_not_exists(a) OR attribute_type(b, :t) AND begins_with(c, :p) AND contains(d, :e)",
+			expected: []bool{true, true, false, false, false},
+		},
+		{
+			exp:      "(attribute_not_exists(a) OR attribute_type(b, :t)) AND (begins_with(c, :p) OR contains(d, :e))",
+			expected: []bool{true, true, false, true, true},
+		},
+		{
+			// a > :n is never evaluated when a is missing, comparing a missing attribute would fail
+			exp:      "attribute_exists(a) AND attribute_type(b, :t) AND begins_with(c, :p) AND contains(d, :e) AND a > :n",
+			expected: []bool{true, false, false, false, false},
+		},
+		{
+			exp:      "NOT attribute_exists(a) AND begins_with(c, :p)",
+			expected: []bool{false, true, false, false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		condition, err := BuildCondition(
+			tt.exp,
+			make(map[string]string),
+			expressionAttributeValues,
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v when building condition %s", err, tt.exp)
+		}
+
+		for i, entry := range entries {
+			result, err := condition.Check(entry)
+			if err != nil {
+				t.Fatalf("unexpected error: %v for condition %s on entry %d", err, tt.exp, i)
+			}
+
+			if result != tt.expected[i] {
+				t.Fatalf("expected %v but got %v for condition %s on entry %d", tt.expected[i], result, tt.exp, i)
+			}
+		}
+	}
+}
+
 func TestConditionBuilder_BuildAndConditionExpression(t *testing.T) {
 	entries := []*core.Entry{
 		{
@@ -395,6 +893,146 @@ func TestConditionBuilder_BuildOrConditionExpression(t *testing.T) {
 	}
 }
 
+func TestConditionBuilder_AttributeNotExistsOrComparator(t *testing.T) {
+	entries := []*core.Entry{
+		// the item doesn't exist yet
+		{
+			Body: map[string]core.AttributeValue{},
+		},
+		{
+			Body: map[string]core.AttributeValue{
+				"pk":      {S: aws.String("foo")},
+				"version": {N: aws.String("1")},
+			},
+		},
+		{
+			Body: map[string]core.AttributeValue{
+				"pk":      {S: aws.String("foo")},
+				"version": {N: aws.String("3")},
+			},
+		},
+	}
+
+	tests := []struct {
+		exp      string
+		expected []bool
+	}{
+		{
+			exp:      "attribute_not_exists(pk) OR version < :v",
+			expected: []bool{true, true, false},
+		},
+		{
+			exp:      "(attribute_not_exists(pk)) OR (version < :v)",
+			expected: []bool{true, true, false},
+		},
+		{
+			exp:      "attribute_not_exists(pk) OR version < :v AND version > :zero",
+			expected: []bool{true, true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		condition, err := BuildCondition(
+			tt.exp,
+			make(map[string]string),
+			map[string]core.AttributeValue{
+				":v":    {N: aws.String("2")},
+				":zero": {N: aws.String("0")},
+			})
+		if err != nil {
+			t.Fatalf("unexpected error: %v when building condition %s", err, tt.exp)
+		}
+
+		for i, entry := range entries {
+			result, err := condition.Check(entry)
+			if err != nil {
+				t.Fatalf("unexpected error: %v for condition %s", err, tt.exp)
+			}
+
+			if result != tt.expected[i] {
+				t.Fatalf("expected %v but got %v for condition %s", tt.expected[i], result, tt.exp)
+			}
+		}
+	}
+}
+
+func TestConditionBuilder_NestedAttributeNotExistsAndSibling(t *testing.T) {
+	info := func(m map[string]core.AttributeValue) map[string]core.AttributeValue {
+		return map[string]core.AttributeValue{
+			"pk":   {S: aws.String("foo")},
+			"info": {M: &m},
+		}
+	}
+	entries := []*core.Entry{
+		// active and not deleted
+		{Body: info(map[string]core.AttributeValue{"status": {S: aws.String("active")}})},
+		// soft deleted
+		{Body: info(map[string]core.AttributeValue{
+			"status":    {S: aws.String("active")},
+			"deletedAt": {N: aws.String("1700000000")},
+		})},
+		// a NULL deletedAt still exists
+		{Body: info(map[string]core.AttributeValue{
+			"status":    {S: aws.String("active")},
+			"deletedAt": {NULL: aws.Bool(true)},
+		})},
+		{Body: info(map[string]core.AttributeValue{"status": {S: aws.String("archived")}})},
+		// info isn't a map
+		{Body: map[string]core.AttributeValue{
+			"pk":   {S: aws.String("foo")},
+			"info": {S: aws.String("active")},
+		}},
+		{Body: map[string]core.AttributeValue{}},
+	}
+
+	tests := []struct {
+		exp      string
+		expected []bool
+	}{
+		// status is a reserved word, so it needs a placeholder as it does on DynamoDB
+		{
+			exp:      "attribute_not_exists(info.deletedAt) AND info.#status = :active",
+			expected: []bool{true, false, false, false, false, false},
+		},
+		{
+			exp:      "attribute_not_exists(#info.#deletedAt) AND #info.#status = :active",
+			expected: []bool{true, false, false, false, false, false},
+		},
+		// a comparison with a missing attribute is false, even <>
+		{
+			exp:      "attribute_exists(info.deletedAt) OR info.#status <> :active",
+			expected: []bool{false, true, true, true, false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		condition, err := BuildCondition(
+			tt.exp,
+			map[string]string{
+				"#info":      "info",
+				"#deletedAt": "deletedAt",
+				"#status":    "status",
+			},
+			map[string]core.AttributeValue{
+				":active": {S: aws.String("active")},
+			})
+		if err != nil {
+			t.Fatalf("unexpected error: %v when building condition %s", err, tt.exp)
+		}
+
+		for i, entry := range entries {
+			result, err := condition.Check(entry)
+			if err != nil {
+				t.Fatalf("unexpected error: %v for condition %s", err, tt.exp)
+			}
+
+			if result != tt.expected[i] {
+				t.Fatalf("expected %v but got %v for entry %d and condition %s", tt.expected[i], result, i, tt.exp)
+			}
+		}
+	}
+}
+
 func TestConditionBuilder_BuildNotCondition(t *testing.T) {
 	entries := []*core.Entry{
 		{
@@ -448,17 +1086,29 @@ func TestConditionBuilder_BuildNotCondition(t *testing.T) {
 	}
 }
 
-func TestConditionBuilder_BuildAttributeTypeFunction(t *testing.T) {
-
+func TestConditionBuilder_NotWithParenthesesAndFunctions(t *testing.T) {
+	// every combination of attribute_exists(a) and b = :v
 	entries := []*core.Entry{
 		{
 			Body: map[string]core.AttributeValue{
-				"createdYear": {N: aws.String("2024")},
+				"a": {S: aws.String("a")},
+				"b": {N: aws.String("1")},
+			},
+		},
+		{
+			Body: map[string]core.AttributeValue{
+				"a": {S: aws.String("a")},
+				"b": {N: aws.String("2")},
 			},
 		},
 		{
 			Body: map[string]core.AttributeValue{
-				"createdYear": {S: aws.String("2025")},
+				"b": {N: aws.String("1")},
+			},
+		},
+		{
+			Body: map[string]core.AttributeValue{
+				"b": {N: aws.String("2")},
 			},
 		},
 	}
@@ -468,8 +1118,25 @@ func TestConditionBuilder_BuildAttributeTypeFunction(t *testing.T) {
 		expected []bool
 	}{
 		{
-			exp:      "attribute_type(createdYear, :type)",
-			expected: []bool{true, false},
+			exp:      "NOT (attribute_exists(a) AND b = :v)",
+			expected: []bool{false, true, true, true},
+		},
+		{
+			// NOT binds tighter than AND without the parentheses
+			exp:      "NOT attribute_exists(a) AND b = :v",
+			expected: []bool{false, false, true, false},
+		},
+		{
+			exp:      "NOT (attribute_exists(a) OR b = :v)",
+			expected: []bool{false, false, false, true},
+		},
+		{
+			exp:      "NOT (NOT attribute_exists(a) AND b = :v)",
+			expected: []bool{true, true, false, true},
+		},
+		{
+			exp:      "NOT (attribute_exists(a) AND b = :v) AND b = :v",
+			expected: []bool{false, false, true, false},
 		},
 	}
 
@@ -478,7 +1145,7 @@ func TestConditionBuilder_BuildAttributeTypeFunction(t *testing.T) {
 			tt.exp,
 			make(map[string]string),
 			map[string]core.AttributeValue{
-				":type": {S: aws.String("N")},
+				":v": {N: aws.String("1")},
 			})
 		if err != nil {
 			t.Fatalf("unexpected error: %v when building condition %s", err, tt.exp)
@@ -491,11 +1158,62 @@ func TestConditionBuilder_BuildAttributeTypeFunction(t *testing.T) {
 			}
 
 			if result != tt.expected[i] {
-				t.Fatalf("expected %v but got %v for condition %s", tt.expected[i], result, tt.exp)
+				t.Fatalf("expected %v but got %v for entry %d and condition %s", tt.expected[i], result, i, tt.exp)
+			}
+		}
+	}
+}
+
+func TestConditionBuilder_BuildAttributeTypeFunction(t *testing.T) {
+	entry := &core.Entry{
+		Body: map[string]core.AttributeValue{
+			"B":    {B: &[]byte{1}},
+			"N":    {N: aws.String("2024")},
+			"S":    {S: aws.String("2025")},
+			"BOOL": {BOOL: aws.Bool(true)},
+			"NULL": {NULL: aws.Bool(true)},
+			"L":    {L: &[]core.AttributeValue{{S: aws.String("a")}}},
+			"M":    {M: &map[string]core.AttributeValue{"a": {S: aws.String("a")}}},
+			"BS":   {BS: &[][]byte{{1}}},
+			"NS":   {NS: &[]string{"1"}},
+			"SS":   {SS: &[]string{"a"}},
+		},
+	}
+
+	// every attribute of entry is named after its type, so attribute_type only matches the attribute of the same name
+	exp := "attribute_type(#a, :type)"
+	for _, typeName := range attributeTypeNames {
+		for attributeName := range entry.Body {
+			condition, err := BuildCondition(
+				exp,
+				map[string]string{"#a": attributeName},
+				map[string]core.AttributeValue{
+					":type": {S: aws.String(typeName)},
+				})
+			if err != nil {
+				t.Fatalf("unexpected error: %v when building condition %s", err, exp)
+			}
+
+			result, err := condition.Check(entry)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != (typeName == attributeName) {
+				t.Fatalf("expected %v but got %v for attribute %s and type %s", typeName == attributeName, result, attributeName, typeName)
 			}
 		}
 	}
 
+	_, err := BuildCondition(
+		"attribute_type(L, :type)",
+		make(map[string]string),
+		map[string]core.AttributeValue{
+			":type": {S: aws.String("XYZ")},
+		})
+	expected := "Invalid attribute type name found; type: XYZ, valid types: { B,N,S,BOOL,NULL,L,M,BS,NS,SS }"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("expected error %s, got %v", expected, err)
+	}
 }
 
 func TestBuildConditionReservedWord(t *testing.T) {
@@ -516,3 +1234,49 @@ func TestBuildConditionReservedWord(t *testing.T) {
 		t.Fatalf("expected reserved word error, got: %v", err)
 	}
 }
+
+// BenchmarkConditionCheck builds and checks conditions referencing the same nested paths repeatedly,
+// ns/op should grow linearly with the number of clauses
+func BenchmarkConditionCheck(b *testing.B) {
+	info := make(map[string]core.AttributeValue)
+	for i := 0; i < 10; i++ {
+		info[fmt.Sprintf("a%d", i)] = core.AttributeValue{N: aws.String("1")}
+	}
+	entry := &core.Entry{
+		Body: map[string]core.AttributeValue{
+			"info": {M: &info},
+		},
+	}
+	exprAttrValues := map[string]core.AttributeValue{
+		":v": {N: aws.String("1")},
+	}
+
+	for _, count := range []int{10, 100, 1000} {
+		clauses := make([]string, count)
+		for i := range clauses {
+			clauses[i] = fmt.Sprintf("info.a%d = :v", i%10)
+		}
+		exp := strings.Join(clauses, " AND ")
+
+		b.Run(fmt.Sprintf("build-%d", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := BuildCondition(exp, make(map[string]string), exprAttrValues); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+		})
+
+		condition, err := BuildCondition(exp, make(map[string]string), exprAttrValues)
+		if err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+		b.Run(fmt.Sprintf("check-%d", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				matched, err := condition.Check(entry)
+				if err != nil || !matched {
+					b.Fatalf("expected condition to match, got %v, %v", matched, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package ddb
+
+import (
+	"math"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ocowchun/baddb/ddb/core"
+)
+
+const (
+	// READ_CAPACITY_UNIT_BYTES is the item size one RCU reads with a strongly consistent read
+	READ_CAPACITY_UNIT_BYTES = 4 * 1024
+	// WRITE_CAPACITY_UNIT_BYTES is the item size one WCU writes
+	WRITE_CAPACITY_UNIT_BYTES = 1024
+)
+
+// readCapacityUnits estimates the RCUs of reading an item of size bytes, the size is rounded up to the next 4KB
+// and an eventually consistent read costs half. Reading a missing item still costs the minimum.
+// https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/provisioned-capacity-mode.html#read-write-capacity-units
+func readCapacityUnits(size int, consistentRead bool) float64 {
+	units := math.Max(1, math.Ceil(float64(size)/READ_CAPACITY_UNIT_BYTES))
+	if !consistentRead {
+		units /= 2
+	}
+	return units
+}
+
+// writeCapacityUnits estimates the WCUs of writing an item of size bytes, the size is rounded up to the next 1KB
+func writeCapacityUnits(size int) float64 {
+	return math.Max(1, math.Ceil(float64(size)/WRITE_CAPACITY_UNIT_BYTES))
+}
+
+// buildConsumedCapacities returns one ConsumedCapacity per table of unitsByTable sorted by table name,
+// or nil when the caller doesn't ask for it
+func buildConsumedCapacities(returnConsumedCapacity types.ReturnConsumedCapacity, unitsByTable map[string]float64) []types.ConsumedCapacity {
+	if returnConsumedCapacity != types.ReturnConsumedCapacityTotal && returnConsumedCapacity != types.ReturnConsumedCapacityIndexes {
+		return nil
+	}
+
+	tableNames := make([]string, 0, len(unitsByTable))
+	for tableName := range unitsByTable {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+
+	consumedCapacities := make([]types.ConsumedCapacity, len(tableNames))
+	for i, tableName := range tableNames {
+		consumedCapacities[i] = types.ConsumedCapacity{
+			TableName:     aws.String(tableName),
+			CapacityUnits: aws.Float64(unitsByTable[tableName]),
+		}
+		if returnConsumedCapacity == types.ReturnConsumedCapacityIndexes {
+			consumedCapacities[i].Table = &types.Capacity{
+				CapacityUnits: aws.Float64(unitsByTable[tableName]),
+			}
+		}
+	}
+	return consumedCapacities
+}
+
+// buildConsumedCapacity returns the ConsumedCapacity of a single read on table, or nil when the caller doesn't
+// ask for it. With INDEXES the units of a read on indexName are reported as the index's, and the table's are 0
+func buildConsumedCapacity(returnConsumedCapacity types.ReturnConsumedCapacity, table *core.TableMetaData, indexName *string, units float64) *types.ConsumedCapacity {
+	if returnConsumedCapacity != types.ReturnConsumedCapacityTotal && returnConsumedCapacity != types.ReturnConsumedCapacityIndexes {
+		return nil
+	}
+
+	consumedCapacity := &types.ConsumedCapacity{
+		TableName:     aws.String(table.Name),
+		CapacityUnits: aws.Float64(units),
+	}
+	if returnConsumedCapacity != types.ReturnConsumedCapacityIndexes {
+		return consumedCapacity
+	}
+
+	if indexName == nil {
+		consumedCapacity.Table = &types.Capacity{CapacityUnits: aws.Float64(units)}
+		return consumedCapacity
+	}
+	consumedCapacity.Table = &types.Capacity{CapacityUnits: aws.Float64(0)}
+	indexCapacity := map[string]types.Capacity{
+		*indexName: {CapacityUnits: aws.Float64(units)},
+	}
+	if _, ok := table.GetLocalSecondaryIndexSetting(*indexName); ok {
+		consumedCapacity.LocalSecondaryIndexes = indexCapacity
+	} else {
+		consumedCapacity.GlobalSecondaryIndexes = indexCapacity
+	}
+	return consumedCapacity
+}
+
+// buildWriteConsumedCapacity returns the ConsumedCapacity of a write replacing oldEntry with newEntry on table, or nil
+// when the caller doesn't ask for it. Either entry is nil when the item doesn't exist before or after the write. The
+// table consumes the units of the larger entry and every GSI and LSI the write changes consumes the units of its
+// entries, CapacityUnits is the sum of both
+func buildWriteConsumedCapacity(returnConsumedCapacity types.ReturnConsumedCapacity, table *core.TableMetaData, oldEntry *core.Entry, newEntry *core.Entry) *types.ConsumedCapacity {
+	if returnConsumedCapacity != types.ReturnConsumedCapacityTotal && returnConsumedCapacity != types.ReturnConsumedCapacityIndexes {
+		return nil
+	}
+
+	tableUnits := writeCapacityUnits(max(entrySize(oldEntry), entrySize(newEntry)))
+	units := tableUnits
+	globalSecondaryIndexes := make(map[string]types.Capacity)
+	for _, gsi := range table.GlobalSecondaryIndexSettings {
+		if indexUnits := indexWriteCapacityUnits(table, gsi, oldEntry, newEntry); indexUnits > 0 {
+			globalSecondaryIndexes[*gsi.IndexName] = types.Capacity{CapacityUnits: aws.Float64(indexUnits)}
+			units += indexUnits
+		}
+	}
+	localSecondaryIndexes := make(map[string]types.Capacity)
+	for _, lsi := range table.LocalSecondaryIndexSettings {
+		if indexUnits := indexWriteCapacityUnits(table, lsi, oldEntry, newEntry); indexUnits > 0 {
+			localSecondaryIndexes[*lsi.IndexName] = types.Capacity{CapacityUnits: aws.Float64(indexUnits)}
+			units += indexUnits
+		}
+	}
+
+	consumedCapacity := &types.ConsumedCapacity{
+		TableName:     aws.String(table.Name),
+		CapacityUnits: aws.Float64(units),
+	}
+	if returnConsumedCapacity != types.ReturnConsumedCapacityIndexes {
+		return consumedCapacity
+	}
+
+	consumedCapacity.Table = &types.Capacity{CapacityUnits: aws.Float64(tableUnits)}
+	if len(globalSecondaryIndexes) > 0 {
+		consumedCapacity.GlobalSecondaryIndexes = globalSecondaryIndexes
+	}
+	if len(localSecondaryIndexes) > 0 {
+		consumedCapacity.LocalSecondaryIndexes = localSecondaryIndexes
+	}
+	return consumedCapacity
+}
+
+// indexWriteCapacityUnits estimates the WCUs the index consumes when oldEntry is replaced by newEntry. Writing an item
+// into or out of the index costs one write, changing its index key costs two: the old index entry is deleted and the
+// new one is put. Changing no attribute projected into the index costs nothing
+func indexWriteCapacityUnits(table *core.TableMetaData, index core.GlobalSecondaryIndexSetting, oldEntry *core.Entry, newEntry *core.Entry) float64 {
+	oldIndexEntry := indexProjection(table, index, oldEntry)
+	newIndexEntry := indexProjection(table, index, newEntry)
+	switch {
+	case oldIndexEntry == nil && newIndexEntry == nil:
+		return 0
+	case oldIndexEntry == nil:
+		return writeCapacityUnits(newIndexEntry.Size())
+	case newIndexEntry == nil:
+		return writeCapacityUnits(oldIndexEntry.Size())
+	}
+
+	keyChanged := false
+	for _, keySchema := range []*core.KeySchema{index.PartitionKeySchema, index.SortKeySchema} {
+		if keySchema != nil && !oldIndexEntry.Body[keySchema.AttributeName].Equal(newIndexEntry.Body[keySchema.AttributeName]) {
+			keyChanged = true
+		}
+	}
+	if keyChanged {
+		return writeCapacityUnits(oldIndexEntry.Size()) + writeCapacityUnits(newIndexEntry.Size())
+	}
+	if entriesEqual(oldIndexEntry, newIndexEntry) {
+		return 0
+	}
+	return writeCapacityUnits(max(oldIndexEntry.Size(), newIndexEntry.Size()))
+}
+
+// indexProjection returns the attributes of entry the index holds, or nil when entry is nil or isn't in the index
+// because it lacks a key attribute of the index
+func indexProjection(table *core.TableMetaData, index core.GlobalSecondaryIndexSetting, entry *core.Entry) *core.Entry {
+	if entry == nil {
+		return nil
+	}
+
+	projection := &core.Entry{Body: make(map[string]core.AttributeValue)}
+	for _, keySchema := range []*core.KeySchema{index.PartitionKeySchema, index.SortKeySchema, table.PartitionKeySchema, table.SortKeySchema} {
+		if keySchema == nil {
+			continue
+		}
+		val, ok := entry.Body[keySchema.AttributeName]
+		if !ok {
+			return nil
+		}
+		projection.Body[keySchema.AttributeName] = val
+	}
+
+	switch index.ProjectionType {
+	case core.PROJECTION_TYPE_ALL:
+		return entry
+	case core.PROJECTION_TYPE_INCLUDE:
+		for _, attr := range index.NonKeyAttributes {
+			if val, ok := entry.Body[attr]; ok {
+				projection.Body[attr] = val
+			}
+		}
+	}
+	return projection
+}
+
+func entriesEqual(a *core.Entry, b *core.Entry) bool {
+	if len(a.Body) != len(b.Body) {
+		return false
+	}
+	for key, val := range a.Body {
+		other, ok := b.Body[key]
+		if !ok || !val.Equal(other) {
+			return false
+		}
+	}
+	return true
+}
+
+func entrySize(entry *core.Entry) int {
+	if entry == nil {
+		return 0
+	}
+	return entry.Size()
+}
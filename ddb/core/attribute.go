@@ -19,6 +19,19 @@ const (
 	ScalarAttributeTypeS
 )
 
+func (t ScalarAttributeType) String() string {
+	switch t {
+	case ScalarAttributeTypeB:
+		return "B"
+	case ScalarAttributeTypeN:
+		return "N"
+	case ScalarAttributeTypeS:
+		return "S"
+	default:
+		return "unknown"
+	}
+}
+
 func GetScalarAttributeType(def types.AttributeDefinition) (ScalarAttributeType, error) {
 	switch def.AttributeType {
 	case types.ScalarAttributeTypeB:
@@ -33,9 +46,9 @@ func GetScalarAttributeType(def types.AttributeDefinition) (ScalarAttributeType,
 }
 
 type AttributeValue struct {
-	B    *[]byte `json:",omitempty"`
-	BOOL *bool   `json:",omitempty"`
-	// BS
+	B    *[]byte                    `json:",omitempty"`
+	BOOL *bool                      `json:",omitempty"`
+	BS   *[][]byte                  `json:",omitempty"`
 	L    *[]AttributeValue          `json:",omitempty"`
 	M    *map[string]AttributeValue `json:",omitempty"`
 	N    *string                    `json:",omitempty"`
@@ -50,6 +63,8 @@ func (a AttributeValue) Type() string {
 		return "B"
 	} else if a.BOOL != nil {
 		return "BOOL"
+	} else if a.BS != nil {
+		return "BS"
 	} else if a.L != nil {
 		return "L"
 	} else if a.M != nil {
@@ -91,6 +106,8 @@ func (a AttributeValue) Bytes() []byte {
 		} else {
 			return []byte{0}
 		}
+	} else if a.BS != nil {
+		panic("can't convert BS to bytes")
 	} else if a.L != nil {
 		panic("can't convert L to bytes")
 	} else if a.M != nil {
@@ -115,6 +132,15 @@ func (a AttributeValue) String() string {
 		return fmt.Sprintf("B=%s", *a.B)
 	} else if a.BOOL != nil {
 		return fmt.Sprintf("BOOL=%t", *a.BOOL)
+	} else if a.BS != nil {
+		var b strings.Builder
+		b.WriteString("BS=[")
+		for _, v := range *a.BS {
+			b.WriteString(fmt.Sprintf("%s", v))
+			b.WriteString(",")
+		}
+		b.WriteString("]")
+		return b.String()
 	} else if a.L != nil {
 		var b strings.Builder
 		b.WriteString("L=[")
@@ -162,12 +188,14 @@ func (a AttributeValue) String() string {
 	panic("unreachable")
 }
 
-func (a AttributeValue) BeginsWith(prefix string) (bool, error) {
-	if a.S != nil {
-		return strings.HasPrefix(*a.S, prefix), nil
+func (a AttributeValue) BeginsWith(prefix AttributeValue) (bool, error) {
+	if a.S != nil && prefix.S != nil {
+		return strings.HasPrefix(*a.S, *prefix.S), nil
+	} else if a.B != nil && prefix.B != nil {
+		return bytes.HasPrefix(*a.B, *prefix.B), nil
 	}
 
-	return false, fmt.Errorf("can't perform BeginsWith with type %s", a.Type())
+	return false, fmt.Errorf("can't perform BeginsWith with type %s and %s", a.Type(), prefix.Type())
 }
 
 func (a AttributeValue) Compare(other AttributeValue) (int, error) {
@@ -189,6 +217,8 @@ func (a AttributeValue) Compare(other AttributeValue) (int, error) {
 		} else {
 			return -1, nil
 		}
+	} else if a.BS != nil {
+		return -1, errors.New("can't compare BS")
 	} else if a.L != nil {
 		return -1, errors.New("can't compare L")
 	} else if a.M != nil {
@@ -248,6 +278,13 @@ func (a AttributeValue) Equal(other AttributeValue) bool {
 		}
 
 		return *a.BOOL == *other.BOOL
+	} else if a.BS != nil {
+		if other.BS == nil {
+			return false
+		}
+		return setEqual(binarySetElements(*a.BS), binarySetElements(*other.BS), func(x string, y string) bool {
+			return x == y
+		})
 	} else if a.L != nil {
 		if other.L == nil {
 			return false
@@ -284,18 +321,19 @@ func (a AttributeValue) Equal(other AttributeValue) bool {
 		if other.N == nil {
 			return false
 		}
-		return *a.N == *other.N
+		// numbers are equal by value, so 1.0 equals 1.00
+		compared, err := a.Compare(other)
+		if err != nil {
+			return *a.N == *other.N
+		}
+		return compared == 0
 	} else if a.NS != nil {
 		if other.NS == nil {
 			return false
 		}
-		for i, v := range *a.NS {
-			v2 := (*other.NS)[i]
-			if v != v2 {
-				return false
-			}
-		}
-		return true
+		return setEqual(*a.NS, *other.NS, func(x string, y string) bool {
+			return AttributeValue{N: &x}.Equal(AttributeValue{N: &y})
+		})
 	} else if a.NULL != nil {
 		if other.NULL == nil {
 			return false
@@ -311,16 +349,40 @@ func (a AttributeValue) Equal(other AttributeValue) bool {
 		if other.SS == nil {
 			return false
 		}
-		for i, v := range *a.SS {
-			v2 := (*other.SS)[i]
-			if v != v2 {
-				return false
+		return setEqual(*a.SS, *other.SS, func(x string, y string) bool {
+			return x == y
+		})
+	}
+
+	panic("unreachable")
+}
+
+// setEqual ignores the order of elements, a set doesn't keep one
+func setEqual(a []string, b []string, equal func(x string, y string) bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, x := range a {
+		found := false
+		for _, y := range b {
+			if equal(x, y) {
+				found = true
+				break
 			}
 		}
-		return true
+		if !found {
+			return false
+		}
 	}
+	return true
+}
 
-	panic("unreachable")
+func binarySetElements(bs [][]byte) []string {
+	elements := make([]string, len(bs))
+	for i, b := range bs {
+		elements[i] = string(b)
+	}
+	return elements
 }
 
 func (a AttributeValue) Clone() AttributeValue {
@@ -333,6 +395,14 @@ func (a AttributeValue) Clone() AttributeValue {
 	} else if a.BOOL != nil {
 		b := *a.BOOL
 		clonedVal.BOOL = &b
+	} else if a.BS != nil {
+		bs := make([][]byte, len(*a.BS))
+		for i, v := range *a.BS {
+			b := make([]byte, len(v))
+			copy(b, v)
+			bs[i] = b
+		}
+		clonedVal.BS = &bs
 	} else if a.L != nil {
 		l := make([]AttributeValue, len(*a.L))
 		for i, v := range *a.L {
@@ -368,11 +438,67 @@ func (a AttributeValue) Clone() AttributeValue {
 	return clonedVal
 }
 
+// Size approximates the bytes DynamoDB counts for the value, see
+// https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/CapacityUnitCalculations.html
+func (a AttributeValue) Size() int {
+	if a.B != nil {
+		return len(*a.B)
+	} else if a.BOOL != nil || a.NULL != nil {
+		return 1
+	} else if a.BS != nil {
+		size := 0
+		for _, b := range *a.BS {
+			size += len(b)
+		}
+		return size
+	} else if a.L != nil {
+		size := 3
+		for _, v := range *a.L {
+			size += v.Size() + 1
+		}
+		return size
+	} else if a.M != nil {
+		size := 3
+		for k, v := range *a.M {
+			size += len(k) + v.Size() + 1
+		}
+		return size
+	} else if a.N != nil {
+		return numberSize(*a.N)
+	} else if a.NS != nil {
+		size := 0
+		for _, n := range *a.NS {
+			size += numberSize(n)
+		}
+		return size
+	} else if a.S != nil {
+		return len(*a.S)
+	} else if a.SS != nil {
+		size := 0
+		for _, s := range *a.SS {
+			size += len(s)
+		}
+		return size
+	}
+	return 0
+}
+
+// numberSize is 1 byte per two significant digits plus 1 byte
+func numberSize(n string) int {
+	if i := strings.IndexAny(n, "eE"); i >= 0 {
+		n = n[:i]
+	}
+	digits := strings.Trim(strings.Replace(strings.TrimLeft(n, "+-"), ".", "", 1), "0")
+	return (len(digits)+1)/2 + 1
+}
+
 func (a AttributeValue) ToDdbAttributeValue() types.AttributeValue {
 	if a.B != nil {
 		return &types.AttributeValueMemberB{Value: *a.B}
 	} else if a.BOOL != nil {
 		return &types.AttributeValueMemberBOOL{Value: *a.BOOL}
+	} else if a.BS != nil {
+		return &types.AttributeValueMemberBS{Value: *a.BS}
 	} else if a.L != nil {
 		vals := make([]types.AttributeValue, len(*a.L))
 		for i, v := range *a.L {
@@ -433,6 +559,11 @@ func TransformDdbAttributeValue(val types.AttributeValue) (AttributeValue, error
 		return AttributeValue{
 			BOOL: &b.Value,
 		}, nil
+	case *types.AttributeValueMemberBS:
+		bs := val.(*types.AttributeValueMemberBS)
+		return AttributeValue{
+			BS: &bs.Value,
+		}, nil
 	case *types.AttributeValueMemberL:
 		l := val.(*types.AttributeValueMemberL)
 		list := make([]AttributeValue, len(l.Value))
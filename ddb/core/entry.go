@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"time"
 )
 
 type Entry struct {
@@ -21,6 +22,36 @@ func (e *Entry) Clone() *Entry {
 	}
 }
 
+// IsExpired is true when the Number at timeToLiveAttributeName, as epoch seconds, is before now.
+// An entry without the attribute or with a non Number one never expires.
+func (e *Entry) IsExpired(timeToLiveAttributeName string, now time.Time) bool {
+	val, ok := e.Body[timeToLiveAttributeName]
+	if !ok || val.N == nil {
+		return false
+	}
+	expireAt, err := strconv.ParseFloat(*val.N, 64)
+	if err != nil {
+		return false
+	}
+	return expireAt < float64(now.Unix())
+}
+
+// MAX_ITEM_SIZE_BYTES is the 400KB DynamoDB allows for an item
+const MAX_ITEM_SIZE_BYTES = 400 * 1024
+
+// Size is the sum of the attribute names and values with the overhead of each type, the way DynamoDB sizes an
+// item and limits it to MAX_ITEM_SIZE_BYTES, see AttributeValue.Size
+func (e *Entry) Size() int {
+	size := 0
+	for key, val := range e.Body {
+		size += len(key) + val.Size()
+	}
+	return size
+}
+
+// ErrPathNotFound is returned by Get when the path doesn't resolve to an attribute of the entry
+var ErrPathNotFound = errors.New("path not found")
+
 func (e *Entry) Get(path PathOperand) (AttributeValue, error) {
 	return getValueFromPath(e.Body, path)
 }
@@ -31,7 +62,7 @@ func getValueFromPath(entry map[string]AttributeValue, path PathOperand) (Attrib
 		key := path.Name
 		val, ok := entry[key]
 		if !ok {
-			return AttributeValue{}, fmt.Errorf("key %s not found", key)
+			return AttributeValue{}, fmt.Errorf("key %s not found: %w", key, ErrPathNotFound)
 		}
 		return val, nil
 	case *IndexOperand:
@@ -40,11 +71,11 @@ func getValueFromPath(entry map[string]AttributeValue, path PathOperand) (Attrib
 			return AttributeValue{}, err
 		}
 		if leftVal.L == nil {
-			return AttributeValue{}, fmt.Errorf("operand is not a list")
+			return AttributeValue{}, fmt.Errorf("operand is not a list: %w", ErrPathNotFound)
 		}
 		list := *leftVal.L
 		if path.Index < 0 || path.Index >= len(list) {
-			return AttributeValue{}, fmt.Errorf("index out of range")
+			return AttributeValue{}, fmt.Errorf("index out of range: %w", ErrPathNotFound)
 		}
 		return list[path.Index], nil
 	case *DotOperand:
@@ -53,7 +84,7 @@ func getValueFromPath(entry map[string]AttributeValue, path PathOperand) (Attrib
 			return AttributeValue{}, err
 		}
 		if leftVal.M == nil {
-			return AttributeValue{}, fmt.Errorf("operand is not a map")
+			return AttributeValue{}, fmt.Errorf("operand is not a map: %w", ErrPathNotFound)
 		}
 		return getValueFromPath(*leftVal.M, path.Right)
 	default:
@@ -153,9 +184,14 @@ func removeAttribute(m map[string]AttributeValue, path PathOperand) error {
 
 }
 
+// ErrIncorrectOperandType is returned when ADD or DELETE targets an attribute of a type the operand can't apply to
+var ErrIncorrectOperandType = errors.New("An operand in the update expression has an incorrect data type")
+
+// Add adds a number to a number attribute, a missing attribute counts as 0, or unions a set into a set attribute of
+// the same type, a missing attribute counts as the empty set
 func (e *Entry) Add(path *AttributeNameOperand, val AttributeValue) error {
+	currentVal, ok := e.Body[path.Name]
 	if val.N != nil {
-		currentVal, ok := e.Body[path.Name]
 		if !ok {
 			e.Body[path.Name] = val
 		} else if currentVal.N != nil {
@@ -175,119 +211,149 @@ func (e *Entry) Add(path *AttributeNameOperand, val AttributeValue) error {
 				N: &newVal,
 			}
 		} else {
-			return errors.New("An operand in the update expression has an incorrect data type")
+			return ErrIncorrectOperandType
 		}
 	} else if val.SS != nil {
-		currentVal, ok := e.Body[path.Name]
 		if !ok {
 			e.Body[path.Name] = val
 		} else if currentVal.SS != nil {
-			ss := make(map[string]bool)
-			for _, v := range *currentVal.SS {
-				ss[v] = true
-			}
-			for _, v := range *val.SS {
-				ss[v] = true
-			}
-			newVal := make([]string, 0)
-			for k := range ss {
-				newVal = append(newVal, k)
-			}
-			sort.Strings(newVal)
-
+			newVal := unionStrings(*currentVal.SS, *val.SS)
 			e.Body[path.Name] = AttributeValue{
 				SS: &newVal,
 			}
 		} else {
-			return errors.New("An operand in the update expression has an incorrect data type")
+			return ErrIncorrectOperandType
 		}
 	} else if val.NS != nil {
-		currentVal, ok := e.Body[path.Name]
 		if !ok {
 			e.Body[path.Name] = val
 		} else if currentVal.NS != nil {
-			ss := make(map[string]bool)
-			for _, v := range *currentVal.NS {
-				ss[v] = true
-			}
-			for _, v := range *val.NS {
-				ss[v] = true
+			newVal := unionStrings(*currentVal.NS, *val.NS)
+			e.Body[path.Name] = AttributeValue{
+				NS: &newVal,
 			}
-			newVal := make([]string, 0)
-			for k := range ss {
-				newVal = append(newVal, k)
+		} else {
+			return ErrIncorrectOperandType
+		}
+	} else if val.BS != nil {
+		if !ok {
+			e.Body[path.Name] = val
+		} else if currentVal.BS != nil {
+			newVal := make([][]byte, 0)
+			for _, s := range unionStrings(bytesToStrings(*currentVal.BS), bytesToStrings(*val.BS)) {
+				newVal = append(newVal, []byte(s))
 			}
-			sort.Strings(newVal)
-
 			e.Body[path.Name] = AttributeValue{
-				NS: &newVal,
+				BS: &newVal,
 			}
 		} else {
-			return errors.New("An operand in the update expression has an incorrect data type")
+			return ErrIncorrectOperandType
 		}
 	} else {
-		return fmt.Errorf("Incorrect operand type for operator or function; operator: ADD, operand type: %s, typeSet: ALLOWED_FOR_ADD_OPERAND", val.Type())
+		return &InvalidUpdateExpressionError{
+			RawErr: fmt.Errorf("Incorrect operand type for operator or function; operator: ADD, operand type: %s, typeSet: ALLOWED_FOR_ADD_OPERAND", val.Type()),
+		}
 	}
 
 	return nil
 }
 
+// unionStrings is the sorted union of the elements of a and b
+func unionStrings(a []string, b []string) []string {
+	ss := make(map[string]bool)
+	for _, v := range a {
+		ss[v] = true
+	}
+	for _, v := range b {
+		ss[v] = true
+	}
+	union := make([]string, 0)
+	for k := range ss {
+		union = append(union, k)
+	}
+	sort.Strings(union)
+	return union
+}
+
+func bytesToStrings(bs [][]byte) []string {
+	ss := make([]string, len(bs))
+	for i, b := range bs {
+		ss[i] = string(b)
+	}
+	return ss
+}
+
+// Delete removes the elements of a set from a set attribute of the same type, the attribute is removed when no element
+// is left. Deleting from a missing attribute does nothing
 func (e *Entry) Delete(path *AttributeNameOperand, val AttributeValue) error {
+	currentVal, ok := e.Body[path.Name]
 	if val.SS != nil {
-		currentVal, ok := e.Body[path.Name]
 		if !ok {
 			// no op
 		} else if currentVal.SS != nil {
-			ss := make(map[string]bool)
-			for _, v := range *currentVal.SS {
-				ss[v] = true
-			}
-			for _, v := range *val.SS {
-				delete(ss, v)
-			}
-
-			newVal := make([]string, 0)
-			for k := range ss {
-				newVal = append(newVal, k)
-			}
-			sort.Strings(newVal)
-
+			newVal := subtractStrings(*currentVal.SS, *val.SS)
 			e.Body[path.Name] = AttributeValue{
 				SS: &newVal,
 			}
 		} else {
-			return errors.New("An operand in the update expression has an incorrect data type")
+			return ErrIncorrectOperandType
 		}
-
 	} else if val.NS != nil {
-		currentVal, ok := e.Body[path.Name]
 		if !ok {
 			// no op
 		} else if currentVal.NS != nil {
-			ss := make(map[string]bool)
-			for _, v := range *currentVal.NS {
-				ss[v] = true
-			}
-			for _, v := range *val.NS {
-				delete(ss, v)
+			newVal := subtractStrings(*currentVal.NS, *val.NS)
+			e.Body[path.Name] = AttributeValue{
+				NS: &newVal,
 			}
-
-			newVal := make([]string, 0)
-			for k := range ss {
-				newVal = append(newVal, k)
+		} else {
+			return ErrIncorrectOperandType
+		}
+	} else if val.BS != nil {
+		if !ok {
+			// no op
+		} else if currentVal.BS != nil {
+			newVal := make([][]byte, 0)
+			for _, s := range subtractStrings(bytesToStrings(*currentVal.BS), bytesToStrings(*val.BS)) {
+				newVal = append(newVal, []byte(s))
 			}
-			sort.Strings(newVal)
-
 			e.Body[path.Name] = AttributeValue{
-				NS: &newVal,
+				BS: &newVal,
 			}
 		} else {
-			return errors.New("An operand in the update expression has an incorrect data type")
+			return ErrIncorrectOperandType
 		}
 	} else {
-		return fmt.Errorf("Incorrect operand type for operator or function; operator: DELETE, operand type: %s, typeSet: ALLOWED_FOR_DELETE_OPERAND", val.Type())
+		return &InvalidUpdateExpressionError{
+			RawErr: fmt.Errorf("Incorrect operand type for operator or function; operator: DELETE, operand type: %s, typeSet: ALLOWED_FOR_DELETE_OPERAND", val.Type()),
+		}
+	}
 
+	// DynamoDB has no empty sets
+	if newVal, ok := e.Body[path.Name]; ok && isEmptySet(newVal) {
+		delete(e.Body, path.Name)
 	}
 
 	return nil
 }
+
+func isEmptySet(val AttributeValue) bool {
+	return (val.SS != nil && len(*val.SS) == 0) || (val.NS != nil && len(*val.NS) == 0) || (val.BS != nil && len(*val.BS) == 0)
+}
+
+// subtractStrings is the sorted elements of a that aren't in b
+func subtractStrings(a []string, b []string) []string {
+	ss := make(map[string]bool)
+	for _, v := range a {
+		ss[v] = true
+	}
+	for _, v := range b {
+		delete(ss, v)
+	}
+	difference := make([]string, 0)
+	for k := range ss {
+		difference = append(difference, k)
+	}
+	sort.Strings(difference)
+	return difference
+}
@@ -63,3 +63,23 @@ func TestEntrySet(t *testing.T) {
 		t.Fatalf("expected %v, got %v", "new value", *entry.Body["foo"].S)
 	}
 }
+
+func TestEntrySize(t *testing.T) {
+	list := []AttributeValue{
+		{S: aws.String("ab")},
+		{BOOL: aws.Bool(true)},
+	}
+	entry := &Entry{
+		Body: map[string]AttributeValue{
+			"name":  {S: aws.String("hello")},
+			"price": {N: aws.String("-012.3400")},
+			"tags":  {L: &list},
+		},
+	}
+
+	// name: 4 + 5, price: 5 + (4 significant digits / 2 + 1), tags: 4 + 3 + (2 + 1) + (1 + 1)
+	expected := 9 + 8 + 12
+	if entry.Size() != expected {
+		t.Fatalf("Expected size %d, got %d", expected, entry.Size())
+	}
+}
@@ -9,3 +9,43 @@ type InvalidConditionExpressionError struct {
 func (e *InvalidConditionExpressionError) Error() string {
 	return fmt.Sprintf("Invalid ConditionExpression: %v", e.RawErr)
 }
+
+func (e *InvalidConditionExpressionError) Unwrap() error {
+	return e.RawErr
+}
+
+type InvalidFilterExpressionError struct {
+	RawErr error
+}
+
+func (e *InvalidFilterExpressionError) Error() string {
+	return fmt.Sprintf("Invalid FilterExpression: %v", e.RawErr)
+}
+
+func (e *InvalidFilterExpressionError) Unwrap() error {
+	return e.RawErr
+}
+
+type InvalidUpdateExpressionError struct {
+	RawErr error
+}
+
+func (e *InvalidUpdateExpressionError) Error() string {
+	return fmt.Sprintf("Invalid UpdateExpression: %v", e.RawErr)
+}
+
+func (e *InvalidUpdateExpressionError) Unwrap() error {
+	return e.RawErr
+}
+
+type InvalidProjectionExpressionError struct {
+	RawErr error
+}
+
+func (e *InvalidProjectionExpressionError) Error() string {
+	return fmt.Sprintf("Invalid ProjectionExpression: %v", e.RawErr)
+}
+
+func (e *InvalidProjectionExpressionError) Unwrap() error {
+	return e.RawErr
+}
@@ -1,5 +1,11 @@
 package core
 
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
 type GlobalSecondaryIndexSetting struct {
 	IndexName             *string
 	PartitionKeySchema    *KeySchema
@@ -7,6 +13,19 @@ type GlobalSecondaryIndexSetting struct {
 	NonKeyAttributes      []string
 	ProjectionType        ProjectionType
 	ProvisionedThroughput *ProvisionedThroughput
+	// BackfillingAt and ActiveAt are when a GSI added by UpdateTable starts backfilling and turns ACTIVE, the GSI is
+	// CREATING before ActiveAt. A nil ActiveAt is always ACTIVE
+	BackfillingAt *time.Time
+	ActiveAt      *time.Time
+}
+
+// IndexStatus is CREATING until ActiveAt and ACTIVE afterward, backfilling is true from BackfillingAt to ActiveAt
+func (gsi GlobalSecondaryIndexSetting) IndexStatus() (status types.IndexStatus, backfilling bool) {
+	now := time.Now()
+	if gsi.ActiveAt == nil || !now.Before(*gsi.ActiveAt) {
+		return types.IndexStatusActive, false
+	}
+	return types.IndexStatusCreating, gsi.BackfillingAt != nil && !now.Before(*gsi.BackfillingAt)
 }
 
 func (gsi GlobalSecondaryIndexSetting) PartitionKeyName() *string {
@@ -21,3 +40,35 @@ func (gsi GlobalSecondaryIndexSetting) SortKeyName() *string {
 
 	return nil
 }
+
+// KeySchema is the key schema of the index, the partition key comes first
+func (gsi GlobalSecondaryIndexSetting) KeySchema() []types.KeySchemaElement {
+	keySchema := []types.KeySchemaElement{{
+		AttributeName: &gsi.PartitionKeySchema.AttributeName,
+		KeyType:       types.KeyTypeHash,
+	}}
+	if gsi.SortKeySchema != nil {
+		keySchema = append(keySchema, types.KeySchemaElement{
+			AttributeName: &gsi.SortKeySchema.AttributeName,
+			KeyType:       types.KeyTypeRange,
+		})
+	}
+	return keySchema
+}
+
+func (gsi GlobalSecondaryIndexSetting) Projection() types.Projection {
+	projectionType := types.ProjectionTypeAll
+	switch gsi.ProjectionType {
+	case PROJECTION_TYPE_KEYS_ONLY:
+		projectionType = types.ProjectionTypeKeysOnly
+	case PROJECTION_TYPE_INCLUDE:
+		projectionType = types.ProjectionTypeInclude
+	case PROJECTION_TYPE_ALL:
+		projectionType = types.ProjectionTypeAll
+	}
+
+	return types.Projection{
+		NonKeyAttributes: gsi.NonKeyAttributes,
+		ProjectionType:   projectionType,
+	}
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+)
+
+// EncodePrimaryKey builds the composite primary key stored in sqlite. The partition key is prefixed with its length,
+// so pk: ab, sk: |cd and pk: ab|, sk: cd don't collide, and keys of the same partition are ordered by their sort key.
+func EncodePrimaryKey(partitionKey []byte, sortKey []byte) []byte {
+	bs := make([]byte, 0, binary.MaxVarintLen64+len(partitionKey)+len(sortKey))
+	bs = binary.AppendUvarint(bs, uint64(len(partitionKey)))
+	bs = append(bs, partitionKey...)
+	bs = append(bs, sortKey...)
+
+	return bs
+}
+
+// EncodeSortKey encodes a sort key so that comparing the bytes orders keys the way DynamoDB does.
+// S and B are ordered by their bytes already, N is encoded by encodeNumberSortKey.
+func EncodeSortKey(val AttributeValue) []byte {
+	if val.N != nil {
+		return encodeNumberSortKey(*val.N)
+	}
+	return val.Bytes()
+}
+
+const (
+	sortKeyNegative = 0x00
+	sortKeyZero     = 0x01
+	sortKeyPositive = 0x02
+)
+
+// encodeNumberSortKey normalizes n to 0.d1d2...dn * 10^exponent, without leading and trailing zeros, and encodes
+// it as a sign byte, the biased exponent and the digits. The exponent and digits of a negative number are
+// complemented and terminated by 0xFF, so a larger magnitude is ordered first and -0.12 is after -0.123
+func encodeNumberSortKey(n string) []byte {
+	negative := strings.HasPrefix(n, "-")
+	n = strings.TrimLeft(n, "+-")
+
+	exponent := 0
+	if i := strings.IndexAny(n, "eE"); i >= 0 {
+		exponent, _ = strconv.Atoi(n[i+1:])
+		n = n[:i]
+	}
+	integer, fraction, _ := strings.Cut(n, ".")
+	digits := integer + fraction
+	exponent += len(integer)
+
+	trimmed := strings.TrimLeft(digits, "0")
+	exponent -= len(digits) - len(trimmed)
+	digits = strings.TrimRight(trimmed, "0")
+	if digits == "" {
+		return []byte{sortKeyZero}
+	}
+
+	biasedExponent := uint16(exponent + 1<<15)
+	if !negative {
+		bs := []byte{sortKeyPositive}
+		bs = binary.BigEndian.AppendUint16(bs, biasedExponent)
+		return append(bs, digits...)
+	}
+
+	bs := []byte{sortKeyNegative}
+	bs = binary.BigEndian.AppendUint16(bs, ^biasedExponent)
+	for i := 0; i < len(digits); i++ {
+		bs = append(bs, ^digits[i])
+	}
+	return append(bs, 0xFF)
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestEncodeSortKeyOrdersNumbers(t *testing.T) {
+	// in ascending order
+	numbers := []string{"-1e3", "-100", "-20", "-10", "-2.5", "-2", "-0.123", "-0.12", "0", "0.001", "0.12", "0.123", "1", "2", "10", "20", "100", "1e3", "123456789012345678901234567890"}
+	for i := 1; i < len(numbers); i++ {
+		prev := EncodeSortKey(AttributeValue{N: aws.String(numbers[i-1])})
+		curr := EncodeSortKey(AttributeValue{N: aws.String(numbers[i])})
+		if bytes.Compare(prev, curr) >= 0 {
+			t.Errorf("expected %s to be encoded before %s", numbers[i-1], numbers[i])
+		}
+	}
+
+	// the same number in different forms is one sort key
+	equals := [][]string{
+		{"10", "10.0", "1e1", "+10", "0010"},
+		{"0", "-0", "0.00", "0e5"},
+		{"-0.5", "-.5", "-5e-1"},
+	}
+	for _, forms := range equals {
+		expected := EncodeSortKey(AttributeValue{N: aws.String(forms[0])})
+		for _, form := range forms[1:] {
+			if !bytes.Equal(expected, EncodeSortKey(AttributeValue{N: aws.String(form)})) {
+				t.Errorf("expected %s to be encoded as %s", form, forms[0])
+			}
+		}
+	}
+}
+
+func TestEncodeSortKeyKeepsStringsAndBinaries(t *testing.T) {
+	if !bytes.Equal(EncodeSortKey(AttributeValue{S: aws.String("10")}), []byte("10")) {
+		t.Errorf("expected S to be encoded as its bytes")
+	}
+	b := []byte{0x00, 0xFF}
+	if !bytes.Equal(EncodeSortKey(AttributeValue{B: &b}), b) {
+		t.Errorf("expected B to be encoded as its bytes")
+	}
+}
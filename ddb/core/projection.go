@@ -0,0 +1,120 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+)
+
+// projectionNode is a tree of the paths to project, a leaf node projects the whole value
+type projectionNode struct {
+	leaf    bool
+	names   map[string]*projectionNode
+	indexes map[int]*projectionNode
+}
+
+func newProjectionNode() *projectionNode {
+	return &projectionNode{
+		names:   make(map[string]*projectionNode),
+		indexes: make(map[int]*projectionNode),
+	}
+}
+
+func (n *projectionNode) insert(path PathOperand) (*projectionNode, error) {
+	switch path := path.(type) {
+	case *AttributeNameOperand:
+		child, ok := n.names[path.Name]
+		if !ok {
+			child = newProjectionNode()
+			n.names[path.Name] = child
+		}
+		return child, nil
+	case *IndexOperand:
+		left, err := n.insert(path.Left)
+		if err != nil {
+			return nil, err
+		}
+		child, ok := left.indexes[path.Index]
+		if !ok {
+			child = newProjectionNode()
+			left.indexes[path.Index] = child
+		}
+		return child, nil
+	case *DotOperand:
+		left, err := n.insert(path.Left)
+		if err != nil {
+			return nil, err
+		}
+		return left.insert(path.Right)
+	default:
+		return nil, fmt.Errorf("unknown path operand type: %T", path)
+	}
+}
+
+func (n *projectionNode) projectMap(m map[string]AttributeValue) map[string]AttributeValue {
+	projected := make(map[string]AttributeValue)
+	for name, child := range n.names {
+		val, ok := m[name]
+		if !ok {
+			continue
+		}
+		if projectedVal, ok := child.project(val); ok {
+			projected[name] = projectedVal
+		}
+	}
+	return projected
+}
+
+func (n *projectionNode) project(val AttributeValue) (AttributeValue, bool) {
+	if n.leaf {
+		return val.Clone(), true
+	}
+
+	if val.M != nil && len(n.names) > 0 {
+		projected := n.projectMap(*val.M)
+		if len(projected) == 0 {
+			return AttributeValue{}, false
+		}
+		return AttributeValue{M: &projected}, true
+	}
+
+	if val.L != nil && len(n.indexes) > 0 {
+		indexes := make([]int, 0, len(n.indexes))
+		for index := range n.indexes {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+
+		projected := make([]AttributeValue, 0, len(indexes))
+		for _, index := range indexes {
+			if index < 0 || index >= len(*val.L) {
+				continue
+			}
+			if projectedVal, ok := n.indexes[index].project((*val.L)[index]); ok {
+				projected = append(projected, projectedVal)
+			}
+		}
+		if len(projected) == 0 {
+			return AttributeValue{}, false
+		}
+		return AttributeValue{L: &projected}, true
+	}
+
+	return AttributeValue{}, false
+}
+
+// Project returns a new entry with only the attributes at paths, nested attributes keep their parent maps,
+// and list elements keep their relative order with the list compacted. Paths not found in the entry are skipped.
+func (e *Entry) Project(paths []PathOperand) (*Entry, error) {
+	root := newProjectionNode()
+	for _, path := range paths {
+		node, err := root.insert(path)
+		if err != nil {
+			return nil, err
+		}
+		node.leaf = true
+	}
+
+	return &Entry{
+		Body: root.projectMap(e.Body),
+	}, nil
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"testing"
+)
+
+func TestEntryProject(t *testing.T) {
+	entry := &Entry{
+		Body: map[string]AttributeValue{
+			"title": {S: aws.String("a movie")},
+			"info": {M: &map[string]AttributeValue{
+				"rating": {N: aws.String("9")},
+				"plot":   {S: aws.String("nothing happens")},
+			}},
+			"actors": {L: &[]AttributeValue{
+				{S: aws.String("actor0")},
+				{S: aws.String("actor1")},
+				{S: aws.String("actor2")},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		paths    []PathOperand
+		expected map[string]AttributeValue
+	}{
+		{
+			name:  "top level attributes",
+			paths: []PathOperand{&AttributeNameOperand{Name: "title"}, &AttributeNameOperand{Name: "info"}},
+			expected: map[string]AttributeValue{
+				"title": {S: aws.String("a movie")},
+				"info": {M: &map[string]AttributeValue{
+					"rating": {N: aws.String("9")},
+					"plot":   {S: aws.String("nothing happens")},
+				}},
+			},
+		},
+		{
+			name: "nested attribute keeps its parent map",
+			paths: []PathOperand{&DotOperand{
+				Left:  &AttributeNameOperand{Name: "info"},
+				Right: &AttributeNameOperand{Name: "rating"},
+			}},
+			expected: map[string]AttributeValue{
+				"info": {M: &map[string]AttributeValue{
+					"rating": {N: aws.String("9")},
+				}},
+			},
+		},
+		{
+			name: "list elements are compacted",
+			paths: []PathOperand{
+				&IndexOperand{Left: &AttributeNameOperand{Name: "actors"}, Index: 2},
+				&IndexOperand{Left: &AttributeNameOperand{Name: "actors"}, Index: 0},
+				&IndexOperand{Left: &AttributeNameOperand{Name: "actors"}, Index: 5},
+			},
+			expected: map[string]AttributeValue{
+				"actors": {L: &[]AttributeValue{
+					{S: aws.String("actor0")},
+					{S: aws.String("actor2")},
+				}},
+			},
+		},
+		{
+			name: "missing paths are skipped",
+			paths: []PathOperand{
+				&AttributeNameOperand{Name: "year"},
+				&DotOperand{
+					Left:  &AttributeNameOperand{Name: "title"},
+					Right: &AttributeNameOperand{Name: "name"},
+				},
+			},
+			expected: map[string]AttributeValue{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projected, err := entry.Project(tt.paths)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(projected.Body) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, projected.Body)
+			}
+			for key, expectedValue := range tt.expected {
+				if val, ok := projected.Body[key]; !ok || !val.Equal(expectedValue) {
+					t.Fatalf("Expected %v for key %s, got %v", expectedValue, key, val)
+				}
+			}
+		})
+	}
+}
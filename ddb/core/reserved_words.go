@@ -1,5 +1,15 @@
 package core
 
+import "strings"
+
+// IsReservedWord reports whether name is a DynamoDB reserved word, reserved words are case-insensitive,
+// so they can only be used as attribute names through ExpressionAttributeNames
+func IsReservedWord(name string) bool {
+	return ReservedWords[strings.ToUpper(name)]
+}
+
+// ReservedWords are the reserved words of DynamoDB in upper case
+// https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/ReservedWords.html
 var ReservedWords = map[string]bool{
 	"ABORT":          true,
 	"ABSOLUTE":       true,
@@ -573,5 +583,5 @@ var ReservedWords = map[string]bool{
 	"WRAPPED":        true,
 	"WRITE":          true,
 	"YEAR":           true,
-	"ZONE,":          true,
+	"ZONE":           true,
 }
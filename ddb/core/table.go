@@ -44,12 +44,32 @@ type TableMetaData struct {
 	AttributeDefinitions         []types.AttributeDefinition
 	KeySchema                    []types.KeySchemaElement
 	GlobalSecondaryIndexSettings []GlobalSecondaryIndexSetting
-	LocalSecondaryIndexes        []types.LocalSecondaryIndex
 	ProvisionedThroughput        *ProvisionedThroughput
 	CreationDateTime             *time.Time
 	PartitionKeySchema           *KeySchema
 	SortKeySchema                *KeySchema
 	BillingMode                  BillingMode
+	// LocalSecondaryIndexSettings have the PartitionKeySchema of the table and no ProvisionedThroughput
+	LocalSecondaryIndexSettings []GlobalSecondaryIndexSetting
+	// LastUpdateToPayPerRequestDateTime is when the table last switched to PAY_PER_REQUEST, nil if it never did
+	LastUpdateToPayPerRequestDateTime *time.Time
+	// ActiveAt is when the table turns ACTIVE, the table is CREATING before it. A nil ActiveAt is always ACTIVE
+	ActiveAt *time.Time
+	// TimeToLiveAttributeName is the attribute keeping the expiry time of items when TimeToLiveEnabled is true
+	TimeToLiveAttributeName string
+	TimeToLiveEnabled       bool
+	// StreamSpecification is nil when the table never had a stream, LatestStreamArn keeps the last stream after it's disabled
+	StreamSpecification *types.StreamSpecification
+	LatestStreamArn     *string
+	LatestStreamLabel   *string
+}
+
+// Status is CREATING until ActiveAt and ACTIVE afterward
+func (m *TableMetaData) Status() types.TableStatus {
+	if m.ActiveAt != nil && time.Now().Before(*m.ActiveAt) {
+		return types.TableStatusCreating
+	}
+	return types.TableStatusActive
 }
 
 func (m *TableMetaData) GetGlobalSecondaryIndexSetting(indexName string) (GlobalSecondaryIndexSetting, bool) {
@@ -61,6 +81,23 @@ func (m *TableMetaData) GetGlobalSecondaryIndexSetting(indexName string) (Global
 	return GlobalSecondaryIndexSetting{}, false
 }
 
+func (m *TableMetaData) GetLocalSecondaryIndexSetting(indexName string) (GlobalSecondaryIndexSetting, bool) {
+	for _, setting := range m.LocalSecondaryIndexSettings {
+		if setting.IndexName != nil && *setting.IndexName == indexName {
+			return setting, true
+		}
+	}
+	return GlobalSecondaryIndexSetting{}, false
+}
+
+// GetSecondaryIndexSetting finds indexName in both the GSIs and the LSIs of the table
+func (m *TableMetaData) GetSecondaryIndexSetting(indexName string) (GlobalSecondaryIndexSetting, bool) {
+	if setting, ok := m.GetGlobalSecondaryIndexSetting(indexName); ok {
+		return setting, true
+	}
+	return m.GetLocalSecondaryIndexSetting(indexName)
+}
+
 func (m *TableMetaData) FindKeySchema(attributeName string) *KeySchema {
 	if m.PartitionKeySchema != nil && m.PartitionKeySchema.AttributeName == attributeName {
 		return m.PartitionKeySchema
@@ -70,7 +107,10 @@ func (m *TableMetaData) FindKeySchema(attributeName string) *KeySchema {
 		return m.SortKeySchema
 	}
 
-	for _, index := range m.GlobalSecondaryIndexSettings {
+	indexes := make([]GlobalSecondaryIndexSetting, 0, len(m.GlobalSecondaryIndexSettings)+len(m.LocalSecondaryIndexSettings))
+	indexes = append(indexes, m.GlobalSecondaryIndexSettings...)
+	indexes = append(indexes, m.LocalSecondaryIndexSettings...)
+	for _, index := range indexes {
 		if index.PartitionKeySchema != nil && index.PartitionKeySchema.AttributeName == attributeName {
 			return &KeySchema{
 				AttributeName: index.PartitionKeySchema.AttributeName,
@@ -90,8 +130,10 @@ func (m *TableMetaData) FindKeySchema(attributeName string) *KeySchema {
 
 func (m *TableMetaData) Clone() *TableMetaData {
 	clone := &TableMetaData{
-		Name:        m.Name,
-		BillingMode: m.BillingMode,
+		Name:                    m.Name,
+		BillingMode:             m.BillingMode,
+		TimeToLiveAttributeName: m.TimeToLiveAttributeName,
+		TimeToLiveEnabled:       m.TimeToLiveEnabled,
 	}
 
 	if len(m.AttributeDefinitions) > 0 {
@@ -104,43 +146,8 @@ func (m *TableMetaData) Clone() *TableMetaData {
 		copy(clone.KeySchema, m.KeySchema)
 	}
 
-	if len(m.GlobalSecondaryIndexSettings) > 0 {
-		clone.GlobalSecondaryIndexSettings = make([]GlobalSecondaryIndexSetting, len(m.GlobalSecondaryIndexSettings))
-		for i, gsi := range m.GlobalSecondaryIndexSettings {
-			clone.GlobalSecondaryIndexSettings[i] = GlobalSecondaryIndexSetting{
-				ProjectionType: gsi.ProjectionType,
-			}
-
-			if gsi.IndexName != nil {
-				indexName := *gsi.IndexName
-				clone.GlobalSecondaryIndexSettings[i].IndexName = &indexName
-			}
-
-			if gsi.PartitionKeySchema != nil {
-				clone.GlobalSecondaryIndexSettings[i].PartitionKeySchema = &KeySchema{
-					AttributeName: gsi.PartitionKeySchema.AttributeName,
-					AttributeType: gsi.PartitionKeySchema.AttributeType,
-				}
-			}
-
-			if gsi.SortKeySchema != nil {
-				clone.GlobalSecondaryIndexSettings[i].SortKeySchema = &KeySchema{
-					AttributeName: gsi.SortKeySchema.AttributeName,
-					AttributeType: gsi.SortKeySchema.AttributeType,
-				}
-			}
-
-			if len(gsi.NonKeyAttributes) > 0 {
-				clone.GlobalSecondaryIndexSettings[i].NonKeyAttributes = make([]string, len(gsi.NonKeyAttributes))
-				copy(clone.GlobalSecondaryIndexSettings[i].NonKeyAttributes, gsi.NonKeyAttributes)
-			}
-		}
-	}
-
-	if len(m.LocalSecondaryIndexes) > 0 {
-		clone.LocalSecondaryIndexes = make([]types.LocalSecondaryIndex, len(m.LocalSecondaryIndexes))
-		copy(clone.LocalSecondaryIndexes, m.LocalSecondaryIndexes)
-	}
+	clone.GlobalSecondaryIndexSettings = cloneIndexSettings(m.GlobalSecondaryIndexSettings)
+	clone.LocalSecondaryIndexSettings = cloneIndexSettings(m.LocalSecondaryIndexSettings)
 
 	if m.ProvisionedThroughput != nil {
 		clone.ProvisionedThroughput = &ProvisionedThroughput{
@@ -154,6 +161,35 @@ func (m *TableMetaData) Clone() *TableMetaData {
 		clone.CreationDateTime = &creationTime
 	}
 
+	if m.LastUpdateToPayPerRequestDateTime != nil {
+		lastUpdateToPayPerRequestDateTime := *m.LastUpdateToPayPerRequestDateTime
+		clone.LastUpdateToPayPerRequestDateTime = &lastUpdateToPayPerRequestDateTime
+	}
+
+	if m.ActiveAt != nil {
+		activeAt := *m.ActiveAt
+		clone.ActiveAt = &activeAt
+	}
+
+	if m.StreamSpecification != nil {
+		streamSpecification := *m.StreamSpecification
+		if streamSpecification.StreamEnabled != nil {
+			streamEnabled := *streamSpecification.StreamEnabled
+			streamSpecification.StreamEnabled = &streamEnabled
+		}
+		clone.StreamSpecification = &streamSpecification
+	}
+
+	if m.LatestStreamArn != nil {
+		latestStreamArn := *m.LatestStreamArn
+		clone.LatestStreamArn = &latestStreamArn
+	}
+
+	if m.LatestStreamLabel != nil {
+		latestStreamLabel := *m.LatestStreamLabel
+		clone.LatestStreamLabel = &latestStreamLabel
+	}
+
 	if m.PartitionKeySchema != nil {
 		clone.PartitionKeySchema = &KeySchema{
 			AttributeName: m.PartitionKeySchema.AttributeName,
@@ -171,8 +207,56 @@ func (m *TableMetaData) Clone() *TableMetaData {
 	return clone
 }
 
-func (m *TableMetaData) Description(itemCount int64) *types.TableDescription {
-	tableSizeBytes := itemCount * 100
+func cloneIndexSettings(settings []GlobalSecondaryIndexSetting) []GlobalSecondaryIndexSetting {
+	if len(settings) == 0 {
+		return nil
+	}
+
+	clones := make([]GlobalSecondaryIndexSetting, len(settings))
+	for i, gsi := range settings {
+		clones[i] = GlobalSecondaryIndexSetting{
+			ProjectionType: gsi.ProjectionType,
+		}
+
+		if gsi.IndexName != nil {
+			indexName := *gsi.IndexName
+			clones[i].IndexName = &indexName
+		}
+
+		if gsi.PartitionKeySchema != nil {
+			clones[i].PartitionKeySchema = &KeySchema{
+				AttributeName: gsi.PartitionKeySchema.AttributeName,
+				AttributeType: gsi.PartitionKeySchema.AttributeType,
+			}
+		}
+
+		if gsi.SortKeySchema != nil {
+			clones[i].SortKeySchema = &KeySchema{
+				AttributeName: gsi.SortKeySchema.AttributeName,
+				AttributeType: gsi.SortKeySchema.AttributeType,
+			}
+		}
+
+		if len(gsi.NonKeyAttributes) > 0 {
+			clones[i].NonKeyAttributes = make([]string, len(gsi.NonKeyAttributes))
+			copy(clones[i].NonKeyAttributes, gsi.NonKeyAttributes)
+		}
+
+		if gsi.BackfillingAt != nil {
+			backfillingAt := *gsi.BackfillingAt
+			clones[i].BackfillingAt = &backfillingAt
+		}
+
+		if gsi.ActiveAt != nil {
+			activeAt := *gsi.ActiveAt
+			clones[i].ActiveAt = &activeAt
+		}
+	}
+	return clones
+}
+
+// PrimaryKeySchema is the key schema of the table, the partition key comes first
+func (m *TableMetaData) PrimaryKeySchema() []types.KeySchemaElement {
 	keySchema := make([]types.KeySchemaElement, 0)
 
 	keySchema = append(keySchema, types.KeySchemaElement{
@@ -187,43 +271,48 @@ func (m *TableMetaData) Description(itemCount int64) *types.TableDescription {
 		})
 	}
 
+	return keySchema
+}
+
+func (m *TableMetaData) Description(itemCount int64) *types.TableDescription {
+	tableSizeBytes := itemCount * 100
+	keySchema := m.PrimaryKeySchema()
+
+	tableStatus := m.Status()
 	gsi := make([]types.GlobalSecondaryIndexDescription, 0)
 	// TODO: implement GlobalSecondaryIndexDescription
 	for _, setting := range m.GlobalSecondaryIndexSettings {
-		gsiKeySchema := make([]types.KeySchemaElement, 0)
-		gsiKeySchema = append(gsiKeySchema, types.KeySchemaElement{
-			AttributeName: &setting.PartitionKeySchema.AttributeName,
-			KeyType:       types.KeyTypeHash,
-		})
-		if setting.SortKeySchema != nil {
-			gsiKeySchema = append(gsiKeySchema, types.KeySchemaElement{
-				AttributeName: &setting.SortKeySchema.AttributeName,
-				KeyType:       types.KeyTypeRange,
-			})
+		projection := setting.Projection()
+		indexStatus, backfilling := setting.IndexStatus()
+		// the GSIs of a CREATING table are created along with it
+		if tableStatus == types.TableStatusCreating {
+			indexStatus = types.IndexStatusCreating
 		}
-
-		projectionType := types.ProjectionTypeAll
-		switch setting.ProjectionType {
-		case PROJECTION_TYPE_KEYS_ONLY:
-			projectionType = types.ProjectionTypeKeysOnly
-		case PROJECTION_TYPE_INCLUDE:
-			projectionType = types.ProjectionTypeInclude
-		case PROJECTION_TYPE_ALL:
-			projectionType = types.ProjectionTypeAll
-		}
-
-		projection := types.Projection{
-			NonKeyAttributes: setting.NonKeyAttributes,
-			ProjectionType:   projectionType,
+		var backfillingPtr *bool
+		if indexStatus == types.IndexStatusCreating {
+			backfillingPtr = &backfilling
 		}
-
 		gsi = append(gsi, types.GlobalSecondaryIndexDescription{
 			IndexName: setting.IndexName,
-			KeySchema: gsiKeySchema,
+			KeySchema: setting.KeySchema(),
 			// TODO: fix it later, GSI item count might be different from the main table, but for now, we just use the same item count
 			ItemCount:      &itemCount,
 			IndexSizeBytes: &tableSizeBytes,
 			Projection:     &projection,
+			IndexStatus:    indexStatus,
+			Backfilling:    backfillingPtr,
+		})
+	}
+
+	var lsi []types.LocalSecondaryIndexDescription
+	for _, setting := range m.LocalSecondaryIndexSettings {
+		projection := setting.Projection()
+		lsi = append(lsi, types.LocalSecondaryIndexDescription{
+			IndexName:      setting.IndexName,
+			KeySchema:      setting.KeySchema(),
+			ItemCount:      &itemCount,
+			IndexSizeBytes: &tableSizeBytes,
+			Projection:     &projection,
 		})
 	}
 
@@ -238,17 +327,36 @@ func (m *TableMetaData) Description(itemCount int64) *types.TableDescription {
 		WriteCapacityUnits: &writeCapacityUnits,
 	}
 
+	// DynamoDB omits the summary of tables which were always provisioned
+	var billingModeSummary *types.BillingModeSummary
+	if m.BillingMode == BILLING_MODE_PAY_PER_REQUEST || m.LastUpdateToPayPerRequestDateTime != nil {
+		billingMode := types.BillingModeProvisioned
+		if m.BillingMode == BILLING_MODE_PAY_PER_REQUEST {
+			billingMode = types.BillingModePayPerRequest
+		}
+		billingModeSummary = &types.BillingModeSummary{
+			BillingMode:                       billingMode,
+			LastUpdateToPayPerRequestDateTime: m.LastUpdateToPayPerRequestDateTime,
+		}
+	}
+
 	tableDescription := &types.TableDescription{
 		AttributeDefinitions:   m.AttributeDefinitions,
+		BillingModeSummary:     billingModeSummary,
 		CreationDateTime:       m.CreationDateTime,
 		KeySchema:              keySchema,
 		GlobalSecondaryIndexes: gsi,
+		LocalSecondaryIndexes:  lsi,
 
 		ProvisionedThroughput: provisionedThroughput,
 		ItemCount:             &itemCount,
 		TableName:             &m.Name,
 		TableSizeBytes:        &tableSizeBytes,
-		TableStatus:           types.TableStatusActive,
+		TableStatus:           tableStatus,
+
+		StreamSpecification: m.StreamSpecification,
+		LatestStreamArn:     m.LatestStreamArn,
+		LatestStreamLabel:   m.LatestStreamLabel,
 	}
 
 	return tableDescription
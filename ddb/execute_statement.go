@@ -0,0 +1,419 @@
+package ddb
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ocowchun/baddb/ddb/core"
+	"github.com/ocowchun/baddb/ddb/partiql"
+	"github.com/ocowchun/baddb/ddb/storage"
+)
+
+// ExecuteStatement runs a PartiQL SELECT, INSERT, UPDATE or DELETE by translating it to Query, Scan, PutItem,
+// UpdateItem or DeleteItem. A SELECT with the equality of the partition key is a Query, otherwise it's a Scan.
+// https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_ExecuteStatement.html
+func (svc *Service) ExecuteStatement(ctx context.Context, input *dynamodb.ExecuteStatementInput) (*dynamodb.ExecuteStatementOutput, error) {
+	if input.Statement == nil {
+		msg := "1 validation error detected: Value null at 'statement' failed to satisfy constraint: Member must not be null"
+		return nil, &ValidationException{Message: msg}
+	}
+
+	stmt, err := partiql.Parse(*input.Statement)
+	if err != nil {
+		msg := fmt.Sprintf("Statement wasn't well formed, can't be processed: %s", err.Error())
+		return nil, &ValidationException{Message: msg}
+	}
+	if stmt.ParameterCount() != len(input.Parameters) {
+		return nil, &ValidationException{Message: "Number of parameters in request and statement don't match."}
+	}
+
+	switch stmt := stmt.(type) {
+	case *partiql.SelectStatement:
+		return svc.executeSelect(ctx, stmt, input)
+	case *partiql.InsertStatement:
+		return svc.executeInsert(ctx, stmt, input)
+	case *partiql.UpdateStatement:
+		return svc.executeUpdate(ctx, stmt, input)
+	case *partiql.DeleteStatement:
+		return svc.executeDelete(ctx, stmt, input)
+	default:
+		return nil, fmt.Errorf("unknown statement type: %T", stmt)
+	}
+}
+
+// statementKeySchema returns the key names of the table, or the index when indexName is not nil
+func (svc *Service) statementKeySchema(tableName string, indexName *string) (string, string, error) {
+	svc.tableLock.RLock()
+	defer svc.tableLock.RUnlock()
+
+	if err := validateNotMetadataTable(tableName, "ExecuteStatement"); err != nil {
+		return "", "", err
+	}
+	table, ok := svc.tableMetadataStore[tableName]
+	if !ok {
+		msg := "Cannot do operations on a non-existent table"
+		return "", "", &types.ResourceNotFoundException{
+			Message: &msg,
+		}
+	}
+
+	partitionKeySchema, sortKeySchema := table.PartitionKeySchema, table.SortKeySchema
+	if indexName != nil {
+		gsi, ok := table.GetSecondaryIndexSetting(*indexName)
+		if !ok {
+			msg := fmt.Sprintf("The table does not have the specified index: %s", *indexName)
+			return "", "", &ValidationException{Message: msg}
+		}
+		partitionKeySchema, sortKeySchema = gsi.PartitionKeySchema, gsi.SortKeySchema
+	}
+
+	sortKey := ""
+	if sortKeySchema != nil {
+		sortKey = sortKeySchema.AttributeName
+	}
+	return partitionKeySchema.AttributeName, sortKey, nil
+}
+
+// MAX_STATEMENT_PAGE_SIZE is how many items a page of a SELECT returns at most, the rest are read with NextToken
+const MAX_STATEMENT_PAGE_SIZE = 100
+
+func (svc *Service) executeSelect(ctx context.Context, stmt *partiql.SelectStatement, input *dynamodb.ExecuteStatementInput) (*dynamodb.ExecuteStatementOutput, error) {
+	partitionKey, sortKey, err := svc.statementKeySchema(stmt.TableName, stmt.IndexName)
+	if err != nil {
+		return nil, err
+	}
+
+	exclusiveStartKey, err := decodeNextToken(input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// the projection is resolved on whole items, a ProjectionExpression would compact the lists of info.actors[1]
+	builder := partiql.NewExpressionBuilder(input.Parameters)
+
+	// a page of a SELECT has at most MAX_STATEMENT_PAGE_SIZE items, whatever Limit is
+	pageSize := int32(MAX_STATEMENT_PAGE_SIZE)
+	if input.Limit != nil && *input.Limit < pageSize {
+		pageSize = *input.Limit
+	}
+
+	var items []map[string]types.AttributeValue
+	var lastEvaluatedKey map[string]types.AttributeValue
+	partitionKeyCondition, sortKeyCondition, filter, isQuery := partiql.SplitKeyCondition(stmt.Where, partitionKey, sortKey)
+	if !isQuery {
+		filter = stmt.Where
+	}
+	var filterExpression *string
+	if filter != nil {
+		f, err := builder.Condition(filter)
+		if err != nil {
+			return nil, &ValidationException{Message: err.Error()}
+		}
+		filterExpression = &f
+	}
+
+	if isQuery {
+		keyConditionExpression, err := builder.Condition(partitionKeyCondition)
+		if err != nil {
+			return nil, &ValidationException{Message: err.Error()}
+		}
+		if sortKeyCondition != nil {
+			sortKeyConditionExpression, err := builder.Condition(sortKeyCondition)
+			if err != nil {
+				return nil, &ValidationException{Message: err.Error()}
+			}
+			keyConditionExpression = keyConditionExpression + " AND " + sortKeyConditionExpression
+		}
+
+		output, err := svc.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 &stmt.TableName,
+			IndexName:                 stmt.IndexName,
+			KeyConditionExpression:    &keyConditionExpression,
+			FilterExpression:          filterExpression,
+			ExpressionAttributeNames:  statementAttributeNames(builder),
+			ExpressionAttributeValues: statementAttributeValues(builder),
+			ConsistentRead:            input.ConsistentRead,
+			Limit:                     &pageSize,
+			ExclusiveStartKey:         exclusiveStartKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		items, lastEvaluatedKey = output.Items, output.LastEvaluatedKey
+	} else {
+		output, err := svc.Scan(ctx, &dynamodb.ScanInput{
+			TableName:                 &stmt.TableName,
+			IndexName:                 stmt.IndexName,
+			FilterExpression:          filterExpression,
+			ExpressionAttributeNames:  statementAttributeNames(builder),
+			ExpressionAttributeValues: statementAttributeValues(builder),
+			ConsistentRead:            input.ConsistentRead,
+			Limit:                     &pageSize,
+			ExclusiveStartKey:         exclusiveStartKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		items, lastEvaluatedKey = output.Items, output.LastEvaluatedKey
+	}
+
+	if stmt.Projection != nil {
+		for i, item := range items {
+			items[i] = selectedItem(item, stmt.Projection)
+		}
+	}
+	output := &dynamodb.ExecuteStatementOutput{
+		Items: items,
+	}
+	// there may be more items when the read stops at a full page
+	if len(lastEvaluatedKey) > 0 && int32(len(items)) >= pageSize {
+		nextToken, err := encodeNextToken(lastEvaluatedKey)
+		if err != nil {
+			return nil, err
+		}
+		output.NextToken = &nextToken
+		output.LastEvaluatedKey = lastEvaluatedKey
+	}
+
+	return output, nil
+}
+
+// selectedItem flattens an item read with the projection of a SELECT, each path is named after its last attribute
+// name the way DynamoDB does, SELECT info.rating returns {"rating": ...} rather than {"info": {"rating": ...}}
+func selectedItem(item map[string]types.AttributeValue, projection []partiql.Path) map[string]types.AttributeValue {
+	selected := make(map[string]types.AttributeValue, len(projection))
+	for _, path := range projection {
+		if val, ok := resolvePath(item, path); ok {
+			selected[path.Name()] = val
+		}
+	}
+	return selected
+}
+
+// resolvePath finds the value at path in item, ok is false when the item has nothing there
+func resolvePath(item map[string]types.AttributeValue, path partiql.Path) (types.AttributeValue, bool) {
+	val, ok := item[path[0].Name]
+	for _, element := range path[1:] {
+		if !ok {
+			return nil, false
+		}
+		if element.IsIndex {
+			list, isList := val.(*types.AttributeValueMemberL)
+			if !isList || element.Index >= len(list.Value) {
+				return nil, false
+			}
+			val = list.Value[element.Index]
+		} else {
+			m, isMap := val.(*types.AttributeValueMemberM)
+			if !isMap {
+				return nil, false
+			}
+			val, ok = m.Value[element.Name]
+		}
+	}
+	return val, ok
+}
+
+func (svc *Service) executeInsert(ctx context.Context, stmt *partiql.InsertStatement, input *dynamodb.ExecuteStatementInput) (*dynamodb.ExecuteStatementOutput, error) {
+	partitionKey, _, err := svc.statementKeySchema(stmt.TableName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := partiql.NewExpressionBuilder(input.Parameters)
+	item, err := builder.Bind(stmt.Item)
+	if err != nil {
+		return nil, &ValidationException{Message: err.Error()}
+	}
+
+	// INSERT never replaces an item, unlike PutItem
+	conditionExpression := fmt.Sprintf("attribute_not_exists(%s)", builder.Path(partiql.Path{{Name: partitionKey}}))
+	_, err = svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                &stmt.TableName,
+		Item:                     item.(*types.AttributeValueMemberM).Value,
+		ConditionExpression:      &conditionExpression,
+		ExpressionAttributeNames: builder.Names,
+	})
+	var conditionalCheckFailedException *storage.ConditionalCheckFailedException
+	if errors.As(err, &conditionalCheckFailedException) {
+		msg := "Duplicate primary key exists in table"
+		return nil, &types.DuplicateItemException{
+			Message: &msg,
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.ExecuteStatementOutput{
+		Items: make([]map[string]types.AttributeValue, 0),
+	}, nil
+}
+
+func (svc *Service) executeUpdate(ctx context.Context, stmt *partiql.UpdateStatement, input *dynamodb.ExecuteStatementInput) (*dynamodb.ExecuteStatementOutput, error) {
+	partitionKey, sortKey, err := svc.statementKeySchema(stmt.TableName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := partiql.NewExpressionBuilder(input.Parameters)
+	key, filter, err := bindStatementKey(builder, stmt.Where, partitionKey, sortKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clauses := make([]string, 0, 2)
+	if len(stmt.Set) > 0 {
+		actions := make([]string, len(stmt.Set))
+		for i, action := range stmt.Set {
+			value, err := builder.Value(action.Value)
+			if err != nil {
+				return nil, &ValidationException{Message: err.Error()}
+			}
+			actions[i] = fmt.Sprintf("%s = %s", builder.Path(action.Path), value)
+		}
+		clauses = append(clauses, "SET "+strings.Join(actions, ", "))
+	}
+	if len(stmt.Remove) > 0 {
+		paths := make([]string, len(stmt.Remove))
+		for i, path := range stmt.Remove {
+			paths[i] = builder.Path(path)
+		}
+		clauses = append(clauses, "REMOVE "+strings.Join(paths, ", "))
+	}
+	updateExpression := strings.Join(clauses, " ")
+
+	// UPDATE never creates an item, unlike UpdateItem
+	conditionExpression := fmt.Sprintf("attribute_exists(%s)", builder.Path(partiql.Path{{Name: partitionKey}}))
+	if filter != nil {
+		f, err := builder.Condition(filter)
+		if err != nil {
+			return nil, &ValidationException{Message: err.Error()}
+		}
+		conditionExpression = fmt.Sprintf("%s AND (%s)", conditionExpression, f)
+	}
+
+	_, err = svc.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &stmt.TableName,
+		Key:                       key,
+		UpdateExpression:          &updateExpression,
+		ConditionExpression:       &conditionExpression,
+		ExpressionAttributeNames:  builder.Names,
+		ExpressionAttributeValues: builder.Values,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.ExecuteStatementOutput{
+		Items: make([]map[string]types.AttributeValue, 0),
+	}, nil
+}
+
+func (svc *Service) executeDelete(ctx context.Context, stmt *partiql.DeleteStatement, input *dynamodb.ExecuteStatementInput) (*dynamodb.ExecuteStatementOutput, error) {
+	partitionKey, sortKey, err := svc.statementKeySchema(stmt.TableName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := partiql.NewExpressionBuilder(input.Parameters)
+	key, filter, err := bindStatementKey(builder, stmt.Where, partitionKey, sortKey)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteItemInput := &dynamodb.DeleteItemInput{
+		TableName: &stmt.TableName,
+		Key:       key,
+	}
+	if filter != nil {
+		conditionExpression, err := builder.Condition(filter)
+		if err != nil {
+			return nil, &ValidationException{Message: err.Error()}
+		}
+		deleteItemInput.ConditionExpression = &conditionExpression
+		deleteItemInput.ExpressionAttributeNames = builder.Names
+		deleteItemInput.ExpressionAttributeValues = builder.Values
+	}
+
+	if _, err := svc.DeleteItem(ctx, deleteItemInput); err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.ExecuteStatementOutput{
+		Items: make([]map[string]types.AttributeValue, 0),
+	}, nil
+}
+
+// bindStatementKey returns the primary key an UPDATE or DELETE writes and the rest of its WHERE clause
+func bindStatementKey(builder *partiql.ExpressionBuilder, where partiql.Condition, partitionKey string, sortKey string) (map[string]types.AttributeValue, partiql.Condition, error) {
+	keyValues, filter, ok := partiql.SplitKey(where, partitionKey, sortKey)
+	if !ok {
+		return nil, nil, &ValidationException{Message: "Where clause does not contain a mentioned primary key condition"}
+	}
+
+	key := make(map[string]types.AttributeValue, len(keyValues))
+	for name, value := range keyValues {
+		attributeValue, err := builder.Bind(value)
+		if err != nil {
+			return nil, nil, &ValidationException{Message: err.Error()}
+		}
+		key[name] = attributeValue
+	}
+	return key, filter, nil
+}
+
+// statementAttributeNames is nil when the statement has no attribute names, e.g. SELECT * without WHERE
+func statementAttributeNames(builder *partiql.ExpressionBuilder) map[string]string {
+	if len(builder.Names) == 0 {
+		return nil
+	}
+	return builder.Names
+}
+
+func statementAttributeValues(builder *partiql.ExpressionBuilder) map[string]types.AttributeValue {
+	if len(builder.Values) == 0 {
+		return nil
+	}
+	return builder.Values
+}
+
+// encodeNextToken hides the LastEvaluatedKey of a SELECT in an opaque NextToken
+func encodeNextToken(lastEvaluatedKey map[string]types.AttributeValue) (string, error) {
+	key, err := core.TransformAttributeValueMap(lastEvaluatedKey)
+	if err != nil {
+		return "", err
+	}
+	bs, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(bs), nil
+}
+
+func decodeNextToken(nextToken *string) (map[string]types.AttributeValue, error) {
+	if nextToken == nil {
+		return nil, nil
+	}
+
+	invalidNextToken := &ValidationException{Message: "Invalid NextToken"}
+	bs, err := base64.StdEncoding.DecodeString(*nextToken)
+	if err != nil {
+		return nil, invalidNextToken
+	}
+	var key map[string]core.AttributeValue
+	if err := json.Unmarshal(bs, &key); err != nil {
+		return nil, invalidNextToken
+	}
+
+	exclusiveStartKey := make(map[string]types.AttributeValue, len(key))
+	for name, value := range key {
+		exclusiveStartKey[name] = value.ToDdbAttributeValue()
+	}
+	return exclusiveStartKey, nil
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/ocowchun/baddb/ddb/expression/ast"
 	"github.com/ocowchun/baddb/ddb/expression/lexer"
 	"github.com/ocowchun/baddb/ddb/expression/parser"
+	"github.com/ocowchun/baddb/ddb/expression/token"
 	"strings"
 )
 
@@ -27,3 +28,32 @@ func ParseUpdateExpression(content string) (*ast.UpdateExpression, error) {
 
 	return p.ParseUpdateExpression()
 }
+
+func ParseProjectionExpression(content string) ([]ast.PathOperand, error) {
+	l := lexer.New(strings.NewReader(content))
+	p := parser.New(l)
+
+	return p.ParseProjectionExpression()
+}
+
+// Placeholders returns the ExpressionAttributeNames (#name) and ExpressionAttributeValues (:value) referenced
+// by the expressions, a nil expression references nothing
+func Placeholders(expressions ...*string) (map[string]bool, map[string]bool) {
+	names := make(map[string]bool)
+	values := make(map[string]bool)
+	for _, content := range expressions {
+		if content == nil {
+			continue
+		}
+		l := lexer.New(strings.NewReader(*content))
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			switch tok.Type {
+			case token.EXPRESSION_ATTRIBUTE_NAME:
+				names[tok.Literal] = true
+			case token.EXPRESSION_ATTRIBUTE_VALUE:
+				values[tok.Literal] = true
+			}
+		}
+	}
+	return names, values
+}
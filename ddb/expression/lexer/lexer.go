@@ -17,11 +17,21 @@ type Lexer struct {
 	ch           rune
 	currentLine  []rune
 	isEOF        bool
+	// input is the expression read so far with its lines joined by \n, lineOffset is where currentLine starts in it
+	input      []rune
+	lineOffset int
+	scanned    bool
 }
 
+// maxLineSize bounds a single line of an expression, bufio.Scanner stops at lines longer than its 64KB default
+// and the rest of the expression would be silently dropped
+const maxLineSize = 16 * 1024 * 1024
+
 func New(input io.Reader) *Lexer {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(nil, maxLineSize)
 	l := &Lexer{
-		scanner:      bufio.NewScanner(input),
+		scanner:      scanner,
 		position:     0,
 		readPosition: 0,
 		currentLine:  []rune{},
@@ -44,6 +54,12 @@ func (l *Lexer) readRune() {
 			l.currentLine = []rune(l.scanner.Text())
 			l.position = 0
 			l.readPosition = 0
+			if l.scanned {
+				l.input = append(l.input, '\n')
+			}
+			l.scanned = true
+			l.lineOffset = len(l.input)
+			l.input = append(l.input, l.currentLine...)
 		} else {
 			l.isEOF = true
 			return
@@ -70,6 +86,24 @@ func (l *Lexer) skipWhitespace() {
 
 func (l *Lexer) NextToken() token.Token {
 	l.skipWhitespace()
+	position := l.lineOffset + l.position
+	if l.isEOF {
+		position = len(l.input)
+	}
+	tok := l.readToken()
+	tok.Position = position
+	return tok
+}
+
+// Slice returns the text of the expression from the start offset to the end offset in runes, both are clipped to the
+// expression read so far
+func (l *Lexer) Slice(start, end int) string {
+	start = max(0, min(start, len(l.input)))
+	end = max(start, min(end, len(l.input)))
+	return string(l.input[start:end])
+}
+
+func (l *Lexer) readToken() token.Token {
 	var tok token.Token
 	if l.isEOF {
 		return newToken(token.EOF, "")
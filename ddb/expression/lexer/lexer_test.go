@@ -50,3 +50,37 @@ Id = :v1 AND PostedBy BETWEEN :v2a AND :v2b SET #pr.#5star[1] = :r5
 		}
 	}
 }
+
+func TestLexer_NextTokenWithLongLine(t *testing.T) {
+	count := 10000
+	content := strings.Repeat("attr = :val AND ", count) + "attr = :val"
+	lexer := New(strings.NewReader(content))
+
+	tokens := 0
+	for tok := lexer.NextToken(); tok.Type != token.EOF; tok = lexer.NextToken() {
+		tokens++
+	}
+	expected := count*4 + 3
+	if tokens != expected {
+		t.Fatalf("expected %d tokens, got %d", expected, tokens)
+	}
+}
+
+func TestLexer_NextTokenPosition(t *testing.T) {
+	lexer := New(strings.NewReader("#a = :a\n  AND size(b) > :b"))
+	expected := []struct {
+		literal  string
+		position int
+	}{
+		{"#a", 0}, {"=", 3}, {":a", 5}, {"AND", 10}, {"size", 14}, {"(", 18}, {"b", 19}, {")", 20}, {">", 22}, {":b", 24}, {"", 26},
+	}
+	for i, tt := range expected {
+		tok := lexer.NextToken()
+		if tok.Literal != tt.literal || tok.Position != tt.position {
+			t.Fatalf("tests[%d] - expected %q at %d, got %q at %d", i, tt.literal, tt.position, tok.Literal, tok.Position)
+		}
+	}
+	if near := lexer.Slice(5, 13); near != ":a\n  AND" {
+		t.Fatalf("expected the text across lines, got %q", near)
+	}
+}
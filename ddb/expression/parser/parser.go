@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -14,6 +13,7 @@ import (
 
 type Parser struct {
 	l         *lexer.Lexer
+	prevToken token.Token
 	curToken  token.Token
 	peekToken token.Token
 }
@@ -37,6 +37,10 @@ func (e *InvalidKeyConditionExpressionError) Error() string {
 	return fmt.Sprintf("Invalid KeyConditionExpression: %v", e.rawErr)
 }
 
+func (e *InvalidKeyConditionExpressionError) Unwrap() error {
+	return e.rawErr
+}
+
 func (p *Parser) ParseKeyConditionExpression() (*ast.KeyConditionExpression, error) {
 	predicate1, err := p.parseKeyPredicateExpression()
 	if err != nil {
@@ -54,8 +58,7 @@ func (p *Parser) ParseKeyConditionExpression() (*ast.KeyConditionExpression, err
 		}
 		keyCondExpression.Predicate2 = predicate2
 	} else if !p.peekTokenIs(token.EOF) {
-		rawErr := fmt.Errorf("Syntax error; token: \"%s\", near: \"%s %s\"", p.peekToken.Literal, p.curToken.Literal, p.peekToken.Literal)
-		return nil, &InvalidKeyConditionExpressionError{rawErr: rawErr}
+		return nil, &InvalidKeyConditionExpressionError{rawErr: p.syntaxError(p.peekToken)}
 	}
 
 	return keyCondExpression, nil
@@ -77,7 +80,9 @@ func (p *Parser) parseKeyPredicateExpression() (ast.PredicateExpression, error)
 		}
 
 		p.nextToken()
-		if p.curTokenIs(token.BETWEEN) {
+		if p.curTokenIs(token.IN) {
+			return nil, fmt.Errorf("Invalid operator used in KeyConditionExpression: IN")
+		} else if p.curTokenIs(token.BETWEEN) {
 			// sortKeyName BETWEEN :sortkeyval1 AND :sortkeyval2
 			p.nextToken()
 			i, err := p.parseAttributeValueIdentifier()
@@ -89,7 +94,7 @@ func (p *Parser) parseKeyPredicateExpression() (ast.PredicateExpression, error)
 				return nil, fmt.Errorf("failed to parse identifier")
 			}
 			if !p.expectPeek(token.AND) {
-				return nil, fmt.Errorf("failed to parse BETWEEN")
+				return nil, p.syntaxError(p.peekToken)
 			}
 			p.nextToken()
 
@@ -138,7 +143,7 @@ func (p *Parser) parseKeyPredicateExpression() (ast.PredicateExpression, error)
 	} else if p.curTokenIs(token.BEGINS_WITH) {
 		// begins_with ( sortKeyName, :sortkeyval )
 		if !p.expectPeek(token.LPAREN) {
-			return nil, errors.New("failed to parse BEGINS_WITH")
+			return nil, p.syntaxError(p.peekToken)
 		}
 		p.nextToken()
 		attributeName, err := p.parseAttributeName()
@@ -147,7 +152,7 @@ func (p *Parser) parseKeyPredicateExpression() (ast.PredicateExpression, error)
 		}
 
 		if !p.expectPeek(token.COMMA) {
-			return nil, errors.New("failed to parse BEGINS_WITH")
+			return nil, p.syntaxError(p.peekToken)
 		}
 		p.nextToken()
 
@@ -161,7 +166,7 @@ func (p *Parser) parseKeyPredicateExpression() (ast.PredicateExpression, error)
 			return nil, fmt.Errorf("failed to parse attribute value")
 		}
 		if !p.expectPeek(token.RPAREN) {
-			return nil, errors.New("failed to parse BEGINS_WITH")
+			return nil, p.syntaxError(p.peekToken)
 		}
 
 		beginsWithPredicate := &ast.BeginsWithPredicateExpression{
@@ -169,8 +174,11 @@ func (p *Parser) parseKeyPredicateExpression() (ast.PredicateExpression, error)
 			Value:         val,
 		}
 		return beginsWithPredicate, nil
+	} else if p.isFunctionCondition() || p.curTokenIs(token.SIZE) {
+		// begins_with is the only function a key condition can use
+		return nil, fmt.Errorf("Invalid operator used in KeyConditionExpression: %s", p.curToken.Literal)
 	} else {
-		return nil, fmt.Errorf("unexpected token %v", p.curToken)
+		return nil, p.syntaxError(p.curToken)
 	}
 }
 
@@ -215,15 +223,31 @@ func (p *Parser) parseComparator() (string, error) {
 		}
 		return op, nil
 	default:
-		return "", fmt.Errorf("unexpected token %v", p.curToken)
+		return "", p.syntaxError(p.curToken)
 	}
 }
 
 func (p *Parser) nextToken() {
+	p.prevToken = p.curToken
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
 }
 
+// syntaxError reports tok the way DynamoDB does, near is the text of the expression from the token before tok to the
+// end of tok. tok is either curToken or peekToken
+func (p *Parser) syntaxError(tok token.Token) error {
+	before := p.curToken
+	if tok == p.curToken {
+		before = p.prevToken
+	}
+	literal := tok.Literal
+	if tok.Type == token.EOF {
+		literal = "<EOF>"
+	}
+	near := p.l.Slice(before.Position, tok.Position+len([]rune(tok.Literal)))
+	return fmt.Errorf("Syntax error; token: \"%s\", near: \"%s\"", literal, strings.TrimSpace(near))
+}
+
 func (p *Parser) curTokenIs(t token.TokenType) bool {
 	return p.curToken.Type == t
 }
@@ -258,11 +282,13 @@ func (p *Parser) parseAttributeValueIdentifier() (ast.Expression, error) {
 		identifier := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal[1:]}
 		return &ast.AttributeValueIdentifier{Token: p.curToken, Name: identifier}, nil
 	} else {
-		// TODO: confirm error message
-		return nil, fmt.Errorf("failed to parse attribute value identifier")
+		return nil, p.syntaxError(p.curToken)
 	}
 }
 
+// MAX_IN_OPERANDS is how many values the IN operator compares against at most
+const MAX_IN_OPERANDS = 100
+
 // DynamoDB evaluates conditions from left to right using the following precedence rules:
 // = <> < <= > >=
 // IN
@@ -362,13 +388,25 @@ func (p *Parser) isFunctionCondition() bool {
 
 // https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/Expressions.OperatorsAndFunctions.html
 func (p *Parser) ParseConditionExpression() (ast.ConditionExpression, error) {
-	return p.parseConditionExpression(PRECEDENCE_LOWEST)
+	return p.parseWholeConditionExpression()
 }
 
 // https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/Scan.html#Scan.FilterExpression
 // The syntax for a filter expression is identical to that of a condition expression.
 func (p *Parser) ParseFilterExpression() (ast.ConditionExpression, error) {
-	return p.parseConditionExpression(PRECEDENCE_LOWEST)
+	return p.parseWholeConditionExpression()
+}
+
+// parseWholeConditionExpression rejects tokens left after the condition, e.g. `a = :a b`
+func (p *Parser) parseWholeConditionExpression() (ast.ConditionExpression, error) {
+	cond, err := p.parseConditionExpression(PRECEDENCE_LOWEST)
+	if err != nil {
+		return nil, err
+	}
+	if !p.peekTokenIs(token.EOF) {
+		return nil, p.syntaxError(p.peekToken)
+	}
+	return cond, nil
 }
 
 func (p *Parser) parseConditionExpression(precedence uint8) (ast.ConditionExpression, error) {
@@ -376,12 +414,13 @@ func (p *Parser) parseConditionExpression(precedence uint8) (ast.ConditionExpres
 	var err error
 	if p.curTokenIs(token.LPAREN) {
 		p.nextToken()
-		left, err = p.parseConditionExpression(precedence)
+		// parentheses start over from the lowest precedence, so `a AND (b OR c)` keeps the OR inside
+		left, err = p.parseConditionExpression(PRECEDENCE_LOWEST)
 		if err != nil {
 			return nil, err
 		}
 		if !p.expectPeek(token.RPAREN) {
-			return nil, fmt.Errorf("failed to parse RPAREN")
+			return nil, p.syntaxError(p.peekToken)
 		}
 	} else if p.isFunctionCondition() {
 		functionExpression, err := p.parseFunctionConditionExpression()
@@ -395,7 +434,8 @@ func (p *Parser) parseConditionExpression(precedence uint8) (ast.ConditionExpres
 	} else if p.curTokenIs(token.NOT) {
 		p.nextToken()
 
-		cond, err := p.parseConditionExpression(precedence)
+		// NOT binds tighter than AND and OR, so `NOT a AND b` is `(NOT a) AND b`
+		cond, err := p.parseConditionExpression(PRECEDENCE_NOT)
 		if err != nil {
 			return nil, err
 		}
@@ -421,7 +461,7 @@ func (p *Parser) parseConditionExpression(precedence uint8) (ast.ConditionExpres
 			}
 
 			if !p.expectPeek(token.AND) {
-				return nil, fmt.Errorf("failed to parse AND")
+				return nil, p.syntaxError(p.peekToken)
 			}
 			p.nextToken()
 
@@ -440,7 +480,7 @@ func (p *Parser) parseConditionExpression(precedence uint8) (ast.ConditionExpres
 			p.nextToken()
 
 			if !p.expectPeek(token.LPAREN) {
-				return nil, fmt.Errorf("failed to parse LPAREN")
+				return nil, p.syntaxError(p.peekToken)
 			}
 			p.nextToken()
 
@@ -457,6 +497,12 @@ func (p *Parser) parseConditionExpression(precedence uint8) (ast.ConditionExpres
 				}
 				p.nextToken()
 			}
+			if len(values) == 0 {
+				return nil, fmt.Errorf("Syntax error; token: \")\", near: \"IN ()\"")
+			}
+			if len(values) > MAX_IN_OPERANDS {
+				return nil, fmt.Errorf("The IN operator is provided with too many operands; number of operands: %d", len(values))
+			}
 
 			left = &ast.InConditionExpression{
 				Operand: operand,
@@ -517,7 +563,7 @@ func (p *Parser) parseInfixConditionExpression(left ast.ConditionExpression) (as
 			Right: right,
 		}, nil
 	default:
-		return nil, fmt.Errorf("unexpected token %v", infixOp)
+		return nil, p.syntaxError(infixOp)
 	}
 }
 
@@ -525,7 +571,7 @@ func (p *Parser) parseFunctionConditionExpression() (ast.FunctionExpression, err
 	switch p.curToken.Type {
 	case token.ATTRIBUTE_EXISTS:
 		if !p.expectPeek(token.LPAREN) {
-			return nil, fmt.Errorf("failed to parse LPAREN")
+			return nil, p.syntaxError(p.peekToken)
 		}
 		p.nextToken()
 
@@ -535,7 +581,7 @@ func (p *Parser) parseFunctionConditionExpression() (ast.FunctionExpression, err
 		}
 
 		if !p.expectPeek(token.RPAREN) {
-			return nil, fmt.Errorf("failed to parse RPAREN")
+			return nil, p.syntaxError(p.peekToken)
 		}
 
 		return &ast.AttributeExistsFunctionExpression{
@@ -543,7 +589,7 @@ func (p *Parser) parseFunctionConditionExpression() (ast.FunctionExpression, err
 		}, nil
 	case token.ATTRIBUTE_NOT_EXISTS:
 		if !p.expectPeek(token.LPAREN) {
-			return nil, fmt.Errorf("failed to parse LPAREN")
+			return nil, p.syntaxError(p.peekToken)
 		}
 		p.nextToken()
 
@@ -553,7 +599,7 @@ func (p *Parser) parseFunctionConditionExpression() (ast.FunctionExpression, err
 		}
 
 		if !p.expectPeek(token.RPAREN) {
-			return nil, fmt.Errorf("failed to parse RPAREN")
+			return nil, p.syntaxError(p.peekToken)
 		}
 
 		return &ast.AttributeNotExistsFunctionExpression{
@@ -561,7 +607,7 @@ func (p *Parser) parseFunctionConditionExpression() (ast.FunctionExpression, err
 		}, nil
 	case token.ATTRIBUTE_TYPE:
 		if !p.expectPeek(token.LPAREN) {
-			return nil, fmt.Errorf("failed to parse LPAREN")
+			return nil, p.syntaxError(p.peekToken)
 		}
 		p.nextToken()
 
@@ -571,7 +617,7 @@ func (p *Parser) parseFunctionConditionExpression() (ast.FunctionExpression, err
 		}
 
 		if !p.expectPeek(token.COMMA) {
-			return nil, fmt.Errorf("failed to parse COMMA")
+			return nil, p.syntaxError(p.peekToken)
 		}
 		p.nextToken()
 
@@ -581,7 +627,7 @@ func (p *Parser) parseFunctionConditionExpression() (ast.FunctionExpression, err
 		}
 
 		if !p.expectPeek(token.RPAREN) {
-			return nil, fmt.Errorf("failed to parse RPAREN")
+			return nil, p.syntaxError(p.peekToken)
 		}
 
 		return &ast.AttributeTypeFunctionExpression{
@@ -590,7 +636,7 @@ func (p *Parser) parseFunctionConditionExpression() (ast.FunctionExpression, err
 		}, nil
 	case token.BEGINS_WITH:
 		if !p.expectPeek(token.LPAREN) {
-			return nil, fmt.Errorf("failed to parse LPAREN")
+			return nil, p.syntaxError(p.peekToken)
 		}
 		p.nextToken()
 
@@ -600,7 +646,7 @@ func (p *Parser) parseFunctionConditionExpression() (ast.FunctionExpression, err
 		}
 
 		if !p.expectPeek(token.COMMA) {
-			return nil, fmt.Errorf("failed to parse COMMA")
+			return nil, p.syntaxError(p.peekToken)
 		}
 		p.nextToken()
 
@@ -610,7 +656,7 @@ func (p *Parser) parseFunctionConditionExpression() (ast.FunctionExpression, err
 		}
 
 		if !p.expectPeek(token.RPAREN) {
-			return nil, fmt.Errorf("failed to parse RPAREN")
+			return nil, p.syntaxError(p.peekToken)
 		}
 
 		return &ast.BeginsWithFunctionExpression{
@@ -619,7 +665,7 @@ func (p *Parser) parseFunctionConditionExpression() (ast.FunctionExpression, err
 		}, nil
 	case token.CONTAINS:
 		if !p.expectPeek(token.LPAREN) {
-			return nil, fmt.Errorf("failed to parse LPAREN")
+			return nil, p.syntaxError(p.peekToken)
 		}
 		p.nextToken()
 
@@ -629,7 +675,7 @@ func (p *Parser) parseFunctionConditionExpression() (ast.FunctionExpression, err
 		}
 
 		if !p.expectPeek(token.COMMA) {
-			return nil, fmt.Errorf("failed to parse COMMA")
+			return nil, p.syntaxError(p.peekToken)
 		}
 		p.nextToken()
 
@@ -639,7 +685,7 @@ func (p *Parser) parseFunctionConditionExpression() (ast.FunctionExpression, err
 		}
 
 		if !p.expectPeek(token.RPAREN) {
-			return nil, fmt.Errorf("failed to parse RPAREN")
+			return nil, p.syntaxError(p.peekToken)
 		}
 
 		return &ast.ContainsFunctionExpression{
@@ -647,7 +693,7 @@ func (p *Parser) parseFunctionConditionExpression() (ast.FunctionExpression, err
 			Operand: operand,
 		}, nil
 	default:
-		return nil, fmt.Errorf("failed to parse function condition expression")
+		return nil, p.syntaxError(p.curToken)
 	}
 }
 
@@ -672,7 +718,7 @@ func (p *Parser) parseAttributeNameOperand() (*ast.AttributeNameOperand, error)
 		}
 
 		// check if the identifier is a reserved keyword
-		if _, ok := core.ReservedWords[strings.ToUpper(identifier.Value)]; ok {
+		if core.IsReservedWord(identifier.Value) {
 			return nil, &ReservedKeywordException{ReservedKeyword: identifier.Value}
 		}
 
@@ -692,7 +738,7 @@ func (p *Parser) parseAttributeNameOperand() (*ast.AttributeNameOperand, error)
 			HasColon:   true,
 		}, nil
 	} else {
-		return nil, fmt.Errorf("failed to parse attribute name")
+		return nil, p.syntaxError(p.curToken)
 	}
 }
 
@@ -704,18 +750,9 @@ func (p *Parser) parsePathOperand() (ast.PathOperand, error) {
 	}
 	operand = attributeNameOperand
 
-	if p.peekTokenIs(token.DOT) {
-		p.nextToken()
-		p.nextToken()
-		rightOperand, err := p.parsePathOperand()
-		if err != nil {
-			return nil, err
-		}
-		operand = &ast.DotOperand{
-			Left:  operand,
-			Right: rightOperand,
-		}
-	} else if p.peekTokenIs(token.LBRACKET) {
+	// indexes apply to the attribute before them, e.g. a[0][1] is the second element of the first element of a,
+	// and the rest of the path after a dot resolves in the value so far, e.g. c[0].d in b of a.b[2].c[0].d
+	for p.peekTokenIs(token.LBRACKET) {
 		p.nextToken()
 		p.nextToken()
 
@@ -725,7 +762,7 @@ func (p *Parser) parsePathOperand() (ast.PathOperand, error) {
 		}
 
 		if !p.expectPeek(token.RBRACKET) {
-			return nil, fmt.Errorf("failed to parse RBRACKET")
+			return nil, p.syntaxError(p.peekToken)
 		}
 
 		operand = &ast.IndexOperand{
@@ -734,13 +771,26 @@ func (p *Parser) parsePathOperand() (ast.PathOperand, error) {
 		}
 	}
 
+	if p.peekTokenIs(token.DOT) {
+		p.nextToken()
+		p.nextToken()
+		rightOperand, err := p.parsePathOperand()
+		if err != nil {
+			return nil, err
+		}
+		operand = &ast.DotOperand{
+			Left:  operand,
+			Right: rightOperand,
+		}
+	}
+
 	return operand, nil
 }
 
 func (p *Parser) parseOperand() (ast.Operand, error) {
 	if p.curTokenIs(token.SIZE) {
 		if !p.expectPeek(token.LPAREN) {
-			return nil, fmt.Errorf("failed to parse LPAREN")
+			return nil, p.syntaxError(p.peekToken)
 		}
 		p.nextToken()
 
@@ -750,7 +800,7 @@ func (p *Parser) parseOperand() (ast.Operand, error) {
 		}
 
 		if !p.expectPeek(token.RPAREN) {
-			return nil, fmt.Errorf("failed to parse RPAREN")
+			return nil, p.syntaxError(p.peekToken)
 		}
 
 		return &ast.SizeOperand{
@@ -762,6 +812,30 @@ func (p *Parser) parseOperand() (ast.Operand, error) {
 	}
 }
 
+// https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/Expressions.ProjectionExpressions.html
+func (p *Parser) ParseProjectionExpression() ([]ast.PathOperand, error) {
+	paths := make([]ast.PathOperand, 0)
+	for {
+		path, err := p.parsePathOperand()
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+		p.nextToken()
+	}
+
+	if !p.peekTokenIs(token.EOF) {
+		return nil, p.syntaxError(p.peekToken)
+	}
+
+	return paths, nil
+}
+
 func (p *Parser) ParseUpdateExpression() (*ast.UpdateExpression, error) {
 	updateExpression := &ast.UpdateExpression{}
 	for !p.curTokenIs(token.EOF) {
@@ -807,7 +881,7 @@ func (p *Parser) ParseUpdateExpression() (*ast.UpdateExpression, error) {
 		case token.EOF:
 			break
 		default:
-			return nil, fmt.Errorf("unexpected token %v", p.curToken)
+			return nil, p.syntaxError(p.curToken)
 		}
 
 		p.nextToken()
@@ -830,7 +904,7 @@ func (p *Parser) parseSetClause() (*ast.SetClause, error) {
 		}
 
 		if !p.expectPeek(token.EQ) {
-			return nil, fmt.Errorf("expected EQ got %v", p.peekToken)
+			return nil, p.syntaxError(p.peekToken)
 		}
 		p.nextToken()
 
@@ -907,10 +981,10 @@ func (p *Parser) parseSetActionOperand() (ast.SetActionOperand, error) {
 
 func (p *Parser) parseIfNotExistsExpression() (*ast.IfNotExistsExpression, error) {
 	if !p.curTokenIs(token.IF_NOT_EXISTS) {
-		return nil, fmt.Errorf("expected if_not_exists got %v", p.curToken)
+		return nil, p.syntaxError(p.curToken)
 	}
 	if !p.expectPeek(token.LPAREN) {
-		return nil, fmt.Errorf("expected `(` got %v", p.peekToken)
+		return nil, p.syntaxError(p.peekToken)
 	}
 	p.nextToken()
 
@@ -920,7 +994,7 @@ func (p *Parser) parseIfNotExistsExpression() (*ast.IfNotExistsExpression, error
 	}
 
 	if !p.expectPeek(token.COMMA) {
-		return nil, fmt.Errorf("expected `,` got %v", p.peekToken)
+		return nil, p.syntaxError(p.peekToken)
 	}
 	p.nextToken()
 
@@ -930,7 +1004,7 @@ func (p *Parser) parseIfNotExistsExpression() (*ast.IfNotExistsExpression, error
 	}
 
 	if !p.expectPeek(token.RPAREN) {
-		return nil, fmt.Errorf("expected `)` got %v", p.peekToken)
+		return nil, p.syntaxError(p.peekToken)
 	}
 
 	return &ast.IfNotExistsExpression{
@@ -941,10 +1015,10 @@ func (p *Parser) parseIfNotExistsExpression() (*ast.IfNotExistsExpression, error
 
 func (p *Parser) parseListAppendExpression() (*ast.ListAppendExpression, error) {
 	if !p.curTokenIs(token.LIST_APPEND) {
-		return nil, fmt.Errorf("expected list_append got %v", p.curToken)
+		return nil, p.syntaxError(p.curToken)
 	}
 	if !p.expectPeek(token.LPAREN) {
-		return nil, fmt.Errorf("expected `(` got %v", p.peekToken)
+		return nil, p.syntaxError(p.peekToken)
 	}
 	p.nextToken()
 
@@ -954,7 +1028,7 @@ func (p *Parser) parseListAppendExpression() (*ast.ListAppendExpression, error)
 	}
 
 	if !p.expectPeek(token.COMMA) {
-		return nil, fmt.Errorf("expected ',' got %v", p.peekToken)
+		return nil, p.syntaxError(p.peekToken)
 	}
 	p.nextToken()
 
@@ -964,7 +1038,7 @@ func (p *Parser) parseListAppendExpression() (*ast.ListAppendExpression, error)
 	}
 
 	if !p.expectPeek(token.RPAREN) {
-		return nil, fmt.Errorf("expected `)` got %v", p.peekToken)
+		return nil, p.syntaxError(p.peekToken)
 	}
 
 	return &ast.ListAppendExpression{
@@ -1009,7 +1083,7 @@ func (p *Parser) parseAddClause() (*ast.AddClause, error) {
 		if err != nil {
 			return nil, err
 		} else if attributeNameOperand.HasColon {
-			return nil, fmt.Errorf("Invalid UpdateExpression: Syntax error; token: \"%s\"", attributeNameOperand.String())
+			return nil, fmt.Errorf("Syntax error; token: \"%s\"", attributeNameOperand.String())
 		}
 		p.nextToken()
 
@@ -1044,7 +1118,7 @@ func (p *Parser) parseDeleteClause() (*ast.DeleteClause, error) {
 		if err != nil {
 			return nil, err
 		} else if attributeNameOperand.HasColon {
-			return nil, fmt.Errorf("Invalid UpdateExpression: Syntax error; token: \"%s\"", attributeNameOperand.String())
+			return nil, fmt.Errorf("Syntax error; token: \"%s\"", attributeNameOperand.String())
 		}
 
 		p.nextToken()
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -37,6 +39,10 @@ func TestParseKeyConditionExpression(t *testing.T) {
 func TestParseKeyConditionExpressionWithInvalidExp(t *testing.T) {
 	keyConditionExpressions := []string{
 		"#year = :year foo",
+		"#year IN (:year1, :year2)",
+		"#year = :year AND title IN (:title)",
+		"#year = :year AND contains(title, :title)",
+		"#year = :year AND size(title) = :size",
 	}
 	for _, content := range keyConditionExpressions {
 		l := lexer.New(strings.NewReader(content))
@@ -61,6 +67,9 @@ func TestParseOperand(t *testing.T) {
 		{"attributeName[0]", "attributeName[0]"},
 		{":attributeName.subAttribute", ":attributeName.subAttribute"},
 		{"ProductReviews.FiveStar[0]", "ProductReviews.FiveStar[0]"},
+		{"ProductReviews[0].FiveStar", "ProductReviews[0].FiveStar"},
+		{"Matrix[0][1]", "Matrix[0][1]"},
+		{"a.b[2].c[0].d", "a.b[2].c[0].d"},
 		{"size(attributeName)", "size(attributeName)"},
 	}
 
@@ -128,7 +137,13 @@ func TestParseConditionExpression(t *testing.T) {
 		{"(attributeName = :attributeValue OR attributeName2 = :attributeValue2) AND attributeName3 = :attributeValue3", "((attributeName = :attributeValue OR attributeName2 = :attributeValue2) AND attributeName3 = :attributeValue3)"},
 		{"a1 = :v1 AND a2 = :v2 OR a3 = :v3", "((a1 = :v1 AND a2 = :v2) OR a3 = :v3)"},
 		{"a1 = :v1 AND NOT a2 = :v2 OR a3 = :v3", "((a1 = :v1 AND NOT a2 = :v2) OR a3 = :v3)"},
+		{"NOT a1 = :v1 AND a2 = :v2", "(NOT a1 = :v1 AND a2 = :v2)"},
+		{"NOT (attribute_exists(a) AND b = :v)", "NOT (attribute_exists(a) AND b = :v)"},
+		{"NOT (attribute_exists(a) AND b = :v) OR c = :c", "(NOT (attribute_exists(a) AND b = :v) OR c = :c)"},
+		{"NOT attribute_exists(a) AND b = :v", "(NOT attribute_exists(a) AND b = :v)"},
 		{"size(Brand) <= :v_sub AND begins_with(Pictures.FrontView, :v_sub)", "(size(Brand) <= :v_sub AND begins_with(Pictures.FrontView, :v_sub))"},
+		{"a1 = :v1 AND (a2 = :v2 OR a3 = :v3)", "(a1 = :v1 AND (a2 = :v2 OR a3 = :v3))"},
+		{"attribute_not_exists(pk) OR (#s = :s AND (version < :v OR begins_with(message, :prefix)))", "(attribute_not_exists(pk) OR (#s = :s AND (version < :v OR begins_with(message, :prefix))))"},
 	}
 
 	for _, tt := range tests {
@@ -146,6 +161,37 @@ func TestParseConditionExpression(t *testing.T) {
 	}
 }
 
+func TestParseInConditionExpressionOperandCount(t *testing.T) {
+	values := make([]string, MAX_IN_OPERANDS+1)
+	for i := range values {
+		values[i] = fmt.Sprintf(":v%d", i)
+	}
+	tests := []struct {
+		input       string
+		expectedErr string
+	}{
+		{"#s IN ()", `Syntax error; token: ")", near: "IN ()"`},
+		{"#s IN (" + strings.Join(values, ", ") + ")", "The IN operator is provided with too many operands; number of operands: 101"},
+		{"#s IN (:v0)", ""},
+		{"#s IN (" + strings.Join(values[:MAX_IN_OPERANDS], ", ") + ")", ""},
+	}
+
+	for _, parse := range []func(p *Parser) error{parseCondition, parseFilter} {
+		for _, tt := range tests {
+			err := parse(New(lexer.New(strings.NewReader(tt.input))))
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v when parsing %s", err, tt.input)
+				}
+				continue
+			}
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Fatalf("expected error %s when parsing %s, got %v", tt.expectedErr, tt.input, err)
+			}
+		}
+	}
+}
+
 func TestParseUpdateExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -183,3 +229,152 @@ func TestParseUpdateExpression(t *testing.T) {
 	}
 
 }
+
+func TestParseReservedKeyword(t *testing.T) {
+	tests := []struct {
+		input           string
+		parse           func(p *Parser) error
+		reservedKeyword string
+	}{
+		{
+			input:           "year = :y",
+			parse:           func(p *Parser) error { _, err := p.ParseConditionExpression(); return err },
+			reservedKeyword: "year",
+		},
+		{
+			input:           "Year = :y",
+			parse:           func(p *Parser) error { _, err := p.ParseConditionExpression(); return err },
+			reservedKeyword: "Year",
+		},
+		{
+			input:           "attribute_exists(info.zone)",
+			parse:           func(p *Parser) error { _, err := p.ParseConditionExpression(); return err },
+			reservedKeyword: "zone",
+		},
+		{
+			input:           "#pk = :pk AND begins_with(name, :prefix)",
+			parse:           func(p *Parser) error { _, err := p.ParseKeyConditionExpression(); return err },
+			reservedKeyword: "name",
+		},
+		{
+			input:           "SET info.comment = :c REMOVE tags[0]",
+			parse:           func(p *Parser) error { _, err := p.ParseUpdateExpression(); return err },
+			reservedKeyword: "comment",
+		},
+		{
+			input:           "SET message = :m REMOVE tags, status",
+			parse:           func(p *Parser) error { _, err := p.ParseUpdateExpression(); return err },
+			reservedKeyword: "status",
+		},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(strings.NewReader(tt.input)))
+		err := tt.parse(p)
+
+		var reservedKeywordErr *ReservedKeywordException
+		if !errors.As(err, &reservedKeywordErr) {
+			t.Fatalf("expected reserved keyword error when parsing %s, got %v", tt.input, err)
+		}
+		if reservedKeywordErr.ReservedKeyword != tt.reservedKeyword {
+			t.Fatalf("expected reserved keyword %s when parsing %s, got %s", tt.reservedKeyword, tt.input, reservedKeywordErr.ReservedKeyword)
+		}
+	}
+
+	// reserved words are allowed through ExpressionAttributeNames
+	aliased := []string{"#y = :y", "attribute_exists(info.#zone)", "#year BETWEEN :v1 AND :v2"}
+	for _, input := range aliased {
+		p := New(lexer.New(strings.NewReader(input)))
+		if _, err := p.ParseConditionExpression(); err != nil {
+			t.Fatalf("unexpected error: %v when parsing %s", err, input)
+		}
+	}
+}
+
+func TestParseProjectionExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"title", []string{"title"}},
+		{"title, #y", []string{"title", "#y"}},
+		{"info.rating, actors[1], #pr.#5star[0]", []string{"info.rating", "actors[1]", "#pr.#5star[0]"}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(strings.NewReader(tt.input)))
+		paths, err := p.ParseProjectionExpression()
+		if err != nil {
+			t.Fatalf("unexpected error: %v when parsing %s", err, tt.input)
+		}
+		if len(paths) != len(tt.expected) {
+			t.Fatalf("expected %d paths but got %d when parsing %s", len(tt.expected), len(paths), tt.input)
+		}
+		for i, path := range paths {
+			if path.String() != tt.expected[i] {
+				t.Fatalf("expected %s but got %s", tt.expected[i], path.String())
+			}
+		}
+	}
+
+	for _, input := range []string{"title message", "title,", "title = :t"} {
+		p := New(lexer.New(strings.NewReader(input)))
+		if _, err := p.ParseProjectionExpression(); err == nil {
+			t.Fatalf("expect error but get nil, exp: %s", input)
+		}
+	}
+}
+
+func TestParseSyntaxError(t *testing.T) {
+	tests := []struct {
+		input       string
+		parse       func(p *Parser) error
+		expectedErr string
+	}{
+		{"a = :a b", parseCondition, `Syntax error; token: "b", near: ":a b"`},
+		{"a = :a AND", parseCondition, `Syntax error; token: "<EOF>", near: "AND"`},
+		{"a =", parseCondition, `Syntax error; token: "<EOF>", near: "="`},
+		{"attribute_exists(a", parseCondition, `Syntax error; token: "<EOF>", near: "a"`},
+		{"a = :a AND OR b = :b", parseCondition, `Syntax error; token: "OR", near: "AND OR"`},
+		{"SET a = :a foo", parseUpdate, `Syntax error; token: "foo", near: ":a foo"`},
+		{"SET a :a", parseUpdate, `Syntax error; token: ":a", near: "a :a"`},
+		{"SET a = if_not_exists(a :a)", parseUpdate, `Syntax error; token: ":a", near: "a :a"`},
+		{"title message", parseProjection, `Syntax error; token: "message", near: "title message"`},
+		{"#year = :year foo", parseKeyCondition, `Invalid KeyConditionExpression: Syntax error; token: "foo", near: ":year foo"`},
+		{"#year IN (:year)", parseKeyCondition, "Invalid KeyConditionExpression: Invalid operator used in KeyConditionExpression: IN"},
+		{"#year = :year AND contains(title, :title)", parseKeyCondition, "Invalid KeyConditionExpression: Invalid operator used in KeyConditionExpression: contains"},
+		{"#year = :year AND size(title) = :size", parseKeyCondition, "Invalid KeyConditionExpression: Invalid operator used in KeyConditionExpression: size"},
+	}
+
+	for _, tt := range tests {
+		err := tt.parse(New(lexer.New(strings.NewReader(tt.input))))
+		if err == nil || err.Error() != tt.expectedErr {
+			t.Fatalf("expected error %s when parsing %s, got %v", tt.expectedErr, tt.input, err)
+		}
+	}
+}
+
+func parseCondition(p *Parser) error {
+	_, err := p.ParseConditionExpression()
+	return err
+}
+
+func parseFilter(p *Parser) error {
+	_, err := p.ParseFilterExpression()
+	return err
+}
+
+func parseUpdate(p *Parser) error {
+	_, err := p.ParseUpdateExpression()
+	return err
+}
+
+func parseProjection(p *Parser) error {
+	_, err := p.ParseProjectionExpression()
+	return err
+}
+
+func parseKeyCondition(p *Parser) error {
+	_, err := p.ParseKeyConditionExpression()
+	return err
+}
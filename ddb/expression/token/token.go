@@ -136,6 +136,8 @@ func (t TokenType) String() string {
 type Token struct {
 	Type    TokenType
 	Literal string
+	// Position is the offset in runes of the token in the expression, EOF is at the end of the expression
+	Position int
 }
 
 var keywords = map[string]TokenType{
@@ -0,0 +1,41 @@
+package ddb
+
+import (
+	"math"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ocowchun/baddb/ddb/core"
+	"github.com/ocowchun/baddb/ddb/storage"
+)
+
+// SIZE_ESTIMATE_RANGE_BYTES is the width of SizeEstimateRangeGB, 1GB
+const SIZE_ESTIMATE_RANGE_BYTES = 1024 * 1024 * 1024
+
+// buildItemCollectionMetrics returns the metrics of the item collection entry belongs to, or nil when the caller
+// doesn't ask for them or the table has no LSI, DynamoDB only keeps item collections of tables with LSIs.
+// The collection is sized in txn, so it includes the write of txn and no write made after it
+func (svc *Service) buildItemCollectionMetrics(returnItemCollectionMetrics types.ReturnItemCollectionMetrics, table *core.TableMetaData, entry *core.Entry, txn *storage.Txn) (*types.ItemCollectionMetrics, error) {
+	if returnItemCollectionMetrics != types.ReturnItemCollectionMetricsSize || len(table.LocalSecondaryIndexSettings) == 0 {
+		return nil, nil
+	}
+
+	partitionKeyName := table.PartitionKeySchema.AttributeName
+	partitionKey := entry.Body[partitionKeyName]
+	sizeBytes, err := svc.storage.ItemCollectionSizeBytesWithTransaction(table.Name, partitionKey, txn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ItemCollectionMetrics{
+		ItemCollectionKey: map[string]types.AttributeValue{
+			partitionKeyName: partitionKey.ToDdbAttributeValue(),
+		},
+		SizeEstimateRangeGB: sizeEstimateRangeGB(sizeBytes),
+	}, nil
+}
+
+// sizeEstimateRangeGB is the range of whole GBs sizeBytes is in, e.g. [0, 1] for a collection smaller than 1GB
+func sizeEstimateRangeGB(sizeBytes int) []float64 {
+	lower := math.Floor(float64(sizeBytes) / SIZE_ESTIMATE_RANGE_BYTES)
+	return []float64{lower, lower + 1}
+}
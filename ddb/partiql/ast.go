@@ -0,0 +1,177 @@
+package partiql
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Statement is one of SelectStatement, InsertStatement, UpdateStatement and DeleteStatement
+type Statement interface {
+	// ParameterCount is the number of ? in the statement
+	ParameterCount() int
+}
+
+type statementBase struct {
+	parameterCount int
+}
+
+func (s *statementBase) ParameterCount() int {
+	return s.parameterCount
+}
+
+// SelectStatement is SELECT projection FROM "table"."index" WHERE condition
+type SelectStatement struct {
+	statementBase
+	TableName string
+	// IndexName is nil when the statement reads the table
+	IndexName *string
+	// Projection is nil for SELECT *
+	Projection []Path
+	// Where is nil when the statement has no WHERE clause
+	Where Condition
+}
+
+// InsertStatement is INSERT INTO "table" VALUE {...}
+type InsertStatement struct {
+	statementBase
+	TableName string
+	Item      *MapValue
+}
+
+type SetAction struct {
+	Path  Path
+	Value Value
+}
+
+// UpdateStatement is UPDATE "table" SET path = value REMOVE path WHERE condition
+type UpdateStatement struct {
+	statementBase
+	TableName string
+	Set       []SetAction
+	Remove    []Path
+	Where     Condition
+}
+
+// DeleteStatement is DELETE FROM "table" WHERE condition
+type DeleteStatement struct {
+	statementBase
+	TableName string
+	Where     Condition
+}
+
+// PathElement is an attribute name, or a list index when IsIndex is true
+type PathElement struct {
+	Name    string
+	Index   int
+	IsIndex bool
+}
+
+// Path is a document path like info.actors[0]
+type Path []PathElement
+
+// IsAttribute reports whether the path is the top level attribute name
+func (p Path) IsAttribute(name string) bool {
+	return len(p) == 1 && !p[0].IsIndex && p[0].Name == name
+}
+
+// Name is the last attribute name of the path, SELECT names a projected path after it, e.g. rating for info.rating
+// and actors for info.actors[0]
+func (p Path) Name() string {
+	for i := len(p) - 1; i >= 0; i-- {
+		if !p[i].IsIndex {
+			return p[i].Name
+		}
+	}
+	return ""
+}
+
+// Value is one of ParameterValue, LiteralValue, MapValue, ListValue and SetValue
+type Value interface {
+	value()
+}
+
+// ParameterValue is a ?, Index is its position in the Parameters of the request
+type ParameterValue struct {
+	Index int
+}
+
+type LiteralValue struct {
+	AttributeValue types.AttributeValue
+}
+
+type MapValue struct {
+	Fields map[string]Value
+}
+
+type ListValue struct {
+	Elements []Value
+}
+
+// SetValue is <<...>>, a string or number set depending on its elements
+type SetValue struct {
+	Elements []Value
+}
+
+func (v *ParameterValue) value() {}
+func (v *LiteralValue) value()   {}
+func (v *MapValue) value()       {}
+func (v *ListValue) value()      {}
+func (v *SetValue) value()       {}
+
+// Condition is the WHERE clause of a statement
+type Condition interface {
+	condition()
+}
+
+// ComparisonCondition is path operator value, the operator is one of =, <>, <, <=, > and >=
+type ComparisonCondition struct {
+	Path     Path
+	Operator string
+	Value    Value
+}
+
+type BetweenCondition struct {
+	Path       Path
+	LowerBound Value
+	UpperBound Value
+}
+
+type InCondition struct {
+	Path   Path
+	Values []Value
+}
+
+// FunctionCondition is begins_with(path, value) or contains(path, value)
+type FunctionCondition struct {
+	Name  string
+	Path  Path
+	Value Value
+}
+
+// MissingCondition is path IS MISSING, or path IS NOT MISSING when Missing is false
+type MissingCondition struct {
+	Path    Path
+	Missing bool
+}
+
+type AndCondition struct {
+	Left  Condition
+	Right Condition
+}
+
+type OrCondition struct {
+	Left  Condition
+	Right Condition
+}
+
+type NotCondition struct {
+	Condition Condition
+}
+
+func (c *ComparisonCondition) condition() {}
+func (c *BetweenCondition) condition()    {}
+func (c *InCondition) condition()         {}
+func (c *FunctionCondition) condition()   {}
+func (c *MissingCondition) condition()    {}
+func (c *AndCondition) condition()        {}
+func (c *OrCondition) condition()         {}
+func (c *NotCondition) condition()        {}
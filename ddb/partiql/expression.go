@@ -0,0 +1,269 @@
+package partiql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ExpressionBuilder translates paths, values and conditions of a statement to DynamoDB expressions,
+// attribute names and values are replaced with placeholders kept in Names and Values
+type ExpressionBuilder struct {
+	Names      map[string]string
+	Values     map[string]types.AttributeValue
+	parameters []types.AttributeValue
+	// placeholders are keyed by attribute names, so a name used twice has one placeholder
+	placeholders map[string]string
+}
+
+func NewExpressionBuilder(parameters []types.AttributeValue) *ExpressionBuilder {
+	return &ExpressionBuilder{
+		Names:        make(map[string]string),
+		Values:       make(map[string]types.AttributeValue),
+		parameters:   parameters,
+		placeholders: make(map[string]string),
+	}
+}
+
+// Path returns the path with placeholders, e.g. #n0.#n1[0]
+func (b *ExpressionBuilder) Path(path Path) string {
+	var sb strings.Builder
+	for i, element := range path {
+		if element.IsIndex {
+			sb.WriteString(fmt.Sprintf("[%d]", element.Index))
+			continue
+		}
+		if i > 0 {
+			sb.WriteString(".")
+		}
+		placeholder, ok := b.placeholders[element.Name]
+		if !ok {
+			placeholder = fmt.Sprintf("#n%d", len(b.placeholders))
+			b.placeholders[element.Name] = placeholder
+			b.Names[placeholder] = element.Name
+		}
+		sb.WriteString(placeholder)
+	}
+	return sb.String()
+}
+
+// Value binds the value and returns its placeholder
+func (b *ExpressionBuilder) Value(value Value) (string, error) {
+	attributeValue, err := b.Bind(value)
+	if err != nil {
+		return "", err
+	}
+	placeholder := fmt.Sprintf(":v%d", len(b.Values))
+	b.Values[placeholder] = attributeValue
+	return placeholder, nil
+}
+
+// Bind replaces the parameters in value with the Parameters of the request
+func (b *ExpressionBuilder) Bind(value Value) (types.AttributeValue, error) {
+	switch value := value.(type) {
+	case *ParameterValue:
+		if value.Index >= len(b.parameters) {
+			return nil, fmt.Errorf("Number of parameters in request and statement don't match.")
+		}
+		return b.parameters[value.Index], nil
+	case *LiteralValue:
+		return value.AttributeValue, nil
+	case *MapValue:
+		m := make(map[string]types.AttributeValue, len(value.Fields))
+		for name, field := range value.Fields {
+			attributeValue, err := b.Bind(field)
+			if err != nil {
+				return nil, err
+			}
+			m[name] = attributeValue
+		}
+		return &types.AttributeValueMemberM{Value: m}, nil
+	case *ListValue:
+		l := make([]types.AttributeValue, len(value.Elements))
+		for i, element := range value.Elements {
+			attributeValue, err := b.Bind(element)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = attributeValue
+		}
+		return &types.AttributeValueMemberL{Value: l}, nil
+	case *SetValue:
+		strs := make([]string, 0)
+		nums := make([]string, 0)
+		for _, element := range value.Elements {
+			attributeValue, err := b.Bind(element)
+			if err != nil {
+				return nil, err
+			}
+			switch attributeValue := attributeValue.(type) {
+			case *types.AttributeValueMemberS:
+				strs = append(strs, attributeValue.Value)
+			case *types.AttributeValueMemberN:
+				nums = append(nums, attributeValue.Value)
+			default:
+				return nil, fmt.Errorf("set elements must be strings or numbers")
+			}
+		}
+		if len(strs) > 0 && len(nums) > 0 {
+			return nil, fmt.Errorf("set elements must have the same type")
+		}
+		if len(nums) > 0 {
+			return &types.AttributeValueMemberNS{Value: nums}, nil
+		}
+		if len(strs) == 0 {
+			return nil, fmt.Errorf("set must not be empty")
+		}
+		return &types.AttributeValueMemberSS{Value: strs}, nil
+	default:
+		return nil, fmt.Errorf("unknown value type: %T", value)
+	}
+}
+
+// Condition returns the condition as a DynamoDB condition expression
+func (b *ExpressionBuilder) Condition(condition Condition) (string, error) {
+	switch condition := condition.(type) {
+	case *ComparisonCondition:
+		value, err := b.Value(condition.Value)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s %s %s", b.Path(condition.Path), condition.Operator, value), nil
+	case *BetweenCondition:
+		lowerBound, err := b.Value(condition.LowerBound)
+		if err != nil {
+			return "", err
+		}
+		upperBound, err := b.Value(condition.UpperBound)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s BETWEEN %s AND %s", b.Path(condition.Path), lowerBound, upperBound), nil
+	case *InCondition:
+		values := make([]string, len(condition.Values))
+		for i, v := range condition.Values {
+			value, err := b.Value(v)
+			if err != nil {
+				return "", err
+			}
+			values[i] = value
+		}
+		return fmt.Sprintf("%s IN (%s)", b.Path(condition.Path), strings.Join(values, ", ")), nil
+	case *FunctionCondition:
+		value, err := b.Value(condition.Value)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s(%s, %s)", condition.Name, b.Path(condition.Path), value), nil
+	case *MissingCondition:
+		if condition.Missing {
+			return fmt.Sprintf("attribute_not_exists(%s)", b.Path(condition.Path)), nil
+		}
+		return fmt.Sprintf("attribute_exists(%s)", b.Path(condition.Path)), nil
+	case *AndCondition:
+		return b.binaryCondition(condition.Left, "AND", condition.Right)
+	case *OrCondition:
+		return b.binaryCondition(condition.Left, "OR", condition.Right)
+	case *NotCondition:
+		cond, err := b.Condition(condition.Condition)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("NOT (%s)", cond), nil
+	default:
+		return "", fmt.Errorf("unknown condition type: %T", condition)
+	}
+}
+
+func (b *ExpressionBuilder) binaryCondition(left Condition, operator string, right Condition) (string, error) {
+	l, err := b.Condition(left)
+	if err != nil {
+		return "", err
+	}
+	r, err := b.Condition(right)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("(%s) %s (%s)", l, operator, r), nil
+}
+
+// SplitKeyCondition picks the key conditions out of the top level AND of where: the equality of
+// partitionKey, and a comparison, BETWEEN or begins_with of sortKey if sortKey isn't empty.
+// ok is false when where doesn't have the equality of partitionKey. sortKeyCondition and filter are nil when not found.
+func SplitKeyCondition(where Condition, partitionKey string, sortKey string) (partitionKeyCondition Condition, sortKeyCondition Condition, filter Condition, ok bool) {
+	rest := make([]Condition, 0)
+	for _, cond := range flattenAnd(where) {
+		if partitionKeyCondition == nil && isKeyEquality(cond, partitionKey) {
+			partitionKeyCondition = cond
+		} else if sortKey != "" && sortKeyCondition == nil && isSortKeyCondition(cond, sortKey) {
+			sortKeyCondition = cond
+		} else {
+			rest = append(rest, cond)
+		}
+	}
+	if partitionKeyCondition == nil {
+		return nil, nil, nil, false
+	}
+	return partitionKeyCondition, sortKeyCondition, joinAnd(rest), true
+}
+
+// SplitKey picks the equalities of partitionKey and sortKey out of the top level AND of where, they
+// identify the item an UPDATE or DELETE writes. ok is false when any of them is missing.
+func SplitKey(where Condition, partitionKey string, sortKey string) (key map[string]Value, filter Condition, ok bool) {
+	key = make(map[string]Value)
+	rest := make([]Condition, 0)
+	for _, cond := range flattenAnd(where) {
+		comparison, isComparison := cond.(*ComparisonCondition)
+		if isComparison && isKeyEquality(cond, partitionKey) && key[partitionKey] == nil {
+			key[partitionKey] = comparison.Value
+		} else if isComparison && sortKey != "" && isKeyEquality(cond, sortKey) && key[sortKey] == nil {
+			key[sortKey] = comparison.Value
+		} else {
+			rest = append(rest, cond)
+		}
+	}
+	if key[partitionKey] == nil || (sortKey != "" && key[sortKey] == nil) {
+		return nil, nil, false
+	}
+	return key, joinAnd(rest), true
+}
+
+func flattenAnd(condition Condition) []Condition {
+	if condition == nil {
+		return nil
+	}
+	if and, ok := condition.(*AndCondition); ok {
+		return append(flattenAnd(and.Left), flattenAnd(and.Right)...)
+	}
+	return []Condition{condition}
+}
+
+func joinAnd(conditions []Condition) Condition {
+	if len(conditions) == 0 {
+		return nil
+	}
+	joined := conditions[0]
+	for _, cond := range conditions[1:] {
+		joined = &AndCondition{Left: joined, Right: cond}
+	}
+	return joined
+}
+
+func isKeyEquality(condition Condition, keyName string) bool {
+	comparison, ok := condition.(*ComparisonCondition)
+	return ok && comparison.Operator == "=" && comparison.Path.IsAttribute(keyName)
+}
+
+func isSortKeyCondition(condition Condition, sortKey string) bool {
+	switch condition := condition.(type) {
+	case *ComparisonCondition:
+		return condition.Operator != "<>" && condition.Path.IsAttribute(sortKey)
+	case *BetweenCondition:
+		return condition.Path.IsAttribute(sortKey)
+	case *FunctionCondition:
+		return condition.Name == "begins_with" && condition.Path.IsAttribute(sortKey)
+	default:
+		return false
+	}
+}
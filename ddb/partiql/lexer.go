@@ -0,0 +1,109 @@
+package partiql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenType uint8
+
+const (
+	TOKEN_EOF tokenType = iota
+	// TOKEN_IDENTIFIER is an unquoted name, keywords are identifiers too and are matched case-insensitively
+	TOKEN_IDENTIFIER
+	// TOKEN_QUOTED_IDENTIFIER is a name in double quotes, e.g. "movie"
+	TOKEN_QUOTED_IDENTIFIER
+	// TOKEN_STRING is a string literal in single quotes, e.g. 'Hello World'
+	TOKEN_STRING
+	TOKEN_NUMBER
+	TOKEN_SYMBOL
+)
+
+type token struct {
+	tokenType tokenType
+	literal   string
+	// position is the offset of the token in the statement
+	position int
+}
+
+func (t token) String() string {
+	if t.tokenType == TOKEN_EOF {
+		return "EOF"
+	}
+	return t.literal
+}
+
+// symbols are sorted so the longer ones are matched first
+var symbols = []string{"<<", ">>", "<>", "!=", "<=", ">=", "*", ",", ".", "(", ")", "[", "]", "{", "}", ":", "=", "<", ">", "?"}
+
+func tokenize(statement string) ([]token, error) {
+	runes := []rune(statement)
+	tokens := make([]token, 0)
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'' || r == '"':
+			// a quote inside a string or a quoted identifier is escaped by doubling it
+			start := i
+			var sb strings.Builder
+			i++
+			closed := false
+			for i < len(runes) {
+				if runes[i] == r {
+					if i+1 < len(runes) && runes[i+1] == r {
+						sb.WriteRune(r)
+						i += 2
+						continue
+					}
+					closed = true
+					i++
+					break
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated quote at position %d", start)
+			}
+			tokenType := TOKEN_STRING
+			if r == '"' {
+				tokenType = TOKEN_QUOTED_IDENTIFIER
+			}
+			tokens = append(tokens, token{tokenType: tokenType, literal: sb.String(), position: start})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E' ||
+				((runes[i] == '-' || runes[i] == '+') && (runes[i-1] == 'e' || runes[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, token{tokenType: TOKEN_NUMBER, literal: string(runes[start:i]), position: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{tokenType: TOKEN_IDENTIFIER, literal: string(runes[start:i]), position: start})
+		default:
+			matched := false
+			for _, symbol := range symbols {
+				if strings.HasPrefix(string(runes[i:]), symbol) {
+					tokens = append(tokens, token{tokenType: TOKEN_SYMBOL, literal: symbol, position: i})
+					i += len([]rune(symbol))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+			}
+		}
+	}
+	tokens = append(tokens, token{tokenType: TOKEN_EOF, position: len(runes)})
+
+	return tokens, nil
+}
@@ -0,0 +1,557 @@
+package partiql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Parse parses a PartiQL statement supported by ExecuteStatement
+// https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/ql-reference.html
+func Parse(statement string) (Statement, error) {
+	tokens, err := tokenize(statement)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+
+	var stmt Statement
+	switch {
+	case p.curKeywordIs("SELECT"):
+		stmt, err = p.parseSelect()
+	case p.curKeywordIs("INSERT"):
+		stmt, err = p.parseInsert()
+	case p.curKeywordIs("UPDATE"):
+		stmt, err = p.parseUpdate()
+	case p.curKeywordIs("DELETE"):
+		stmt, err = p.parseDelete()
+	default:
+		return nil, p.unexpected()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if p.cur().tokenType != TOKEN_EOF {
+		return nil, p.unexpected()
+	}
+	return stmt, nil
+}
+
+type parser struct {
+	tokens         []token
+	position       int
+	parameterCount int
+}
+
+func (p *parser) cur() token {
+	return p.tokens[p.position]
+}
+
+func (p *parser) peek() token {
+	if p.position+1 < len(p.tokens) {
+		return p.tokens[p.position+1]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *parser) next() {
+	if p.position < len(p.tokens)-1 {
+		p.position++
+	}
+}
+
+func (p *parser) unexpected() error {
+	cur := p.cur()
+	return fmt.Errorf("unexpected token %s at position %d", cur.String(), cur.position)
+}
+
+func (p *parser) curKeywordIs(keyword string) bool {
+	cur := p.cur()
+	return cur.tokenType == TOKEN_IDENTIFIER && strings.EqualFold(cur.literal, keyword)
+}
+
+func (p *parser) curSymbolIs(symbol string) bool {
+	cur := p.cur()
+	return cur.tokenType == TOKEN_SYMBOL && cur.literal == symbol
+}
+
+func (p *parser) expectKeyword(keyword string) error {
+	if !p.curKeywordIs(keyword) {
+		return p.unexpected()
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) expectSymbol(symbol string) error {
+	if !p.curSymbolIs(symbol) {
+		return p.unexpected()
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) parseName() (string, error) {
+	cur := p.cur()
+	if cur.tokenType != TOKEN_IDENTIFIER && cur.tokenType != TOKEN_QUOTED_IDENTIFIER {
+		return "", p.unexpected()
+	}
+	p.next()
+	return cur.literal, nil
+}
+
+func (p *parser) parseSelect() (*SelectStatement, error) {
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+
+	stmt := &SelectStatement{}
+	if p.curSymbolIs("*") {
+		p.next()
+	} else {
+		for {
+			path, err := p.parsePath()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Projection = append(stmt.Projection, path)
+			if !p.curSymbolIs(",") {
+				break
+			}
+			p.next()
+		}
+	}
+
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	tableName, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	stmt.TableName = tableName
+	if p.curSymbolIs(".") {
+		p.next()
+		indexName, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		stmt.IndexName = &indexName
+	}
+
+	if p.curKeywordIs("WHERE") {
+		p.next()
+		where, err := p.parseCondition()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Where = where
+	}
+
+	stmt.parameterCount = p.parameterCount
+	return stmt, nil
+}
+
+func (p *parser) parseInsert() (*InsertStatement, error) {
+	if err := p.expectKeyword("INSERT"); err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("INTO"); err != nil {
+		return nil, err
+	}
+	tableName, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("VALUE"); err != nil {
+		return nil, err
+	}
+	if !p.curSymbolIs("{") {
+		return nil, p.unexpected()
+	}
+	item, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &InsertStatement{
+		TableName: tableName,
+		Item:      item.(*MapValue),
+	}
+	stmt.parameterCount = p.parameterCount
+	return stmt, nil
+}
+
+func (p *parser) parseUpdate() (*UpdateStatement, error) {
+	if err := p.expectKeyword("UPDATE"); err != nil {
+		return nil, err
+	}
+	tableName, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &UpdateStatement{
+		TableName: tableName,
+	}
+	// SET and REMOVE clauses can be repeated, e.g. SET a = 1 SET b = 2 REMOVE c
+	for p.curKeywordIs("SET") || p.curKeywordIs("REMOVE") {
+		isSet := p.curKeywordIs("SET")
+		p.next()
+		for {
+			path, err := p.parsePath()
+			if err != nil {
+				return nil, err
+			}
+			if isSet {
+				if err := p.expectSymbol("="); err != nil {
+					return nil, err
+				}
+				value, err := p.parseValue()
+				if err != nil {
+					return nil, err
+				}
+				stmt.Set = append(stmt.Set, SetAction{Path: path, Value: value})
+			} else {
+				stmt.Remove = append(stmt.Remove, path)
+			}
+			if !p.curSymbolIs(",") {
+				break
+			}
+			p.next()
+		}
+	}
+	if len(stmt.Set) == 0 && len(stmt.Remove) == 0 {
+		return nil, p.unexpected()
+	}
+
+	if err := p.expectKeyword("WHERE"); err != nil {
+		return nil, err
+	}
+	where, err := p.parseCondition()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Where = where
+
+	stmt.parameterCount = p.parameterCount
+	return stmt, nil
+}
+
+func (p *parser) parseDelete() (*DeleteStatement, error) {
+	if err := p.expectKeyword("DELETE"); err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	tableName, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("WHERE"); err != nil {
+		return nil, err
+	}
+	where, err := p.parseCondition()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &DeleteStatement{
+		TableName: tableName,
+		Where:     where,
+	}
+	stmt.parameterCount = p.parameterCount
+	return stmt, nil
+}
+
+func (p *parser) parsePath() (Path, error) {
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	path := Path{{Name: name}}
+
+	for {
+		if p.curSymbolIs(".") {
+			p.next()
+			name, err := p.parseName()
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, PathElement{Name: name})
+		} else if p.curSymbolIs("[") {
+			p.next()
+			cur := p.cur()
+			index, err := strconv.Atoi(cur.literal)
+			if cur.tokenType != TOKEN_NUMBER || err != nil || index < 0 {
+				return nil, p.unexpected()
+			}
+			p.next()
+			if err := p.expectSymbol("]"); err != nil {
+				return nil, err
+			}
+			path = append(path, PathElement{Index: index, IsIndex: true})
+		} else {
+			return path, nil
+		}
+	}
+}
+
+func (p *parser) parseValue() (Value, error) {
+	cur := p.cur()
+	switch cur.tokenType {
+	case TOKEN_STRING:
+		p.next()
+		return &LiteralValue{AttributeValue: &types.AttributeValueMemberS{Value: cur.literal}}, nil
+	case TOKEN_NUMBER:
+		if _, err := strconv.ParseFloat(cur.literal, 64); err != nil {
+			return nil, p.unexpected()
+		}
+		p.next()
+		return &LiteralValue{AttributeValue: &types.AttributeValueMemberN{Value: cur.literal}}, nil
+	case TOKEN_IDENTIFIER:
+		switch strings.ToUpper(cur.literal) {
+		case "TRUE":
+			p.next()
+			return &LiteralValue{AttributeValue: &types.AttributeValueMemberBOOL{Value: true}}, nil
+		case "FALSE":
+			p.next()
+			return &LiteralValue{AttributeValue: &types.AttributeValueMemberBOOL{Value: false}}, nil
+		case "NULL":
+			p.next()
+			return &LiteralValue{AttributeValue: &types.AttributeValueMemberNULL{Value: true}}, nil
+		}
+	case TOKEN_SYMBOL:
+		switch cur.literal {
+		case "?":
+			p.next()
+			value := &ParameterValue{Index: p.parameterCount}
+			p.parameterCount++
+			return value, nil
+		case "{":
+			return p.parseMapValue()
+		case "[":
+			p.next()
+			elements, err := p.parseValues("]")
+			if err != nil {
+				return nil, err
+			}
+			return &ListValue{Elements: elements}, nil
+		case "<<":
+			p.next()
+			elements, err := p.parseValues(">>")
+			if err != nil {
+				return nil, err
+			}
+			return &SetValue{Elements: elements}, nil
+		}
+	}
+
+	return nil, p.unexpected()
+}
+
+func (p *parser) parseMapValue() (Value, error) {
+	if err := p.expectSymbol("{"); err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]Value)
+	for !p.curSymbolIs("}") {
+		cur := p.cur()
+		if cur.tokenType != TOKEN_STRING && cur.tokenType != TOKEN_QUOTED_IDENTIFIER {
+			return nil, p.unexpected()
+		}
+		p.next()
+		if err := p.expectSymbol(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		fields[cur.literal] = value
+
+		if !p.curSymbolIs(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expectSymbol("}"); err != nil {
+		return nil, err
+	}
+
+	return &MapValue{Fields: fields}, nil
+}
+
+// parseValues parses values separated by commas until the closing symbol
+func (p *parser) parseValues(closing string) ([]Value, error) {
+	values := make([]Value, 0)
+	for !p.curSymbolIs(closing) {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		if !p.curSymbolIs(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expectSymbol(closing); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// parseCondition parses OR with the lowest precedence, then AND, then NOT
+func (p *parser) parseCondition() (Condition, error) {
+	left, err := p.parseAndCondition()
+	if err != nil {
+		return nil, err
+	}
+	for p.curKeywordIs("OR") {
+		p.next()
+		right, err := p.parseAndCondition()
+		if err != nil {
+			return nil, err
+		}
+		left = &OrCondition{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAndCondition() (Condition, error) {
+	left, err := p.parseNotCondition()
+	if err != nil {
+		return nil, err
+	}
+	for p.curKeywordIs("AND") {
+		p.next()
+		right, err := p.parseNotCondition()
+		if err != nil {
+			return nil, err
+		}
+		left = &AndCondition{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNotCondition() (Condition, error) {
+	if p.curKeywordIs("NOT") {
+		p.next()
+		cond, err := p.parseNotCondition()
+		if err != nil {
+			return nil, err
+		}
+		return &NotCondition{Condition: cond}, nil
+	}
+	return p.parsePrimaryCondition()
+}
+
+func (p *parser) parsePrimaryCondition() (Condition, error) {
+	if p.curSymbolIs("(") {
+		p.next()
+		cond, err := p.parseCondition()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		return cond, nil
+	}
+
+	if (p.curKeywordIs("begins_with") || p.curKeywordIs("contains")) && p.peek().tokenType == TOKEN_SYMBOL && p.peek().literal == "(" {
+		name := strings.ToLower(p.cur().literal)
+		p.next()
+		p.next()
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectSymbol(","); err != nil {
+			return nil, err
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		return &FunctionCondition{Name: name, Path: path, Value: value}, nil
+	}
+
+	path, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.curKeywordIs("BETWEEN"):
+		p.next()
+		lowerBound, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("AND"); err != nil {
+			return nil, err
+		}
+		upperBound, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return &BetweenCondition{Path: path, LowerBound: lowerBound, UpperBound: upperBound}, nil
+	case p.curKeywordIs("IN"):
+		p.next()
+		closing := "]"
+		if p.curSymbolIs("(") {
+			closing = ")"
+		} else if !p.curSymbolIs("[") {
+			return nil, p.unexpected()
+		}
+		p.next()
+		values, err := p.parseValues(closing)
+		if err != nil {
+			return nil, err
+		}
+		return &InCondition{Path: path, Values: values}, nil
+	case p.curKeywordIs("IS"):
+		p.next()
+		missing := true
+		if p.curKeywordIs("NOT") {
+			p.next()
+			missing = false
+		}
+		if err := p.expectKeyword("MISSING"); err != nil {
+			return nil, err
+		}
+		return &MissingCondition{Path: path, Missing: missing}, nil
+	}
+
+	cur := p.cur()
+	if cur.tokenType != TOKEN_SYMBOL {
+		return nil, p.unexpected()
+	}
+	operator := cur.literal
+	switch operator {
+	case "=", "<>", "<", "<=", ">", ">=":
+	case "!=":
+		operator = "<>"
+	default:
+		return nil, p.unexpected()
+	}
+	p.next()
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	return &ComparisonCondition{Path: path, Operator: operator, Value: value}, nil
+}
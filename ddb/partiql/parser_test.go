@@ -0,0 +1,143 @@
+package partiql
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestParseSelect(t *testing.T) {
+	stmt, err := Parse(`SELECT title, info.actors[0] FROM "movie"."regionGSI" WHERE "year" = ? AND begins_with(title, 'Hello')`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	selectStmt, ok := stmt.(*SelectStatement)
+	if !ok {
+		t.Fatalf("expected SelectStatement, got %T", stmt)
+	}
+	if selectStmt.TableName != "movie" || selectStmt.IndexName == nil || *selectStmt.IndexName != "regionGSI" {
+		t.Fatalf("unexpected table %s and index %v", selectStmt.TableName, selectStmt.IndexName)
+	}
+	if len(selectStmt.Projection) != 2 || len(selectStmt.Projection[1]) != 3 || !selectStmt.Projection[1][2].IsIndex {
+		t.Fatalf("unexpected projection %v", selectStmt.Projection)
+	}
+	if selectStmt.ParameterCount() != 1 {
+		t.Fatalf("expected 1 parameter, got %d", selectStmt.ParameterCount())
+	}
+
+	builder := NewExpressionBuilder([]types.AttributeValue{&types.AttributeValueMemberN{Value: "2025"}})
+	partitionKeyCondition, sortKeyCondition, filter, ok := SplitKeyCondition(selectStmt.Where, "year", "title")
+	if !ok || filter != nil {
+		t.Fatalf("expected the where clause to be key conditions only")
+	}
+	pk, err := builder.Condition(partitionKeyCondition)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sk, err := builder.Condition(sortKeyCondition)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pk != "#n0 = :v0" || sk != "begins_with(#n1, :v1)" {
+		t.Fatalf("unexpected key conditions %s and %s", pk, sk)
+	}
+	if builder.Names["#n0"] != "year" || builder.Values[":v1"].(*types.AttributeValueMemberS).Value != "Hello" {
+		t.Fatalf("unexpected names %v and values %v", builder.Names, builder.Values)
+	}
+}
+
+func TestParseSelectWithoutKey(t *testing.T) {
+	stmt, err := Parse(`select * from movie where message <> 'x' or (rating between 1 and 5 and not tags is missing)`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	selectStmt := stmt.(*SelectStatement)
+	if selectStmt.Projection != nil {
+		t.Fatalf("expected SELECT * to have no projection")
+	}
+
+	if _, _, _, ok := SplitKeyCondition(selectStmt.Where, "year", "title"); ok {
+		t.Fatalf("expected no key condition")
+	}
+	builder := NewExpressionBuilder(nil)
+	filter, err := builder.Condition(selectStmt.Where)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "(#n0 <> :v0) OR ((#n1 BETWEEN :v1 AND :v2) AND (NOT (attribute_not_exists(#n2))))"
+	if filter != expected {
+		t.Fatalf("expected %s but got %s", expected, filter)
+	}
+}
+
+func TestParseInsert(t *testing.T) {
+	stmt, err := Parse(`INSERT INTO "movie" VALUE {'year': ?, 'title': 'It''s', 'tags': <<'a', 'b'>>, 'info': {'actors': ['x', 1, true, null]}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	insertStmt := stmt.(*InsertStatement)
+
+	builder := NewExpressionBuilder([]types.AttributeValue{&types.AttributeValueMemberN{Value: "2025"}})
+	item, err := builder.Bind(insertStmt.Item)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := item.(*types.AttributeValueMemberM).Value
+	if m["year"].(*types.AttributeValueMemberN).Value != "2025" || m["title"].(*types.AttributeValueMemberS).Value != "It's" {
+		t.Fatalf("unexpected item %v", m)
+	}
+	if len(m["tags"].(*types.AttributeValueMemberSS).Value) != 2 {
+		t.Fatalf("expected tags to be a string set, got %T", m["tags"])
+	}
+	actors := m["info"].(*types.AttributeValueMemberM).Value["actors"].(*types.AttributeValueMemberL).Value
+	if len(actors) != 4 {
+		t.Fatalf("expected 4 actors, got %d", len(actors))
+	}
+}
+
+func TestParseUpdateAndDelete(t *testing.T) {
+	stmt, err := Parse(`UPDATE movie SET message = ?, info.rating = 5 REMOVE tags WHERE year = 2025 AND title = ? AND message = 'a'`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updateStmt := stmt.(*UpdateStatement)
+	if len(updateStmt.Set) != 2 || len(updateStmt.Remove) != 1 || updateStmt.ParameterCount() != 2 {
+		t.Fatalf("unexpected update statement %+v", updateStmt)
+	}
+	key, filter, ok := SplitKey(updateStmt.Where, "year", "title")
+	if !ok || len(key) != 2 || filter == nil {
+		t.Fatalf("expected the key and a filter, got %v and %v", key, filter)
+	}
+	if _, _, ok := SplitKey(updateStmt.Where, "year", "message2"); ok {
+		t.Fatalf("expected the key to be incomplete")
+	}
+
+	stmt, err = Parse(`DELETE FROM movie WHERE year = 2025 AND title = 'Hello'`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := stmt.(*DeleteStatement); !ok {
+		t.Fatalf("expected DeleteStatement, got %T", stmt)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"SELECT FROM movie",
+		"SELECT * FROM movie WHERE",
+		"SELECT * FROM movie WHERE year = ",
+		"SELECT * FROM movie extra",
+		"INSERT INTO movie VALUE ['a']",
+		"UPDATE movie WHERE year = 1",
+		"DELETE FROM movie",
+		"SELECT * FROM movie WHERE title = 'unterminated",
+	}
+
+	for _, tt := range tests {
+		if _, err := Parse(tt); err == nil {
+			t.Errorf("expected error when parsing %q", tt)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package projection
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ocowchun/baddb/ddb/core"
+	"github.com/ocowchun/baddb/ddb/expression"
+	"github.com/ocowchun/baddb/ddb/expression/ast"
+)
+
+// Projection picks the attributes of a ProjectionExpression from an entry
+// https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/Expressions.ProjectionExpressions.html
+type Projection struct {
+	paths []core.PathOperand
+}
+
+func BuildProjection(
+	projectionExpressionContent string,
+	expressionAttributeNames map[string]string,
+) (*Projection, error) {
+	if strings.TrimSpace(projectionExpressionContent) == "" {
+		return nil, fmt.Errorf("The expression can not be empty;")
+	}
+
+	astPaths, err := expression.ParseProjectionExpression(projectionExpressionContent)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]core.PathOperand, len(astPaths))
+	elements := make([][]string, len(astPaths))
+	for i, astPath := range astPaths {
+		path, err := buildPath(astPath, expressionAttributeNames)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = path
+		elements[i] = pathElements(path)
+	}
+
+	// DynamoDB rejects duplicated paths and paths nested in another path
+	for i := range elements {
+		for j := i + 1; j < len(elements); j++ {
+			if isPrefix(elements[i], elements[j]) || isPrefix(elements[j], elements[i]) {
+				return nil, fmt.Errorf(
+					"Two document paths overlap with each other; must remove or rewrite one of these paths; path one: [%s], path two: [%s]",
+					strings.Join(elements[i], ", "),
+					strings.Join(elements[j], ", "),
+				)
+			}
+		}
+	}
+
+	return &Projection{paths: paths}, nil
+}
+
+// Project returns an entry with only the projected attributes, the entry is left untouched
+func (p *Projection) Project(entry *core.Entry) (*core.Entry, error) {
+	return entry.Project(p.paths)
+}
+
+func buildPath(operand ast.PathOperand, expressionAttributeNames map[string]string) (core.PathOperand, error) {
+	switch operand := operand.(type) {
+	case *ast.AttributeNameOperand:
+		if operand.HasSharp {
+			key := operand.Identifier.TokenLiteral()
+			name, ok := expressionAttributeNames[key]
+			if !ok {
+				return nil, fmt.Errorf("An expression attribute name used in the document path is not defined; attribute name: %s", key)
+			}
+			return &core.AttributeNameOperand{
+				Name: name,
+			}, nil
+		} else if operand.HasColon {
+			return nil, fmt.Errorf("Syntax error; token: \"%s\"", operand.Identifier.TokenLiteral())
+		} else {
+			return &core.AttributeNameOperand{
+				Name: operand.Identifier.TokenLiteral(),
+			}, nil
+		}
+	case *ast.IndexOperand:
+		left, err := buildPath(operand.Left, expressionAttributeNames)
+		if err != nil {
+			return nil, err
+		}
+		return &core.IndexOperand{
+			Left:  left,
+			Index: operand.Index,
+		}, nil
+	case *ast.DotOperand:
+		left, err := buildPath(operand.Left, expressionAttributeNames)
+		if err != nil {
+			return nil, err
+		}
+		right, err := buildPath(operand.Right, expressionAttributeNames)
+		if err != nil {
+			return nil, err
+		}
+		return &core.DotOperand{
+			Left:  left,
+			Right: right,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown operand type: %T", operand)
+	}
+}
+
+// pathElements flattens info.actors[0] to [info, actors, [0]], the way DynamoDB prints a document path
+func pathElements(path core.PathOperand) []string {
+	switch path := path.(type) {
+	case *core.AttributeNameOperand:
+		return []string{path.Name}
+	case *core.IndexOperand:
+		return append(pathElements(path.Left), fmt.Sprintf("[%d]", path.Index))
+	case *core.DotOperand:
+		return append(pathElements(path.Left), pathElements(path.Right)...)
+	default:
+		return nil
+	}
+}
+
+func isPrefix(prefix []string, elements []string) bool {
+	if len(prefix) > len(elements) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != elements[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package projection
+
+import (
+	"testing"
+
+	"github.com/ocowchun/baddb/ddb/core"
+)
+
+func TestBuildProjection(t *testing.T) {
+	tests := []struct {
+		expression string
+		names      map[string]string
+		expected   string
+	}{
+		{"", nil, "The expression can not be empty;"},
+		{"#rating", nil, "An expression attribute name used in the document path is not defined; attribute name: #rating"},
+		{"title, :title", nil, "Syntax error; token: \":title\""},
+		{"title, title", nil, "Two document paths overlap with each other; must remove or rewrite one of these paths; path one: [title], path two: [title]"},
+		{"info.rating, #info", map[string]string{"#info": "info"}, "Two document paths overlap with each other; must remove or rewrite one of these paths; path one: [info, rating], path two: [info]"},
+		{"actors[0], actors", nil, "Two document paths overlap with each other; must remove or rewrite one of these paths; path one: [actors, [0]], path two: [actors]"},
+	}
+
+	for _, tt := range tests {
+		_, err := BuildProjection(tt.expression, tt.names)
+		if err == nil {
+			t.Fatalf("expected error when building %s, got nil", tt.expression)
+		}
+		if err.Error() != tt.expected {
+			t.Fatalf("expected %s when building %s, got %s", tt.expected, tt.expression, err.Error())
+		}
+	}
+
+	if _, err := BuildProjection("actors[0], actors[1], info.rating, info.plot", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestProjectionProject(t *testing.T) {
+	str := func(s string) *string { return &s }
+	info := map[string]core.AttributeValue{
+		"rating": {N: str("8")},
+		"plot":   {S: str("nothing happens")},
+	}
+	actors := []core.AttributeValue{{S: str("Alice")}, {S: str("Bob")}}
+	entry := &core.Entry{
+		Body: map[string]core.AttributeValue{
+			"year":   {N: str("2025")},
+			"title":  {S: str("Hello World")},
+			"info":   {M: &info},
+			"actors": {L: &actors},
+		},
+	}
+
+	p, err := BuildProjection("#t, info.rating, actors[1], unknownAttr", map[string]string{"#t": "title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	projected, err := p.Project(entry)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(projected.Body) != 3 {
+		t.Fatalf("expected title, info and actors, got %v", projected.Body)
+	}
+	if *projected.Body["title"].S != "Hello World" {
+		t.Fatalf("expected title Hello World, got %s", *projected.Body["title"].S)
+	}
+	projectedInfo := *projected.Body["info"].M
+	if len(projectedInfo) != 1 || *projectedInfo["rating"].N != "8" {
+		t.Fatalf("expected info to only contain rating, got %v", projectedInfo)
+	}
+	projectedActors := *projected.Body["actors"].L
+	if len(projectedActors) != 1 || *projectedActors[0].S != "Bob" {
+		t.Fatalf("expected actors to only contain Bob, got %v", projectedActors)
+	}
+	if len(entry.Body) != 4 || len(info) != 2 {
+		t.Fatalf("expected entry to be left untouched, got %v", entry.Body)
+	}
+}
@@ -60,11 +60,13 @@ type Query struct {
 	SortKeyPredicate  *Predicate
 	ConsistentRead    bool
 	ExclusiveStartKey *[]byte
-	Limit             int
-	ScanIndexForward  bool
-	TableName         string
-	IndexName         *string
-	Filter            *condition.Condition
+	// ExclusiveStartSortKey is the encoded sort key of the table or the GSI in ExclusiveStartKey, nil without a sort key
+	ExclusiveStartSortKey *[]byte
+	Limit                 int
+	ScanIndexForward      bool
+	TableName             string
+	IndexName             *string
+	Filter                *condition.Condition
 }
 
 func (b *QueryBuilder) BuildQuery() (*Query, error) {
@@ -125,13 +127,13 @@ func (b *QueryBuilder) BuildQuery() (*Query, error) {
 			b.ExpressionAttributeValues,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to build filter expression: %v", err)
+			return nil, &core.InvalidFilterExpressionError{RawErr: err}
 		}
 		query.Filter = filter
 	}
 
 	if len(b.ExclusiveStartKey) > 0 {
-		bs := make([]byte, 0)
+		var partitionKey, sortKey []byte
 		tablePartitionKey := b.TableMetadata.PartitionKeySchema.AttributeName
 		body := make(map[string]core.AttributeValue)
 		if val, ok := b.ExclusiveStartKey[tablePartitionKey]; ok {
@@ -140,14 +142,14 @@ func (b *QueryBuilder) BuildQuery() (*Query, error) {
 				return nil, err
 			}
 
-			bs = attrVal.Bytes()
+			partitionKey = attrVal.Bytes()
 			body[tablePartitionKey] = attrVal
 		} else {
 			return nil, fmt.Errorf("Exclusive Start Key must have same size as table's key schema")
 		}
 
 		// check GSI query exclusive start key
-		if b.IndexName == nil && bytes.Compare(*query.PartitionKey, bs) != 0 {
+		if b.IndexName == nil && bytes.Compare(*query.PartitionKey, partitionKey) != 0 {
 			return nil, fmt.Errorf("The provided starting key does not match the range key predicate")
 		}
 
@@ -159,8 +161,7 @@ func (b *QueryBuilder) BuildQuery() (*Query, error) {
 					return nil, err
 				}
 
-				bs = append(bs, []byte("|")...)
-				bs = append(bs, attrVal.Bytes()...)
+				sortKey = core.EncodeSortKey(attrVal)
 				body[tableSortKey] = attrVal
 			} else {
 				return nil, fmt.Errorf("Exclusive Start Key must have same size as table's key schema")
@@ -181,9 +182,16 @@ func (b *QueryBuilder) BuildQuery() (*Query, error) {
 		}
 
 		if b.expectedSortKey() != nil {
-			if _, ok := b.ExclusiveStartKey[*b.expectedSortKey()]; !ok {
+			val, ok := b.ExclusiveStartKey[*b.expectedSortKey()]
+			if !ok {
 				return nil, fmt.Errorf("Exclusive Start Key must have same size as table's key schema")
 			}
+			attrVal, err := core.TransformDdbAttributeValue(val)
+			if err != nil {
+				return nil, err
+			}
+			exclusiveStartSortKey := core.EncodeSortKey(attrVal)
+			query.ExclusiveStartSortKey = &exclusiveStartSortKey
 		}
 
 		sortKeyPredicate := query.SortKeyPredicate
@@ -219,6 +227,7 @@ func (b *QueryBuilder) BuildQuery() (*Query, error) {
 			}
 		}
 
+		bs := core.EncodePrimaryKey(partitionKey, sortKey)
 		query.ExclusiveStartKey = &bs
 	}
 
@@ -283,10 +292,8 @@ func (b *QueryBuilder) extractAttributeValue(identifier *ast.AttributeValueIdent
 
 func (b *QueryBuilder) expectedPartitionKey() *string {
 	if b.IndexName != nil {
-		for _, gsi := range b.TableMetadata.GlobalSecondaryIndexSettings {
-			if *gsi.IndexName == *b.IndexName {
-				return gsi.PartitionKeyName()
-			}
+		if gsi, ok := b.TableMetadata.GetSecondaryIndexSetting(*b.IndexName); ok {
+			return gsi.PartitionKeyName()
 		}
 		log.Fatalf("index %s not found", *b.IndexName)
 	}
@@ -295,10 +302,8 @@ func (b *QueryBuilder) expectedPartitionKey() *string {
 
 func (b *QueryBuilder) expectedSortKey() *string {
 	if b.IndexName != nil {
-		for _, gsi := range b.TableMetadata.GlobalSecondaryIndexSettings {
-			if *gsi.IndexName == *b.IndexName {
-				return gsi.SortKeyName()
-			}
+		if gsi, ok := b.TableMetadata.GetSecondaryIndexSetting(*b.IndexName); ok {
+			return gsi.SortKeyName()
 		}
 		log.Fatalf("index %s not found", *b.IndexName)
 	}
@@ -467,15 +472,17 @@ func (b *QueryBuilder) EvaluatePredicateExpression(expression ast.PredicateExpre
 		if !isSortKey {
 			return nil, fmt.Errorf("only sort key support begins_with predicate expression")
 		}
+		if keySchema := b.TableMetadata.FindKeySchema(key); keySchema != nil && keySchema.AttributeType == core.ScalarAttributeTypeN {
+			return nil, fmt.Errorf("Invalid KeyConditionExpression: Incorrect operand type for operator or function; operator or function: begins_with, operand type: N")
+		}
 
 		prefixVal, err := b.extractAttributeValue(pred.Value)
 		if err != nil {
 			return nil, err
 		}
-		if prefixVal.S == nil {
-			return nil, fmt.Errorf("begins_with predicate value must be a string")
+		if prefixVal.S == nil && prefixVal.B == nil {
+			return nil, fmt.Errorf("begins_with predicate value must be a string or binary")
 		}
-		prefix := *prefixVal.S
 
 		return func(entry *core.Entry) (bool, error) {
 			val, ok := entry.Body[key]
@@ -483,7 +490,7 @@ func (b *QueryBuilder) EvaluatePredicateExpression(expression ast.PredicateExpre
 				return false, fmt.Errorf("key %s not found", key)
 			}
 
-			return val.BeginsWith(prefix)
+			return val.BeginsWith(*prefixVal)
 		}, nil
 	}
 
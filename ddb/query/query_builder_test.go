@@ -43,6 +43,48 @@ func TestSimplePredicateExpression(t *testing.T) {
 	}
 }
 
+func TestScanIndexForward(t *testing.T) {
+	keyConditionExpression, err := expression.ParseKeyConditionExpression("createdYear = :year")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	tests := []struct {
+		scanIndexForward *bool
+		expected         bool
+	}{
+		{scanIndexForward: nil, expected: true},
+		{scanIndexForward: aws.Bool(true), expected: true},
+		{scanIndexForward: aws.Bool(false), expected: false},
+	}
+
+	for _, tt := range tests {
+		builder := &QueryBuilder{
+			KeyConditionExpression: keyConditionExpression,
+			ExpressionAttributeValues: map[string]core.AttributeValue{
+				":year": {
+					N: aws.String("2025"),
+				},
+			},
+			TableMetadata: &core.TableMetaData{
+				PartitionKeySchema: &core.KeySchema{
+					AttributeName: "createdYear",
+					AttributeType: core.ScalarAttributeTypeN,
+				},
+			},
+			ScanIndexForward: tt.scanIndexForward,
+		}
+
+		query, err := builder.BuildQuery()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if query.ScanIndexForward != tt.expected {
+			t.Fatalf("Expected ScanIndexForward to be %v, got %v", tt.expected, query.ScanIndexForward)
+		}
+	}
+}
+
 func TestSimplePredicateExpression_With_SortKey(t *testing.T) {
 	type TestCase struct {
 		exp        string
@@ -229,3 +271,78 @@ func TestSimplePredicateExpression_With_GSI(t *testing.T) {
 		t.Fatalf("Expected partition key to be %v, got %v", exp, *query.PartitionKey)
 	}
 }
+
+func TestBeginsWithPredicateExpression_With_GSI(t *testing.T) {
+	indexName := "regionCode-index"
+	tableMetadata := &core.TableMetaData{
+		PartitionKeySchema: &core.KeySchema{
+			AttributeName: "year",
+			AttributeType: core.ScalarAttributeTypeN,
+		},
+		GlobalSecondaryIndexSettings: []core.GlobalSecondaryIndexSetting{
+			{
+				IndexName: &indexName,
+				PartitionKeySchema: &core.KeySchema{
+					AttributeName: "regionCode",
+					AttributeType: core.ScalarAttributeTypeS,
+				},
+				SortKeySchema: &core.KeySchema{
+					AttributeName: "countryCode",
+					AttributeType: core.ScalarAttributeTypeS,
+				},
+			},
+		},
+	}
+
+	keyConditionExpression, err := expression.ParseKeyConditionExpression("regionCode = :regionCode AND begins_with(countryCode, :prefix)")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	builder := &QueryBuilder{
+		KeyConditionExpression: keyConditionExpression,
+		ExpressionAttributeValues: map[string]core.AttributeValue{
+			":regionCode": {S: aws.String("9527")},
+			":prefix":     {S: aws.String("U")},
+		},
+		TableMetadata: tableMetadata,
+		IndexName:     &indexName,
+	}
+	query, err := builder.BuildQuery()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	pred := *query.SortKeyPredicate
+	for countryCode, expected := range map[string]bool{"US": true, "UK": true, "TW": false} {
+		match, err := pred(&core.Entry{Body: map[string]core.AttributeValue{"countryCode": {S: aws.String(countryCode)}}})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if match != expected {
+			t.Fatalf("Expected %s match to be %v, got %v", countryCode, expected, match)
+		}
+	}
+
+	// begins_with is only for string and binary sort keys
+	tableMetadata.GlobalSecondaryIndexSettings[0].SortKeySchema = &core.KeySchema{
+		AttributeName: "score",
+		AttributeType: core.ScalarAttributeTypeN,
+	}
+	keyConditionExpression, err = expression.ParseKeyConditionExpression("regionCode = :regionCode AND begins_with(score, :prefix)")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	builder = &QueryBuilder{
+		KeyConditionExpression: keyConditionExpression,
+		ExpressionAttributeValues: map[string]core.AttributeValue{
+			":regionCode": {S: aws.String("9527")},
+			":prefix":     {N: aws.String("1")},
+		},
+		TableMetadata: tableMetadata,
+		IndexName:     &indexName,
+	}
+	_, err = builder.BuildQuery()
+	expected := "Invalid KeyConditionExpression: Incorrect operand type for operator or function; operator or function: begins_with, operand type: N"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("Expected error %q, got %v", expected, err)
+	}
+}
@@ -14,6 +14,7 @@ type DeleteRequestBuilder struct {
 	ExpressionAttributeNames  map[string]string
 	ExpressionAttributeValues map[string]types.AttributeValue
 	Key                       map[string]types.AttributeValue
+	TableMetaData             *core.TableMetaData
 }
 
 func (b *DeleteRequestBuilder) Build() (*storage.DeleteRequest, error) {
@@ -26,13 +27,23 @@ func (b *DeleteRequestBuilder) Build() (*storage.DeleteRequest, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := ValidateKey(entry, b.TableMetaData); err != nil {
+		return nil, err
+	}
 
 	var cond *condition.Condition
 	if b.ConditionExpression != nil {
+		attrVals, err := core.TransformAttributeValueMap(b.ExpressionAttributeValues)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateExpressionAttributeValues(attrVals); err != nil {
+			return nil, err
+		}
 		cond, err = condition.BuildCondition(
 			*b.ConditionExpression,
 			b.ExpressionAttributeNames,
-			entry.Body,
+			attrVals,
 		)
 		if err != nil {
 			return nil, &core.InvalidConditionExpressionError{
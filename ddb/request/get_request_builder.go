@@ -27,6 +27,9 @@ func (b *GetRequestBuilder) Build() (*storage.GetRequest, error) {
 		if !pkValue.IsScalarAttributeType(b.TableMetaData.PartitionKeySchema.AttributeType) {
 			return nil, fmt.Errorf("One or more parameter values were invalid: Type mismatch for key")
 		}
+		if err := validateNotEmptyKey(key, b.TableMetaData.PartitionKeySchema.AttributeName); err != nil {
+			return nil, err
+		}
 	} else {
 		return nil, fmt.Errorf("One of the required keys was not given a value")
 	}
@@ -35,6 +38,9 @@ func (b *GetRequestBuilder) Build() (*storage.GetRequest, error) {
 			if !skValue.IsScalarAttributeType(b.TableMetaData.SortKeySchema.AttributeType) {
 				return nil, fmt.Errorf("One or more parameter values were invalid: Type mismatch for key")
 			}
+			if err := validateNotEmptyKey(key, b.TableMetaData.SortKeySchema.AttributeName); err != nil {
+				return nil, err
+			}
 		} else {
 			return nil, fmt.Errorf("One of the required keys was not given a value")
 		}
@@ -0,0 +1,127 @@
+package request
+
+import (
+	"fmt"
+
+	"github.com/ocowchun/baddb/ddb/core"
+)
+
+// ValidateItem checks an item written by a put or an import has valid key attributes and no empty sets
+func ValidateItem(entry *core.Entry, table *core.TableMetaData) error {
+	if err := validateItemKey(entry, table); err != nil {
+		return err
+	}
+	return validateNoEmptySets(entry.Body)
+}
+
+// validateItemKey checks an item written by a put has every key attribute of the table, of the declared type and
+// not empty
+func validateItemKey(entry *core.Entry, table *core.TableMetaData) error {
+	if table == nil || table.PartitionKeySchema == nil {
+		return nil
+	}
+	for _, keySchema := range []*core.KeySchema{table.PartitionKeySchema, table.SortKeySchema} {
+		if keySchema == nil {
+			continue
+		}
+		val, ok := entry.Body[keySchema.AttributeName]
+		if !ok {
+			return fmt.Errorf("One or more parameter values were invalid: Missing the key %s in the item", keySchema.AttributeName)
+		}
+		if !val.IsScalarAttributeType(keySchema.AttributeType) {
+			return fmt.Errorf("One or more parameter values were invalid: Type mismatch for key %s expected: %s actual: %s", keySchema.AttributeName, keySchema.AttributeType, val.Type())
+		}
+		if err := validateNotEmptyKey(entry, keySchema.AttributeName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateKey checks the Key of a delete, an update or a condition check has exactly the key attributes of the
+// table, of the declared types and not empty
+func ValidateKey(key *core.Entry, table *core.TableMetaData) error {
+	if table == nil || table.PartitionKeySchema == nil {
+		return nil
+	}
+	keyCount := 0
+	for _, keySchema := range []*core.KeySchema{table.PartitionKeySchema, table.SortKeySchema} {
+		if keySchema == nil {
+			continue
+		}
+		keyCount++
+		val, ok := key.Body[keySchema.AttributeName]
+		if !ok || !val.IsScalarAttributeType(keySchema.AttributeType) {
+			return fmt.Errorf("The provided key element does not match the schema")
+		}
+		if err := validateNotEmptyKey(key, keySchema.AttributeName); err != nil {
+			return err
+		}
+	}
+	if len(key.Body) != keyCount {
+		return fmt.Errorf("The provided key element does not match the schema")
+	}
+	return nil
+}
+
+func validateNotEmptyKey(entry *core.Entry, attributeName string) error {
+	if emptyValue, ok := EmptyKeyValue(entry, attributeName); ok {
+		return fmt.Errorf("One or more parameter values are not valid. The AttributeValue for a key attribute cannot contain an empty %s value. Key: %s", emptyValue, attributeName)
+	}
+	return nil
+}
+
+// EmptyKeyValue returns "string" or "binary" when the attribute is an empty S or B, which a key attribute can't be
+func EmptyKeyValue(entry *core.Entry, attributeName string) (string, bool) {
+	val, ok := entry.Body[attributeName]
+	if !ok {
+		return "", false
+	}
+	if val.S != nil && *val.S == "" {
+		return "string", true
+	}
+	if val.B != nil && len(*val.B) == 0 {
+		return "binary", true
+	}
+	return "", false
+}
+
+// validateNoEmptySets rejects an empty SS, NS or BS anywhere in vals, DynamoDB has no empty sets
+func validateNoEmptySets(vals map[string]core.AttributeValue) error {
+	for _, val := range vals {
+		if err := validateNotEmptySet(val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateExpressionAttributeValues rejects an empty set in ExpressionAttributeValues
+func validateExpressionAttributeValues(vals map[string]core.AttributeValue) error {
+	for name, val := range vals {
+		if err := validateNotEmptySet(val); err != nil {
+			return fmt.Errorf("ExpressionAttributeValues contains invalid value: %s for key %s", err, name)
+		}
+	}
+	return nil
+}
+
+func validateNotEmptySet(val core.AttributeValue) error {
+	switch {
+	case val.SS != nil && len(*val.SS) == 0:
+		return fmt.Errorf("One or more parameter values were invalid: An string set  may not be empty")
+	case val.NS != nil && len(*val.NS) == 0:
+		return fmt.Errorf("One or more parameter values were invalid: An number set  may not be empty")
+	case val.BS != nil && len(*val.BS) == 0:
+		return fmt.Errorf("One or more parameter values were invalid: Binary sets should not be empty")
+	case val.L != nil:
+		for _, v := range *val.L {
+			if err := validateNotEmptySet(v); err != nil {
+				return err
+			}
+		}
+	case val.M != nil:
+		return validateNoEmptySets(*val.M)
+	}
+	return nil
+}
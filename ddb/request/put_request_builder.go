@@ -14,6 +14,7 @@ type PutRequestBuilder struct {
 	ExpressionAttributeValues map[string]types.AttributeValue
 	Item                      map[string]types.AttributeValue
 	TableName                 *string
+	TableMetaData             *core.TableMetaData
 }
 
 func (b *PutRequestBuilder) Build() (*storage.PutRequest, error) {
@@ -27,12 +28,18 @@ func (b *PutRequestBuilder) Build() (*storage.PutRequest, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := ValidateItem(entry, b.TableMetaData); err != nil {
+		return nil, err
+	}
 
 	if b.ConditionExpression != nil {
 		attrVals, err := core.TransformAttributeValueMap(b.ExpressionAttributeValues)
 		if err != nil {
 			return nil, err
 		}
+		if err := validateExpressionAttributeValues(attrVals); err != nil {
+			return nil, err
+		}
 		cond, err = condition.BuildCondition(
 			*b.ConditionExpression,
 			b.ExpressionAttributeNames,
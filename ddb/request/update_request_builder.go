@@ -2,6 +2,9 @@ package request
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/ocowchun/baddb/ddb/condition"
 	"github.com/ocowchun/baddb/ddb/core"
@@ -16,6 +19,9 @@ type UpdateRequestBuilder struct {
 	ExpressionAttributeValues map[string]types.AttributeValue
 	ConditionExpression       *string
 	Key                       map[string]types.AttributeValue
+	TableMetaData             *core.TableMetaData
+	// AttributeUpdates is the legacy form of UpdateExpression, it is translated to an UpdateExpression
+	AttributeUpdates map[string]types.AttributeValueUpdate
 }
 
 func (b *UpdateRequestBuilder) Build() (*storage.UpdateRequest, error) {
@@ -24,23 +30,55 @@ func (b *UpdateRequestBuilder) Build() (*storage.UpdateRequest, error) {
 	}
 	tableName := *b.TableName
 
-	if b.UpdateExpression == nil {
+	updateExpression := b.UpdateExpression
+	exprAttrNames := b.ExpressionAttributeNames
+	exprAttrValues := b.ExpressionAttributeValues
+	if len(b.AttributeUpdates) > 0 {
+		if err := b.validateAttributeUpdates(); err != nil {
+			return nil, err
+		}
+
+		exp, names, values, err := buildLegacyUpdateExpression(b.AttributeUpdates)
+		if err != nil {
+			return nil, err
+		}
+		updateExpression = &exp
+		exprAttrNames = names
+		exprAttrValues = values
+	}
+
+	if updateExpression == nil {
 		msg := "UpdateExpression must be provided"
 		return nil, fmt.Errorf(msg)
 	}
+	if strings.TrimSpace(*updateExpression) == "" {
+		return nil, &core.InvalidUpdateExpressionError{
+			RawErr: fmt.Errorf("The expression can not be empty;"),
+		}
+	}
 
-	exprVals, err := core.NewEntryFromItem(b.ExpressionAttributeValues)
+	exprVals, err := core.NewEntryFromItem(exprAttrValues)
+	if err != nil {
+		return nil, err
+	}
+	if len(b.AttributeUpdates) > 0 {
+		err = validateNoEmptySets(exprVals.Body)
+	} else {
+		err = validateExpressionAttributeValues(exprVals.Body)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	updateOperation, err := update.BuildUpdateOperation(
-		*b.UpdateExpression,
-		b.ExpressionAttributeNames,
+		*updateExpression,
+		exprAttrNames,
 		exprVals.Body,
 	)
 	if err != nil {
-		return nil, err
+		return nil, &core.InvalidUpdateExpressionError{
+			RawErr: err,
+		}
 	}
 
 	var cond *condition.Condition
@@ -61,6 +99,9 @@ func (b *UpdateRequestBuilder) Build() (*storage.UpdateRequest, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := ValidateKey(key, b.TableMetaData); err != nil {
+		return nil, err
+	}
 
 	req := &storage.UpdateRequest{
 		Key:             key,
@@ -70,3 +111,93 @@ func (b *UpdateRequestBuilder) Build() (*storage.UpdateRequest, error) {
 	}
 	return req, nil
 }
+
+func (b *UpdateRequestBuilder) validateAttributeUpdates() error {
+	expressionParameters := make([]string, 0)
+	if b.UpdateExpression != nil {
+		expressionParameters = append(expressionParameters, "UpdateExpression")
+	}
+	if b.ConditionExpression != nil {
+		expressionParameters = append(expressionParameters, "ConditionExpression")
+	}
+	if len(expressionParameters) > 0 {
+		return fmt.Errorf(
+			"Can not use both expression and non-expression parameters in the same request: Non-expression parameters: {AttributeUpdates} Expression parameters: {%s}",
+			strings.Join(expressionParameters, ", "),
+		)
+	}
+
+	if len(b.ExpressionAttributeNames) > 0 {
+		return fmt.Errorf("ExpressionAttributeNames can only be specified when using expressions")
+	}
+	if len(b.ExpressionAttributeValues) > 0 {
+		return fmt.Errorf("ExpressionAttributeValues can only be specified when using expressions")
+	}
+
+	return nil
+}
+
+// buildLegacyUpdateExpression translates AttributeUpdates to an update expression with its attribute names and values,
+// PUT becomes SET, DELETE becomes REMOVE without a value or DELETE with a value, and ADD stays ADD
+func buildLegacyUpdateExpression(attributeUpdates map[string]types.AttributeValueUpdate) (string, map[string]string, map[string]types.AttributeValue, error) {
+	attributeNames := make([]string, 0, len(attributeUpdates))
+	for name := range attributeUpdates {
+		attributeNames = append(attributeNames, name)
+	}
+	sort.Strings(attributeNames)
+
+	names := make(map[string]string)
+	values := make(map[string]types.AttributeValue)
+	setActions := make([]string, 0)
+	removeActions := make([]string, 0)
+	addActions := make([]string, 0)
+	deleteActions := make([]string, 0)
+	for i, attributeName := range attributeNames {
+		attributeUpdate := attributeUpdates[attributeName]
+		namePlaceholder := fmt.Sprintf("#attr%d", i)
+		valuePlaceholder := fmt.Sprintf(":attr%d", i)
+		names[namePlaceholder] = attributeName
+
+		action := attributeUpdate.Action
+		if action == "" {
+			action = types.AttributeActionPut
+		}
+		if attributeUpdate.Value == nil && action != types.AttributeActionDelete {
+			return "", nil, nil, fmt.Errorf("Only DELETE action is allowed when no attribute value is specified")
+		}
+		if attributeUpdate.Value != nil {
+			values[valuePlaceholder] = attributeUpdate.Value
+		}
+
+		switch action {
+		case types.AttributeActionPut:
+			setActions = append(setActions, namePlaceholder+" = "+valuePlaceholder)
+		case types.AttributeActionAdd:
+			addActions = append(addActions, namePlaceholder+" "+valuePlaceholder)
+		case types.AttributeActionDelete:
+			if attributeUpdate.Value == nil {
+				removeActions = append(removeActions, namePlaceholder)
+			} else {
+				deleteActions = append(deleteActions, namePlaceholder+" "+valuePlaceholder)
+			}
+		default:
+			return "", nil, nil, fmt.Errorf("Invalid AttributeUpdates action %s", action)
+		}
+	}
+
+	clauses := make([]string, 0)
+	if len(setActions) > 0 {
+		clauses = append(clauses, "SET "+strings.Join(setActions, ", "))
+	}
+	if len(removeActions) > 0 {
+		clauses = append(clauses, "REMOVE "+strings.Join(removeActions, ", "))
+	}
+	if len(addActions) > 0 {
+		clauses = append(clauses, "ADD "+strings.Join(addActions, ", "))
+	}
+	if len(deleteActions) > 0 {
+		clauses = append(clauses, "DELETE "+strings.Join(deleteActions, ", "))
+	}
+
+	return strings.Join(clauses, " "), names, values, nil
+}
@@ -0,0 +1,105 @@
+package scan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ocowchun/baddb/ddb/core"
+)
+
+// legacyFilterExpression translates the legacy ScanFilter and ConditionalOperator into a filter expression,
+// attribute names and values are replaced by placeholders, so they don't conflict with reserved words.
+func legacyFilterExpression(
+	scanFilter map[string]types.Condition,
+	conditionalOperator types.ConditionalOperator,
+) (string, map[string]string, map[string]core.AttributeValue, error) {
+	joinOperator := " AND "
+	switch conditionalOperator {
+	case "", types.ConditionalOperatorAnd:
+	case types.ConditionalOperatorOr:
+		joinOperator = " OR "
+	default:
+		return "", nil, nil, fmt.Errorf("1 validation error detected: Value '%s' at 'conditionalOperator' failed to satisfy constraint: Member must satisfy enum value set: [AND, OR]", conditionalOperator)
+	}
+
+	attributeNames := make([]string, 0, len(scanFilter))
+	for attributeName := range scanFilter {
+		attributeNames = append(attributeNames, attributeName)
+	}
+	sort.Strings(attributeNames)
+
+	expressionAttributeNames := make(map[string]string)
+	expressionAttributeValues := make(map[string]core.AttributeValue)
+	clauses := make([]string, 0, len(attributeNames))
+	for i, attributeName := range attributeNames {
+		cond := scanFilter[attributeName]
+		name := fmt.Sprintf("#legacy%d", i)
+		expressionAttributeNames[name] = attributeName
+
+		values := make([]string, len(cond.AttributeValueList))
+		for j, val := range cond.AttributeValueList {
+			attrVal, err := core.TransformDdbAttributeValue(val)
+			if err != nil {
+				return "", nil, nil, err
+			}
+			values[j] = fmt.Sprintf(":legacy%d_%d", i, j)
+			expressionAttributeValues[values[j]] = attrVal
+		}
+
+		clause, err := legacyClause(name, cond.ComparisonOperator, values)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+
+	return strings.Join(clauses, joinOperator), expressionAttributeNames, expressionAttributeValues, nil
+}
+
+var legacyComparators = map[types.ComparisonOperator]string{
+	types.ComparisonOperatorEq: "=",
+	types.ComparisonOperatorNe: "<>",
+	types.ComparisonOperatorLe: "<=",
+	types.ComparisonOperatorLt: "<",
+	types.ComparisonOperatorGe: ">=",
+	types.ComparisonOperatorGt: ">",
+}
+
+func legacyClause(name string, comparisonOperator types.ComparisonOperator, values []string) (string, error) {
+	validValueCount := len(values) == 1
+	switch comparisonOperator {
+	case types.ComparisonOperatorNotNull, types.ComparisonOperatorNull:
+		validValueCount = len(values) == 0
+	case types.ComparisonOperatorBetween:
+		validValueCount = len(values) == 2
+	case types.ComparisonOperatorIn:
+		validValueCount = len(values) > 0
+	}
+	if !validValueCount {
+		return "", fmt.Errorf("One or more parameter values were invalid: Invalid number of argument(s) for the %s ComparisonOperator", comparisonOperator)
+	}
+
+	if comparator, ok := legacyComparators[comparisonOperator]; ok {
+		return fmt.Sprintf("%s %s %s", name, comparator, values[0]), nil
+	}
+	switch comparisonOperator {
+	case types.ComparisonOperatorNotNull:
+		return fmt.Sprintf("attribute_exists(%s)", name), nil
+	case types.ComparisonOperatorNull:
+		return fmt.Sprintf("attribute_not_exists(%s)", name), nil
+	case types.ComparisonOperatorContains:
+		return fmt.Sprintf("contains(%s, %s)", name, values[0]), nil
+	case types.ComparisonOperatorNotContains:
+		return fmt.Sprintf("NOT contains(%s, %s)", name, values[0]), nil
+	case types.ComparisonOperatorBeginsWith:
+		return fmt.Sprintf("begins_with(%s, %s)", name, values[0]), nil
+	case types.ComparisonOperatorBetween:
+		return fmt.Sprintf("%s BETWEEN %s AND %s", name, values[0], values[1]), nil
+	case types.ComparisonOperatorIn:
+		return fmt.Sprintf("%s IN (%s)", name, strings.Join(values, ", ")), nil
+	default:
+		return "", fmt.Errorf("1 validation error detected: Value '%s' at 'scanFilter.comparisonOperator' failed to satisfy constraint: Member must satisfy enum value set: [IN, NULL, BETWEEN, LT, NOT_CONTAINS, EQ, GT, NOT_NULL, NE, LE, BEGINS_WITH, GE, CONTAINS]", comparisonOperator)
+	}
+}
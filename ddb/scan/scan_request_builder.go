@@ -7,6 +7,10 @@ import (
 	"github.com/ocowchun/baddb/ddb/core"
 )
 
+// MAX_TOTAL_SEGMENTS is the max TotalSegments of a parallel scan
+// https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_Scan.html#DDB-Scan-request-TotalSegments
+const MAX_TOTAL_SEGMENTS = 1000000
+
 type RequestBuilder struct {
 	FilterExpressionStr       *string
 	ExpressionAttributeValues map[string]core.AttributeValue
@@ -18,6 +22,9 @@ type RequestBuilder struct {
 	IndexName                 *string
 	Segment                   *int32
 	TotalSegments             *int32
+	// ScanFilter and ConditionalOperator are the legacy parameters of FilterExpression
+	ScanFilter          map[string]types.Condition
+	ConditionalOperator types.ConditionalOperator
 }
 
 type Request struct {
@@ -31,15 +38,11 @@ type Request struct {
 	TotalSegments     *int32
 }
 
-type InvalidFilterExpressionError struct {
-	rawErr error
-}
-
-func (e *InvalidFilterExpressionError) Error() string {
-	return fmt.Sprintf("Invalid FilterExpression: %v", e.rawErr)
-}
-
 func (b *RequestBuilder) Build() (*Request, error) {
+	if err := b.validateSegment(); err != nil {
+		return nil, err
+	}
+
 	req := &Request{
 		ConsistentRead: b.ConsistentRead != nil && *b.ConsistentRead,
 		TableName:      b.TableMetadata.Name,
@@ -47,9 +50,6 @@ func (b *RequestBuilder) Build() (*Request, error) {
 		Segment:        b.Segment,
 		TotalSegments:  b.TotalSegments,
 	}
-	if req.ConsistentRead && req.IndexName != nil {
-		return nil, fmt.Errorf("ConsistentRead cannot be true when IndexName is set")
-	}
 
 	if b.Limit != nil {
 		req.Limit = int(*b.Limit)
@@ -63,20 +63,35 @@ func (b *RequestBuilder) Build() (*Request, error) {
 			b.ExpressionAttributeValues,
 		)
 		if err != nil {
-			return nil, &InvalidFilterExpressionError{rawErr: err}
+			return nil, &core.InvalidFilterExpressionError{RawErr: err}
+		}
+		req.Filter = filter
+	}
+	if len(b.ScanFilter) > 0 {
+		if b.FilterExpressionStr != nil {
+			return nil, fmt.Errorf("Can not use both expression and non-expression parameters in the same request: Non-expression parameters: {ScanFilter} Expression parameters: {FilterExpression}")
+		}
+
+		filterExpression, names, values, err := legacyFilterExpression(b.ScanFilter, b.ConditionalOperator)
+		if err != nil {
+			return nil, err
+		}
+		filter, err := condition.BuildCondition(filterExpression, names, values)
+		if err != nil {
+			return nil, &core.InvalidFilterExpressionError{RawErr: err}
 		}
 		req.Filter = filter
 	}
 
 	if len(b.ExclusiveStartKey) > 0 {
-		bs := make([]byte, 0)
+		var partitionKey, sortKey []byte
 		tablePartitionKey := b.TableMetadata.PartitionKeySchema.AttributeName
 		if val, ok := b.ExclusiveStartKey[tablePartitionKey]; ok {
 			attrVal, err := core.TransformDdbAttributeValue(val)
 			if err != nil {
 				return nil, err
 			}
-			bs = attrVal.Bytes()
+			partitionKey = attrVal.Bytes()
 		} else {
 			return nil, fmt.Errorf("partition key %s not found in ExclusiveStartKey", tablePartitionKey)
 		}
@@ -89,14 +104,42 @@ func (b *RequestBuilder) Build() (*Request, error) {
 					return nil, err
 				}
 
-				bs = append(bs, []byte("|")...)
-				bs = append(bs, attrVal.Bytes()...)
+				sortKey = core.EncodeSortKey(attrVal)
 			} else {
 				return nil, fmt.Errorf("sort key %s not found in ExclusiveStartKey", tableSortKey)
 			}
 		}
+		bs := core.EncodePrimaryKey(partitionKey, sortKey)
 		req.ExclusiveStartKey = &bs
 	}
 
 	return req, nil
 }
+
+// validateSegment checks Segment and TotalSegments of a parallel scan are given together and 0 <= Segment < TotalSegments
+func (b *RequestBuilder) validateSegment() error {
+	if b.Segment != nil && (*b.Segment < 0 || *b.Segment >= MAX_TOTAL_SEGMENTS) {
+		bound := "greater than or equal to 0"
+		if *b.Segment >= MAX_TOTAL_SEGMENTS {
+			bound = fmt.Sprintf("less than or equal to %d", MAX_TOTAL_SEGMENTS-1)
+		}
+		return fmt.Errorf("1 validation error detected: Value '%d' at 'segment' failed to satisfy constraint: Member must have value %s", *b.Segment, bound)
+	}
+	if b.TotalSegments != nil && (*b.TotalSegments < 1 || *b.TotalSegments > MAX_TOTAL_SEGMENTS) {
+		bound := "greater than or equal to 1"
+		if *b.TotalSegments > MAX_TOTAL_SEGMENTS {
+			bound = fmt.Sprintf("less than or equal to %d", MAX_TOTAL_SEGMENTS)
+		}
+		return fmt.Errorf("1 validation error detected: Value '%d' at 'totalSegments' failed to satisfy constraint: Member must have value %s", *b.TotalSegments, bound)
+	}
+	if b.Segment != nil && b.TotalSegments == nil {
+		return fmt.Errorf("The TotalSegments parameter is required but was not present in the request when Segment parameter is present")
+	}
+	if b.Segment == nil && b.TotalSegments != nil {
+		return fmt.Errorf("The Segment parameter is required but was not present in the request when parameter TotalSegments is present")
+	}
+	if b.Segment != nil && *b.Segment >= *b.TotalSegments {
+		return fmt.Errorf("The Segment parameter is zero-based and must be less than parameter TotalSegments: Segment: %d is out of bounds for TotalSegments: %d", *b.Segment, *b.TotalSegments)
+	}
+	return nil
+}
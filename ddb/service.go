@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,6 +14,7 @@ import (
 	"github.com/ocowchun/baddb/ddb/condition"
 	"github.com/ocowchun/baddb/ddb/core"
 	"github.com/ocowchun/baddb/ddb/expression"
+	"github.com/ocowchun/baddb/ddb/projection"
 	"github.com/ocowchun/baddb/ddb/query"
 	"github.com/ocowchun/baddb/ddb/request"
 	"github.com/ocowchun/baddb/ddb/scan"
@@ -22,31 +25,145 @@ type Service struct {
 	tableLock          sync.RWMutex
 	tableMetadataStore map[string]*core.TableMetaData
 	storage            *storage.InnerStorage
+	config             Config
+	// clientRequestTokens makes TransactWriteItems idempotent by ClientRequestToken
+	clientRequestTokens clientRequestTokens
 }
 
+type Config struct {
+	// GsiStronglyConsistent makes GSI reads reflect base table writes immediately, regardless of gsiDelaySeconds
+	GsiStronglyConsistent bool
+	// DbPath is the sqlite file used by a file-backed service, an in-memory service dumps its tables to it on Close
+	DbPath string
+	// FileBacked stores tables in DbPath instead of memory, tables already in DbPath are loaded on start
+	FileBacked bool
+	// Strict enables the item validations DynamoDB does but baddb skips by default, see validateStrictItem
+	Strict bool
+	// TableCreationDelay keeps a new table CREATING for the delay, reads and writes to it fail until it's ACTIVE
+	TableCreationDelay time.Duration
+	// IndexCreationDelay keeps a GSI added by UpdateTable CREATING for the delay, it's backfilling for the second half
+	// of the delay and can't be read until it's ACTIVE
+	IndexCreationDelay time.Duration
+	// MaxBatchGetItemResponseBytes caps the size of items returned by a BatchGetItem call, keys beyond it are
+	// moved to UnprocessedKeys. Defaults to DEFAULT_MAX_BATCH_GET_ITEM_RESPONSE_BYTES
+	MaxBatchGetItemResponseBytes int
+}
+
+// DEFAULT_MAX_BATCH_GET_ITEM_RESPONSE_BYTES is the 16MB cap DynamoDB puts on a BatchGetItem response
+const DEFAULT_MAX_BATCH_GET_ITEM_RESPONSE_BYTES = 16 * 1024 * 1024
+
+// MAX_BATCH_WRITE_ITEM_REQUESTS is the max number of puts and deletes of a BatchWriteItem call
+const MAX_BATCH_WRITE_ITEM_REQUESTS = 25
+
+// MAX_BATCH_WRITE_ITEM_REQUEST_BYTES is the 16MB cap DynamoDB puts on the items of a BatchWriteItem call
+const MAX_BATCH_WRITE_ITEM_REQUEST_BYTES = 16 * 1024 * 1024
+
+// MAX_LIST_TABLES_LIMIT is the default and the max number of table names ListTables returns at once
+const MAX_LIST_TABLES_LIMIT = 100
+
 func NewDdbService() *Service {
-	innerStorage := storage.NewInnerStorage()
+	svc, err := NewDdbServiceWithConfig(Config{})
+	if err != nil {
+		panic(err)
+	}
+	return svc
+}
+
+func NewDdbServiceWithConfig(config Config) (*Service, error) {
+	if config.FileBacked && config.DbPath == "" {
+		return nil, errors.New("DbPath is required for a file-backed service")
+	}
+
+	var innerStorage *storage.InnerStorage
 	tableMetadatas := make(map[string]*core.TableMetaData)
+	if config.FileBacked {
+		var err error
+		innerStorage, err = storage.NewFileInnerStorage(config.DbPath)
+		if err != nil {
+			return nil, err
+		}
+		tableMetadatas, err = innerStorage.LoadSchema()
+		if err != nil {
+			innerStorage.Close()
+			return nil, err
+		}
+	} else {
+		innerStorage = storage.NewInnerStorage()
+	}
+	innerStorage.GsiStronglyConsistent = config.GsiStronglyConsistent
+	if config.MaxBatchGetItemResponseBytes <= 0 {
+		config.MaxBatchGetItemResponseBytes = DEFAULT_MAX_BATCH_GET_ITEM_RESPONSE_BYTES
+	}
 	tableMetadatas[storage.METADATA_TABLE_NAME] = &core.TableMetaData{}
 
 	return &Service{
 		tableMetadataStore: tableMetadatas,
 		storage:            innerStorage,
+		config:             config,
+	}, nil
+}
+
+// Close releases the storage. When DbPath is given, an in-memory service dumps its tables to DbPath first,
+// so the next file-backed service started with the same DbPath sees them.
+func (svc *Service) Close() error {
+	svc.tableLock.Lock()
+	defer svc.tableLock.Unlock()
+
+	if svc.config.DbPath != "" {
+		if err := svc.storage.SaveSchema(svc.tableMetadataStore); err != nil {
+			return err
+		}
+		if !svc.config.FileBacked {
+			if err := svc.storage.DumpTo(svc.config.DbPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	return svc.storage.Close()
+}
+
+// persistSchema keeps the schemas in DbPath up to date with table changes, callers must hold tableLock
+func (svc *Service) persistSchema() error {
+	if !svc.config.FileBacked {
+		return nil
 	}
+	return svc.storage.SaveSchema(svc.tableMetadataStore)
 }
 
 func (svc *Service) ListTables(ctx context.Context, input *dynamodb.ListTablesInput) (*dynamodb.ListTablesOutput, error) {
 	svc.tableLock.RLock()
 	defer svc.tableLock.RUnlock()
 
-	// TODO: implement paging
+	limit := MAX_LIST_TABLES_LIMIT
+	if input.Limit != nil {
+		if *input.Limit < 1 || *input.Limit > MAX_LIST_TABLES_LIMIT {
+			msg := fmt.Sprintf("1 validation error detected: Value '%d' at 'limit' failed to satisfy constraint: Member must have value less than or equal to %d and greater than or equal to 1", *input.Limit, MAX_LIST_TABLES_LIMIT)
+			return nil, &ValidationException{Message: msg}
+		}
+		limit = int(*input.Limit)
+	}
+
 	tableNames := make([]string, 0)
-	for tableName, _ := range svc.tableMetadataStore {
+	for tableName := range svc.tableMetadataStore {
+		if tableName == storage.METADATA_TABLE_NAME {
+			continue
+		}
+		// table names are sorted, so a page starts right after ExclusiveStartTableName even if it's deleted
+		if input.ExclusiveStartTableName != nil && tableName <= *input.ExclusiveStartTableName {
+			continue
+		}
 		tableNames = append(tableNames, tableName)
 	}
+	sort.Strings(tableNames)
+
 	output := &dynamodb.ListTablesOutput{
 		TableNames: tableNames,
 	}
+	if len(tableNames) > limit {
+		output.TableNames = tableNames[:limit]
+		output.LastEvaluatedTableName = &tableNames[limit-1]
+	}
 
 	return output, nil
 }
@@ -124,6 +241,18 @@ func (svc *Service) CreateTable(ctx context.Context, input *dynamodb.CreateTable
 		return nil, err
 	}
 
+	// GSIs and LSIs share one namespace, storage keeps both kinds in one map keyed by index name
+	indexNames := make(map[string]bool)
+	validateIndexNameUnique := func(indexName string) error {
+		if indexNames[indexName] {
+			return &ValidationException{
+				Message: fmt.Sprintf("One or more parameter values were invalid: Duplicate index name: %s", indexName),
+			}
+		}
+		indexNames[indexName] = true
+		return nil
+	}
+
 	gsiSettings := make([]core.GlobalSecondaryIndexSetting, len(input.GlobalSecondaryIndexes))
 	for i, gsi := range input.GlobalSecondaryIndexes {
 		nonKeyAttributes := make([]string, len(gsi.Projection.NonKeyAttributes))
@@ -135,56 +264,83 @@ func (svc *Service) CreateTable(ctx context.Context, input *dynamodb.CreateTable
 				Message: err.Error(),
 			}
 		}
+		if err := validateIndexNameUnique(*gsi.IndexName); err != nil {
+			return nil, err
+		}
 
-		var partitionKey *core.KeySchema
-		var sortKey *core.KeySchema
-		for _, key := range gsi.KeySchema {
-			def, ok := attributeDefinitionMap[*key.AttributeName]
-			if !ok {
-				msg := fmt.Sprintf("%s not found in attribute definitions", *key.AttributeName)
-				err := &ValidationException{
-					Message: msg,
-				}
-				return nil, err
-			}
-			attrType, err := core.GetScalarAttributeType(def)
+		partitionKey, sortKey, err := buildIndexKeySchema(gsi.KeySchema, attributeDefinitionMap)
+		if err != nil {
+			return nil, err
+		}
+		projectionType := buildProjectionType(gsi.Projection.ProjectionType)
+
+		// a GSI has its own provisioned throughput, reading the GSI doesn't consume the table's read capacity
+		var gsiProvisionedThroughput *core.ProvisionedThroughput
+		if input.BillingMode == types.BillingModeProvisioned && gsi.ProvisionedThroughput != nil &&
+			gsi.ProvisionedThroughput.ReadCapacityUnits != nil && gsi.ProvisionedThroughput.WriteCapacityUnits != nil {
+			pt, err := core.BuildProvisionedThroughput(gsi.ProvisionedThroughput)
 			if err != nil {
-				err := &ValidationException{
-					Message: err.Error(),
-				}
-				return nil, err
+				return nil, &ValidationException{Message: err.Error()}
+			}
+			gsiProvisionedThroughput = pt
+		}
+
+		gsiSettings[i] = core.GlobalSecondaryIndexSetting{
+			IndexName:             gsi.IndexName,
+			PartitionKeySchema:    partitionKey,
+			SortKeySchema:         sortKey,
+			NonKeyAttributes:      nonKeyAttributes,
+			ProjectionType:        projectionType,
+			ProvisionedThroughput: gsiProvisionedThroughput,
+		}
+	}
+
+	lsiSettings := make([]core.GlobalSecondaryIndexSetting, len(input.LocalSecondaryIndexes))
+	for i, lsi := range input.LocalSecondaryIndexes {
+		if err := core.ValidateTableName(*lsi.IndexName); err != nil {
+			return nil, &ValidationException{
+				Message: err.Error(),
 			}
+		}
+		if err := validateIndexNameUnique(*lsi.IndexName); err != nil {
+			return nil, err
+		}
+		if sortKeySchema == nil {
+			return nil, &ValidationException{
+				Message: "One or more parameter values were invalid: Table KeySchema does not have a range key, which is required when specifying a LocalSecondaryIndex",
+			}
+		}
 
-			if key.KeyType == types.KeyTypeHash {
-				partitionKey = &core.KeySchema{
-					AttributeName: *key.AttributeName,
-					AttributeType: attrType,
-				}
-			} else if key.KeyType == types.KeyTypeRange {
-				sortKey = &core.KeySchema{
-					AttributeName: *key.AttributeName,
-					AttributeType: attrType,
-				}
+		partitionKey, sortKey, err := buildIndexKeySchema(lsi.KeySchema, attributeDefinitionMap)
+		if err != nil {
+			return nil, err
+		}
+		if partitionKey == nil || partitionKey.AttributeName != partitionKeySchema.AttributeName {
+			indexHashKey := ""
+			if partitionKey != nil {
+				indexHashKey = partitionKey.AttributeName
+			}
+			return nil, &ValidationException{
+				Message: fmt.Sprintf("One or more parameter values were invalid: Index KeySchema does not have the same leading hash key as table KeySchema for index: %s. index hash key: %s, table hash key: %s", *lsi.IndexName, indexHashKey, partitionKeySchema.AttributeName),
 			}
 		}
-		var projectionType core.ProjectionType
-		switch gsi.Projection.ProjectionType {
-		case types.ProjectionTypeKeysOnly:
-			projectionType = core.PROJECTION_TYPE_KEYS_ONLY
-		case types.ProjectionTypeInclude:
-			projectionType = core.PROJECTION_TYPE_INCLUDE
-		case types.ProjectionTypeAll:
-			projectionType = core.PROJECTION_TYPE_ALL
+		if sortKey == nil {
+			return nil, &ValidationException{
+				Message: fmt.Sprintf("One or more parameter values were invalid: Index KeySchema does not have a range key for index: %s", *lsi.IndexName),
+			}
 		}
 
-		gsiSettings[i] = core.GlobalSecondaryIndexSetting{
-			IndexName:          gsi.IndexName,
+		nonKeyAttributes := make([]string, len(lsi.Projection.NonKeyAttributes))
+		copy(nonKeyAttributes, lsi.Projection.NonKeyAttributes)
+		lsiSettings[i] = core.GlobalSecondaryIndexSetting{
+			IndexName:          lsi.IndexName,
 			PartitionKeySchema: partitionKey,
 			SortKeySchema:      sortKey,
 			NonKeyAttributes:   nonKeyAttributes,
-			ProjectionType:     projectionType,
+			ProjectionType:     buildProjectionType(lsi.Projection.ProjectionType),
 		}
 	}
+
 	// api error ValidationException:
 	billingMode := core.BILLING_MODE_PAY_PER_REQUEST
 	if input.BillingMode == types.BillingModeProvisioned {
@@ -210,23 +366,59 @@ func (svc *Service) CreateTable(ctx context.Context, input *dynamodb.CreateTable
 		return nil, &ValidationException{Message: err.Error()}
 	}
 
+	if input.StreamSpecification != nil {
+		if err := validateStreamSpecification(input.StreamSpecification); err != nil {
+			return nil, err
+		}
+	}
+
+	var activeAt *time.Time
+	if svc.config.TableCreationDelay > 0 {
+		t := now.Add(svc.config.TableCreationDelay)
+		activeAt = &t
+	}
+
+	var lastUpdateToPayPerRequestDateTime *time.Time
+	if billingMode == core.BILLING_MODE_PAY_PER_REQUEST {
+		lastUpdateToPayPerRequestDateTime = &now
+	}
+
 	meta := &core.TableMetaData{
 		AttributeDefinitions:         input.AttributeDefinitions,
 		GlobalSecondaryIndexSettings: gsiSettings,
-		LocalSecondaryIndexes:        input.LocalSecondaryIndexes,
+		LocalSecondaryIndexSettings:  lsiSettings,
 		ProvisionedThroughput:        provisionedThroughput,
 		CreationDateTime:             &now,
+		ActiveAt:                     activeAt,
 		PartitionKeySchema:           partitionKeySchema,
 		SortKeySchema:                sortKeySchema,
 		Name:                         tableName,
 		BillingMode:                  billingMode,
+
+		LastUpdateToPayPerRequestDateTime: lastUpdateToPayPerRequestDateTime,
 	}
 	err = svc.storage.CreateTable(meta)
 	if err != nil {
 		return nil, err
 	}
+	// a table which can't be set up is dropped, so a retry can create it again
+	rollback := func(err error) error {
+		delete(svc.tableMetadataStore, tableName)
+		if dropErr := svc.storage.DropTable(tableName); dropErr != nil {
+			return errors.Join(err, dropErr)
+		}
+		return err
+	}
+	if input.StreamSpecification != nil && *input.StreamSpecification.StreamEnabled {
+		if err := svc.enableStream(meta, input.StreamSpecification.StreamViewType, now); err != nil {
+			return nil, rollback(err)
+		}
+	}
 
 	svc.tableMetadataStore[tableName] = meta
+	if err := svc.persistSchema(); err != nil {
+		return nil, rollback(err)
+	}
 
 	itemCount, err := svc.storage.QueryItemCount(tableName)
 	if err != nil {
@@ -239,6 +431,51 @@ func (svc *Service) CreateTable(ctx context.Context, input *dynamodb.CreateTable
 	return &output, nil
 }
 
+// buildIndexKeySchema returns the partition key and the sort key of an index, the sort key is nil without a RANGE key
+func buildIndexKeySchema(keySchema []types.KeySchemaElement, attributeDefinitionMap map[string]types.AttributeDefinition) (*core.KeySchema, *core.KeySchema, error) {
+	var partitionKey *core.KeySchema
+	var sortKey *core.KeySchema
+	for _, key := range keySchema {
+		def, ok := attributeDefinitionMap[*key.AttributeName]
+		if !ok {
+			msg := fmt.Sprintf("%s not found in attribute definitions", *key.AttributeName)
+			return nil, nil, &ValidationException{
+				Message: msg,
+			}
+		}
+		attrType, err := core.GetScalarAttributeType(def)
+		if err != nil {
+			return nil, nil, &ValidationException{
+				Message: err.Error(),
+			}
+		}
+
+		if key.KeyType == types.KeyTypeHash {
+			partitionKey = &core.KeySchema{
+				AttributeName: *key.AttributeName,
+				AttributeType: attrType,
+			}
+		} else if key.KeyType == types.KeyTypeRange {
+			sortKey = &core.KeySchema{
+				AttributeName: *key.AttributeName,
+				AttributeType: attrType,
+			}
+		}
+	}
+	return partitionKey, sortKey, nil
+}
+
+func buildProjectionType(projectionType types.ProjectionType) core.ProjectionType {
+	switch projectionType {
+	case types.ProjectionTypeInclude:
+		return core.PROJECTION_TYPE_INCLUDE
+	case types.ProjectionTypeAll:
+		return core.PROJECTION_TYPE_ALL
+	default:
+		return core.PROJECTION_TYPE_KEYS_ONLY
+	}
+}
+
 func (svc *Service) BatchGetItem(ctx context.Context, input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
 	// https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_BatchGetItem.html
 	svc.tableLock.RLock()
@@ -259,6 +496,17 @@ func (svc *Service) BatchGetItem(ctx context.Context, input *dynamodb.BatchGetIt
 
 	responses := make(map[string][]map[string]types.AttributeValue)
 	unprocessedKeys := make(map[string]types.KeysAndAttributes)
+	addUnprocessedKey := func(tableName string, key map[string]types.AttributeValue) {
+		unprocessedSummary, ok := unprocessedKeys[tableName]
+		if !ok {
+			unprocessedSummary = types.KeysAndAttributes{}
+		}
+		unprocessedSummary.Keys = append(unprocessedSummary.Keys, key)
+		unprocessedKeys[tableName] = unprocessedSummary
+	}
+	responseSize := 0
+	responseSizeLimitReached := false
+	consumedCapacityUnits := make(map[string]float64)
 
 	for tableName, r := range input.RequestItems {
 		_, ok := svc.tableMetadataStore[tableName]
@@ -269,8 +517,19 @@ func (svc *Service) BatchGetItem(ctx context.Context, input *dynamodb.BatchGetIt
 			}
 			return nil, err
 		}
+		if err := validateExpressionPlaceholders(r.ExpressionAttributeNames, nil, r.ProjectionExpression); err != nil {
+			return nil, err
+		}
 
 		for _, key := range r.Keys {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if responseSizeLimitReached {
+				addUnprocessedKey(tableName, key)
+				continue
+			}
+
 			getItemInput := &dynamodb.GetItemInput{
 				Key:                      key,
 				TableName:                &tableName,
@@ -282,31 +541,42 @@ func (svc *Service) BatchGetItem(ctx context.Context, input *dynamodb.BatchGetIt
 			item, err := svc.GetItem(ctx, getItemInput)
 			if err != nil {
 				if errors.Is(err, storage.ErrUnprocessed) {
-					unprocessedSummary, ok := unprocessedKeys[tableName]
-					if !ok {
-						unprocessedSummary = types.KeysAndAttributes{}
-					}
-					unprocessedSummary.Keys = append(unprocessedSummary.Keys, key)
-					unprocessedKeys[tableName] = unprocessedSummary
+					addUnprocessedKey(tableName, key)
 					continue
 				}
 
 				return nil, err
 			}
 
+			consistentRead := r.ConsistentRead != nil && *r.ConsistentRead
 			if item.Item != nil {
+				entry, err := core.NewEntryFromItem(item.Item)
+				if err != nil {
+					return nil, err
+				}
+				if responseSize+entry.Size() > svc.config.MaxBatchGetItemResponseBytes {
+					responseSizeLimitReached = true
+					addUnprocessedKey(tableName, key)
+					continue
+				}
+				responseSize += entry.Size()
+				consumedCapacityUnits[tableName] += readCapacityUnits(entry.Size(), consistentRead)
+
 				responseSummary, ok := responses[tableName]
 				if !ok {
 					responseSummary = make([]map[string]types.AttributeValue, 0)
 				}
 				responses[tableName] = append(responseSummary, item.Item)
+			} else {
+				consumedCapacityUnits[tableName] += readCapacityUnits(0, consistentRead)
 			}
 		}
 	}
 
 	output := &dynamodb.BatchGetItemOutput{
-		Responses:       responses,
-		UnprocessedKeys: unprocessedKeys,
+		Responses:        responses,
+		UnprocessedKeys:  unprocessedKeys,
+		ConsumedCapacity: buildConsumedCapacities(input.ReturnConsumedCapacity, consumedCapacityUnits),
 	}
 
 	return output, nil
@@ -324,54 +594,38 @@ func (svc *Service) BatchWriteItem(ctx context.Context, input *dynamodb.BatchWri
 	//	}
 	//	return nil, err
 	//}
-	for tableName, requests := range input.RequestItems {
-		if len(requests) == 0 {
-			msg := fmt.Sprintf("The batch write request list for a table cannot be null or empty: %s", tableName)
-			err := &ValidationException{
-				Message: msg,
-			}
-			return nil, err
-		}
-	}
-
-	reqCount := 0
-	for _, r := range input.RequestItems {
-		reqCount += len(r)
-	}
-
-	if reqCount > 25 {
-		msg := "Too many items requested for the BatchWriteItem call"
-		err := &ValidationException{
-			Message: msg,
-		}
+	if err := svc.validateBatchWriteItemInput(input); err != nil {
 		return nil, err
 	}
 
 	unprocessedItems := make(map[string][]types.WriteRequest)
+	consumedCapacityUnits := make(map[string]float64)
 	for tableName, requests := range input.RequestItems {
-		_, ok := svc.tableMetadataStore[tableName]
-		if !ok {
-			msg := "Cannot do operations on a non-existent table"
-			err := &types.ResourceNotFoundException{
-				Message: &msg,
-			}
-			return nil, err
-		}
-
 		for _, request := range requests {
 			var err error
+			var consumedCapacity *types.ConsumedCapacity
 			if request.PutRequest != nil {
 				putItemInput := &dynamodb.PutItemInput{
-					Item:      request.PutRequest.Item,
-					TableName: &tableName,
+					Item:                   request.PutRequest.Item,
+					TableName:              &tableName,
+					ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+				}
+				var output *dynamodb.PutItemOutput
+				output, err = svc.PutItem(ctx, putItemInput)
+				if err == nil {
+					consumedCapacity = output.ConsumedCapacity
 				}
-				_, err = svc.PutItem(ctx, putItemInput)
 			} else if request.DeleteRequest != nil {
 				deleteItemInput := &dynamodb.DeleteItemInput{
-					Key:       request.DeleteRequest.Key,
-					TableName: &tableName,
+					Key:                    request.DeleteRequest.Key,
+					TableName:              &tableName,
+					ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+				}
+				var output *dynamodb.DeleteItemOutput
+				output, err = svc.DeleteItem(ctx, deleteItemInput)
+				if err == nil {
+					consumedCapacity = output.ConsumedCapacity
 				}
-				_, err = svc.DeleteItem(ctx, deleteItemInput)
 			} else {
 				msg := "Invalid request"
 				err = &ValidationException{
@@ -390,29 +644,111 @@ func (svc *Service) BatchWriteItem(ctx context.Context, input *dynamodb.BatchWri
 				}
 				return nil, err
 			}
-
+			consumedCapacityUnits[tableName] += *consumedCapacity.CapacityUnits
 		}
 
 	}
 
 	output := &dynamodb.BatchWriteItemOutput{
 		UnprocessedItems: unprocessedItems,
+		ConsumedCapacity: buildConsumedCapacities(input.ReturnConsumedCapacity, consumedCapacityUnits),
 	}
 	return output, nil
 }
 
+// validateBatchWriteItemInput checks the limits of a BatchWriteItem request before any of its writes is made:
+// at most MAX_BATCH_WRITE_ITEM_REQUESTS writes of MAX_BATCH_WRITE_ITEM_REQUEST_BYTES in total, to existing tables,
+// and at most one write per item
+func (svc *Service) validateBatchWriteItemInput(input *dynamodb.BatchWriteItemInput) error {
+	reqCount := 0
+	for tableName, requests := range input.RequestItems {
+		if len(requests) == 0 {
+			msg := fmt.Sprintf("The batch write request list for a table cannot be null or empty: %s", tableName)
+			return &ValidationException{
+				Message: msg,
+			}
+		}
+		reqCount += len(requests)
+	}
+
+	if reqCount > MAX_BATCH_WRITE_ITEM_REQUESTS {
+		msg := "Too many items requested for the BatchWriteItem call"
+		return &ValidationException{
+			Message: msg,
+		}
+	}
+
+	requestBytes := 0
+	for tableName, requests := range input.RequestItems {
+		table, ok := svc.tableMetadataStore[tableName]
+		if !ok {
+			msg := "Cannot do operations on a non-existent table"
+			return &types.ResourceNotFoundException{
+				Message: &msg,
+			}
+		}
+
+		primaryKeys := make(map[string]bool)
+		for _, request := range requests {
+			var item map[string]types.AttributeValue
+			if request.PutRequest != nil {
+				item = request.PutRequest.Item
+			} else if request.DeleteRequest != nil {
+				item = request.DeleteRequest.Key
+			} else {
+				continue
+			}
+			entry, err := core.NewEntryFromItem(item)
+			if err != nil {
+				return &ValidationException{
+					Message: err.Error(),
+				}
+			}
+			requestBytes += entry.Size()
+
+			// the metadata table has no key schema, its items are never stored
+			if table.PartitionKeySchema == nil {
+				continue
+			}
+			pk, err := svc.buildTablePrimaryKey(entry, table)
+			if err != nil {
+				return &ValidationException{
+					Message: "The provided key element does not match the schema",
+				}
+			}
+			if primaryKeys[pk.String()] {
+				return &ValidationException{
+					Message: "Provided list of item keys contains duplicates",
+				}
+			}
+			primaryKeys[pk.String()] = true
+		}
+	}
+
+	if requestBytes > MAX_BATCH_WRITE_ITEM_REQUEST_BYTES {
+		return &ValidationException{
+			Message: fmt.Sprintf("Request size exceeded %d bytes", MAX_BATCH_WRITE_ITEM_REQUEST_BYTES),
+		}
+	}
+	return nil
+}
+
 func (svc *Service) PutItem(ctx context.Context, input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
 	svc.tableLock.RLock()
 	defer svc.tableLock.RUnlock()
 
 	tableName := *input.TableName
-	if _, ok := svc.tableMetadataStore[tableName]; ok {
+	if err := svc.validateTableActive(tableName); err != nil {
+		return nil, err
+	}
+	if table, ok := svc.tableMetadataStore[tableName]; ok {
 		builder := &request.PutRequestBuilder{
 			ConditionExpression:       input.ConditionExpression,
 			ExpressionAttributeNames:  input.ExpressionAttributeNames,
 			ExpressionAttributeValues: input.ExpressionAttributeValues,
 			Item:                      input.Item,
 			TableName:                 input.TableName,
+			TableMetaData:             table,
 		}
 		req, err := builder.Build()
 		if err != nil {
@@ -420,13 +756,37 @@ func (svc *Service) PutItem(ctx context.Context, input *dynamodb.PutItemInput) (
 				Message: err.Error(),
 			}
 		}
-		err = svc.storage.Put(req)
+		if err := validateExpressionPlaceholders(input.ExpressionAttributeNames, input.ExpressionAttributeValues, input.ConditionExpression); err != nil {
+			return nil, err
+		}
+		if err := validateItemSize(req.Entry); err != nil {
+			return nil, err
+		}
+		if err := svc.validateStrictItem(req.Entry, table); err != nil {
+			return nil, err
+		}
+		txn, err := svc.storage.BeginTxn()
+		if err != nil {
+			return nil, err
+		}
+		defer txn.Rollback()
+
+		res, err := svc.storage.PutWithTransaction(req, txn)
 		if err != nil {
 			return nil, wrapError(err)
 		}
+		itemCollectionMetrics, err := svc.buildItemCollectionMetrics(input.ReturnItemCollectionMetrics, table, req.Entry, txn)
+		if err != nil {
+			return nil, err
+		}
+		if err := txn.Commit(); err != nil {
+			return nil, err
+		}
 
-		//TODO: configure PutItemOutput
-		output := &dynamodb.PutItemOutput{}
+		output := &dynamodb.PutItemOutput{
+			ConsumedCapacity:      buildWriteConsumedCapacity(input.ReturnConsumedCapacity, table, res.OldEntry, req.Entry),
+			ItemCollectionMetrics: itemCollectionMetrics,
+		}
 		return output, nil
 	} else {
 		msg := "Cannot do operations on a non-existent table"
@@ -442,14 +802,22 @@ func (svc *Service) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemIn
 	defer svc.tableLock.RUnlock()
 
 	tableName := *input.TableName
-	if _, ok := svc.tableMetadataStore[tableName]; ok {
-		builder := &request.UpdateRequestBuilder{
-			TableName:                 input.TableName,
-			UpdateExpression:          input.UpdateExpression,
+	if err := validateNotMetadataTable(tableName, "UpdateItem"); err != nil {
+		return nil, err
+	}
+	if err := svc.validateTableActive(tableName); err != nil {
+		return nil, err
+	}
+	if table, ok := svc.tableMetadataStore[tableName]; ok {
+		builder := &request.UpdateRequestBuilder{
+			TableName:                 input.TableName,
+			UpdateExpression:          input.UpdateExpression,
 			ExpressionAttributeNames:  input.ExpressionAttributeNames,
 			ExpressionAttributeValues: input.ExpressionAttributeValues,
 			ConditionExpression:       input.ConditionExpression,
 			Key:                       input.Key,
+			AttributeUpdates:          input.AttributeUpdates,
+			TableMetaData:             table,
 		}
 		req, err := builder.Build()
 		if err != nil {
@@ -457,15 +825,42 @@ func (svc *Service) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemIn
 				Message: err.Error(),
 			}
 		}
+		if err := validateExpressionPlaceholders(input.ExpressionAttributeNames, input.ExpressionAttributeValues, input.UpdateExpression, input.ConditionExpression); err != nil {
+			return nil, err
+		}
+		if err := validateUpdateItemReturnValues(input.ReturnValues); err != nil {
+			return nil, err
+		}
+		req.ValidateEntry = func(entry *core.Entry) error {
+			return svc.validateStrictItem(entry, table)
+		}
+
+		txn, err := svc.storage.BeginTxn()
+		if err != nil {
+			return nil, err
+		}
+		defer txn.Rollback()
 
-		res, err := svc.storage.Update(req)
+		res, err := svc.storage.UpdateWithTransaction(req, txn)
 		if err != nil {
 			return nil, wrapError(err)
 		}
 
-		// TODO: consider ReturnValues
+		attributes, err := updateItemAttributes(input.ReturnValues, req, res)
+		if err != nil {
+			return nil, err
+		}
+		itemCollectionMetrics, err := svc.buildItemCollectionMetrics(input.ReturnItemCollectionMetrics, table, req.Key, txn)
+		if err != nil {
+			return nil, err
+		}
+		if err := txn.Commit(); err != nil {
+			return nil, err
+		}
 		output := &dynamodb.UpdateItemOutput{
-			Attributes: core.NewItemFromEntry(res.NewEntry.Body),
+			Attributes:            attributes,
+			ConsumedCapacity:      buildWriteConsumedCapacity(input.ReturnConsumedCapacity, table, res.OldEntry, res.NewEntry),
+			ItemCollectionMetrics: itemCollectionMetrics,
 		}
 
 		return output, nil
@@ -479,18 +874,68 @@ func (svc *Service) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemIn
 
 }
 
+func validateUpdateItemReturnValues(returnValues types.ReturnValue) error {
+	switch returnValues {
+	case "", types.ReturnValueNone, types.ReturnValueAllOld, types.ReturnValueUpdatedOld, types.ReturnValueAllNew, types.ReturnValueUpdatedNew:
+		return nil
+	default:
+		return &ValidationException{
+			Message: fmt.Sprintf("1 validation error detected: Value '%s' at 'returnValues' failed to satisfy constraint: Member must satisfy enum value set: [ALL_NEW, UPDATED_OLD, ALL_OLD, NONE, UPDATED_NEW]", returnValues),
+		}
+	}
+}
+
+// updateItemAttributes picks the attributes UpdateItem returns, UPDATED_OLD and UPDATED_NEW only return
+// the paths of the update expression, an attribute missing before or after the update is left out
+func updateItemAttributes(returnValues types.ReturnValue, req *storage.UpdateRequest, res *storage.UpdateResponse) (map[string]types.AttributeValue, error) {
+	var entry *core.Entry
+	switch returnValues {
+	case types.ReturnValueAllOld:
+		entry = res.OldEntry
+	case types.ReturnValueAllNew:
+		entry = res.NewEntry
+	case types.ReturnValueUpdatedOld, types.ReturnValueUpdatedNew:
+		paths, err := req.UpdateOperation.Paths()
+		if err != nil {
+			return nil, err
+		}
+		entry = res.NewEntry
+		if returnValues == types.ReturnValueUpdatedOld {
+			entry = res.OldEntry
+		}
+		entry, err = entry.Project(paths)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+
+	if len(entry.Body) == 0 {
+		return nil, nil
+	}
+	return core.NewItemFromEntry(entry.Body), nil
+}
+
 func (svc *Service) DeleteItem(ctx context.Context, input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
 	svc.tableLock.RLock()
 	defer svc.tableLock.RUnlock()
 
 	tableName := *input.TableName
-	if _, ok := svc.tableMetadataStore[tableName]; ok {
+	if err := validateNotMetadataTable(tableName, "DeleteItem"); err != nil {
+		return nil, err
+	}
+	if err := svc.validateTableActive(tableName); err != nil {
+		return nil, err
+	}
+	if table, ok := svc.tableMetadataStore[tableName]; ok {
 		builder := &request.DeleteRequestBuilder{
 			TableName:                 input.TableName,
 			ConditionExpression:       input.ConditionExpression,
 			ExpressionAttributeNames:  input.ExpressionAttributeNames,
 			ExpressionAttributeValues: input.ExpressionAttributeValues,
 			Key:                       input.Key,
+			TableMetaData:             table,
 		}
 		req, err := builder.Build()
 		if err != nil {
@@ -498,12 +943,31 @@ func (svc *Service) DeleteItem(ctx context.Context, input *dynamodb.DeleteItemIn
 				Message: err.Error(),
 			}
 		}
+		if err := validateExpressionPlaceholders(input.ExpressionAttributeNames, input.ExpressionAttributeValues, input.ConditionExpression); err != nil {
+			return nil, err
+		}
+
+		txn, err := svc.storage.BeginTxn()
+		if err != nil {
+			return nil, err
+		}
+		defer txn.Rollback()
 
-		err = svc.storage.Delete(req)
+		res, err := svc.storage.DeleteWithTransaction(req, txn)
 		if err != nil {
 			return nil, wrapError(err)
 		}
-		output := &dynamodb.DeleteItemOutput{}
+		itemCollectionMetrics, err := svc.buildItemCollectionMetrics(input.ReturnItemCollectionMetrics, table, req.Entry, txn)
+		if err != nil {
+			return nil, err
+		}
+		if err := txn.Commit(); err != nil {
+			return nil, err
+		}
+		output := &dynamodb.DeleteItemOutput{
+			ConsumedCapacity:      buildWriteConsumedCapacity(input.ReturnConsumedCapacity, table, res.OldEntry, nil),
+			ItemCollectionMetrics: itemCollectionMetrics,
+		}
 
 		return output, nil
 	} else {
@@ -520,10 +984,16 @@ func (svc *Service) GetItem(ctx context.Context, input *dynamodb.GetItemInput) (
 	defer svc.tableLock.RUnlock()
 
 	tableName := *input.TableName
-	if _, ok := svc.tableMetadataStore[tableName]; ok {
+	if err := validateNotMetadataTable(tableName, "GetItem"); err != nil {
+		return nil, err
+	}
+	if err := svc.validateTableActive(tableName); err != nil {
+		return nil, err
+	}
+	if table, ok := svc.tableMetadataStore[tableName]; ok {
 		builder := request.GetRequestBuilder{
 			Input:         input,
-			TableMetaData: svc.tableMetadataStore[tableName],
+			TableMetaData: table,
 		}
 		req, err := builder.Build()
 		if err != nil {
@@ -531,22 +1001,28 @@ func (svc *Service) GetItem(ctx context.Context, input *dynamodb.GetItemInput) (
 				Message: err.Error(),
 			}
 		}
+		if err := validateExpressionPlaceholders(input.ExpressionAttributeNames, nil, input.ProjectionExpression); err != nil {
+			return nil, err
+		}
 
 		entry, err := svc.storage.Get(req)
 
 		if err != nil {
 			return nil, wrapError(err)
 		}
+		consistentRead := input.ConsistentRead != nil && *input.ConsistentRead
 		if entry == nil {
 			output := dynamodb.GetItemOutput{
-				Item: nil,
+				Item:             nil,
+				ConsumedCapacity: buildConsumedCapacity(input.ReturnConsumedCapacity, table, nil, readCapacityUnits(0, consistentRead)),
 			}
 			return &output, nil
 		}
 
 		item := core.NewItemFromEntry(entry.Body)
 		output := dynamodb.GetItemOutput{
-			Item: item,
+			Item:             item,
+			ConsumedCapacity: buildConsumedCapacity(input.ReturnConsumedCapacity, table, nil, readCapacityUnits(entry.Size(), consistentRead)),
 		}
 
 		return &output, nil
@@ -564,6 +1040,10 @@ var (
 	ProvisionedThroughputExceededException        = &types.ProvisionedThroughputExceededException{
 		Message: &provisionedThroughputExceededExceptionMessage,
 	}
+	// itemSizeToUpdateExceededException is returned when an update results in an item larger than core.MAX_ITEM_SIZE_BYTES
+	itemSizeToUpdateExceededException = &ValidationException{
+		Message: "Item size to update has exceeded the maximum allowed size",
+	}
 )
 
 type ValidationException struct {
@@ -579,6 +1059,12 @@ func (svc *Service) Query(ctx context.Context, input *dynamodb.QueryInput) (*dyn
 	defer svc.tableLock.RUnlock()
 
 	tableName := *input.TableName
+	if err := validateNotMetadataTable(tableName, "Query"); err != nil {
+		return nil, err
+	}
+	if err := svc.validateTableActive(tableName); err != nil {
+		return nil, err
+	}
 	tableMetadata, ok := svc.tableMetadataStore[tableName]
 	if !ok {
 		msg := "Cannot do operations on a non-existent table"
@@ -593,6 +1079,12 @@ func (svc *Service) Query(ctx context.Context, input *dynamodb.QueryInput) (*dyn
 		}
 		return nil, err
 	}
+	if err := validateIndexName(tableMetadata, input.IndexName); err != nil {
+		return nil, err
+	}
+	if err := validateConsistentRead(tableMetadata, input.IndexName, input.ConsistentRead); err != nil {
+		return nil, err
+	}
 
 	keyConditionExpression, err := expression.ParseKeyConditionExpression(*input.KeyConditionExpression)
 	if err != nil {
@@ -630,59 +1122,27 @@ func (svc *Service) Query(ctx context.Context, input *dynamodb.QueryInput) (*dyn
 	}
 	queryReq.TableName = tableName
 
-	res, err := svc.storage.Query(queryReq)
+	selectType, proj, err := buildReadProjection("Querying", input.Select, input.ProjectionExpression, input.ExpressionAttributeNames, tableMetadata, input.IndexName)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateExpressionPlaceholders(input.ExpressionAttributeNames, input.ExpressionAttributeValues, input.KeyConditionExpression, input.FilterExpression, input.ProjectionExpression); err != nil {
+		return nil, err
+	}
+
+	res, err := svc.storage.Query(ctx, queryReq)
 	if err != nil {
 		return nil, wrapError(err)
 	}
 	entries := res.Entries
-	items := make([]map[string]types.AttributeValue, len(entries))
-	for i, entry := range entries {
-		items[i] = core.NewItemFromEntry(entry.Body)
+	items, err := buildReadItems(entries, selectType, proj)
+	if err != nil {
+		return nil, err
 	}
 
-	lastEvaluatedKey := make(map[string]types.AttributeValue)
-	if len(entries) > 0 {
-		// include hashKey, rangeKey, and GSI keys(if query is GSI)
-		lastEntry := entries[len(entries)-1]
-		partitionKeyName := tableMetadata.PartitionKeySchema.AttributeName
-		pk, ok := lastEntry.Body[partitionKeyName]
-		if !ok {
-			return nil, fmt.Errorf("can't found partition key in last entry")
-		}
-		lastEvaluatedKey[partitionKeyName] = pk.ToDdbAttributeValue()
-		if tableMetadata.SortKeySchema != nil {
-			sortKeyName := tableMetadata.SortKeySchema.AttributeName
-			sk, ok := lastEntry.Body[sortKeyName]
-			if !ok {
-				return nil, fmt.Errorf("can't found sort key in last entry")
-			}
-			lastEvaluatedKey[sortKeyName] = sk.ToDdbAttributeValue()
-		}
-
-		if input.IndexName != nil {
-			gsiSetting, ok := tableMetadata.GetGlobalSecondaryIndexSetting(*input.IndexName)
-			if !ok {
-				return nil, fmt.Errorf("GSI %s not found in table %s", *input.IndexName, tableName)
-			}
-			gsiPkName := gsiSetting.PartitionKeySchema.AttributeName
-			if _, ok := lastEvaluatedKey[gsiPkName]; !ok {
-				gsiPk, ok := lastEntry.Body[gsiPkName]
-				if !ok {
-					return nil, fmt.Errorf("can't found GSI partition key in last entry")
-				}
-				lastEvaluatedKey[gsiPkName] = gsiPk.ToDdbAttributeValue()
-			}
-			if gsiSetting.SortKeySchema != nil {
-				gsiSkName := gsiSetting.SortKeySchema.AttributeName
-				if _, ok := lastEvaluatedKey[gsiSkName]; !ok {
-					gsiSk, ok := lastEntry.Body[gsiSkName]
-					if !ok {
-						return nil, fmt.Errorf("can't found GSI sort key in last entry")
-					}
-					lastEvaluatedKey[gsiSkName] = gsiSk.ToDdbAttributeValue()
-				}
-			}
-		}
+	lastEvaluatedKey, err := buildLastEvaluatedKey(entries, tableMetadata, input.IndexName)
+	if err != nil {
+		return nil, err
 	}
 
 	output := &dynamodb.QueryOutput{
@@ -690,6 +1150,7 @@ func (svc *Service) Query(ctx context.Context, input *dynamodb.QueryInput) (*dyn
 		Items:            items,
 		LastEvaluatedKey: lastEvaluatedKey,
 		ScannedCount:     res.ScannedCount,
+		ConsumedCapacity: buildConsumedCapacity(input.ReturnConsumedCapacity, tableMetadata, input.IndexName, readCapacityUnits(res.ScannedSize, queryReq.ConsistentRead)),
 	}
 
 	return output, nil
@@ -700,6 +1161,12 @@ func (svc *Service) UpdateTable(ctx context.Context, input *dynamodb.UpdateTable
 	defer svc.tableLock.Unlock()
 
 	tableName := *input.TableName
+	if err := validateNotMetadataTable(tableName, "UpdateTable"); err != nil {
+		return nil, err
+	}
+	if err := svc.validateTableNotCreating(tableName); err != nil {
+		return nil, err
+	}
 	table, ok := svc.tableMetadataStore[tableName]
 	if !ok {
 		msg := "Cannot do operations on a non-existent table"
@@ -711,6 +1178,21 @@ func (svc *Service) UpdateTable(ctx context.Context, input *dynamodb.UpdateTable
 
 	originalTable := table.Clone()
 
+	if input.StreamSpecification != nil {
+		if err := validateStreamSpecification(input.StreamSpecification); err != nil {
+			return nil, err
+		}
+		streamEnabled := table.StreamSpecification != nil && *table.StreamSpecification.StreamEnabled
+		if *input.StreamSpecification.StreamEnabled && streamEnabled {
+			msg := fmt.Sprintf("Table already has an enabled stream: TableName: %s", tableName)
+			return nil, &ValidationException{Message: msg}
+		}
+		if !*input.StreamSpecification.StreamEnabled && !streamEnabled {
+			msg := fmt.Sprintf("Table does not have an enabled stream: TableName: %s", tableName)
+			return nil, &ValidationException{Message: msg}
+		}
+	}
+
 	if input.BillingMode != "" {
 		switch input.BillingMode {
 		case types.BillingModeProvisioned:
@@ -734,6 +1216,10 @@ func (svc *Service) UpdateTable(ctx context.Context, input *dynamodb.UpdateTable
 				return nil, err
 			}
 			table.ProvisionedThroughput = nil
+			if originalTable.BillingMode != core.BILLING_MODE_PAY_PER_REQUEST {
+				now := time.Now()
+				table.LastUpdateToPayPerRequestDateTime = &now
+			}
 		default:
 			svc.tableMetadataStore[tableName] = originalTable
 			msg := "Invalid billing mode"
@@ -776,6 +1262,32 @@ func (svc *Service) UpdateTable(ctx context.Context, input *dynamodb.UpdateTable
 		return nil, err
 	}
 
+	if input.StreamSpecification != nil {
+		if *input.StreamSpecification.StreamEnabled {
+			err = svc.enableStream(table, input.StreamSpecification.StreamViewType, time.Now())
+		} else {
+			err = svc.storage.DisableStream(tableName)
+			table.StreamSpecification = nil
+		}
+		if err != nil {
+			svc.tableMetadataStore[tableName] = originalTable
+			return nil, err
+		}
+	}
+	if err := svc.persistSchema(); err != nil {
+		svc.tableMetadataStore[tableName] = originalTable
+		if input.StreamSpecification != nil {
+			var streamArn *string
+			if originalTable.StreamSpecification != nil && *originalTable.StreamSpecification.StreamEnabled {
+				streamArn = originalTable.LatestStreamArn
+			}
+			if restoreErr := svc.storage.RestoreStream(tableName, streamArn); restoreErr != nil {
+				return nil, errors.Join(err, restoreErr)
+			}
+		}
+		return nil, err
+	}
+
 	itemCount, err := svc.storage.QueryItemCount(tableName)
 	if err != nil {
 		svc.tableMetadataStore[tableName] = originalTable
@@ -903,8 +1415,8 @@ func (svc *Service) validateGSICreate(table *core.TableMetaData, create *types.C
 		}
 	}
 
-	// For CREATE: GSI must NOT exist
-	if svc.gsiExists(table, create.IndexName) {
+	// For CREATE: neither a GSI nor an LSI may have the name
+	if _, ok := table.GetSecondaryIndexSetting(*create.IndexName); ok {
 		return &ValidationException{Message: "Global Secondary Index already exists"}
 	}
 
@@ -1036,18 +1548,7 @@ func (svc *Service) validateGSIProjection(table *core.TableMetaData, projection
 		if len(projection.NonKeyAttributes) == 0 {
 			return &ValidationException{Message: "NonKeyAttributes required with INCLUDE projection"}
 		}
-
-		// Validate that NonKeyAttributes exist in table
-		attributeDefinitionMap := make(map[string]bool)
-		for _, attrDef := range table.AttributeDefinitions {
-			attributeDefinitionMap[*attrDef.AttributeName] = true
-		}
-
-		for _, attr := range projection.NonKeyAttributes {
-			if !attributeDefinitionMap[attr] {
-				return &ValidationException{Message: "NonKeyAttribute not found in table attribute definitions"}
-			}
-		}
+		// NonKeyAttributes aren't keys, so they don't need to be in AttributeDefinitions
 	default:
 		return &ValidationException{Message: "Invalid projection type"}
 	}
@@ -1115,6 +1616,13 @@ func (svc *Service) addGSIToTableMetadata(table *core.TableMetaData, create *typ
 		SortKeySchema:      sortKeySchema,
 		ProjectionType:     projectionType,
 	}
+	if svc.config.IndexCreationDelay > 0 {
+		now := time.Now()
+		backfillingAt := now.Add(svc.config.IndexCreationDelay / 2)
+		activeAt := now.Add(svc.config.IndexCreationDelay)
+		gsiSetting.BackfillingAt = &backfillingAt
+		gsiSetting.ActiveAt = &activeAt
+	}
 
 	if create.Projection != nil && len(create.Projection.NonKeyAttributes) > 0 {
 		gsiSetting.NonKeyAttributes = create.Projection.NonKeyAttributes
@@ -1192,6 +1700,12 @@ func (svc *Service) DeleteTable(ctx context.Context, input *dynamodb.DeleteTable
 	defer svc.tableLock.Unlock()
 
 	tableName := *input.TableName
+	if err := validateNotMetadataTable(tableName, "DeleteTable"); err != nil {
+		return nil, err
+	}
+	if err := svc.validateTableNotCreating(tableName); err != nil {
+		return nil, err
+	}
 	if _, ok := svc.tableMetadataStore[tableName]; ok {
 		table := svc.tableMetadataStore[tableName]
 
@@ -1200,9 +1714,14 @@ func (svc *Service) DeleteTable(ctx context.Context, input *dynamodb.DeleteTable
 			return nil, err
 		}
 		tableDescription := table.Description(itemCount)
+		if err := svc.storage.DropTable(tableName); err != nil {
+			return nil, err
+		}
 		delete(svc.tableMetadataStore, tableName)
+		if err := svc.persistSchema(); err != nil {
+			return nil, err
+		}
 
-		// TODO: delete from storage
 		output := &dynamodb.DeleteTableOutput{
 			TableDescription: tableDescription,
 		}
@@ -1222,6 +1741,9 @@ func (svc *Service) DescribeTable(ctx context.Context, input *dynamodb.DescribeT
 	defer svc.tableLock.RUnlock()
 
 	tableName := *input.TableName
+	if err := validateNotMetadataTable(tableName, "DescribeTable"); err != nil {
+		return nil, err
+	}
 	if _, ok := svc.tableMetadataStore[tableName]; ok {
 		table := svc.tableMetadataStore[tableName]
 		itemCount, err := svc.storage.QueryItemCount(tableName)
@@ -1244,6 +1766,114 @@ func (svc *Service) DescribeTable(ctx context.Context, input *dynamodb.DescribeT
 	}
 }
 
+func (svc *Service) UpdateTimeToLive(ctx context.Context, input *dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	svc.tableLock.Lock()
+	defer svc.tableLock.Unlock()
+
+	tableName := *input.TableName
+	if err := validateNotMetadataTable(tableName, "UpdateTimeToLive"); err != nil {
+		return nil, err
+	}
+	table, ok := svc.tableMetadataStore[tableName]
+	if !ok {
+		msg := "Cannot do operations on a non-existent table"
+		return nil, &types.ResourceNotFoundException{
+			Message: &msg,
+		}
+	}
+
+	spec := input.TimeToLiveSpecification
+	if spec == nil {
+		return nil, &ValidationException{Message: "1 validation error detected: Value null at 'timeToLiveSpecification' failed to satisfy constraint: Member must not be null"}
+	}
+	if spec.AttributeName == nil {
+		return nil, &ValidationException{Message: "1 validation error detected: Value null at 'timeToLiveSpecification.attributeName' failed to satisfy constraint: Member must not be null"}
+	}
+	if len(*spec.AttributeName) == 0 {
+		return nil, &ValidationException{Message: "1 validation error detected: Value '' at 'timeToLiveSpecification.attributeName' failed to satisfy constraint: Member must have length greater than or equal to 1"}
+	}
+	if spec.Enabled == nil {
+		return nil, &ValidationException{Message: "1 validation error detected: Value null at 'timeToLiveSpecification.enabled' failed to satisfy constraint: Member must not be null"}
+	}
+	if *spec.Enabled && table.TimeToLiveEnabled {
+		return nil, &ValidationException{Message: "TimeToLive is already enabled"}
+	}
+	if !*spec.Enabled && !table.TimeToLiveEnabled {
+		return nil, &ValidationException{Message: "TimeToLive is already disabled"}
+	}
+	if !*spec.Enabled && table.TimeToLiveAttributeName != *spec.AttributeName {
+		msg := fmt.Sprintf("TimeToLive is active on a different AttributeName: current AttributeName is %s", table.TimeToLiveAttributeName)
+		return nil, &ValidationException{Message: msg}
+	}
+
+	var attributeName *string
+	if *spec.Enabled {
+		attributeName = spec.AttributeName
+	}
+	if err := svc.storage.UpdateTimeToLive(tableName, attributeName); err != nil {
+		return nil, err
+	}
+	table.TimeToLiveEnabled = *spec.Enabled
+	table.TimeToLiveAttributeName = *spec.AttributeName
+	if err := svc.persistSchema(); err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.UpdateTimeToLiveOutput{
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: spec.AttributeName,
+			Enabled:       spec.Enabled,
+		},
+	}, nil
+}
+
+func (svc *Service) DescribeTimeToLive(ctx context.Context, input *dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	svc.tableLock.RLock()
+	defer svc.tableLock.RUnlock()
+
+	tableName := *input.TableName
+	if err := validateNotMetadataTable(tableName, "DescribeTimeToLive"); err != nil {
+		return nil, err
+	}
+	table, ok := svc.tableMetadataStore[tableName]
+	if !ok {
+		msg := "Cannot do operations on a non-existent table"
+		return nil, &types.ResourceNotFoundException{
+			Message: &msg,
+		}
+	}
+
+	// DynamoDB only shows the attribute name while TTL is enabled
+	description := &types.TimeToLiveDescription{
+		TimeToLiveStatus: types.TimeToLiveStatusDisabled,
+	}
+	if table.TimeToLiveEnabled {
+		attributeName := table.TimeToLiveAttributeName
+		description.AttributeName = &attributeName
+		description.TimeToLiveStatus = types.TimeToLiveStatusEnabled
+	}
+
+	return &dynamodb.DescribeTimeToLiveOutput{
+		TimeToLiveDescription: description,
+	}, nil
+}
+
+// ENDPOINT_CACHE_PERIOD_IN_MINUTES is how long SDKs may cache the endpoint returned by DescribeEndpoints, the same as DynamoDB
+const ENDPOINT_CACHE_PERIOD_IN_MINUTES = 1440
+
+// DescribeEndpoints returns address as the only endpoint, address is where the request reached baddb,
+// so SDKs with endpoint discovery enabled keep sending requests to baddb
+func (svc *Service) DescribeEndpoints(ctx context.Context, input *dynamodb.DescribeEndpointsInput, address string) (*dynamodb.DescribeEndpointsOutput, error) {
+	return &dynamodb.DescribeEndpointsOutput{
+		Endpoints: []types.Endpoint{
+			{
+				Address:              &address,
+				CachePeriodInMinutes: ENDPOINT_CACHE_PERIOD_IN_MINUTES,
+			},
+		},
+	}, nil
+}
+
 const (
 	MAX_ACTION_REQUEST = 100
 )
@@ -1254,6 +1884,11 @@ func (svc *Service) validateTransactWriteItemsInput(input *dynamodb.TransactWrit
 			Message: fmt.Sprintf("Member must have length less than or equal to %d", MAX_ACTION_REQUEST),
 		}
 	}
+	if input.ClientRequestToken != nil && (len(*input.ClientRequestToken) == 0 || len(*input.ClientRequestToken) > MAX_CLIENT_REQUEST_TOKEN_LENGTH) {
+		return &ValidationException{
+			Message: fmt.Sprintf("1 validation error detected: Value '%s' at 'clientRequestToken' failed to satisfy constraint: Member must have length between 1 and %d", *input.ClientRequestToken, MAX_CLIENT_REQUEST_TOKEN_LENGTH),
+		}
+	}
 
 	primaryKeys := make(map[string]map[string]bool)
 	for _, writeItem := range input.TransactItems {
@@ -1263,6 +1898,9 @@ func (svc *Service) validateTransactWriteItemsInput(input *dynamodb.TransactWrit
 			conditionCheck := writeItem.ConditionCheck
 
 			tableName = *conditionCheck.TableName
+			if err := validateNotMetadataTable(tableName, "TransactWriteItems"); err != nil {
+				return err
+			}
 			tableMetadata, ok := svc.tableMetadataStore[tableName]
 			if !ok {
 				msg := "Cannot do operations on a non-existent table"
@@ -1283,6 +1921,9 @@ func (svc *Service) validateTransactWriteItemsInput(input *dynamodb.TransactWrit
 			put := writeItem.Put
 
 			tableName = *put.TableName
+			if err := validateNotMetadataTable(tableName, "TransactWriteItems"); err != nil {
+				return err
+			}
 			tableMetadata, ok := svc.tableMetadataStore[tableName]
 			if !ok {
 				msg := "Cannot do operations on a non-existent table"
@@ -1303,6 +1944,9 @@ func (svc *Service) validateTransactWriteItemsInput(input *dynamodb.TransactWrit
 			deleteReq := writeItem.Delete
 
 			tableName = *deleteReq.TableName
+			if err := validateNotMetadataTable(tableName, "TransactWriteItems"); err != nil {
+				return err
+			}
 			tableMetadata, ok := svc.tableMetadataStore[tableName]
 			if !ok {
 				msg := "Cannot do operations on a non-existent table"
@@ -1323,6 +1967,9 @@ func (svc *Service) validateTransactWriteItemsInput(input *dynamodb.TransactWrit
 			update := writeItem.Update
 
 			tableName = *update.TableName
+			if err := validateNotMetadataTable(tableName, "TransactWriteItems"); err != nil {
+				return err
+			}
 			tableMetadata, ok := svc.tableMetadataStore[tableName]
 			if !ok {
 				msg := "Cannot do operations on a non-existent table"
@@ -1340,6 +1987,9 @@ func (svc *Service) validateTransactWriteItemsInput(input *dynamodb.TransactWrit
 			}
 
 		}
+		if err := svc.validateTableActive(tableName); err != nil {
+			return err
+		}
 
 		if _, ok := primaryKeys[tableName]; !ok {
 			primaryKeys[tableName] = make(map[string]bool)
@@ -1357,6 +2007,114 @@ func (svc *Service) validateTransactWriteItemsInput(input *dynamodb.TransactWrit
 	return nil
 }
 
+// validateTableActive rejects reads and writes to a CREATING table the way DynamoDB does, a table that doesn't exist
+// is left to the caller
+func (svc *Service) validateTableActive(tableName string) error {
+	table, ok := svc.tableMetadataStore[tableName]
+	if !ok || table.Status() == types.TableStatusActive {
+		return nil
+	}
+	msg := "Requested resource not found"
+	return &types.ResourceNotFoundException{
+		Message: &msg,
+	}
+}
+
+// validateTableNotCreating rejects changes to a CREATING table
+func (svc *Service) validateTableNotCreating(tableName string) error {
+	table, ok := svc.tableMetadataStore[tableName]
+	if !ok || table.Status() != types.TableStatusCreating {
+		return nil
+	}
+	msg := fmt.Sprintf("Attempt to change a resource which is still in use: Table is being created: %s", tableName)
+	return &types.ResourceInUseException{
+		Message: &msg,
+	}
+}
+
+// validateNotMetadataTable rejects operations on baddb_table_metadata, the table is only a control channel
+// to configure other tables through PutItem and does not keep items
+func validateNotMetadataTable(tableName string, operation string) error {
+	if tableName != storage.METADATA_TABLE_NAME {
+		return nil
+	}
+	return &ValidationException{
+		Message: fmt.Sprintf("%s is not supported on %s, only PutItem is supported", operation, tableName),
+	}
+}
+
+// validateIndexName rejects reads on an index the table doesn't have or a GSI which isn't ACTIVE yet, indexName is
+// either a GSI or an LSI
+func validateIndexName(table *core.TableMetaData, indexName *string) error {
+	if indexName == nil {
+		return nil
+	}
+	setting, ok := table.GetSecondaryIndexSetting(*indexName)
+	if !ok {
+		return &ValidationException{
+			Message: fmt.Sprintf("The table does not have the specified index: %s", *indexName),
+		}
+	}
+	if status, _ := setting.IndexStatus(); status != types.IndexStatusActive {
+		return &ValidationException{
+			Message: fmt.Sprintf("Cannot read from backfilling global secondary index: %s", *indexName),
+		}
+	}
+	return nil
+}
+
+// validateItemSize rejects an item larger than core.MAX_ITEM_SIZE_BYTES, unlike validateStrictItem it always runs
+func validateItemSize(entry *core.Entry) error {
+	if entry.Size() > core.MAX_ITEM_SIZE_BYTES {
+		return &ValidationException{
+			Message: "Item size has exceeded the maximum allowed size",
+		}
+	}
+	return nil
+}
+
+// validateExpressionPlaceholders rejects ExpressionAttributeNames and ExpressionAttributeValues that none of
+// the expressions of a request reference
+func validateExpressionPlaceholders(names map[string]string, values map[string]types.AttributeValue, expressions ...*string) error {
+	usedNames, usedValues := expression.Placeholders(expressions...)
+	if unused := unusedPlaceholders(names, usedNames); len(unused) > 0 {
+		return &ValidationException{
+			Message: fmt.Sprintf("Value provided in ExpressionAttributeNames unused in expressions: keys: {%s}", strings.Join(unused, ", ")),
+		}
+	}
+	if unused := unusedPlaceholders(values, usedValues); len(unused) > 0 {
+		return &ValidationException{
+			Message: fmt.Sprintf("Value provided in ExpressionAttributeValues unused in expressions: keys: {%s}", strings.Join(unused, ", ")),
+		}
+	}
+	return nil
+}
+
+func unusedPlaceholders[T any](placeholders map[string]T, used map[string]bool) []string {
+	unused := make([]string, 0)
+	for placeholder := range placeholders {
+		if !used[placeholder] {
+			unused = append(unused, placeholder)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// validateConsistentRead rejects consistent reads on a GSI, GSIs are updated asynchronously so DynamoDB only
+// supports eventually consistent reads on them. Consistent reads on the table and its LSIs are allowed
+func validateConsistentRead(table *core.TableMetaData, indexName *string, consistentRead *bool) error {
+	if indexName == nil || consistentRead == nil || !*consistentRead {
+		return nil
+	}
+	if _, ok := table.GetGlobalSecondaryIndexSetting(*indexName); !ok {
+		return nil
+	}
+	return &ValidationException{
+		Message: "Consistent reads are not supported on global secondary indexes",
+	}
+}
+
 // TODO: refactor it
 func (svc *Service) buildTablePrimaryKey(entry *core.Entry, table *core.TableMetaData) (*storage.PrimaryKey, error) {
 	primaryKey := &storage.PrimaryKey{
@@ -1376,7 +2134,7 @@ func (svc *Service) buildTablePrimaryKey(entry *core.Entry, table *core.TableMet
 		if !ok {
 			return primaryKey, errors.New("sortKey not found")
 		}
-		primaryKey.SortKey = sk.Bytes()
+		primaryKey.SortKey = core.EncodeSortKey(sk)
 	}
 
 	return primaryKey, nil
@@ -1389,13 +2147,16 @@ type TransactionCanceledException struct {
 
 type CancellationReason struct {
 	Code    string
-	Message string
+	Message string                         `json:",omitempty"`
+	Item    map[string]core.AttributeValue `json:",omitempty"`
 }
 
 func (e *TransactionCanceledException) Error() string {
-	// TODO: improve error message
-	var reason = "ConditionalCheckFailed"
-	return fmt.Sprintf("Transaction cancelled, please refer cancellation reasons for specific reasons [%s]", reason)
+	codes := make([]string, len(e.CancellationReasons))
+	for i, reason := range e.CancellationReasons {
+		codes[i] = reason.Code
+	}
+	return fmt.Sprintf("Transaction cancelled, please refer cancellation reasons for specific reasons [%s]", strings.Join(codes, ", "))
 }
 
 func (svc *Service) TransactWriteItems(ctx context.Context, input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
@@ -1408,17 +2169,32 @@ func (svc *Service) TransactWriteItems(ctx context.Context, input *dynamodb.Tran
 		return nil, err
 	}
 
+	if input.ClientRequestToken != nil {
+		svc.clientRequestTokens.Lock()
+		defer svc.clientRequestTokens.Unlock()
+
+		output, ok, err := svc.clientRequestTokens.lookup(*input.ClientRequestToken, input.TransactItems, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return output, nil
+		}
+	}
+
 	txn, err := svc.storage.BeginTxn()
 	if err != nil {
 		return nil, err
 	}
 	defer txn.Rollback()
 
-	for _, writeItem := range input.TransactItems {
+	itemCount := len(input.TransactItems)
+	for i, writeItem := range input.TransactItems {
 		if writeItem.ConditionCheck != nil {
 			conditionCheck := writeItem.ConditionCheck
 			tableName := *conditionCheck.TableName
-			if _, ok := svc.tableMetadataStore[tableName]; !ok {
+			table, ok := svc.tableMetadataStore[tableName]
+			if !ok {
 				msg := "Cannot do operations on a non-existent table"
 				err = &types.ResourceNotFoundException{
 					Message: &msg,
@@ -1445,10 +2221,14 @@ func (svc *Service) TransactWriteItems(ctx context.Context, input *dynamodb.Tran
 				expressionAttributeValues,
 			)
 			if err != nil {
+				invalidConditionErr := &core.InvalidConditionExpressionError{RawErr: err}
 				return nil, &ValidationException{
-					Message: err.Error(),
+					Message: invalidConditionErr.Error(),
 				}
 			}
+			if err := validateExpressionPlaceholders(conditionCheck.ExpressionAttributeNames, conditionCheck.ExpressionAttributeValues, conditionCheck.ConditionExpression); err != nil {
+				return nil, err
+			}
 
 			key, err := core.NewEntryFromItem(conditionCheck.Key)
 			if err != nil {
@@ -1456,17 +2236,23 @@ func (svc *Service) TransactWriteItems(ctx context.Context, input *dynamodb.Tran
 					Message: err.Error(),
 				}
 			}
+			if err := request.ValidateKey(key, table); err != nil {
+				return nil, &ValidationException{
+					Message: err.Error(),
+				}
+			}
 
 			req := &storage.GetRequest{
 				Entry:          key,
 				TableName:      tableName,
 				ConsistentRead: true,
 			}
-			entry, err := svc.storage.GetWithTransaction(req, txn)
+			currentEntry, err := svc.storage.GetWithTransaction(req, txn)
 			if err != nil {
 				return nil, err
 			}
 
+			entry := currentEntry
 			if entry == nil {
 				entry = &core.Entry{
 					Body: make(map[string]core.AttributeValue),
@@ -1479,13 +2265,18 @@ func (svc *Service) TransactWriteItems(ctx context.Context, input *dynamodb.Tran
 			} else if matched {
 				continue
 			} else {
-				return nil, wrapTransactionError(err)
+				err = &storage.ConditionalCheckFailedException{
+					Message: "The conditional request failed",
+					Item:    currentEntry,
+				}
+				return nil, wrapTransactionError(err, i, itemCount, conditionCheck.ReturnValuesOnConditionCheckFailure)
 			}
 
 		} else if writeItem.Put != nil {
 			put := writeItem.Put
 			tableName := *put.TableName
-			if _, ok := svc.tableMetadataStore[tableName]; !ok {
+			table, ok := svc.tableMetadataStore[tableName]
+			if !ok {
 				msg := "Cannot do operations on a non-existent table"
 				err = &types.ResourceNotFoundException{
 					Message: &msg,
@@ -1498,6 +2289,7 @@ func (svc *Service) TransactWriteItems(ctx context.Context, input *dynamodb.Tran
 				ExpressionAttributeValues: put.ExpressionAttributeValues,
 				Item:                      put.Item,
 				TableName:                 put.TableName,
+				TableMetaData:             table,
 			}
 			req, err := builder.Build()
 			if err != nil {
@@ -1505,14 +2297,24 @@ func (svc *Service) TransactWriteItems(ctx context.Context, input *dynamodb.Tran
 					Message: err.Error(),
 				}
 			}
-			err = svc.storage.PutWithTransaction(req, txn)
+			if err := validateExpressionPlaceholders(put.ExpressionAttributeNames, put.ExpressionAttributeValues, put.ConditionExpression); err != nil {
+				return nil, err
+			}
+			if err := validateItemSize(req.Entry); err != nil {
+				return nil, err
+			}
+			if err := svc.validateStrictItem(req.Entry, table); err != nil {
+				return nil, err
+			}
+			_, err = svc.storage.PutWithTransaction(req, txn)
 			if err != nil {
-				return nil, wrapTransactionError(err)
+				return nil, wrapTransactionError(err, i, itemCount, put.ReturnValuesOnConditionCheckFailure)
 			}
 		} else if writeItem.Delete != nil {
 			deleteReq := writeItem.Delete
 			tableName := *deleteReq.TableName
-			if _, ok := svc.tableMetadataStore[tableName]; !ok {
+			table, ok := svc.tableMetadataStore[tableName]
+			if !ok {
 				msg := "Cannot do operations on a non-existent table"
 				err = &types.ResourceNotFoundException{
 					Message: &msg,
@@ -1526,6 +2328,7 @@ func (svc *Service) TransactWriteItems(ctx context.Context, input *dynamodb.Tran
 				ExpressionAttributeNames:  deleteReq.ExpressionAttributeNames,
 				ExpressionAttributeValues: deleteReq.ExpressionAttributeValues,
 				Key:                       deleteReq.Key,
+				TableMetaData:             table,
 			}
 			req, err := builder.Build()
 			if err != nil {
@@ -1533,15 +2336,19 @@ func (svc *Service) TransactWriteItems(ctx context.Context, input *dynamodb.Tran
 					Message: err.Error(),
 				}
 			}
+			if err := validateExpressionPlaceholders(deleteReq.ExpressionAttributeNames, deleteReq.ExpressionAttributeValues, deleteReq.ConditionExpression); err != nil {
+				return nil, err
+			}
 
-			err = svc.storage.DeleteWithTransaction(req, txn)
+			_, err = svc.storage.DeleteWithTransaction(req, txn)
 			if err != nil {
-				return nil, err
+				return nil, wrapTransactionError(err, i, itemCount, deleteReq.ReturnValuesOnConditionCheckFailure)
 			}
 		} else if writeItem.Update != nil {
 			updateReq := writeItem.Update
 			tableName := *updateReq.TableName
-			if _, ok := svc.tableMetadataStore[tableName]; !ok {
+			table, ok := svc.tableMetadataStore[tableName]
+			if !ok {
 				msg := "Cannot do operations on a non-existent table"
 				err = &types.ResourceNotFoundException{
 					Message: &msg,
@@ -1556,6 +2363,7 @@ func (svc *Service) TransactWriteItems(ctx context.Context, input *dynamodb.Tran
 				ExpressionAttributeValues: updateReq.ExpressionAttributeValues,
 				ConditionExpression:       updateReq.ConditionExpression,
 				Key:                       updateReq.Key,
+				TableMetaData:             table,
 			}
 			req, err := builder.Build()
 			if err != nil {
@@ -1563,10 +2371,16 @@ func (svc *Service) TransactWriteItems(ctx context.Context, input *dynamodb.Tran
 					Message: err.Error(),
 				}
 			}
+			if err := validateExpressionPlaceholders(updateReq.ExpressionAttributeNames, updateReq.ExpressionAttributeValues, updateReq.UpdateExpression, updateReq.ConditionExpression); err != nil {
+				return nil, err
+			}
+			req.ValidateEntry = func(entry *core.Entry) error {
+				return svc.validateStrictItem(entry, table)
+			}
 
 			_, err = svc.storage.UpdateWithTransaction(req, txn)
 			if err != nil {
-				return nil, wrapTransactionError(err)
+				return nil, wrapTransactionError(err, i, itemCount, updateReq.ReturnValuesOnConditionCheckFailure)
 			}
 		}
 
@@ -1577,32 +2391,50 @@ func (svc *Service) TransactWriteItems(ctx context.Context, input *dynamodb.Tran
 	}
 
 	output := &dynamodb.TransactWriteItemsOutput{}
+	if input.ClientRequestToken != nil {
+		svc.clientRequestTokens.store(*input.ClientRequestToken, input.TransactItems, output, time.Now())
+	}
 
 	return output, nil
 }
 
-func wrapTransactionError(err error) error {
+// wrapTransactionError reports a failed conditional check at itemIndex, every other item gets a None reason
+func wrapTransactionError(err error, itemIndex int, itemCount int, returnValues types.ReturnValuesOnConditionCheckFailure) error {
 	var conditionalCheckFailedException *storage.ConditionalCheckFailedException
 	if errors.As(err, &conditionalCheckFailedException) {
+		reasons := make([]CancellationReason, itemCount)
+		for i := range reasons {
+			reasons[i] = CancellationReason{Code: "None"}
+		}
+		reason := CancellationReason{
+			Code:    "ConditionalCheckFailed",
+			Message: "The conditional request failed",
+		}
+		if returnValues == types.ReturnValuesOnConditionCheckFailureAllOld && conditionalCheckFailedException.Item != nil {
+			reason.Item = conditionalCheckFailedException.Item.Body
+		}
+		reasons[itemIndex] = reason
+
 		return &TransactionCanceledException{
-			rawError: err,
-			CancellationReasons: []CancellationReason{
-				{
-					Code:    "ConditionalCheckFailed",
-					Message: "The conditional request failed",
-				},
-			},
+			rawError:            err,
+			CancellationReasons: reasons,
 		}
-	} else if errors.Is(err, storage.RateLimitReachedError) {
+	} else if errors.Is(err, storage.RateLimitReachedError) || errors.Is(err, storage.ErrPartitionLimitExceeded) {
 		return ProvisionedThroughputExceededException
+	} else if errors.Is(err, storage.ErrItemSizeExceeded) {
+		return itemSizeToUpdateExceededException
 	} else {
 		return err
 	}
 }
 
 func wrapError(err error) error {
-	if errors.Is(err, storage.RateLimitReachedError) {
+	if errors.Is(err, storage.RateLimitReachedError) || errors.Is(err, storage.ErrPartitionLimitExceeded) {
 		return ProvisionedThroughputExceededException
+	} else if errors.Is(err, storage.ErrItemSizeExceeded) {
+		return itemSizeToUpdateExceededException
+	} else if errors.Is(err, core.ErrIncorrectOperandType) || errors.As(err, new(*core.InvalidUpdateExpressionError)) {
+		return &ValidationException{Message: err.Error()}
 	} else {
 		return err
 	}
@@ -1614,6 +2446,12 @@ func (svc *Service) Scan(ctx context.Context, input *dynamodb.ScanInput) (*dynam
 	defer svc.tableLock.RUnlock()
 
 	tableName := *input.TableName
+	if err := validateNotMetadataTable(tableName, "Scan"); err != nil {
+		return nil, err
+	}
+	if err := svc.validateTableActive(tableName); err != nil {
+		return nil, err
+	}
 	tableMetadata, ok := svc.tableMetadataStore[tableName]
 	if !ok {
 		msg := "Cannot do operations on a non-existent table"
@@ -1622,6 +2460,12 @@ func (svc *Service) Scan(ctx context.Context, input *dynamodb.ScanInput) (*dynam
 		}
 		return nil, err
 	}
+	if err := validateIndexName(tableMetadata, input.IndexName); err != nil {
+		return nil, err
+	}
+	if err := validateConsistentRead(tableMetadata, input.IndexName, input.ConsistentRead); err != nil {
+		return nil, err
+	}
 
 	expressionAttributeValues, err := core.TransformAttributeValueMap(input.ExpressionAttributeValues)
 	if err != nil {
@@ -1641,6 +2485,8 @@ func (svc *Service) Scan(ctx context.Context, input *dynamodb.ScanInput) (*dynam
 		IndexName:                 input.IndexName,
 		Segment:                   input.Segment,
 		TotalSegments:             input.TotalSegments,
+		ScanFilter:                input.ScanFilter,
+		ConditionalOperator:       input.ConditionalOperator,
 	}
 	scanReq, err := scanReqBuilder.Build()
 	if err != nil {
@@ -1649,49 +2495,399 @@ func (svc *Service) Scan(ctx context.Context, input *dynamodb.ScanInput) (*dynam
 		}
 	}
 
-	res, err := svc.storage.Scan(scanReq)
+	selectType, proj, err := buildReadProjection("Scanning", input.Select, input.ProjectionExpression, input.ExpressionAttributeNames, tableMetadata, input.IndexName)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateExpressionPlaceholders(input.ExpressionAttributeNames, input.ExpressionAttributeValues, input.FilterExpression, input.ProjectionExpression); err != nil {
+		return nil, err
+	}
+
+	res, err := svc.storage.Scan(ctx, scanReq)
 	if err != nil {
 		return nil, wrapError(err)
 	}
 
 	entries := res.Entries
-	items := make([]map[string]types.AttributeValue, len(entries))
-	for i, entry := range entries {
-		items[i] = core.NewItemFromEntry(entry.Body)
+	items, err := buildReadItems(entries, selectType, proj)
+	if err != nil {
+		return nil, err
+	}
+	lastEvaluatedKey, err := buildLastEvaluatedKey(entries, tableMetadata, input.IndexName)
+	if err != nil {
+		return nil, err
 	}
-	lastEvaluatedKey, err := buildLastEvaluatedKey(entries, tableMetadata)
 
 	output := &dynamodb.ScanOutput{
 		Count:            int32(len(res.Entries)),
 		ScannedCount:     res.ScannedCount,
 		LastEvaluatedKey: lastEvaluatedKey,
 		Items:            items,
+		ConsumedCapacity: buildConsumedCapacity(input.ReturnConsumedCapacity, tableMetadata, input.IndexName, readCapacityUnits(res.ScannedSize, scanReq.ConsistentRead)),
 	}
 
-	// TODO: handle select,ProjectionExpression
-
 	return output, nil
 }
 
-func buildLastEvaluatedKey(entries []*core.Entry, tableMetadata *core.TableMetaData) (map[string]types.AttributeValue, error) {
+// buildReadProjection resolves Select and ProjectionExpression of Query and Scan, the projection is nil when
+// all the attributes of the table or index are returned
+func buildReadProjection(
+	operation string,
+	selectType types.Select,
+	projectionExpression *string,
+	expressionAttributeNames map[string]string,
+	tableMetadata *core.TableMetaData,
+	indexName *string,
+) (types.Select, *projection.Projection, error) {
+	if selectType == "" {
+		if projectionExpression != nil {
+			selectType = types.SelectSpecificAttributes
+		} else if indexName != nil {
+			selectType = types.SelectAllProjectedAttributes
+		} else {
+			selectType = types.SelectAllAttributes
+		}
+	}
+
+	switch selectType {
+	case types.SelectAllAttributes:
+		if indexName != nil {
+			gsiSetting, ok := tableMetadata.GetGlobalSecondaryIndexSetting(*indexName)
+			if ok && gsiSetting.ProjectionType != core.PROJECTION_TYPE_ALL {
+				return "", nil, &ValidationException{
+					Message: fmt.Sprintf("One or more parameter values were invalid: Select type ALL_ATTRIBUTES is not supported for global secondary index %s because its projection type is not ALL", *indexName),
+				}
+			}
+		}
+	case types.SelectAllProjectedAttributes:
+		if indexName == nil {
+			return "", nil, &ValidationException{
+				Message: fmt.Sprintf("One or more parameter values were invalid: ALL_PROJECTED_ATTRIBUTES can be used only when %s using an IndexName", operation),
+			}
+		}
+	case types.SelectSpecificAttributes:
+		if projectionExpression == nil {
+			return "", nil, &ValidationException{
+				Message: "One or more parameter values were invalid: Select type SPECIFIC_ATTRIBUTES requires either AttributesToGet or ProjectionExpression",
+			}
+		}
+	case types.SelectCount:
+	default:
+		return "", nil, &ValidationException{
+			Message: fmt.Sprintf("1 validation error detected: Value '%s' at 'select' failed to satisfy constraint: Member must satisfy enum value set: [SPECIFIC_ATTRIBUTES, COUNT, ALL_ATTRIBUTES, ALL_PROJECTED_ATTRIBUTES]", selectType),
+		}
+	}
+
+	if projectionExpression == nil {
+		return selectType, nil, nil
+	}
+	if selectType != types.SelectSpecificAttributes {
+		return "", nil, &ValidationException{
+			Message: fmt.Sprintf("Cannot specify the ProjectionExpression when choosing to get %s", selectType),
+		}
+	}
+	proj, err := projection.BuildProjection(*projectionExpression, expressionAttributeNames)
+	if err != nil {
+		invalidProjectionErr := &core.InvalidProjectionExpressionError{RawErr: err}
+		return "", nil, &ValidationException{
+			Message: invalidProjectionErr.Error(),
+		}
+	}
+	return selectType, proj, nil
+}
+
+// buildReadItems converts the entries read by Query and Scan to items, COUNT returns no item
+func buildReadItems(entries []*core.Entry, selectType types.Select, proj *projection.Projection) ([]map[string]types.AttributeValue, error) {
+	if selectType == types.SelectCount {
+		return nil, nil
+	}
+
+	items := make([]map[string]types.AttributeValue, len(entries))
+	for i, entry := range entries {
+		if proj != nil {
+			projected, err := proj.Project(entry)
+			if err != nil {
+				return nil, err
+			}
+			entry = projected
+		}
+		items[i] = core.NewItemFromEntry(entry.Body)
+	}
+	return items, nil
+}
+
+// buildLastEvaluatedKey returns the key attributes of the last entry, the table keys and, when reading an index,
+// the index keys. The other attributes of the entry are left out the way DynamoDB does.
+func buildLastEvaluatedKey(entries []*core.Entry, tableMetadata *core.TableMetaData, indexName *string) (map[string]types.AttributeValue, error) {
 	lastEvaluatedKey := make(map[string]types.AttributeValue)
-	if len(entries) > 0 {
-		lastEntry := entries[len(entries)-1]
-		partitionKeyName := tableMetadata.PartitionKeySchema.AttributeName
-		pk, ok := lastEntry.Body[partitionKeyName]
+	if len(entries) == 0 {
+		return lastEvaluatedKey, nil
+	}
+
+	keySchemas := []*core.KeySchema{tableMetadata.PartitionKeySchema, tableMetadata.SortKeySchema}
+	if indexName != nil {
+		gsiSetting, ok := tableMetadata.GetSecondaryIndexSetting(*indexName)
 		if !ok {
-			return nil, fmt.Errorf("can't found partition key in last entry")
+			return nil, fmt.Errorf("index %s not found in table %s", *indexName, tableMetadata.Name)
 		}
-		lastEvaluatedKey[partitionKeyName] = pk.ToDdbAttributeValue()
-		if tableMetadata.SortKeySchema != nil {
-			sortKeyName := tableMetadata.SortKeySchema.AttributeName
-			sk, ok := lastEntry.Body[sortKeyName]
-			if !ok {
-				return nil, fmt.Errorf("can't found sort key in last entry")
-			}
-			lastEvaluatedKey[sortKeyName] = sk.ToDdbAttributeValue()
+		keySchemas = append(keySchemas, gsiSetting.PartitionKeySchema, gsiSetting.SortKeySchema)
+	}
+
+	lastEntry := entries[len(entries)-1]
+	for _, keySchema := range keySchemas {
+		if keySchema == nil {
+			continue
+		}
+		val, ok := lastEntry.Body[keySchema.AttributeName]
+		if !ok {
+			return nil, fmt.Errorf("can't found key %s in last entry", keySchema.AttributeName)
 		}
+		lastEvaluatedKey[keySchema.AttributeName] = val.ToDdbAttributeValue()
 	}
 
 	return lastEvaluatedKey, nil
 }
+
+type UpdateConsistencyDelayInput struct {
+	// TableNames are the tables to update, all tables are updated when it is empty
+	TableNames        []string
+	TableDelaySeconds int
+	GsiDelaySeconds   int
+}
+
+type ConsistencyDelay struct {
+	TableName         string
+	TableDelaySeconds int
+	GsiDelaySeconds   int
+}
+
+type UpdateConsistencyDelayOutput struct {
+	Tables []ConsistencyDelay
+}
+
+// UpdateConsistencyDelay sets tableDelaySeconds and gsiDelaySeconds of many tables at once,
+// it does the same as putting an item to baddb_table_metadata for each table, but either all tables are updated or none.
+func (svc *Service) UpdateConsistencyDelay(ctx context.Context, input *UpdateConsistencyDelayInput) (*UpdateConsistencyDelayOutput, error) {
+	if input.TableDelaySeconds < 0 || input.GsiDelaySeconds < 0 {
+		return nil, &ValidationException{Message: "TableDelaySeconds and GsiDelaySeconds must not be negative"}
+	}
+
+	svc.tableLock.RLock()
+	defer svc.tableLock.RUnlock()
+
+	tableNames, err := svc.resolveAdminTables(input.TableNames)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := svc.storage.UpdateDelaySeconds(tableNames, input.TableDelaySeconds, input.GsiDelaySeconds); err != nil {
+		return nil, err
+	}
+
+	output := &UpdateConsistencyDelayOutput{
+		Tables: make([]ConsistencyDelay, len(tableNames)),
+	}
+	for i, tableName := range tableNames {
+		output.Tables[i] = ConsistencyDelay{
+			TableName:         tableName,
+			TableDelaySeconds: input.TableDelaySeconds,
+			GsiDelaySeconds:   input.GsiDelaySeconds,
+		}
+	}
+
+	return output, nil
+}
+
+type UpdatePartitionLimitInput struct {
+	// TableNames are the tables to update, all tables are updated when it is empty
+	TableNames []string
+	// MaxItemCount and MaxSizeBytes are the limits of a single partition, 0 disables the limit
+	MaxItemCount int
+	MaxSizeBytes int
+}
+
+type PartitionLimit struct {
+	TableName    string
+	MaxItemCount int
+	MaxSizeBytes int
+}
+
+type UpdatePartitionLimitOutput struct {
+	Tables []PartitionLimit
+}
+
+// UpdatePartitionLimit caps the item count and size of a single partition of many tables to simulate hot partitions,
+// a write making a partition exceed the limit is throttled with ProvisionedThroughputExceededException.
+// Either all tables are updated or none.
+func (svc *Service) UpdatePartitionLimit(ctx context.Context, input *UpdatePartitionLimitInput) (*UpdatePartitionLimitOutput, error) {
+	if input.MaxItemCount < 0 || input.MaxSizeBytes < 0 {
+		return nil, &ValidationException{Message: "MaxItemCount and MaxSizeBytes must not be negative"}
+	}
+
+	svc.tableLock.RLock()
+	defer svc.tableLock.RUnlock()
+
+	tableNames, err := svc.resolveAdminTables(input.TableNames)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := svc.storage.UpdatePartitionLimit(tableNames, input.MaxItemCount, input.MaxSizeBytes); err != nil {
+		return nil, err
+	}
+
+	output := &UpdatePartitionLimitOutput{
+		Tables: make([]PartitionLimit, len(tableNames)),
+	}
+	for i, tableName := range tableNames {
+		output.Tables[i] = PartitionLimit{
+			TableName:    tableName,
+			MaxItemCount: input.MaxItemCount,
+			MaxSizeBytes: input.MaxSizeBytes,
+		}
+	}
+
+	return output, nil
+}
+
+type ResetRateLimitersInput struct {
+	// TableNames are the tables to reset, all tables are reset when it is empty
+	TableNames []string
+}
+
+type ResetRateLimitersOutput struct {
+	TableNames []string
+}
+
+// ResetRateLimiters refills the rate limiters of many tables and their GSIs to full burst,
+// so throttling tests sharing a server don't depend on the capacity consumed by previous tests.
+func (svc *Service) ResetRateLimiters(ctx context.Context, input *ResetRateLimitersInput) (*ResetRateLimitersOutput, error) {
+	svc.tableLock.RLock()
+	defer svc.tableLock.RUnlock()
+
+	tableNames, err := svc.resolveAdminTables(input.TableNames)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := svc.storage.ResetRateLimiters(tableNames); err != nil {
+		return nil, err
+	}
+
+	return &ResetRateLimitersOutput{
+		TableNames: tableNames,
+	}, nil
+}
+
+// resolveAdminTables sorts the tables an admin operation applies to, all tables when names is empty.
+// baddb_table_metadata isn't a table to them, so naming it is the same as naming a non-existent table
+func (svc *Service) resolveAdminTables(names []string) ([]string, error) {
+	tableNames := make([]string, 0, len(svc.tableMetadataStore))
+	if len(names) == 0 {
+		for tableName := range svc.tableMetadataStore {
+			if tableName != storage.METADATA_TABLE_NAME {
+				tableNames = append(tableNames, tableName)
+			}
+		}
+	} else {
+		tableNames = append(tableNames, names...)
+	}
+	sort.Strings(tableNames)
+
+	for _, tableName := range tableNames {
+		if _, ok := svc.tableMetadataStore[tableName]; !ok || tableName == storage.METADATA_TABLE_NAME {
+			msg := "Cannot do operations on a non-existent table"
+			return nil, &types.ResourceNotFoundException{
+				Message: &msg,
+			}
+		}
+	}
+
+	return tableNames, nil
+}
+
+type ImportItemsInput struct {
+	TableName string
+	Items     []map[string]core.AttributeValue
+}
+
+type ImportItemsOutput struct {
+	ImportedItemCount int
+	// FailedItems are the items rejected by the schema of the table, the other items are still imported
+	FailedItems []ImportItemError `json:",omitempty"`
+}
+
+// ImportItemError is why the item at Index of ImportItemsInput.Items isn't imported
+type ImportItemError struct {
+	Index   int
+	Message string
+}
+
+// ImportItems seeds a table with items through storage.BulkPut, it's much faster than putting the items one by one
+// and isn't affected by the rate limiter or the consistency delay. The valid items are imported, and the rest are
+// reported in FailedItems with the reason they are rejected.
+func (svc *Service) ImportItems(ctx context.Context, input *ImportItemsInput) (*ImportItemsOutput, error) {
+	svc.tableLock.RLock()
+	defer svc.tableLock.RUnlock()
+
+	if err := validateNotMetadataTable(input.TableName, "ImportItems"); err != nil {
+		return nil, err
+	}
+	if _, ok := svc.tableMetadataStore[input.TableName]; !ok {
+		msg := "Cannot do operations on a non-existent table"
+		return nil, &types.ResourceNotFoundException{
+			Message: &msg,
+		}
+	}
+	if err := svc.validateTableActive(input.TableName); err != nil {
+		return nil, err
+	}
+
+	table := svc.tableMetadataStore[input.TableName]
+	entries := make([]*core.Entry, 0, len(input.Items))
+	failedItems := make([]ImportItemError, 0)
+	for i, item := range input.Items {
+		entry := &core.Entry{Body: item}
+		if err := validateImportItem(entry, table); err != nil {
+			failedItems = append(failedItems, ImportItemError{Index: i, Message: err.Error()})
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := svc.storage.BulkPut(input.TableName, entries); err != nil {
+		return nil, &ValidationException{Message: err.Error()}
+	}
+
+	return &ImportItemsOutput{
+		ImportedItemCount: len(entries),
+		FailedItems:       failedItems,
+	}, nil
+}
+
+// validateImportItem checks an imported item the way a put checks it, and the key attributes of the GSIs and LSIs it
+// has are of the types of their definitions. An item missing the key attributes of an index isn't in the index
+func validateImportItem(entry *core.Entry, table *core.TableMetaData) error {
+	if err := validateItemSize(entry); err != nil {
+		return err
+	}
+	if err := request.ValidateItem(entry, table); err != nil {
+		return &ValidationException{
+			Message: err.Error(),
+		}
+	}
+
+	indexes := append(append([]core.GlobalSecondaryIndexSetting{}, table.GlobalSecondaryIndexSettings...), table.LocalSecondaryIndexSettings...)
+	for _, gsi := range indexes {
+		for _, keySchema := range []*core.KeySchema{gsi.PartitionKeySchema, gsi.SortKeySchema} {
+			if keySchema == nil {
+				continue
+			}
+			if val, ok := entry.Body[keySchema.AttributeName]; ok && !val.IsScalarAttributeType(keySchema.AttributeType) {
+				return &ValidationException{
+					Message: fmt.Sprintf("One or more parameter values were invalid: Type mismatch for Index Key %s Expected: %s Actual: %s IndexName: %s", keySchema.AttributeName, keySchema.AttributeType, val.Type(), *gsi.IndexName),
+				}
+			}
+		}
+	}
+	return nil
+}
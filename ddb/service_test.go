@@ -1,9 +1,19 @@
 package ddb
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/ocowchun/baddb/ddb/core"
-	"testing"
 )
 
 func TestEncoding(t *testing.T) {
@@ -40,3 +50,1295 @@ func TestEncoding(t *testing.T) {
 	}
 
 }
+
+func createMovieTable(t *testing.T, svc *Service, tableName string) {
+	_, err := svc.CreateTable(context.Background(), &dynamodb.CreateTableInput{
+		TableName:   aws.String(tableName),
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("year"), AttributeType: types.ScalarAttributeTypeN},
+			{AttributeName: aws.String("title"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("year"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("title"), KeyType: types.KeyTypeRange},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error creating table %s, got %v", tableName, err)
+	}
+}
+
+func TestInMemoryServiceDumpsToFileBackedService(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "baddb.db")
+
+	svc, err := NewDdbServiceWithConfig(Config{DbPath: dbPath})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	createMovieTable(t, svc, "movie")
+	_, err = svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String("movie"),
+		Item: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "1984"},
+			"title": &types.AttributeValueMemberS{Value: "Nausicaä"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := svc.Close(); err != nil {
+		t.Fatalf("Expected no error closing service, got %v", err)
+	}
+
+	svc, err = NewDdbServiceWithConfig(Config{DbPath: dbPath, FileBacked: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer svc.Close()
+
+	describeOutput, err := svc.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String("movie")})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if *describeOutput.Table.ItemCount != 1 {
+		t.Fatalf("Expected item count 1, got %d", *describeOutput.Table.ItemCount)
+	}
+
+	getOutput, err := svc.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String("movie"),
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "1984"},
+			"title": &types.AttributeValueMemberS{Value: "Nausicaä"},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if getOutput.Item == nil {
+		t.Fatalf("Expected item to be found after restart")
+	}
+
+	// a table created after the restart must not reuse the storage of the restored one
+	createMovieTable(t, svc, "movie2")
+	describeOutput, err = svc.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String("movie2")})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if *describeOutput.Table.ItemCount != 0 {
+		t.Fatalf("Expected item count 0, got %d", *describeOutput.Table.ItemCount)
+	}
+}
+
+func TestItemSizeLimit(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewDdbServiceWithConfig(Config{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer svc.Close()
+	createMovieTable(t, svc, "movie")
+
+	// the key attributes and names take about 30 bytes, so the messages make ~399KB and ~401KB items
+	item := func(title string, messageSize int) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"year":    &types.AttributeValueMemberN{Value: "2025"},
+			"title":   &types.AttributeValueMemberS{Value: title},
+			"message": &types.AttributeValueMemberS{Value: strings.Repeat("a", messageSize)},
+		}
+	}
+	key := func(title string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: title},
+		}
+	}
+	assertValidationException := func(err error, expected string) {
+		t.Helper()
+		var validationErr *ValidationException
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected ValidationException, got %v", err)
+		}
+		if validationErr.Message != expected {
+			t.Fatalf("Expected %q, got %q", expected, validationErr.Message)
+		}
+	}
+
+	_, err = svc.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String("movie"), Item: item("small", 399*1024)})
+	if err != nil {
+		t.Fatalf("Expected a ~399KB item to be accepted, got %v", err)
+	}
+	_, err = svc.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String("movie"), Item: item("large", 401*1024)})
+	assertValidationException(err, "Item size has exceeded the maximum allowed size")
+
+	// the size of an update is the size of the resulting item
+	_, err = svc.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String("movie"),
+		Key:              key("small"),
+		UpdateExpression: aws.String("SET extra = :extra"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":extra": &types.AttributeValueMemberS{Value: strings.Repeat("b", 2*1024)},
+		},
+	})
+	assertValidationException(err, "Item size to update has exceeded the maximum allowed size")
+	_, err = svc.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String("movie"),
+		Key:              key("small"),
+		UpdateExpression: aws.String("SET extra = :extra"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":extra": &types.AttributeValueMemberS{Value: strings.Repeat("b", 512)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected an update under the limit to be accepted, got %v", err)
+	}
+
+	_, err = svc.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{TableName: aws.String("movie"), Item: item("transact small", 399*1024)}},
+			{Put: &types.Put{TableName: aws.String("movie"), Item: item("transact large", 401*1024)}},
+		},
+	})
+	assertValidationException(err, "Item size has exceeded the maximum allowed size")
+	getOutput, err := svc.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String("movie"),
+		Key:            key("transact small"),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if getOutput.Item != nil {
+		t.Fatalf("Expected the transaction to write nothing")
+	}
+
+	_, err = svc.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Update: &types.Update{
+				TableName:        aws.String("movie"),
+				Key:              key("small"),
+				UpdateExpression: aws.String("SET extra = :extra"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":extra": &types.AttributeValueMemberS{Value: strings.Repeat("b", 2*1024)},
+				},
+			}},
+		},
+	})
+	assertValidationException(err, "Item size to update has exceeded the maximum allowed size")
+}
+
+func createMovieTableWithRegionIndex(t *testing.T, svc *Service, tableName string) {
+	_, err := svc.CreateTable(context.Background(), &dynamodb.CreateTableInput{
+		TableName:   aws.String(tableName),
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("year"), AttributeType: types.ScalarAttributeTypeN},
+			{AttributeName: aws.String("title"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("region"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("year"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("title"), KeyType: types.KeyTypeRange},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{{
+			IndexName: aws.String("regionIndex"),
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("region"), KeyType: types.KeyTypeHash},
+			},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error creating table %s, got %v", tableName, err)
+	}
+}
+
+var itemWithEmptyIndexKey = map[string]types.AttributeValue{
+	"year":   &types.AttributeValueMemberN{Value: "2025"},
+	"title":  &types.AttributeValueMemberS{Value: "Hello World"},
+	"region": &types.AttributeValueMemberS{Value: ""},
+}
+
+func TestStrictModeRejectsEmptyIndexKey(t *testing.T) {
+	ctx := context.Background()
+	strict, err := NewDdbServiceWithConfig(Config{Strict: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer strict.Close()
+	createMovieTableWithRegionIndex(t, strict, "movie")
+
+	var validationErr *ValidationException
+	_, err = strict.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String("movie"),
+		Item:      itemWithEmptyIndexKey,
+	})
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected ValidationException for an empty GSI key in strict mode, got %v", err)
+	}
+	expected := "One or more parameter values are not valid. A value specified for a secondary index key is not supported. The AttributeValue for a key attribute cannot contain an empty string value. IndexName: regionIndex, IndexKey: region"
+	if validationErr.Message != expected {
+		t.Fatalf("Expected %q, got %q", expected, validationErr.Message)
+	}
+}
+
+func TestNonStrictModeAcceptsEmptyIndexKey(t *testing.T) {
+	ctx := context.Background()
+	svc := NewDdbService()
+	defer svc.Close()
+	createMovieTableWithRegionIndex(t, svc, "movie")
+
+	_, err := svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String("movie"),
+		Item:      itemWithEmptyIndexKey,
+	})
+	if err != nil {
+		t.Fatalf("Expected an empty GSI key to be accepted without strict mode, got %v", err)
+	}
+}
+
+func TestStrictModeValidatesUpdatedItem(t *testing.T) {
+	ctx := context.Background()
+	strict, err := NewDdbServiceWithConfig(Config{Strict: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer strict.Close()
+	createMovieTable(t, strict, "movie")
+
+	var nested types.AttributeValue = &types.AttributeValueMemberS{Value: "deep"}
+	for i := 0; i <= MAX_NESTING_DEPTH; i++ {
+		nested = &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"a": nested}}
+	}
+	key := map[string]types.AttributeValue{
+		"year":  &types.AttributeValueMemberN{Value: "2025"},
+		"title": &types.AttributeValueMemberS{Value: "Hello World"},
+	}
+	expected := "Nesting Levels have exceeded supported limits"
+
+	var validationErr *ValidationException
+	_, err = strict.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String("movie"),
+		Key:                       key,
+		UpdateExpression:          aws.String("SET info = :info"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":info": nested},
+	})
+	if !errors.As(err, &validationErr) || validationErr.Message != expected {
+		t.Fatalf("Expected ValidationException %q, got %v", expected, err)
+	}
+
+	_, err = strict.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{{
+			Update: &types.Update{
+				TableName:                 aws.String("movie"),
+				Key:                       key,
+				UpdateExpression:          aws.String("SET info = :info"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{":info": nested},
+			},
+		}},
+	})
+	if !errors.As(err, &validationErr) || validationErr.Message != expected {
+		t.Fatalf("Expected ValidationException %q, got %v", expected, err)
+	}
+
+	getOutput, err := strict.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String("movie"),
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if getOutput.Item != nil {
+		t.Fatalf("Expected the rejected updates not to write the item, got %v", getOutput.Item)
+	}
+}
+
+func TestRejectsInvalidKeysAndEmptySets(t *testing.T) {
+	ctx := context.Background()
+	svc := NewDdbService()
+	defer svc.Close()
+	createMovieTable(t, svc, "movie")
+
+	assertValidationException := func(err error, expected string) {
+		t.Helper()
+		var validationErr *ValidationException
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected ValidationException, got %v", err)
+		}
+		if validationErr.Message != expected {
+			t.Fatalf("Expected %q, got %q", expected, validationErr.Message)
+		}
+	}
+
+	_, err := svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String("movie"),
+		Item: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: ""},
+		},
+	})
+	assertValidationException(err, "One or more parameter values are not valid. The AttributeValue for a key attribute cannot contain an empty string value. Key: title")
+
+	_, err = svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String("movie"),
+		Item: map[string]types.AttributeValue{
+			"title": &types.AttributeValueMemberS{Value: "Hello World"},
+		},
+	})
+	assertValidationException(err, "One or more parameter values were invalid: Missing the key year in the item")
+
+	_, err = svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String("movie"),
+		Item: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberS{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World"},
+		},
+	})
+	assertValidationException(err, "One or more parameter values were invalid: Type mismatch for key year expected: N actual: S")
+
+	_, err = svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String("movie"),
+		Item: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World"},
+			"tags":  &types.AttributeValueMemberSS{Value: []string{}},
+		},
+	})
+	assertValidationException(err, "One or more parameter values were invalid: An string set  may not be empty")
+
+	_, err = svc.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String("movie"),
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: ""},
+		},
+	})
+	assertValidationException(err, "One or more parameter values are not valid. The AttributeValue for a key attribute cannot contain an empty string value. Key: title")
+
+	_, err = svc.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String("movie"),
+		Key: map[string]types.AttributeValue{
+			"year": &types.AttributeValueMemberN{Value: "2025"},
+		},
+	})
+	assertValidationException(err, "The provided key element does not match the schema")
+
+	_, err = svc.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String("movie"),
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberN{Value: "1"},
+		},
+	})
+	assertValidationException(err, "The provided key element does not match the schema")
+
+	_, err = svc.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String("movie"),
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World"},
+		},
+		UpdateExpression: aws.String("SET tags = :tags"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":tags": &types.AttributeValueMemberNS{Value: []string{}},
+		},
+	})
+	assertValidationException(err, "ExpressionAttributeValues contains invalid value: One or more parameter values were invalid: An number set  may not be empty for key :tags")
+
+	_, err = svc.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:   aws.String("director"),
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("name"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("name"), KeyType: types.KeyTypeHash},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, err = svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String("director"),
+		Item: map[string]types.AttributeValue{
+			"name": &types.AttributeValueMemberS{Value: ""},
+		},
+	})
+	assertValidationException(err, "One or more parameter values are not valid. The AttributeValue for a key attribute cannot contain an empty string value. Key: name")
+}
+
+func TestBatchWriteItemLimits(t *testing.T) {
+	ctx := context.Background()
+	svc := NewDdbService()
+	defer svc.Close()
+	createMovieTable(t, svc, "movie")
+
+	assertValidationException := func(err error, expected string) {
+		t.Helper()
+		var validationErr *ValidationException
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected ValidationException, got %v", err)
+		}
+		if validationErr.Message != expected {
+			t.Fatalf("Expected %q, got %q", expected, validationErr.Message)
+		}
+	}
+	item := func(title string, size int) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: title},
+			"body":  &types.AttributeValueMemberS{Value: strings.Repeat("a", size)},
+		}
+	}
+
+	_, err := svc.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]types.WriteRequest{
+			"movie": {
+				{PutRequest: &types.PutRequest{Item: item("first", 10)}},
+				{PutRequest: &types.PutRequest{Item: item("duplicate", 10)}},
+				{PutRequest: &types.PutRequest{Item: item("duplicate", 20)}},
+			},
+		},
+	})
+	assertValidationException(err, "Provided list of item keys contains duplicates")
+	getOutput, err := svc.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String("movie"),
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "first"},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if getOutput.Item != nil {
+		t.Fatalf("Expected the rejected batch to write nothing")
+	}
+
+	requests := make([]types.WriteRequest, 0)
+	for i := 0; i < 25; i++ {
+		requests = append(requests, types.WriteRequest{PutRequest: &types.PutRequest{Item: item(fmt.Sprintf("large %d", i), 700*1024)}})
+	}
+	_, err = svc.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]types.WriteRequest{"movie": requests},
+	})
+	assertValidationException(err, "Request size exceeded 16777216 bytes")
+}
+
+func TestTableCreationDelay(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewDdbServiceWithConfig(Config{TableCreationDelay: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer svc.Close()
+	createMovieTable(t, svc, "movie")
+
+	describeOutput, err := svc.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String("movie")})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if describeOutput.Table.TableStatus != types.TableStatusCreating {
+		t.Fatalf("Expected table to be CREATING, got %s", describeOutput.Table.TableStatus)
+	}
+
+	item := map[string]types.AttributeValue{
+		"year":  &types.AttributeValueMemberN{Value: "2025"},
+		"title": &types.AttributeValueMemberS{Value: "Hello World"},
+	}
+	_, err = svc.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String("movie"), Item: item})
+	var resourceNotFoundErr *types.ResourceNotFoundException
+	if !errors.As(err, &resourceNotFoundErr) {
+		t.Fatalf("Expected ResourceNotFoundException writing to a CREATING table, got %v", err)
+	}
+	if resourceNotFoundErr.ErrorMessage() != "Requested resource not found" {
+		t.Fatalf("Unexpected message: %s", resourceNotFoundErr.ErrorMessage())
+	}
+
+	_, err = svc.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: aws.String("movie")})
+	var resourceInUseErr *types.ResourceInUseException
+	if !errors.As(err, &resourceInUseErr) {
+		t.Fatalf("Expected ResourceInUseException deleting a CREATING table, got %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	describeOutput, err = svc.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String("movie")})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if describeOutput.Table.TableStatus != types.TableStatusActive {
+		t.Fatalf("Expected table to be ACTIVE, got %s", describeOutput.Table.TableStatus)
+	}
+	_, err = svc.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String("movie"), Item: item})
+	if err != nil {
+		t.Fatalf("Expected no error writing to an ACTIVE table, got %v", err)
+	}
+}
+
+func TestIndexCreationDelay(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewDdbServiceWithConfig(Config{TableCreationDelay: 200 * time.Millisecond, IndexCreationDelay: 600 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer svc.Close()
+	createMovieTable(t, svc, "movie")
+
+	describeTable := func() *types.TableDescription {
+		describeOutput, err := svc.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String("movie")})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return describeOutput.Table
+	}
+	if status := describeTable().TableStatus; status != types.TableStatusCreating {
+		t.Fatalf("Expected table to be CREATING, got %s", status)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if status := describeTable().TableStatus; status != types.TableStatusActive {
+		t.Fatalf("Expected table to be ACTIVE, got %s", status)
+	}
+
+	_, err = svc.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+		TableName: aws.String("movie"),
+		GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{{
+			Create: &types.CreateGlobalSecondaryIndexAction{
+				IndexName: aws.String("titleGSI"),
+				KeySchema: []types.KeySchemaElement{
+					{AttributeName: aws.String("title"), KeyType: types.KeyTypeHash},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	assertIndexStatus := func(expectedStatus types.IndexStatus, expectedBackfilling *bool) {
+		gsi := describeTable().GlobalSecondaryIndexes
+		if len(gsi) != 1 {
+			t.Fatalf("Expected 1 GSI, got %d", len(gsi))
+		}
+		if gsi[0].IndexStatus != expectedStatus {
+			t.Fatalf("Expected GSI to be %s, got %s", expectedStatus, gsi[0].IndexStatus)
+		}
+		if (expectedBackfilling == nil) != (gsi[0].Backfilling == nil) || (expectedBackfilling != nil && *expectedBackfilling != *gsi[0].Backfilling) {
+			t.Fatalf("Expected Backfilling %v, got %v", expectedBackfilling, gsi[0].Backfilling)
+		}
+	}
+	query := func() error {
+		_, err := svc.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String("movie"),
+			IndexName:              aws.String("titleGSI"),
+			KeyConditionExpression: aws.String("title = :title"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":title": &types.AttributeValueMemberS{Value: "Hello World"},
+			},
+		})
+		return err
+	}
+
+	assertIndexStatus(types.IndexStatusCreating, aws.Bool(false))
+	var validationErr *ValidationException
+	if err := query(); !errors.As(err, &validationErr) || validationErr.Message != "Cannot read from backfilling global secondary index: titleGSI" {
+		t.Fatalf("Expected ValidationException querying a CREATING GSI, got %v", err)
+	}
+
+	time.Sleep(400 * time.Millisecond)
+	assertIndexStatus(types.IndexStatusCreating, aws.Bool(true))
+
+	time.Sleep(300 * time.Millisecond)
+	assertIndexStatus(types.IndexStatusActive, nil)
+	if err := query(); err != nil {
+		t.Fatalf("Expected no error querying an ACTIVE GSI, got %v", err)
+	}
+}
+
+func TestScanStopsWhenContextIsDone(t *testing.T) {
+	svc, err := NewDdbServiceWithConfig(Config{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer svc.Close()
+	createMovieTable(t, svc, "movie")
+
+	items := make([]map[string]core.AttributeValue, 10000)
+	for i := range items {
+		year := strconv.Itoa(2000 + i%20)
+		title := fmt.Sprintf("Hello World %d", i)
+		items[i] = map[string]core.AttributeValue{
+			"year":  {N: &year},
+			"title": {S: &title},
+		}
+	}
+	_, err = svc.ImportItems(context.Background(), &ImportItemsInput{TableName: "movie", Items: items})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(5*time.Millisecond, cancel)
+	start := time.Now()
+	// the filter matches nothing, so the scan reads the whole table looking for the first item
+	_, err = svc.Scan(ctx, &dynamodb.ScanInput{
+		TableName:        aws.String("movie"),
+		FilterExpression: aws.String("title = :title"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":title": &types.AttributeValueMemberS{Value: "Not Found"},
+		},
+		Limit: aws.Int32(1),
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Expected the scan to return promptly after cancel, took %v", elapsed)
+	}
+}
+
+func TestReservedKeywordErrors(t *testing.T) {
+	svc, err := NewDdbServiceWithConfig(Config{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	createMovieTable(t, svc, "movie")
+
+	key := map[string]types.AttributeValue{
+		"year":  &types.AttributeValueMemberN{Value: "2025"},
+		"title": &types.AttributeValueMemberS{Value: "Hello World"},
+	}
+	values := map[string]types.AttributeValue{
+		":v": &types.AttributeValueMemberS{Value: "English"},
+		":y": &types.AttributeValueMemberN{Value: "2025"},
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		call     func() error
+		expected string
+	}{
+		{
+			name: "PutItem ConditionExpression",
+			call: func() error {
+				_, err := svc.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String("movie"), Item: key, ConditionExpression: aws.String("language = :v"), ExpressionAttributeValues: values})
+				return err
+			},
+			expected: "Invalid ConditionExpression: Attribute name is a reserved keyword; reserved keyword: language",
+		},
+		{
+			name: "DeleteItem nested ConditionExpression",
+			call: func() error {
+				_, err := svc.DeleteItem(ctx, &dynamodb.DeleteItemInput{TableName: aws.String("movie"), Key: key, ConditionExpression: aws.String("info.language = :v"), ExpressionAttributeValues: values})
+				return err
+			},
+			expected: "Invalid ConditionExpression: Attribute name is a reserved keyword; reserved keyword: language",
+		},
+		{
+			name: "UpdateItem UpdateExpression",
+			call: func() error {
+				_, err := svc.UpdateItem(ctx, &dynamodb.UpdateItemInput{TableName: aws.String("movie"), Key: key, UpdateExpression: aws.String("SET language = :v"), ExpressionAttributeValues: values})
+				return err
+			},
+			expected: "Invalid UpdateExpression: Attribute name is a reserved keyword; reserved keyword: language",
+		},
+		{
+			name: "Query KeyConditionExpression",
+			call: func() error {
+				_, err := svc.Query(ctx, &dynamodb.QueryInput{TableName: aws.String("movie"), KeyConditionExpression: aws.String("year = :y"), ExpressionAttributeValues: values})
+				return err
+			},
+			expected: "Invalid KeyConditionExpression: Attribute name is a reserved keyword; reserved keyword: year",
+		},
+		{
+			name: "Query FilterExpression",
+			call: func() error {
+				_, err := svc.Query(ctx, &dynamodb.QueryInput{TableName: aws.String("movie"), KeyConditionExpression: aws.String("#y = :y"), ExpressionAttributeNames: map[string]string{"#y": "year"}, FilterExpression: aws.String("language = :v"), ExpressionAttributeValues: values})
+				return err
+			},
+			expected: "Invalid FilterExpression: Attribute name is a reserved keyword; reserved keyword: language",
+		},
+		{
+			name: "Scan FilterExpression",
+			call: func() error {
+				_, err := svc.Scan(ctx, &dynamodb.ScanInput{TableName: aws.String("movie"), FilterExpression: aws.String("language = :v"), ExpressionAttributeValues: values})
+				return err
+			},
+			expected: "Invalid FilterExpression: Attribute name is a reserved keyword; reserved keyword: language",
+		},
+		{
+			name: "TransactWriteItems ConditionCheck",
+			call: func() error {
+				_, err := svc.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: []types.TransactWriteItem{
+					{ConditionCheck: &types.ConditionCheck{TableName: aws.String("movie"), Key: key, ConditionExpression: aws.String("language = :v"), ExpressionAttributeValues: values}},
+				}})
+				return err
+			},
+			expected: "Invalid ConditionExpression: Attribute name is a reserved keyword; reserved keyword: language",
+		},
+		{
+			name: "TransactWriteItems Update",
+			call: func() error {
+				_, err := svc.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: []types.TransactWriteItem{
+					{Update: &types.Update{TableName: aws.String("movie"), Key: key, UpdateExpression: aws.String("SET language = :v"), ExpressionAttributeValues: values}},
+				}})
+				return err
+			},
+			expected: "Invalid UpdateExpression: Attribute name is a reserved keyword; reserved keyword: language",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			var validationErr *ValidationException
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected ValidationException, got %v", err)
+			}
+			if validationErr.Message != tt.expected {
+				t.Fatalf("Expected %s, got %s", tt.expected, validationErr.Message)
+			}
+		})
+	}
+
+	// aliased reserved words are allowed
+	_, err = svc.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String("movie"),
+		Key:                       key,
+		UpdateExpression:          aws.String("SET #language = :v"),
+		ConditionExpression:       aws.String("attribute_not_exists(#language)"),
+		ExpressionAttributeNames:  map[string]string{"#language": "language"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":v": values[":v"]},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error with an aliased reserved word, got %v", err)
+	}
+}
+
+func TestBillingModeSummarySurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "baddb.db")
+	svc, err := NewDdbServiceWithConfig(Config{DbPath: dbPath, FileBacked: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	createMovieTable(t, svc, "movie")
+	describeOutput, err := svc.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String("movie")})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := describeOutput.Table.BillingModeSummary
+
+	if err := svc.Close(); err != nil {
+		t.Fatalf("Expected no error closing service, got %v", err)
+	}
+	svc, err = NewDdbServiceWithConfig(Config{DbPath: dbPath, FileBacked: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer svc.Close()
+
+	describeOutput, err = svc.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String("movie")})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	summary := describeOutput.Table.BillingModeSummary
+	if summary == nil || summary.BillingMode != expected.BillingMode || summary.LastUpdateToPayPerRequestDateTime == nil ||
+		!summary.LastUpdateToPayPerRequestDateTime.Equal(*expected.LastUpdateToPayPerRequestDateTime) {
+		t.Fatalf("Expected billing mode summary %+v, got %+v", expected, summary)
+	}
+}
+
+func TestTimeToLive(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "baddb.db")
+	svc, err := NewDdbServiceWithConfig(Config{DbPath: dbPath, FileBacked: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	createMovieTable(t, svc, "movie")
+
+	past := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	future := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	items := []map[string]types.AttributeValue{
+		{"title": &types.AttributeValueMemberS{Value: "expired"}, "expireAt": &types.AttributeValueMemberN{Value: past}},
+		{"title": &types.AttributeValueMemberS{Value: "alive"}, "expireAt": &types.AttributeValueMemberN{Value: future}},
+		{"title": &types.AttributeValueMemberS{Value: "forever"}},
+		{"title": &types.AttributeValueMemberS{Value: "not a number"}, "expireAt": &types.AttributeValueMemberS{Value: past}},
+	}
+	for _, item := range items {
+		item["year"] = &types.AttributeValueMemberN{Value: "2025"}
+		if _, err := svc.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String("movie"), Item: item}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	_, err = svc.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String("movie"),
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: aws.String("expireAt"),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// the TTL config survives a restart
+	if err := svc.Close(); err != nil {
+		t.Fatalf("Expected no error closing service, got %v", err)
+	}
+	svc, err = NewDdbServiceWithConfig(Config{DbPath: dbPath, FileBacked: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer svc.Close()
+
+	describeOutput, err := svc.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: aws.String("movie")})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	description := describeOutput.TimeToLiveDescription
+	if description.TimeToLiveStatus != types.TimeToLiveStatusEnabled || *description.AttributeName != "expireAt" {
+		t.Fatalf("Expected TTL to be enabled on expireAt, got %s %v", description.TimeToLiveStatus, description.AttributeName)
+	}
+
+	getOutput, err := svc.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String("movie"),
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "expired"},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if getOutput.Item != nil {
+		t.Fatalf("Expected expired item to be hidden, got %v", getOutput.Item)
+	}
+
+	queryOutput, err := svc.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String("movie"),
+		KeyConditionExpression: aws.String("#year = :year"),
+		ExpressionAttributeNames: map[string]string{
+			"#year": "year",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":year": &types.AttributeValueMemberN{Value: "2025"},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if queryOutput.Count != 3 || queryOutput.ScannedCount != 4 {
+		t.Fatalf("Expected Count 3 and ScannedCount 4, got %d and %d", queryOutput.Count, queryOutput.ScannedCount)
+	}
+	for _, item := range queryOutput.Items {
+		if item["title"].(*types.AttributeValueMemberS).Value == "expired" {
+			t.Fatalf("Expected expired item to be filtered from Query")
+		}
+	}
+
+	scanOutput, err := svc.Scan(ctx, &dynamodb.ScanInput{TableName: aws.String("movie"), ConsistentRead: aws.Bool(true)})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if scanOutput.Count != 3 || scanOutput.ScannedCount != 4 {
+		t.Fatalf("Expected Count 3 and ScannedCount 4, got %d and %d", scanOutput.Count, scanOutput.ScannedCount)
+	}
+
+	tests := []struct {
+		spec     *types.TimeToLiveSpecification
+		expected string
+	}{
+		{
+			spec:     &types.TimeToLiveSpecification{AttributeName: aws.String("expireAt"), Enabled: aws.Bool(true)},
+			expected: "TimeToLive is already enabled",
+		},
+		{
+			spec:     &types.TimeToLiveSpecification{AttributeName: aws.String("ttl"), Enabled: aws.Bool(false)},
+			expected: "TimeToLive is active on a different AttributeName: current AttributeName is expireAt",
+		},
+		{
+			spec:     &types.TimeToLiveSpecification{Enabled: aws.Bool(false)},
+			expected: "1 validation error detected: Value null at 'timeToLiveSpecification.attributeName' failed to satisfy constraint: Member must not be null",
+		},
+	}
+	for _, tt := range tests {
+		_, err := svc.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+			TableName:               aws.String("movie"),
+			TimeToLiveSpecification: tt.spec,
+		})
+		var validationErr *ValidationException
+		if !errors.As(err, &validationErr) || validationErr.Message != tt.expected {
+			t.Fatalf("Expected ValidationException %s, got %v", tt.expected, err)
+		}
+	}
+
+	_, err = svc.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String("movie"),
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: aws.String("expireAt"),
+			Enabled:       aws.Bool(false),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	describeOutput, err = svc.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: aws.String("movie")})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if describeOutput.TimeToLiveDescription.TimeToLiveStatus != types.TimeToLiveStatusDisabled || describeOutput.TimeToLiveDescription.AttributeName != nil {
+		t.Fatalf("Expected TTL to be disabled, got %v", describeOutput.TimeToLiveDescription)
+	}
+}
+
+func TestCreateTableWithStream(t *testing.T) {
+	ctx := context.Background()
+	svc := NewDdbService()
+	input := &dynamodb.CreateTableInput{
+		TableName:   aws.String("movie"),
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("year"), AttributeType: types.ScalarAttributeTypeN},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("year"), KeyType: types.KeyTypeHash},
+		},
+		StreamSpecification: &types.StreamSpecification{
+			StreamEnabled: aws.Bool(true),
+		},
+	}
+	_, err := svc.CreateTable(ctx, input)
+	var validationException *ValidationException
+	if !errors.As(err, &validationException) {
+		t.Fatalf("Expected ValidationException without StreamViewType, got %v", err)
+	}
+
+	input.StreamSpecification.StreamViewType = types.StreamViewTypeNewImage
+	output, err := svc.CreateTable(ctx, input)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	streamArn := output.TableDescription.LatestStreamArn
+	if streamArn == nil || output.TableDescription.StreamSpecification == nil {
+		t.Fatalf("Expected the table to have a stream")
+	}
+
+	_, err = svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String("movie"),
+		Item: map[string]types.AttributeValue{
+			"year": &types.AttributeValueMemberN{Value: "1984"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	describeOutput, err := svc.DescribeStream(ctx, &DescribeStreamInput{StreamArn: streamArn})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	iteratorOutput, err := svc.GetShardIterator(ctx, &GetShardIteratorInput{
+		StreamArn:         streamArn,
+		ShardId:           describeOutput.StreamDescription.Shards[0].ShardId,
+		ShardIteratorType: SHARD_ITERATOR_TYPE_TRIM_HORIZON,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	recordsOutput, err := svc.GetRecords(ctx, &GetRecordsInput{ShardIterator: iteratorOutput.ShardIterator})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(recordsOutput.Records) != 1 || recordsOutput.Records[0].EventName != "INSERT" {
+		t.Fatalf("Expected 1 INSERT record, got %+v", recordsOutput.Records)
+	}
+
+	if _, err := svc.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: aws.String("movie")}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// the stream goes away with its table
+	_, err = svc.DescribeStream(ctx, &DescribeStreamInput{StreamArn: streamArn})
+	var notFound *types.ResourceNotFoundException
+	if !errors.As(err, &notFound) {
+		t.Fatalf("Expected ResourceNotFoundException, got %v", err)
+	}
+}
+
+func TestStreamChangeRollsBackWhenSchemaCannotBePersisted(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewDdbServiceWithConfig(Config{DbPath: filepath.Join(t.TempDir(), "baddb.db"), FileBacked: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer svc.Close()
+	createMovieTable(t, svc, "movie")
+
+	// a table unknown to the storage makes saving the schema fail
+	svc.tableMetadataStore["ghost"] = &core.TableMetaData{Name: "ghost"}
+	_, err = svc.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+		TableName: aws.String("movie"),
+		StreamSpecification: &types.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: types.StreamViewTypeNewImage,
+		},
+	})
+	if err == nil {
+		t.Fatalf("Expected UpdateTable to fail")
+	}
+	createInput := &dynamodb.CreateTableInput{
+		TableName:   aws.String("book"),
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("isbn"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("isbn"), KeyType: types.KeyTypeHash},
+		},
+		StreamSpecification: &types.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: types.StreamViewTypeNewImage,
+		},
+	}
+	_, err = svc.CreateTable(ctx, createInput)
+	if err == nil {
+		t.Fatalf("Expected CreateTable to fail")
+	}
+	delete(svc.tableMetadataStore, "ghost")
+
+	describeOutput, err := svc.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String("movie")})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if describeOutput.Table.StreamSpecification != nil || describeOutput.Table.LatestStreamArn != nil {
+		t.Fatalf("Expected movie to have no stream, got %v", describeOutput.Table.StreamSpecification)
+	}
+
+	_, err = svc.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String("book")})
+	var notFound *types.ResourceNotFoundException
+	if !errors.As(err, &notFound) {
+		t.Fatalf("Expected ResourceNotFoundException, got %v", err)
+	}
+	if _, err := svc.CreateTable(ctx, createInput); err != nil {
+		t.Fatalf("Expected book to be created again, got %v", err)
+	}
+}
+
+func TestItemCollectionMetrics(t *testing.T) {
+	ctx := context.Background()
+	svc := NewDdbService()
+	defer svc.Close()
+	createMovieTable(t, svc, "movie")
+	_, err := svc.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:   aws.String("scores"),
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("player"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("game"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("score"), AttributeType: types.ScalarAttributeTypeN},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("player"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("game"), KeyType: types.KeyTypeRange},
+		},
+		LocalSecondaryIndexes: []types.LocalSecondaryIndex{{
+			IndexName: aws.String("playerScoreLSI"),
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("player"), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("score"), KeyType: types.KeyTypeRange},
+			},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// a table without LSIs has no item collections
+	putOutput, err := svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String("movie"),
+		Item: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World"},
+		},
+		ReturnItemCollectionMetrics: types.ReturnItemCollectionMetricsSize,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if putOutput.ItemCollectionMetrics != nil {
+		t.Fatalf("Expected no ItemCollectionMetrics for a table without LSIs, got %v", putOutput.ItemCollectionMetrics)
+	}
+
+	playerKey := &types.AttributeValueMemberS{Value: "alice"}
+	previousSize := 0
+	previousRange := []float64{0, 0}
+	for i := 0; i < 3; i++ {
+		putOutput, err := svc.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String("scores"),
+			Item: map[string]types.AttributeValue{
+				"player":  playerKey,
+				"game":    &types.AttributeValueMemberS{Value: fmt.Sprintf("game %d", i)},
+				"score":   &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", i)},
+				"history": &types.AttributeValueMemberS{Value: strings.Repeat("a", 100*1024)},
+			},
+			ReturnItemCollectionMetrics: types.ReturnItemCollectionMetricsSize,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		metrics := putOutput.ItemCollectionMetrics
+		if metrics == nil {
+			t.Fatalf("Expected ItemCollectionMetrics")
+		}
+		if key, ok := metrics.ItemCollectionKey["player"].(*types.AttributeValueMemberS); !ok || key.Value != "alice" || len(metrics.ItemCollectionKey) != 1 {
+			t.Fatalf("Expected ItemCollectionKey {player: alice}, got %v", metrics.ItemCollectionKey)
+		}
+		sizeRange := metrics.SizeEstimateRangeGB
+		if len(sizeRange) != 2 || sizeRange[0] > sizeRange[1] || sizeRange[0] < previousRange[0] || sizeRange[1] < previousRange[1] {
+			t.Fatalf("Expected a range not smaller than %v, got %v", previousRange, sizeRange)
+		}
+		previousRange = sizeRange
+
+		size, err := svc.storage.ItemCollectionSizeBytes("scores", core.AttributeValue{S: aws.String("alice")})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if size <= previousSize {
+			t.Fatalf("Expected the item collection to grow from %d bytes, got %d", previousSize, size)
+		}
+		previousSize = size
+	}
+
+	putOutput, err = svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String("scores"),
+		Item: map[string]types.AttributeValue{
+			"player": playerKey,
+			"game":   &types.AttributeValueMemberS{Value: "game 3"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if putOutput.ItemCollectionMetrics != nil {
+		t.Fatalf("Expected no ItemCollectionMetrics when they aren't asked for, got %v", putOutput.ItemCollectionMetrics)
+	}
+
+	deleteOutput, err := svc.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String("scores"),
+		Key: map[string]types.AttributeValue{
+			"player": playerKey,
+			"game":   &types.AttributeValueMemberS{Value: "game 0"},
+		},
+		ReturnItemCollectionMetrics: types.ReturnItemCollectionMetricsSize,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if deleteOutput.ItemCollectionMetrics == nil || len(deleteOutput.ItemCollectionMetrics.SizeEstimateRangeGB) != 2 {
+		t.Fatalf("Expected ItemCollectionMetrics, got %v", deleteOutput.ItemCollectionMetrics)
+	}
+
+	for _, tt := range []struct {
+		sizeBytes int
+		expected  []float64
+	}{
+		{0, []float64{0, 1}},
+		{SIZE_ESTIMATE_RANGE_BYTES - 1, []float64{0, 1}},
+		{SIZE_ESTIMATE_RANGE_BYTES, []float64{1, 2}},
+		{10*SIZE_ESTIMATE_RANGE_BYTES + 1, []float64{10, 11}},
+	} {
+		if sizeRange := sizeEstimateRangeGB(tt.sizeBytes); sizeRange[0] != tt.expected[0] || sizeRange[1] != tt.expected[1] {
+			t.Fatalf("Expected %v for %d bytes, got %v", tt.expected, tt.sizeBytes, sizeRange)
+		}
+	}
+}
+
+func TestSecondaryIndexNamesAreUnique(t *testing.T) {
+	ctx := context.Background()
+	svc := NewDdbService()
+	defer svc.Close()
+
+	index := func(indexName string, hashKey string) (types.GlobalSecondaryIndex, types.LocalSecondaryIndex) {
+		keySchema := []types.KeySchemaElement{
+			{AttributeName: aws.String(hashKey), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("score"), KeyType: types.KeyTypeRange},
+		}
+		projection := &types.Projection{ProjectionType: types.ProjectionTypeAll}
+		return types.GlobalSecondaryIndex{IndexName: aws.String(indexName), KeySchema: keySchema, Projection: projection},
+			types.LocalSecondaryIndex{IndexName: aws.String(indexName), KeySchema: keySchema, Projection: projection}
+	}
+	newCreateTableInput := func(gsis []types.GlobalSecondaryIndex, lsis []types.LocalSecondaryIndex) *dynamodb.CreateTableInput {
+		return &dynamodb.CreateTableInput{
+			TableName:   aws.String("scores"),
+			BillingMode: types.BillingModePayPerRequest,
+			AttributeDefinitions: []types.AttributeDefinition{
+				{AttributeName: aws.String("player"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("game"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("score"), AttributeType: types.ScalarAttributeTypeN},
+			},
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("player"), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("game"), KeyType: types.KeyTypeRange},
+			},
+			GlobalSecondaryIndexes: gsis,
+			LocalSecondaryIndexes:  lsis,
+		}
+	}
+	gsi, _ := index("idx", "game")
+	_, lsi := index("idx", "player")
+
+	tests := []struct {
+		gsis []types.GlobalSecondaryIndex
+		lsis []types.LocalSecondaryIndex
+	}{
+		{gsis: []types.GlobalSecondaryIndex{gsi}, lsis: []types.LocalSecondaryIndex{lsi}},
+		{gsis: []types.GlobalSecondaryIndex{gsi, gsi}},
+		{lsis: []types.LocalSecondaryIndex{lsi, lsi}},
+	}
+	for _, tt := range tests {
+		_, err := svc.CreateTable(ctx, newCreateTableInput(tt.gsis, tt.lsis))
+		var validationErr *ValidationException
+		expected := "One or more parameter values were invalid: Duplicate index name: idx"
+		if !errors.As(err, &validationErr) || validationErr.Message != expected {
+			t.Fatalf("Expected ValidationException %q, got %v", expected, err)
+		}
+	}
+
+	// a GSI can't take the name of an LSI of the table either
+	if _, err := svc.CreateTable(ctx, newCreateTableInput(nil, []types.LocalSecondaryIndex{lsi})); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, err := svc.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+		TableName: aws.String("scores"),
+		GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{{
+			Create: &types.CreateGlobalSecondaryIndexAction{
+				IndexName:  gsi.IndexName,
+				KeySchema:  gsi.KeySchema,
+				Projection: gsi.Projection,
+			},
+		}},
+	})
+	var validationErr *ValidationException
+	if !errors.As(err, &validationErr) || validationErr.Message != "Global Secondary Index already exists" {
+		t.Fatalf("Expected ValidationException for the existing LSI name, got %v", err)
+	}
+
+	describeOutput, err := svc.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String("scores")})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(describeOutput.Table.GlobalSecondaryIndexes) != 0 || len(describeOutput.Table.LocalSecondaryIndexes) != 1 {
+		t.Fatalf("Expected only the LSI, got GSIs %v and LSIs %v", describeOutput.Table.GlobalSecondaryIndexes, describeOutput.Table.LocalSecondaryIndexes)
+	}
+}
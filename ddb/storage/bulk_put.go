@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ocowchun/baddb/ddb/core"
+)
+
+// BulkPut puts entries to tableName in a single transaction for seeding a table, GSIs are synced like Put does.
+// It skips the rate limiter and unprocessedRequests, and the entries are created at the zero time,
+// so even eventually consistent reads see them right away. No entry is put if any of them fails.
+func (s *InnerStorage) BulkPut(tableName string, entries []*core.Entry) error {
+	txn, err := s.BeginTxn()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	tableMetadata, ok := s.TableMetaDatas[tableName]
+	if !ok {
+		return fmt.Errorf("table %s not found", tableName)
+	}
+
+	for _, entry := range entries {
+		entryWrapper := &EntryWrapper{
+			Entry:     entry,
+			IsDeleted: false,
+			CreatedAt: time.Time{},
+		}
+		_, err = s.put(entryWrapper, tableMetadata, nil, txn)
+		if err != nil {
+			return err
+		}
+	}
+
+	return txn.Commit()
+}
@@ -14,26 +14,31 @@ type DeleteRequest struct {
 	Condition *condition.Condition
 }
 
-func (s *InnerStorage) Delete(req *DeleteRequest) error {
+type DeleteResponse struct {
+	// OldEntry is nil when the item didn't exist
+	OldEntry *core.Entry
+}
+
+func (s *InnerStorage) Delete(req *DeleteRequest) (*DeleteResponse, error) {
 	txn, err := s.BeginTxn()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer txn.Rollback()
 
-	err = s.DeleteWithTransaction(req, txn)
+	res, err := s.DeleteWithTransaction(req, txn)
 	if err != nil {
-		return err
+		return nil, err
 
 	}
 
-	return txn.Commit()
+	return res, txn.Commit()
 }
 
-func (s *InnerStorage) DeleteWithTransaction(req *DeleteRequest, txn *Txn) error {
+func (s *InnerStorage) DeleteWithTransaction(req *DeleteRequest, txn *Txn) (*DeleteResponse, error) {
 	tableMetadata, ok := s.TableMetaDatas[req.TableName]
 	if !ok {
-		return fmt.Errorf("table %s not found", req.TableName)
+		return nil, fmt.Errorf("table %s not found", req.TableName)
 	}
 
 	for {
@@ -42,13 +47,13 @@ func (s *InnerStorage) DeleteWithTransaction(req *DeleteRequest, txn *Txn) error
 			break
 		}
 		if tableMetadata.unprocessedRequests.CompareAndSwap(count, count-1) {
-			return ErrUnprocessed
+			return nil, ErrUnprocessed
 		}
 	}
 
 	if tableMetadata.billingMode == core.BILLING_MODE_PROVISIONED {
 		if !tableMetadata.writeRateLimiter.AllowN(time.Now(), 1) {
-			return RateLimitReachedError
+			return nil, RateLimitReachedError
 		}
 	}
 
@@ -57,5 +62,10 @@ func (s *InnerStorage) DeleteWithTransaction(req *DeleteRequest, txn *Txn) error
 		IsDeleted: true,
 		CreatedAt: time.Now(),
 	}
-	return s.put(entryWrapper, tableMetadata, req.Condition, txn.tx)
+	oldEntry, err := s.put(entryWrapper, tableMetadata, req.Condition, txn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeleteResponse{OldEntry: oldEntry}, nil
 }
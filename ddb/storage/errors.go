@@ -1,15 +1,26 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/ocowchun/baddb/ddb/core"
+)
 
 // Shared errors across operations
 var (
 	RateLimitReachedError = errors.New("rate limit reached")
 	ErrUnprocessed        = errors.New("unprocessed entry")
+	ErrStreamNotFound     = errors.New("stream not found")
+	// ErrItemSizeExceeded is returned when an update results in an item larger than core.MAX_ITEM_SIZE_BYTES
+	ErrItemSizeExceeded = errors.New("item size to update has exceeded the maximum allowed size")
+	// ErrPartitionLimitExceeded is returned when a write makes a partition exceed the limit set by UpdatePartitionLimit
+	ErrPartitionLimitExceeded = errors.New("partition limit exceeded")
 )
 
 type ConditionalCheckFailedException struct {
 	Message string
+	// Item is the current item the condition was evaluated against, nil when the item does not exist
+	Item *core.Entry
 }
 
 func (e *ConditionalCheckFailedException) Error() string {
@@ -24,6 +24,10 @@ func (s *InnerStorage) Get(req *GetRequest) (*core.Entry, error) {
 	if err != nil {
 		return nil, err
 	}
+	// expired items are deleted lazily, writes still see them but reads don't
+	if entry != nil && s.TableMetaDatas[req.TableName].isExpired(entry) {
+		entry = nil
+	}
 
 	return entry, txn.Commit()
 }
@@ -67,18 +71,25 @@ func (s *InnerStorage) GetWithTransaction(req *GetRequest, txn *Txn) (*core.Entr
 		return nil, nil
 	}
 
-	readTs, err := s.readTs(req.TableName, false)
+	readTs, err := s.readTs(req.TableName, nil)
 	if err != nil {
 		return nil, err
 	}
 	return tuple.getEntry(req.ConsistentRead, readTs, false), nil
 }
 
-func (s *InnerStorage) readTs(tableName string, isGsi bool) (time.Time, error) {
+// readTs is how far back an eventually consistent read of the table, or of gsiName when it isn't nil, sees writes
+func (s *InnerStorage) readTs(tableName string, gsiName *string) (time.Time, error) {
 	m := s.TableMetaDatas[tableName]
 
-	if isGsi {
-		return time.Now().Add(time.Second * time.Duration(m.gsiDelaySeconds*-1)), nil
+	if gsiName != nil && s.GsiStronglyConsistent {
+		return time.Now(), nil
+	} else if gsiName != nil {
+		delaySeconds := m.gsiDelaySeconds
+		if gsi := m.GlobalSecondaryIndexSettings[*gsiName]; gsi.delaySeconds != nil {
+			delaySeconds = *gsi.delaySeconds
+		}
+		return time.Now().Add(time.Second * time.Duration(delaySeconds*-1)), nil
 	} else {
 		return time.Now().Add(time.Second * time.Duration(m.tableDelaySeconds*-1)), nil
 	}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ocowchun/baddb/ddb/core"
+)
+
+// ItemCollectionSizeBytes is the size of the item collection of partitionKey: the items of the table with the
+// partition key and their entries in the LSIs of the table
+func (s *InnerStorage) ItemCollectionSizeBytes(tableName string, partitionKey core.AttributeValue) (int, error) {
+	txn, err := s.BeginTxn()
+	if err != nil {
+		return 0, err
+	}
+	defer txn.Rollback()
+
+	sizeBytes, err := s.ItemCollectionSizeBytesWithTransaction(tableName, partitionKey, txn)
+	if err != nil {
+		return 0, err
+	}
+
+	return sizeBytes, txn.Commit()
+}
+
+// ItemCollectionSizeBytesWithTransaction is ItemCollectionSizeBytes seeing the writes of txn
+func (s *InnerStorage) ItemCollectionSizeBytesWithTransaction(tableName string, partitionKey core.AttributeValue, txn *Txn) (int, error) {
+	table, ok := s.TableMetaDatas[tableName]
+	if !ok {
+		return 0, fmt.Errorf("table %s not found", tableName)
+	}
+
+	rows, err := txn.tx.Query("select body from "+table.Name+" where partition_key = ?", partitionKey.Bytes())
+	if err != nil {
+		return 0, err
+	}
+	sizeBytes, err := sumEntrySizes(rows, table)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, index := range table.GlobalSecondaryIndexSettings {
+		if !index.isLocal {
+			continue
+		}
+		// an item without the sort key of the LSI isn't in the LSI
+		rows, err := txn.tx.Query("select body from "+index.IndexTableName+" where main_partition_key = ? and sort_key is not null", partitionKey.Bytes())
+		if err != nil {
+			return 0, err
+		}
+		indexSizeBytes, err := sumEntrySizes(rows, table)
+		if err != nil {
+			return 0, err
+		}
+		sizeBytes += indexSizeBytes
+	}
+
+	return sizeBytes, nil
+}
+
+// sumEntrySizes closes rows after summing the sizes of their current entries, deleted and expired entries are skipped
+func sumEntrySizes(rows *sql.Rows, table *InnerTableMetadata) (int, error) {
+	defer rows.Close()
+
+	sizeBytes := 0
+	for rows.Next() {
+		var body []byte
+		if err := rows.Scan(&body); err != nil {
+			return 0, err
+		}
+
+		var tuple Tuple
+		if err := json.Unmarshal(body, &tuple); err != nil {
+			return 0, err
+		}
+		current := tuple.currentEntry()
+		if current == nil || table.isExpired(current) {
+			continue
+		}
+		sizeBytes += current.Size()
+	}
+	return sizeBytes, rows.Err()
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/ocowchun/baddb/ddb/core"
+)
+
+// checkPartitionLimit returns ErrPartitionLimitExceeded when putting entry makes its partition exceed
+// maxPartitionItemCount or maxPartitionSizeBytes of table. The current item with the same primary key is replaced by entry.
+// The partition is only read when a limit is set, and its items are only sized when maxPartitionSizeBytes is set.
+func (s *InnerStorage) checkPartitionLimit(entry *core.Entry, table *InnerTableMetadata, txn *Txn) error {
+	if table.maxPartitionItemCount == 0 && table.maxPartitionSizeBytes == 0 {
+		return nil
+	}
+
+	primaryKey, err := s.buildTablePrimaryKey(entry, table)
+	if err != nil {
+		return err
+	}
+
+	rows, err := txn.tx.Query("select primary_key, body from "+table.Name+" where partition_key = ?", primaryKey.PartitionKey)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	sizeLimited := table.maxPartitionSizeBytes > 0
+	itemCount := 1
+	sizeBytes := 0
+	if sizeLimited {
+		sizeBytes = entry.Size()
+	}
+	for rows.Next() {
+		var key, body []byte
+		if err := rows.Scan(&key, &body); err != nil {
+			return err
+		}
+		if bytes.Equal(key, primaryKey.Bytes()) {
+			continue
+		}
+
+		var tuple Tuple
+		if err := json.Unmarshal(body, &tuple); err != nil {
+			return err
+		}
+		current := tuple.currentEntry()
+		if current == nil || table.isExpired(current) {
+			continue
+		}
+		itemCount++
+		if sizeLimited {
+			sizeBytes += current.Size()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if table.maxPartitionItemCount > 0 && itemCount > table.maxPartitionItemCount {
+		return ErrPartitionLimitExceeded
+	}
+	if sizeLimited && sizeBytes > table.maxPartitionSizeBytes {
+		return ErrPartitionLimitExceeded
+	}
+
+	return nil
+}
@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ocowchun/baddb/ddb/core"
+	"golang.org/x/time/rate"
+)
+
+// SCHEMA_TABLE_NAME is the sqlite table keeping table schemas, so a file-backed storage can be reopened
+const SCHEMA_TABLE_NAME = "baddb_schema"
+
+type persistedGsiSetting struct {
+	IndexTableName    string
+	PartitionKeyName  *string
+	SortKeyName       *string
+	NonKeyAttributes  []string
+	ProjectionType    core.ProjectionType
+	ReadCapacityUnits int
+	IsLocal           bool
+	DelaySeconds      *int
+}
+
+type persistedTableMetadata struct {
+	// TableMetaData keeps what the service describes, e.g. the TTL, the stream and LastUpdateToPayPerRequestDateTime
+	TableMetaData                *core.TableMetaData
+	Name                         string
+	GlobalSecondaryIndexSettings map[string]persistedGsiSetting
+	BillingMode                  core.BillingMode
+	ReadCapacityUnits            int
+	WriteCapacityUnits           int
+	TableDelaySeconds            int
+	GsiDelaySeconds              int
+}
+
+// NewFileInnerStorage opens a sqlite database at path, tables saved by SaveSchema can be restored by LoadSchema
+func NewFileInnerStorage(path string) (*InnerStorage, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newInnerStorage(db)
+	if err := s.createSchemaTable(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *InnerStorage) createSchemaTable() error {
+	_, err := s.db.Exec("create table if not exists " + SCHEMA_TABLE_NAME + "(table_name text not null primary key, body blob)")
+	return err
+}
+
+// SaveSchema replaces the persisted schemas with tables
+func (s *InnerStorage) SaveSchema(tables map[string]*core.TableMetaData) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.createSchemaTable(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("delete from " + SCHEMA_TABLE_NAME); err != nil {
+		return err
+	}
+
+	for tableName, meta := range tables {
+		if tableName == METADATA_TABLE_NAME {
+			continue
+		}
+		innerMeta, ok := s.TableMetaDatas[tableName]
+		if !ok {
+			return fmt.Errorf("table %s not found", tableName)
+		}
+
+		gsiSettings := make(map[string]persistedGsiSetting)
+		for indexName, gsi := range innerMeta.GlobalSecondaryIndexSettings {
+			persistedGsi := persistedGsiSetting{
+				IndexTableName:   gsi.IndexTableName,
+				PartitionKeyName: gsi.PartitionKeyName,
+				SortKeyName:      gsi.SortKeyName,
+				NonKeyAttributes: gsi.NonKeyAttributes,
+				ProjectionType:   gsi.ProjectionType,
+				IsLocal:          gsi.isLocal,
+				DelaySeconds:     gsi.delaySeconds,
+			}
+			if !gsi.isLocal {
+				persistedGsi.ReadCapacityUnits = gsi.readRateLimiter.Burst()
+			}
+			gsiSettings[indexName] = persistedGsi
+		}
+		body, err := json.Marshal(&persistedTableMetadata{
+			TableMetaData:                meta,
+			Name:                         innerMeta.Name,
+			GlobalSecondaryIndexSettings: gsiSettings,
+			BillingMode:                  innerMeta.billingMode,
+			ReadCapacityUnits:            innerMeta.readCapacityUnits,
+			WriteCapacityUnits:           innerMeta.writeCapacityUnits,
+			TableDelaySeconds:            innerMeta.tableDelaySeconds,
+			GsiDelaySeconds:              innerMeta.gsiDelaySeconds,
+		})
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("insert into "+SCHEMA_TABLE_NAME+"(table_name, body) values(?, ?)", tableName, body); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LoadSchema restores the tables saved by SaveSchema and returns their metadata
+func (s *InnerStorage) LoadSchema() (map[string]*core.TableMetaData, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rows, err := s.db.Query("select table_name, body from " + SCHEMA_TABLE_NAME)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make(map[string]*core.TableMetaData)
+	maxCounter := s.counter.Load()
+	for rows.Next() {
+		var tableName string
+		var body []byte
+		if err := rows.Scan(&tableName, &body); err != nil {
+			return nil, err
+		}
+
+		var persisted persistedTableMetadata
+		if err := json.Unmarshal(body, &persisted); err != nil {
+			return nil, err
+		}
+
+		gsiSettings := make(map[string]InnerTableGlobalSecondaryIndexSetting)
+		for indexName, gsi := range persisted.GlobalSecondaryIndexSettings {
+			gsiSetting := InnerTableGlobalSecondaryIndexSetting{
+				IndexTableName:   gsi.IndexTableName,
+				PartitionKeyName: gsi.PartitionKeyName,
+				SortKeyName:      gsi.SortKeyName,
+				NonKeyAttributes: gsi.NonKeyAttributes,
+				ProjectionType:   gsi.ProjectionType,
+				isLocal:          gsi.IsLocal,
+				delaySeconds:     gsi.DelaySeconds,
+			}
+			if !gsi.IsLocal {
+				gsiSetting.readRateLimiter = rate.NewLimiter(rate.Limit(gsi.ReadCapacityUnits), gsi.ReadCapacityUnits)
+			}
+			gsiSettings[indexName] = gsiSetting
+			maxCounter = max(maxCounter, tableCounter(gsi.IndexTableName))
+		}
+
+		s.TableMetaDatas[tableName] = &InnerTableMetadata{
+			Name:                         persisted.Name,
+			GlobalSecondaryIndexSettings: gsiSettings,
+			PartitionKeySchema:           persisted.TableMetaData.PartitionKeySchema,
+			SortKeySchema:                persisted.TableMetaData.SortKeySchema,
+			billingMode:                  persisted.BillingMode,
+			readCapacityUnits:            persisted.ReadCapacityUnits,
+			writeCapacityUnits:           persisted.WriteCapacityUnits,
+			readRateLimiter:              rate.NewLimiter(rate.Limit(persisted.ReadCapacityUnits), persisted.ReadCapacityUnits),
+			writeRateLimiter:             rate.NewLimiter(rate.Limit(persisted.WriteCapacityUnits), persisted.WriteCapacityUnits),
+			tableDelaySeconds:            persisted.TableDelaySeconds,
+			gsiDelaySeconds:              persisted.GsiDelaySeconds,
+		}
+		if persisted.TableMetaData.TimeToLiveEnabled {
+			timeToLiveAttributeName := persisted.TableMetaData.TimeToLiveAttributeName
+			s.TableMetaDatas[tableName].timeToLiveAttributeName = &timeToLiveAttributeName
+		}
+		// stream records are kept in memory, a restored table starts an empty stream
+		if spec := persisted.TableMetaData.StreamSpecification; spec != nil && spec.StreamEnabled != nil && *spec.StreamEnabled &&
+			persisted.TableMetaData.LatestStreamArn != nil && persisted.TableMetaData.LatestStreamLabel != nil {
+			createdAt, err := time.Parse(STREAM_LABEL_LAYOUT, *persisted.TableMetaData.LatestStreamLabel)
+			if err != nil {
+				return nil, err
+			}
+			err = s.enableStream(tableName, *persisted.TableMetaData.LatestStreamArn, spec.StreamViewType, persisted.TableMetaData.PrimaryKeySchema(), createdAt)
+			if err != nil {
+				return nil, err
+			}
+		}
+		maxCounter = max(maxCounter, tableCounter(persisted.Name))
+		tables[tableName] = persisted.TableMetaData
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// new sqlite tables must not reuse the names of the restored ones
+	s.counter.Store(maxCounter)
+
+	return tables, nil
+}
+
+// tableCounter extracts N from table_N or gsi_N
+func tableCounter(name string) int32 {
+	idx := strings.LastIndex(name, "_")
+	if idx < 0 {
+		return 0
+	}
+	n, err := strconv.Atoi(name[idx+1:])
+	if err != nil {
+		return 0
+	}
+	return int32(n)
+}
+
+// DumpTo writes a copy of the database to path, call SaveSchema first so the copy can be loaded by LoadSchema
+func (s *InnerStorage) DumpTo(path string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// VACUUM INTO refuses to overwrite a file, dump to a temporary file and replace path with it
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+	os.Remove(tmpPath)
+
+	if _, err := s.db.Exec("vacuum into ?", tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+func (s *InnerStorage) Close() error {
+	return s.db.Close()
+}
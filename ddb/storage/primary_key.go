@@ -3,6 +3,8 @@ package storage
 import (
 	"bytes"
 	"fmt"
+
+	"github.com/ocowchun/baddb/ddb/core"
 )
 
 type PrimaryKey struct {
@@ -11,17 +13,7 @@ type PrimaryKey struct {
 }
 
 func (k *PrimaryKey) Bytes() []byte {
-	bs := make([]byte, 0)
-	bs = append(bs, k.PartitionKey...)
-
-	//	// TODO: need a better way to generate primary key, current approach will failed for below case
-	//	// pk: ab, sk: |cd and pk: ab|, sk: cd
-	if len(k.SortKey) > 0 {
-		bs = append(bs, []byte("|")...)
-		bs = append(bs, k.SortKey...)
-	}
-
-	return bs
+	return core.EncodePrimaryKey(k.PartitionKey, k.SortKey)
 }
 
 func (k *PrimaryKey) String() string {
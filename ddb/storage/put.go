@@ -1,7 +1,6 @@
 package storage
 
 import (
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -16,40 +15,41 @@ type PutRequest struct {
 	Condition *condition.Condition
 }
 
-func (s *InnerStorage) Put(req *PutRequest) error {
+type PutResponse struct {
+	// OldEntry is the replaced item, nil when the item didn't exist or the put configured another table
+	OldEntry *core.Entry
+}
+
+func (s *InnerStorage) Put(req *PutRequest) (*PutResponse, error) {
 	txn, err := s.BeginTxn()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer txn.Rollback()
 
+	res, err := s.PutWithTransaction(req, txn)
+	if err != nil {
+		return nil, err
+
+	}
+
+	return res, txn.Commit()
+}
+
+func (s *InnerStorage) PutWithTransaction(req *PutRequest, txn *Txn) (*PutResponse, error) {
+	// an item put into the metadata table configures another table, it isn't kept
 	if req.TableName == METADATA_TABLE_NAME {
 		tableMetadata, err := s.extractTableMetadata(req.Entry)
 		if err != nil {
-			return err
-		}
-
-		err = s.updateTableMetadata(tableMetadata)
-		if err != nil {
-			return err
+			return nil, err
 		}
-		return txn.Commit()
 
+		return &PutResponse{}, s.updateTableMetadata(tableMetadata)
 	}
 
-	err = s.PutWithTransaction(req, txn)
-	if err != nil {
-		return err
-
-	}
-
-	return txn.Commit()
-}
-
-func (s *InnerStorage) PutWithTransaction(req *PutRequest, txn *Txn) error {
 	tableMetadata, ok := s.TableMetaDatas[req.TableName]
 	if !ok {
-		return fmt.Errorf("table %s not found", req.TableName)
+		return nil, fmt.Errorf("table %s not found", req.TableName)
 	}
 
 	for {
@@ -58,39 +58,53 @@ func (s *InnerStorage) PutWithTransaction(req *PutRequest, txn *Txn) error {
 			break
 		}
 		if tableMetadata.unprocessedRequests.CompareAndSwap(count, count-1) {
-			return ErrUnprocessed
+			return nil, ErrUnprocessed
 		}
 	}
 
 	if tableMetadata.billingMode == core.BILLING_MODE_PROVISIONED {
 		if !tableMetadata.writeRateLimiter.AllowN(time.Now(), 1) {
-			return RateLimitReachedError
+			return nil, RateLimitReachedError
 		}
 	}
 
+	if err := s.checkPartitionLimit(req.Entry, tableMetadata, txn); err != nil {
+		return nil, err
+	}
+
 	entryWrapper := &EntryWrapper{
 		Entry:     req.Entry,
 		IsDeleted: false,
 		CreatedAt: time.Now(),
 	}
 
-	err := s.put(entryWrapper, tableMetadata, req.Condition, txn.tx)
+	oldEntry, err := s.put(entryWrapper, tableMetadata, req.Condition, txn)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return &PutResponse{OldEntry: oldEntry}, nil
 }
 
-func (s *InnerStorage) put(entry *EntryWrapper, table *InnerTableMetadata, condition *condition.Condition, txn *sql.Tx) error {
+func (s *InnerStorage) put(entry *EntryWrapper, table *InnerTableMetadata, condition *condition.Condition, storageTxn *Txn) (*core.Entry, error) {
+	txn := storageTxn.tx
 	primaryKey, err := s.buildTablePrimaryKey(entry.Entry, table)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	tuple, err := s.getTuple(primaryKey.Bytes(), table.Name, txn)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	var oldEntry *core.Entry
+	if tuple != nil {
+		oldEntry = tuple.currentEntry()
+	}
+	newEntry := entry.Entry
+	if entry.IsDeleted {
+		newEntry = nil
 	}
 
 	if tuple == nil {
@@ -98,10 +112,10 @@ func (s *InnerStorage) put(entry *EntryWrapper, table *InnerTableMetadata, condi
 			matched, err := condition.Check(&core.Entry{Body: make(map[string]core.AttributeValue)})
 
 			// improve error handling
-			if err != nil || !matched {
-				return err
+			if err != nil {
+				return nil, err
 			} else if !matched {
-				return &ConditionalCheckFailedException{"The conditional request failed"}
+				return nil, &ConditionalCheckFailedException{Message: "The conditional request failed"}
 			}
 		}
 
@@ -113,31 +127,32 @@ func (s *InnerStorage) put(entry *EntryWrapper, table *InnerTableMetadata, condi
 		tuple.addEntry(entry)
 		body, err := json.Marshal(&tuple)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		_, err = stmt.Exec(primaryKey.Bytes(), body, primaryKey.PartitionKey, primaryKey.SortKey, buildShardId(primaryKey.PartitionKey))
+		_, err = stmt.Exec(primaryKey.Bytes(), body, primaryKey.PartitionKey, primaryKey.SortKey, s.ShardIdBuilder(primaryKey.PartitionKey))
 		if err != nil {
-			return err
+			return nil, err
 		}
 		defer stmt.Close()
 
 		err = s.syncGlobalSecondaryIndices(primaryKey, entry, txn, table)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	} else {
 		if condition != nil {
 			currentEntry := tuple.currentEntry()
-			if currentEntry == nil {
-				currentEntry = &core.Entry{Body: make(map[string]core.AttributeValue)}
+			checkedEntry := currentEntry
+			if checkedEntry == nil {
+				checkedEntry = &core.Entry{Body: make(map[string]core.AttributeValue)}
 			}
-			matched, err := condition.Check(currentEntry)
+			matched, err := condition.Check(checkedEntry)
 			// improve error handling
 			if err != nil {
-				return err
+				return nil, err
 			} else if !matched {
-				return &ConditionalCheckFailedException{"The conditional request failed"}
+				return nil, &ConditionalCheckFailedException{Message: "The conditional request failed", Item: currentEntry}
 			}
 		}
 
@@ -146,19 +161,20 @@ func (s *InnerStorage) put(entry *EntryWrapper, table *InnerTableMetadata, condi
 		tuple.addEntry(entry)
 		body, err := json.Marshal(&tuple)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		_, err = stmt.Exec(body, primaryKey.Bytes())
 		if err != nil {
-			return err
+			return nil, err
 		}
 		defer stmt.Close()
 
 		err = s.syncGlobalSecondaryIndices(primaryKey, entry, txn, table)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
+	storageTxn.recordStreamEvent(table, oldEntry, newEntry)
 
-	return nil
+	return oldEntry, nil
 }
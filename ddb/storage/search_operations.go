@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -14,12 +15,16 @@ import (
 type QueryResponse struct {
 	Entries      []*core.Entry
 	ScannedCount int32
+	// ScannedSize is the total size of the scanned items, the read capacity is consumed by it
+	ScannedSize int
 }
 
 type searchTableInfo struct {
 	tableName   string
 	rateLimiter interface{ AllowN(time.Time, int) bool }
 	isGsi       bool
+	// gsiName is nil unless isGsi
+	gsiName *string
 }
 
 func (s *InnerStorage) resolveTableForSearch(tableMetadata *InnerTableMetadata, indexName *string) (*searchTableInfo, error) {
@@ -35,22 +40,30 @@ func (s *InnerStorage) resolveTableForSearch(tableMetadata *InnerTableMetadata,
 			return nil, fmt.Errorf("index %s not found", *indexName)
 		}
 		info.tableName = gsi.IndexTableName
-		info.rateLimiter = gsi.readRateLimiter
-		info.isGsi = true
+		if !gsi.isLocal {
+			info.rateLimiter = gsi.readRateLimiter
+			info.isGsi = true
+			info.gsiName = indexName
+		}
 	}
 
 	return info, nil
 }
 
-// Common row processing for both Query and Scan
-func (s *InnerStorage) processRowsForSearch(rows *sql.Rows, tableMetadata *InnerTableMetadata, tableInfo *searchTableInfo, readTs time.Time, consistentRead bool, limit int, filterFunc func(*core.Entry) (bool, error)) ([]*core.Entry, int32, error) {
+// Common row processing for both Query and Scan, it stops with ctx.Err() once ctx is done
+func (s *InnerStorage) processRowsForSearch(ctx context.Context, rows *sql.Rows, tableMetadata *InnerTableMetadata, tableInfo *searchTableInfo, readTs time.Time, consistentRead bool, limit int, filterFunc func(*core.Entry) (bool, error)) ([]*core.Entry, int32, int, error) {
 	var entries []*core.Entry
 	scannedCount := 0
+	scannedSize := 0
 
 	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, 0, err
+		}
+
 		var body []byte
 		if err := rows.Scan(&body); err != nil {
-			return nil, 0, err
+			return nil, 0, 0, err
 		}
 
 		// Rate limiting check
@@ -60,7 +73,7 @@ func (s *InnerStorage) processRowsForSearch(rows *sql.Rows, tableMetadata *Inner
 				n = 2
 			}
 			if !tableInfo.rateLimiter.AllowN(time.Now(), n) {
-				return nil, 0, RateLimitReachedError
+				return nil, 0, 0, RateLimitReachedError
 			}
 		}
 
@@ -68,17 +81,22 @@ func (s *InnerStorage) processRowsForSearch(rows *sql.Rows, tableMetadata *Inner
 		var tuple Tuple
 		scannedCount += 1
 		if err := json.Unmarshal(body, &tuple); err != nil {
-			return nil, 0, err
+			return nil, 0, 0, err
 		}
 
 		entry := tuple.getEntry(consistentRead, readTs, tableInfo.isGsi)
+		// expired items are scanned but never returned
+		if entry != nil && tableMetadata.isExpired(entry) {
+			entry = nil
+		}
 
 		if entry != nil {
+			scannedSize += entry.Size()
 			// Apply custom filtering logic
 			if filterFunc != nil {
 				shouldInclude, err := filterFunc(entry)
 				if err != nil {
-					return nil, 0, err
+					return nil, 0, 0, err
 				}
 				if !shouldInclude {
 					continue
@@ -91,11 +109,14 @@ func (s *InnerStorage) processRowsForSearch(rows *sql.Rows, tableMetadata *Inner
 			break
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, 0, err
+	}
 
-	return entries, int32(scannedCount), nil
+	return entries, int32(scannedCount), scannedSize, nil
 }
 
-func (s *InnerStorage) Query(req *query.Query) (*QueryResponse, error) {
+func (s *InnerStorage) Query(ctx context.Context, req *query.Query) (*QueryResponse, error) {
 	txn, err := s.BeginTxn()
 	if err != nil {
 		return nil, err
@@ -118,7 +139,15 @@ func (s *InnerStorage) Query(req *query.Query) (*QueryResponse, error) {
 	queryStmt := "SELECT body FROM " + tableInfo.tableName + " WHERE partition_key = ?"
 	args := []interface{}{req.PartitionKey}
 
-	if req.ExclusiveStartKey != nil {
+	// rows are ordered by sort_key then primary_key, the sort keys of a GSI may repeat in a partition
+	if req.ExclusiveStartKey != nil && req.ExclusiveStartSortKey != nil {
+		if req.ScanIndexForward {
+			queryStmt += " AND (sort_key > ? OR (sort_key = ? AND primary_key > ?))"
+		} else {
+			queryStmt += " AND (sort_key < ? OR (sort_key = ? AND primary_key < ?))"
+		}
+		args = append(args, *req.ExclusiveStartSortKey, *req.ExclusiveStartSortKey, *req.ExclusiveStartKey)
+	} else if req.ExclusiveStartKey != nil {
 		if req.ScanIndexForward {
 			queryStmt += " AND primary_key > ?"
 		} else {
@@ -136,12 +165,12 @@ func (s *InnerStorage) Query(req *query.Query) (*QueryResponse, error) {
 		queryStmt += ", primary_key DESC "
 	}
 
-	readTs, err := s.readTs(req.TableName, tableInfo.isGsi)
+	readTs, err := s.readTs(req.TableName, tableInfo.gsiName)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := txn.tx.Query(queryStmt, args...)
+	rows, err := txn.tx.QueryContext(ctx, queryStmt, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -170,13 +199,14 @@ func (s *InnerStorage) Query(req *query.Query) (*QueryResponse, error) {
 		return true, nil
 	}
 
-	entries, scannedCount, err := s.processRowsForSearch(rows, tableMetadata, tableInfo, readTs, req.ConsistentRead, req.Limit, queryFilter)
+	entries, scannedCount, scannedSize, err := s.processRowsForSearch(ctx, rows, tableMetadata, tableInfo, readTs, req.ConsistentRead, req.Limit, queryFilter)
 	if err != nil {
 		return nil, err
 	}
 
 	res.Entries = entries
 	res.ScannedCount = scannedCount
+	res.ScannedSize = scannedSize
 
 	return res, txn.Commit()
 }
@@ -227,9 +257,11 @@ func (s *InnerStorage) QueryItemCount(tableName string) (int64, error) {
 type ScanResponse struct {
 	Entries      []*core.Entry
 	ScannedCount int32
+	// ScannedSize is the total size of the scanned items, the read capacity is consumed by it
+	ScannedSize int
 }
 
-func (s *InnerStorage) Scan(req *scan.Request) (*ScanResponse, error) {
+func (s *InnerStorage) Scan(ctx context.Context, req *scan.Request) (*ScanResponse, error) {
 	txn, err := s.BeginTxn()
 	if err != nil {
 		return nil, err
@@ -262,12 +294,12 @@ func (s *InnerStorage) Scan(req *scan.Request) (*ScanResponse, error) {
 
 	queryStmt += " ORDER BY primary_key"
 
-	readTs, err := s.readTs(req.TableName, tableInfo.isGsi)
+	readTs, err := s.readTs(req.TableName, tableInfo.gsiName)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := txn.tx.Query(queryStmt, args...)
+	rows, err := txn.tx.QueryContext(ctx, queryStmt, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -287,12 +319,13 @@ func (s *InnerStorage) Scan(req *scan.Request) (*ScanResponse, error) {
 		return true, nil
 	}
 
-	entries, scannedCount, err := s.processRowsForSearch(rows, tableMetadata, tableInfo, readTs, req.ConsistentRead, req.Limit, scanFilter)
+	entries, scannedCount, scannedSize, err := s.processRowsForSearch(ctx, rows, tableMetadata, tableInfo, readTs, req.ConsistentRead, req.Limit, scanFilter)
 	if err != nil {
 		return nil, err
 	}
 
 	res.Entries = entries
 	res.ScannedCount = scannedCount
+	res.ScannedSize = scannedSize
 	return res, txn.Commit()
 }
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"bytes"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -10,6 +11,7 @@ import (
 	"golang.org/x/time/rate"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type InnerStorage struct {
@@ -17,6 +19,13 @@ type InnerStorage struct {
 	mutex          sync.Mutex
 	TableMetaDatas map[string]*InnerTableMetadata
 	counter        atomic.Int32
+	// ShardIdBuilder decides which parallel scan segment an item belongs to, rows written before it changes keep their shard id
+	ShardIdBuilder ShardIdBuilder
+	// GsiStronglyConsistent ignores gsiDelaySeconds of every table, so GSI reads reflect base table writes immediately
+	GsiStronglyConsistent bool
+	// streams are keyed by stream ARN, a table keeps its disabled streams until it is deleted
+	streams              map[string]*Stream
+	streamSequenceNumber int64
 }
 
 type InnerTableGlobalSecondaryIndexSetting struct {
@@ -25,7 +34,12 @@ type InnerTableGlobalSecondaryIndexSetting struct {
 	SortKeyName      *string
 	NonKeyAttributes []string
 	ProjectionType   core.ProjectionType
-	readRateLimiter  *rate.Limiter
+	// readRateLimiter is nil for an LSI, reads on an LSI consume the read capacity of the table
+	readRateLimiter *rate.Limiter
+	// isLocal is true for an LSI, it's read with the consistency and the delay of the table
+	isLocal bool
+	// delaySeconds is nil when the GSI is read with gsiDelaySeconds of the table
+	delaySeconds *int
 }
 
 type InnerTableMetadata struct {
@@ -42,6 +56,17 @@ type InnerTableMetadata struct {
 	tableDelaySeconds            int
 	gsiDelaySeconds              int
 	unprocessedRequests          atomic.Uint32
+	// timeToLiveAttributeName is nil when TTL is disabled
+	timeToLiveAttributeName *string
+	// stream is nil when the table has no enabled stream
+	stream *Stream
+	// maxPartitionItemCount and maxPartitionSizeBytes simulate hot partitions, a limit of 0 is disabled
+	maxPartitionItemCount int
+	maxPartitionSizeBytes int
+}
+
+func (m *InnerTableMetadata) isExpired(entry *core.Entry) bool {
+	return m.timeToLiveAttributeName != nil && entry.IsExpired(*m.timeToLiveAttributeName, time.Now())
 }
 
 func (m *InnerTableMetadata) Clone() *InnerTableMetadata {
@@ -55,19 +80,34 @@ func (m *InnerTableMetadata) Clone() *InnerTableMetadata {
 		tableDelaySeconds:   m.tableDelaySeconds,
 		gsiDelaySeconds:     m.gsiDelaySeconds,
 		unprocessedRequests: atomic.Uint32{},
+		stream:              m.stream,
+
+		maxPartitionItemCount: m.maxPartitionItemCount,
+		maxPartitionSizeBytes: m.maxPartitionSizeBytes,
+	}
+
+	if m.timeToLiveAttributeName != nil {
+		timeToLiveAttributeName := *m.timeToLiveAttributeName
+		clone.timeToLiveAttributeName = &timeToLiveAttributeName
 	}
 
 	// Copy the unprocessed requests value
 	clone.unprocessedRequests.Store(m.unprocessedRequests.Load())
 
-	// Deep copy GlobalSecondaryIndexSettings
+	// Deep copy GlobalSecondaryIndexSettings, the map is never nil so UpdateTable can add the first GSI of a table
+	clone.GlobalSecondaryIndexSettings = make(map[string]InnerTableGlobalSecondaryIndexSetting)
 	if len(m.GlobalSecondaryIndexSettings) > 0 {
-		clone.GlobalSecondaryIndexSettings = make(map[string]InnerTableGlobalSecondaryIndexSetting)
 		for name, gsi := range m.GlobalSecondaryIndexSettings {
 			clonedGSI := InnerTableGlobalSecondaryIndexSetting{
 				IndexTableName:  gsi.IndexTableName,
 				ProjectionType:  gsi.ProjectionType,
 				readRateLimiter: gsi.readRateLimiter,
+				isLocal:         gsi.isLocal,
+			}
+
+			if gsi.delaySeconds != nil {
+				delaySeconds := *gsi.delaySeconds
+				clonedGSI.delaySeconds = &delaySeconds
 			}
 
 			if gsi.PartitionKeyName != nil {
@@ -114,13 +154,17 @@ func NewInnerStorage() *InnerStorage {
 		panic(err)
 	}
 
-	storage := &InnerStorage{
+	return newInnerStorage(db)
+}
+
+func newInnerStorage(db *sql.DB) *InnerStorage {
+	return &InnerStorage{
 		db:             db,
 		TableMetaDatas: make(map[string]*InnerTableMetadata),
 		counter:        atomic.Int32{},
+		ShardIdBuilder: buildShardId,
+		streams:        make(map[string]*Stream),
 	}
-
-	return storage
 }
 
 func (s *InnerStorage) newTableName() string {
@@ -162,7 +206,12 @@ func (s *InnerStorage) CreateTable(meta *core.TableMetaData) error {
 		create index idx_` + gsiTableName + `_partition_key_sort_key on ` + gsiTableName + `(partition_key, sort_key);
 		`
 
-		readLimiter := rate.NewLimiter(rate.Limit(readCapacity), readCapacity)
+		// each GSI has its own read capacity, it falls back to the table's when the GSI doesn't specify one
+		gsiReadCapacity := readCapacity
+		if billingMode == core.BILLING_MODE_PROVISIONED && gsi.ProvisionedThroughput != nil {
+			gsiReadCapacity = gsi.ProvisionedThroughput.ReadCapacityUnits * 2
+		}
+		readLimiter := rate.NewLimiter(rate.Limit(gsiReadCapacity), gsiReadCapacity)
 		globalSecondarySettings[*gsi.IndexName] = InnerTableGlobalSecondaryIndexSetting{
 			IndexTableName:   gsiTableName,
 			PartitionKeyName: gsi.PartitionKeyName(),
@@ -172,6 +221,23 @@ func (s *InnerStorage) CreateTable(meta *core.TableMetaData) error {
 			readRateLimiter:  readLimiter,
 		}
 	}
+	for _, lsi := range meta.LocalSecondaryIndexSettings {
+		lsiTableName := s.newGsiTableName()
+		sqlStmt += `
+		create table ` + lsiTableName + ` (primary_key blob not null primary key, body blob, main_partition_key blob, main_sort_key blob, partition_key blob, sort_key blob, shard_id integer);
+		delete from ` + lsiTableName + `;
+		create index idx_` + lsiTableName + `_partition_key_sort_key on ` + lsiTableName + `(partition_key, sort_key);
+		`
+
+		globalSecondarySettings[*lsi.IndexName] = InnerTableGlobalSecondaryIndexSetting{
+			IndexTableName:   lsiTableName,
+			PartitionKeyName: lsi.PartitionKeyName(),
+			SortKeyName:      lsi.SortKeyName(),
+			NonKeyAttributes: lsi.NonKeyAttributes,
+			ProjectionType:   lsi.ProjectionType,
+			isLocal:          true,
+		}
+	}
 
 	_, err := s.db.Exec(sqlStmt)
 	if err != nil {
@@ -197,15 +263,23 @@ func (s *InnerStorage) CreateTable(meta *core.TableMetaData) error {
 }
 
 type Txn struct {
-	tx       *sql.Tx
-	s        *InnerStorage
-	isLocked atomic.Bool
+	tx            *sql.Tx
+	s             *InnerStorage
+	isLocked      atomic.Bool
+	streamRecords []pendingStreamRecord
 }
 
 func (txn *Txn) Commit() error {
 	defer txn.unlock()
 
-	return txn.tx.Commit()
+	if err := txn.tx.Commit(); err != nil {
+		return err
+	}
+	// records are only visible once the writes are
+	txn.s.appendStreamRecords(txn.streamRecords)
+	txn.streamRecords = nil
+
+	return nil
 }
 func (txn *Txn) unlock() {
 	for txn.isLocked.Load() {
@@ -256,7 +330,7 @@ func (s *InnerStorage) buildTablePrimaryKey(entry *core.Entry, table *InnerTable
 		if !ok {
 			return primaryKey, errors.New("sortKey not found")
 		}
-		primaryKey.SortKey = sk.Bytes()
+		primaryKey.SortKey = core.EncodeSortKey(sk)
 	}
 
 	return primaryKey, nil
@@ -311,12 +385,17 @@ func (s *InnerStorage) syncSingleGSI(primaryKey *PrimaryKey, entry *EntryWrapper
 	var gsiSortKey []byte
 	if gsi.SortKeyName != nil {
 		if _, ok := entry.Entry.Body[*gsi.SortKeyName]; ok {
-			gsiSortKey = entry.Entry.Body[*gsi.SortKeyName].Bytes()
+			gsiSortKey = core.EncodeSortKey(entry.Entry.Body[*gsi.SortKeyName])
 		}
 	}
 
 	gsiEntry := s.newGsiEntry(entry, gsi, table)
 
+	if tuple != nil && isSameGsiEntry(tuple, gsiEntry) {
+		// the projected attributes are unchanged, keep the existing version instead of rewriting it
+		return nil
+	}
+
 	if tuple == nil {
 		stmt, err := txn.Prepare("insert into " + tableName + "(primary_key, body, main_partition_key, main_sort_key, partition_key, sort_key, shard_id) values(?, ?, ?, ?, ?, ?, ?)")
 		if err != nil {
@@ -333,7 +412,7 @@ func (s *InnerStorage) syncSingleGSI(primaryKey *PrimaryKey, entry *EntryWrapper
 			return err
 		}
 
-		_, err = stmt.Exec(primaryKey.Bytes(), body, primaryKey.PartitionKey, primaryKey.SortKey, gsiPartitionKey, gsiSortKey, buildShardId(gsiPartitionKey))
+		_, err = stmt.Exec(primaryKey.Bytes(), body, primaryKey.PartitionKey, primaryKey.SortKey, gsiPartitionKey, gsiSortKey, s.ShardIdBuilder(gsiPartitionKey))
 		if err != nil {
 			return err
 		}
@@ -349,7 +428,7 @@ func (s *InnerStorage) syncSingleGSI(primaryKey *PrimaryKey, entry *EntryWrapper
 		if err != nil {
 			return err
 		}
-		_, err = stmt.Exec(body, gsiPartitionKey, gsiSortKey, buildShardId(gsiPartitionKey), primaryKey.Bytes())
+		_, err = stmt.Exec(body, gsiPartitionKey, gsiSortKey, s.ShardIdBuilder(gsiPartitionKey), primaryKey.Bytes())
 		if err != nil {
 			return err
 		}
@@ -358,6 +437,26 @@ func (s *InnerStorage) syncSingleGSI(primaryKey *PrimaryKey, entry *EntryWrapper
 	return nil
 }
 
+func isSameGsiEntry(tuple *Tuple, gsiEntry *EntryWrapper) bool {
+	if len(tuple.Entries) == 0 {
+		return false
+	}
+	lastEntry := tuple.Entries[len(tuple.Entries)-1]
+	if lastEntry.IsDeleted || gsiEntry.IsDeleted {
+		return lastEntry.IsDeleted && gsiEntry.IsDeleted
+	}
+	// the body may contain an empty value for a missing gsi key, so compare the encoded bodies instead of using AttributeValue.Equal
+	lastBody, err := json.Marshal(lastEntry.Entry.Body)
+	if err != nil {
+		return false
+	}
+	body, err := json.Marshal(gsiEntry.Entry.Body)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(lastBody, body)
+}
+
 func (s *InnerStorage) newGsiEntry(entry *EntryWrapper, gsi InnerTableGlobalSecondaryIndexSetting, table *InnerTableMetadata) *EntryWrapper {
 	gsiEntry := &core.Entry{
 		Body: make(map[string]core.AttributeValue),
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -9,6 +10,7 @@ import (
 	"github.com/ocowchun/baddb/ddb/query"
 	"github.com/ocowchun/baddb/ddb/scan"
 	"github.com/ocowchun/baddb/ddb/update"
+	"sync"
 	"testing"
 )
 
@@ -130,7 +132,7 @@ func TestInnerStorageQueryWithGsiProjections(t *testing.T) {
 			Body: body,
 		}
 
-		err := storage.Put(&PutRequest{
+		_, err := storage.Put(&PutRequest{
 			Entry:     entry,
 			TableName: "test",
 		})
@@ -148,7 +150,7 @@ func TestInnerStorageQueryWithGsiProjections(t *testing.T) {
 				TableName:      "test",
 			}
 
-			res, err := storage.Query(q)
+			res, err := storage.Query(context.Background(), q)
 
 			if err != nil {
 				t.Fatalf("Query failed: %v", err)
@@ -201,7 +203,7 @@ func TestInnerStorageUnprocessedGet(t *testing.T) {
 	}
 	tableName := "test"
 
-	err := storage.Put(&PutRequest{
+	_, err := storage.Put(&PutRequest{
 		Entry:     entry,
 		TableName: tableName,
 	})
@@ -270,7 +272,7 @@ func TestInnerStorageUnprocessedWrite(t *testing.T) {
 		count := uint32(3)
 		updateTestTableMetadata(storage, "test", 5, 5, count)
 		for count > 0 {
-			err := storage.Put(&PutRequest{
+			_, err := storage.Put(&PutRequest{
 				Entry:     entry,
 				TableName: tableName,
 			})
@@ -282,7 +284,7 @@ func TestInnerStorageUnprocessedWrite(t *testing.T) {
 	}
 
 	{
-		err := storage.Put(&PutRequest{
+		_, err := storage.Put(&PutRequest{
 			Entry:     entry,
 			TableName: tableName,
 		})
@@ -305,7 +307,7 @@ func TestInnerStorageUnprocessedWrite(t *testing.T) {
 				TableName: tableName,
 			}
 
-			err := storage.Delete(deleteReq)
+			_, err := storage.Delete(deleteReq)
 			if err == nil || !errors.Is(err, ErrUnprocessed) {
 				t.Fatalf("expected err to be ErrUnprocessed, got %v", err)
 			}
@@ -324,7 +326,7 @@ func TestInnerStorageUnprocessedWrite(t *testing.T) {
 			TableName: tableName,
 		}
 
-		err := storage.Delete(deleteReq)
+		_, err := storage.Delete(deleteReq)
 		if err != nil {
 			t.Fatalf("Delete failed: %v", err)
 		}
@@ -333,6 +335,95 @@ func TestInnerStorageUnprocessedWrite(t *testing.T) {
 
 }
 
+func TestInnerStorageConditionalPutChecksStoredEntry(t *testing.T) {
+	storage := createTestInnerStorageWithGSI([]core.GlobalSecondaryIndexSetting{})
+	tableName := "test"
+
+	body := make(map[string]core.AttributeValue)
+	body["partitionKey"] = core.AttributeValue{S: aws.String("foo")}
+	body["sortKey"] = core.AttributeValue{S: aws.String("bar")}
+	body["version"] = core.AttributeValue{N: aws.String("1")}
+	_, err := storage.Put(&PutRequest{
+		Entry:     &core.Entry{Body: body},
+		TableName: tableName,
+	})
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	// the new entry has no version, so the condition can only be evaluated against the stored entry
+	newEntry := &core.Entry{
+		Body: map[string]core.AttributeValue{
+			"partitionKey": {S: aws.String("foo")},
+			"sortKey":      {S: aws.String("bar")},
+			"message":      {S: aws.String("hello")},
+		},
+	}
+
+	cond, err := condition.BuildCondition("version < :v", map[string]string{}, map[string]core.AttributeValue{
+		":v": {N: aws.String("1")},
+	})
+	if err != nil {
+		t.Fatalf("BuildCondition failed: %v", err)
+	}
+	_, err = storage.Put(&PutRequest{
+		Entry:     newEntry,
+		TableName: tableName,
+		Condition: cond,
+	})
+	var conditionalErr *ConditionalCheckFailedException
+	if !errors.As(err, &conditionalErr) {
+		t.Fatalf("Expected ConditionalCheckFailedException, got %v", err)
+	}
+	if *conditionalErr.Item.Body["version"].N != "1" {
+		t.Fatalf("Expected stored entry with version 1, got %v", conditionalErr.Item.Body)
+	}
+
+	cond, err = condition.BuildCondition("version < :v", map[string]string{}, map[string]core.AttributeValue{
+		":v": {N: aws.String("2")},
+	})
+	if err != nil {
+		t.Fatalf("BuildCondition failed: %v", err)
+	}
+	_, err = storage.Put(&PutRequest{
+		Entry:     newEntry,
+		TableName: tableName,
+		Condition: cond,
+	})
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	storedEntry, err := storage.Get(&GetRequest{
+		Entry:          newEntry,
+		ConsistentRead: true,
+		TableName:      tableName,
+	})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	assertEntry(storedEntry, newEntry, t)
+
+	// an entry that doesn't exist yet is checked as an empty entry
+	cond, err = condition.BuildCondition("attribute_exists(version)", map[string]string{}, map[string]core.AttributeValue{})
+	if err != nil {
+		t.Fatalf("BuildCondition failed: %v", err)
+	}
+	_, err = storage.Put(&PutRequest{
+		Entry: &core.Entry{
+			Body: map[string]core.AttributeValue{
+				"partitionKey": {S: aws.String("foo")},
+				"sortKey":      {S: aws.String("baz")},
+			},
+		},
+		TableName: tableName,
+		Condition: cond,
+	})
+	if !errors.As(err, &conditionalErr) {
+		t.Fatalf("Expected ConditionalCheckFailedException, got %v", err)
+	}
+}
+
 func TestInnerStoragePutGetAndDelete(t *testing.T) {
 	storage := createTestInnerStorageWithGSI([]core.GlobalSecondaryIndexSetting{})
 	body := make(map[string]core.AttributeValue)
@@ -348,7 +439,7 @@ func TestInnerStoragePutGetAndDelete(t *testing.T) {
 	tableName := "test"
 
 	{
-		err := storage.Put(&PutRequest{
+		_, err := storage.Put(&PutRequest{
 			Entry:     entry,
 			TableName: "test",
 		})
@@ -391,7 +482,7 @@ func TestInnerStoragePutGetAndDelete(t *testing.T) {
 	entryV2 := &core.Entry{
 		Body: bodyV2,
 	}
-	err := storage.Put(&PutRequest{
+	_, err := storage.Put(&PutRequest{
 		Entry:     entryV2,
 		TableName: "test",
 	})
@@ -437,7 +528,7 @@ func TestInnerStoragePutGetAndDelete(t *testing.T) {
 		TableName: tableName,
 	}
 
-	err = storage.Delete(deleteReq)
+	_, err = storage.Delete(deleteReq)
 	if err != nil {
 		t.Fatalf("Delete failed: %v", err)
 	}
@@ -488,7 +579,7 @@ func TestInnerStorageReadLimitReached(t *testing.T) {
 	}
 	tableName := "test"
 
-	err := storage.Put(&PutRequest{
+	_, err := storage.Put(&PutRequest{
 		Entry:     entry,
 		TableName: "test",
 	})
@@ -537,7 +628,7 @@ func TestInnerStorageWriteLimitReached(t *testing.T) {
 
 	var err error
 	for i := 0; i < 10; i++ {
-		err = storage.Put(&PutRequest{
+		_, err = storage.Put(&PutRequest{
 			Entry:     entry,
 			TableName: "test",
 		})
@@ -581,6 +672,74 @@ func assertEntry(actual *core.Entry, expected *core.Entry, t *testing.T) {
 	}
 }
 
+func TestInnerStorageCollidingPrimaryKeys(t *testing.T) {
+	storage := createTestInnerStorageWithGSI([]core.GlobalSecondaryIndexSetting{})
+	newEntry := func(partitionKey string, sortKey string, version string) *core.Entry {
+		return &core.Entry{
+			Body: map[string]core.AttributeValue{
+				"partitionKey": {S: &partitionKey},
+				"sortKey":      {S: &sortKey},
+				"version":      {N: &version},
+			},
+		}
+	}
+
+	// with a plain separator both keys would be encoded as ab||cd
+	entry1 := newEntry("ab", "|cd", "1")
+	entry2 := newEntry("ab|", "cd", "2")
+	for _, entry := range []*core.Entry{entry1, entry2} {
+		_, err := storage.Put(&PutRequest{
+			Entry:     entry,
+			TableName: "test",
+		})
+		if err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	for _, entry := range []*core.Entry{entry1, entry2} {
+		actual, err := storage.Get(&GetRequest{
+			Entry:          entry,
+			ConsistentRead: true,
+			TableName:      "test",
+		})
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		assertEntry(actual, entry, t)
+	}
+
+	for _, entry := range []*core.Entry{entry1, entry2} {
+		partitionKey := entry.Body["partitionKey"].Bytes()
+		res, err := storage.Query(context.Background(), &query.Query{
+			PartitionKey:     &partitionKey,
+			ScanIndexForward: true,
+			Limit:            10,
+			ConsistentRead:   true,
+			TableName:        "test",
+		})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(res.Entries) != 1 {
+			t.Fatalf("Query failed: expected 1 Entry but got %d", len(res.Entries))
+		}
+		assertEntry(res.Entries[0], entry, t)
+	}
+
+	res, err := storage.Scan(context.Background(), &scan.Request{
+		Limit:          10,
+		ConsistentRead: true,
+		TableName:      "test",
+	})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(res.Entries) != 2 {
+		t.Fatalf("Scan failed: expected 2 Entries but got %d", len(res.Entries))
+	}
+}
+
 func TestInnerStorageQuery(t *testing.T) {
 	storage := createTestInnerStorageWithGSI([]core.GlobalSecondaryIndexSetting{})
 	count := 4
@@ -598,7 +757,7 @@ func TestInnerStorageQuery(t *testing.T) {
 			Body: body,
 		}
 
-		err := storage.Put(&PutRequest{
+		_, err := storage.Put(&PutRequest{
 			Entry:     entry,
 			TableName: "test",
 		})
@@ -621,7 +780,7 @@ func TestInnerStorageQuery(t *testing.T) {
 			ConsistentRead:   true,
 			TableName:        "test",
 		}
-		res, err := storage.Query(req)
+		res, err := storage.Query(context.Background(), req)
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
 		}
@@ -640,7 +799,7 @@ func TestInnerStorageQuery(t *testing.T) {
 			ConsistentRead:   false,
 			TableName:        "test",
 		}
-		res2, err := storage.Query(req2)
+		res2, err := storage.Query(context.Background(), req2)
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
 		}
@@ -661,7 +820,7 @@ func TestInnerStorageQuery(t *testing.T) {
 			ConsistentRead:   true,
 			TableName:        "test",
 		}
-		res, err := storage.Query(req)
+		res, err := storage.Query(context.Background(), req)
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
 		}
@@ -680,7 +839,7 @@ func TestInnerStorageQuery(t *testing.T) {
 			ConsistentRead:   false,
 			TableName:        "test",
 		}
-		res2, err := storage.Query(req2)
+		res2, err := storage.Query(context.Background(), req2)
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
 		}
@@ -693,7 +852,7 @@ func TestInnerStorageQuery(t *testing.T) {
 	// Test query with ExclusiveStartKey
 	{
 		partitionKey := []byte("foo")
-		exclusiveSortKey := []byte("foo|bar1")
+		exclusiveSortKey := core.EncodePrimaryKey([]byte("foo"), []byte("bar1"))
 		req := &query.Query{
 			PartitionKey:      &partitionKey,
 			ScanIndexForward:  true,
@@ -702,7 +861,7 @@ func TestInnerStorageQuery(t *testing.T) {
 			ExclusiveStartKey: &exclusiveSortKey,
 			TableName:         "test",
 		}
-		res, err := storage.Query(req)
+		res, err := storage.Query(context.Background(), req)
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
 		}
@@ -722,7 +881,7 @@ func TestInnerStorageQuery(t *testing.T) {
 			ExclusiveStartKey: &exclusiveSortKey,
 			TableName:         "test",
 		}
-		res2, err := storage.Query(req2)
+		res2, err := storage.Query(context.Background(), req2)
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
 		}
@@ -751,7 +910,7 @@ func TestInnerStorageQuery(t *testing.T) {
 			TableName:        "test",
 		}
 
-		res, err := storage.Query(req)
+		res, err := storage.Query(context.Background(), req)
 
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
@@ -772,7 +931,7 @@ func TestInnerStorageQuery(t *testing.T) {
 			TableName:        "test",
 		}
 
-		res2, err := storage.Query(req2)
+		res2, err := storage.Query(context.Background(), req2)
 
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
@@ -818,7 +977,7 @@ func TestInnerStorageQueryWithGsiNoSortKey(t *testing.T) {
 			Body: body,
 		}
 
-		err := storage.Put(&PutRequest{
+		_, err := storage.Put(&PutRequest{
 			Entry:     entry,
 			TableName: "test",
 		})
@@ -841,7 +1000,7 @@ func TestInnerStorageQueryWithGsiNoSortKey(t *testing.T) {
 			ConsistentRead:   true,
 			TableName:        "test",
 		}
-		res, err := storage.Query(req)
+		res, err := storage.Query(context.Background(), req)
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
 		}
@@ -892,7 +1051,7 @@ func TestInnerStorageQueryWithGsi(t *testing.T) {
 			Body: body,
 		}
 
-		err := storage.Put(&PutRequest{
+		_, err := storage.Put(&PutRequest{
 			Entry:     entry,
 			TableName: "test",
 		})
@@ -915,7 +1074,7 @@ func TestInnerStorageQueryWithGsi(t *testing.T) {
 			ConsistentRead:   true,
 			TableName:        "test",
 		}
-		res, err := storage.Query(req)
+		res, err := storage.Query(context.Background(), req)
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
 		}
@@ -927,7 +1086,7 @@ func TestInnerStorageQueryWithGsi(t *testing.T) {
 		assertEntry(entries[1], expectedEntries[1], t)
 
 		updateTestTableMetadata(storage, "test", 5, 10, 0)
-		res2, err := storage.Query(req)
+		res2, err := storage.Query(context.Background(), req)
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
 		}
@@ -949,7 +1108,7 @@ func TestInnerStorageQueryWithGsi(t *testing.T) {
 			ConsistentRead:   true,
 			TableName:        "test",
 		}
-		res, err := storage.Query(req)
+		res, err := storage.Query(context.Background(), req)
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
 		}
@@ -961,7 +1120,7 @@ func TestInnerStorageQueryWithGsi(t *testing.T) {
 		assertEntry(entries[1], expectedEntries[2], t)
 
 		updateTestTableMetadata(storage, "test", 5, 10, 0)
-		res2, err := storage.Query(req)
+		res2, err := storage.Query(context.Background(), req)
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
 		}
@@ -975,7 +1134,7 @@ func TestInnerStorageQueryWithGsi(t *testing.T) {
 	{
 		updateTestTableMetadata(storage, "test", 5, 0, 0)
 		partitionKey := []byte("gsiFoo")
-		exclusiveSortKey := []byte("foo|bar1")
+		exclusiveSortKey := core.EncodePrimaryKey([]byte("foo"), []byte("bar1"))
 		req := &query.Query{
 			IndexName:         &gsiName,
 			PartitionKey:      &partitionKey,
@@ -985,7 +1144,7 @@ func TestInnerStorageQueryWithGsi(t *testing.T) {
 			ExclusiveStartKey: &exclusiveSortKey,
 			TableName:         "test",
 		}
-		res, err := storage.Query(req)
+		res, err := storage.Query(context.Background(), req)
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
 		}
@@ -997,7 +1156,7 @@ func TestInnerStorageQueryWithGsi(t *testing.T) {
 		assertEntry(entries[1], expectedEntries[3], t)
 
 		updateTestTableMetadata(storage, "test", 5, 10, 0)
-		res2, err := storage.Query(req)
+		res2, err := storage.Query(context.Background(), req)
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
 		}
@@ -1028,7 +1187,7 @@ func TestInnerStorageQueryWithGsi(t *testing.T) {
 			TableName:        "test",
 		}
 
-		res, err := storage.Query(req)
+		res, err := storage.Query(context.Background(), req)
 
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
@@ -1040,7 +1199,7 @@ func TestInnerStorageQueryWithGsi(t *testing.T) {
 		assertEntry(entries[0], expectedEntries[2], t)
 
 		updateTestTableMetadata(storage, "test", 5, 10, 0)
-		res2, err := storage.Query(req)
+		res2, err := storage.Query(context.Background(), req)
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
 		}
@@ -1051,6 +1210,155 @@ func TestInnerStorageQueryWithGsi(t *testing.T) {
 	}
 }
 
+func TestInnerStorageQueryWithGsiMultipleVersions(t *testing.T) {
+	gsiName := "gsi1"
+	gsiSettings := []core.GlobalSecondaryIndexSetting{
+		{
+			IndexName: &gsiName,
+			PartitionKeySchema: &core.KeySchema{
+				AttributeName: "gsi1PartitionKey",
+				AttributeType: core.ScalarAttributeTypeS,
+			},
+			ProjectionType: core.PROJECTION_TYPE_ALL,
+		},
+	}
+	storage := createTestInnerStorageWithGSI(gsiSettings)
+
+	newEntry := func(version string) *core.Entry {
+		body := make(map[string]core.AttributeValue)
+		body["partitionKey"] = core.AttributeValue{S: aws.String("foo")}
+		body["sortKey"] = core.AttributeValue{S: aws.String("bar")}
+		body["gsi1PartitionKey"] = core.AttributeValue{S: aws.String("gsiFoo")}
+		body["version"] = core.AttributeValue{N: aws.String(version)}
+		return &core.Entry{Body: body}
+	}
+	v1 := newEntry("1")
+	v2 := newEntry("2")
+	for _, entry := range []*core.Entry{v1, v2} {
+		_, err := storage.Put(&PutRequest{
+			Entry:     entry,
+			TableName: "test",
+		})
+		if err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	// the gsi versions must be written with the same CreatedAt as the base table versions
+	{
+		txn, err := storage.db.Begin()
+		if err != nil {
+			t.Fatalf("Begin failed: %v", err)
+		}
+		primaryKey := &PrimaryKey{
+			PartitionKey: []byte("foo"),
+			SortKey:      []byte("bar"),
+		}
+		tableMetadata := storage.TableMetaDatas["test"]
+		tuple, err := storage.getTuple(primaryKey.Bytes(), tableMetadata.Name, txn)
+		if err != nil {
+			t.Fatalf("getTuple failed: %v", err)
+		}
+		gsiTuple, err := storage.getTuple(primaryKey.Bytes(), tableMetadata.GlobalSecondaryIndexSettings[gsiName].IndexTableName, txn)
+		if err != nil {
+			t.Fatalf("getTuple failed: %v", err)
+		}
+		txn.Rollback()
+
+		if len(tuple.Entries) != 2 || len(gsiTuple.Entries) != 2 {
+			t.Fatalf("Expected 2 versions in both tuples, got %d and %d", len(tuple.Entries), len(gsiTuple.Entries))
+		}
+		for i := range tuple.Entries {
+			if !tuple.Entries[i].CreatedAt.Equal(gsiTuple.Entries[i].CreatedAt) {
+				t.Fatalf("Expected gsi version %d CreatedAt %v, got %v", i, tuple.Entries[i].CreatedAt, gsiTuple.Entries[i].CreatedAt)
+			}
+		}
+	}
+
+	queryGsi := func() *core.Entry {
+		partitionKey := []byte("gsiFoo")
+		res, err := storage.Query(context.Background(), &query.Query{
+			IndexName:    &gsiName,
+			PartitionKey: &partitionKey,
+			Limit:        10,
+			TableName:    "test",
+		})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(res.Entries) != 1 {
+			t.Fatalf("Query failed: expected 1 entry but got %d", len(res.Entries))
+		}
+		return res.Entries[0]
+	}
+
+	// v2 is not visible yet, the stale v1 projection should be returned
+	updateTestTableMetadata(storage, "test", 0, 100, 0)
+	assertEntry(queryGsi(), v1, t)
+
+	updateTestTableMetadata(storage, "test", 0, 0, 0)
+	assertEntry(queryGsi(), v2, t)
+}
+
+func TestInnerStorageQueryWithGsiStronglyConsistent(t *testing.T) {
+	gsiName := "gsi1"
+	gsiSettings := []core.GlobalSecondaryIndexSetting{
+		{
+			IndexName: &gsiName,
+			PartitionKeySchema: &core.KeySchema{
+				AttributeName: "gsi1PartitionKey",
+				AttributeType: core.ScalarAttributeTypeS,
+			},
+			ProjectionType: core.PROJECTION_TYPE_ALL,
+		},
+	}
+	storage := createTestInnerStorageWithGSI(gsiSettings)
+	storage.GsiStronglyConsistent = true
+	updateTestTableMetadata(storage, "test", 100, 100, 0)
+
+	body := make(map[string]core.AttributeValue)
+	body["partitionKey"] = core.AttributeValue{S: aws.String("foo")}
+	body["sortKey"] = core.AttributeValue{S: aws.String("bar")}
+	body["gsi1PartitionKey"] = core.AttributeValue{S: aws.String("gsiFoo")}
+	entry := &core.Entry{Body: body}
+	_, err := storage.Put(&PutRequest{
+		Entry:     entry,
+		TableName: "test",
+	})
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	partitionKey := []byte("gsiFoo")
+	res, err := storage.Query(context.Background(), &query.Query{
+		IndexName:    &gsiName,
+		PartitionKey: &partitionKey,
+		Limit:        10,
+		TableName:    "test",
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(res.Entries) != 1 {
+		t.Fatalf("Query failed: expected 1 entry but got %d", len(res.Entries))
+	}
+	assertEntry(res.Entries[0], entry, t)
+
+	// the base table still honors tableDelaySeconds
+	basePartitionKey := []byte("foo")
+	baseRes, err := storage.Query(context.Background(), &query.Query{
+		PartitionKey: &basePartitionKey,
+		Limit:        10,
+		TableName:    "test",
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(baseRes.Entries) != 0 {
+		t.Fatalf("Query failed: expected 0 entries but got %d", len(baseRes.Entries))
+	}
+}
+
 func TestInnerStorageUpdate(t *testing.T) {
 	storage := createTestInnerStorageWithGSI([]core.GlobalSecondaryIndexSetting{})
 	tableName := "test"
@@ -1153,7 +1461,7 @@ func TestInnerStorageUpdate(t *testing.T) {
 					Body: body,
 				}
 
-				err := storage.Put(&PutRequest{
+				_, err := storage.Put(&PutRequest{
 					Entry:     entry,
 					TableName: tableName,
 				})
@@ -1207,6 +1515,164 @@ func TestInnerStorageUpdate(t *testing.T) {
 	}
 }
 
+func TestInnerStorageUpdateWithAllClauses(t *testing.T) {
+	storage := createTestInnerStorageWithGSI([]core.GlobalSecondaryIndexSetting{})
+	tableName := "test"
+
+	body := make(map[string]core.AttributeValue)
+	body["partitionKey"] = core.AttributeValue{S: aws.String("foo")}
+	body["sortKey"] = core.AttributeValue{S: aws.String("bar")}
+	body["a"] = core.AttributeValue{S: aws.String("old")}
+	body["b"] = core.AttributeValue{S: aws.String("removed")}
+	body["c"] = core.AttributeValue{N: aws.String("1")}
+	body["d"] = core.AttributeValue{SS: &[]string{"x", "y", "z"}}
+	_, err := storage.Put(&PutRequest{
+		Entry:     &core.Entry{Body: body},
+		TableName: tableName,
+	})
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	key := &core.Entry{
+		Body: map[string]core.AttributeValue{
+			"partitionKey": {S: aws.String("foo")},
+			"sortKey":      {S: aws.String("bar")},
+		},
+	}
+	// e copies b before b is removed, SET values are evaluated against the item before the update
+	operation, err := update.BuildUpdateOperation(
+		"SET a = :x, e = b REMOVE b ADD c :y DELETE d :z",
+		map[string]string{},
+		map[string]core.AttributeValue{
+			":x": {S: aws.String("new")},
+			":y": {N: aws.String("2")},
+			":z": {SS: &[]string{"y"}},
+		},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v, when build operation", err)
+	}
+
+	_, err = storage.Update(&UpdateRequest{
+		Key:             key,
+		UpdateOperation: operation,
+		TableName:       tableName,
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	updatedEntry, err := storage.Get(&GetRequest{
+		Entry:          key,
+		ConsistentRead: true,
+		TableName:      tableName,
+	})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+
+	expected := &core.Entry{
+		Body: map[string]core.AttributeValue{
+			"partitionKey": {S: aws.String("foo")},
+			"sortKey":      {S: aws.String("bar")},
+			"a":            {S: aws.String("new")},
+			"c":            {N: aws.String("3")},
+			"d":            {SS: &[]string{"x", "z"}},
+			"e":            {S: aws.String("removed")},
+		},
+	}
+	assertEntry(updatedEntry, expected, t)
+	if _, ok := updatedEntry.Body["b"]; ok {
+		t.Fatalf("Expected b to be removed, got %v", updatedEntry.Body["b"])
+	}
+}
+
+func TestInnerStorageConcurrentOptimisticLockUpdate(t *testing.T) {
+	storage := createTestInnerStorageWithGSI([]core.GlobalSecondaryIndexSetting{})
+	tableName := "test"
+
+	body := make(map[string]core.AttributeValue)
+	body["partitionKey"] = core.AttributeValue{S: aws.String("foo")}
+	body["sortKey"] = core.AttributeValue{S: aws.String("bar")}
+	body["version"] = core.AttributeValue{N: aws.String("1")}
+	_, err := storage.Put(&PutRequest{
+		Entry:     &core.Entry{Body: body},
+		TableName: tableName,
+	})
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	key := &core.Entry{
+		Body: map[string]core.AttributeValue{
+			"partitionKey": {S: aws.String("foo")},
+			"sortKey":      {S: aws.String("bar")},
+		},
+	}
+
+	concurrency := 10
+	errs := make(chan error, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			values := map[string]core.AttributeValue{
+				":new":      {N: aws.String("2")},
+				":expected": {N: aws.String("1")},
+			}
+			operation, err := update.BuildUpdateOperation("SET version = :new", map[string]string{}, values)
+			if err != nil {
+				errs <- err
+				return
+			}
+			cond, err := condition.BuildCondition("version = :expected", map[string]string{}, values)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			_, err = storage.Update(&UpdateRequest{
+				Key:             key,
+				UpdateOperation: operation,
+				TableName:       tableName,
+				Condition:       cond,
+			})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded += 1
+			continue
+		}
+		var conditionalErr *ConditionalCheckFailedException
+		if !errors.As(err, &conditionalErr) {
+			t.Fatalf("Expected ConditionalCheckFailedException, got %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("Expected exactly 1 update to succeed, got %d", succeeded)
+	}
+
+	updatedEntry, err := storage.Get(&GetRequest{
+		Entry:          key,
+		ConsistentRead: true,
+		TableName:      tableName,
+	})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if *updatedEntry.Body["version"].N != "2" {
+		t.Fatalf("Expected version 2, got %s", *updatedEntry.Body["version"].N)
+	}
+}
+
 func TestInnerStorageQueryItemCount(t *testing.T) {
 	storage := createTestInnerStorageWithGSI([]core.GlobalSecondaryIndexSetting{})
 	tableName := "test"
@@ -1224,7 +1690,7 @@ func TestInnerStorageQueryItemCount(t *testing.T) {
 			Body: body,
 		}
 
-		err := storage.Put(&PutRequest{
+		_, err := storage.Put(&PutRequest{
 			Entry:     entry,
 			TableName: tableName,
 		})
@@ -1262,7 +1728,7 @@ func TestInnerStorageScan(t *testing.T) {
 			Body: body,
 		}
 
-		err := storage.Put(&PutRequest{
+		_, err := storage.Put(&PutRequest{
 			Entry:     entry,
 			TableName: "test",
 		})
@@ -1281,7 +1747,7 @@ func TestInnerStorageScan(t *testing.T) {
 			ConsistentRead: true,
 			TableName:      "test",
 		}
-		res, err := storage.Scan(req)
+		res, err := storage.Scan(context.Background(), req)
 		if err != nil {
 			t.Fatalf("Scan failed: %v", err)
 		}
@@ -1298,7 +1764,7 @@ func TestInnerStorageScan(t *testing.T) {
 			ConsistentRead: false,
 			TableName:      "test",
 		}
-		res2, err := storage.Scan(req2)
+		res2, err := storage.Scan(context.Background(), req2)
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
 		}
@@ -1311,14 +1777,14 @@ func TestInnerStorageScan(t *testing.T) {
 
 	// Test scan with ExclusiveStartKey
 	{
-		exclusiveSortKey := []byte("foo|bar1")
+		exclusiveSortKey := core.EncodePrimaryKey([]byte("foo"), []byte("bar1"))
 		req := &scan.Request{
 			Limit:             2,
 			ConsistentRead:    true,
 			ExclusiveStartKey: &exclusiveSortKey,
 			TableName:         "test",
 		}
-		res, err := storage.Scan(req)
+		res, err := storage.Scan(context.Background(), req)
 		if err != nil {
 			t.Fatalf("Scan failed: %v", err)
 		}
@@ -1336,7 +1802,7 @@ func TestInnerStorageScan(t *testing.T) {
 			ExclusiveStartKey: &exclusiveSortKey,
 			TableName:         "test",
 		}
-		res2, err := storage.Scan(req2)
+		res2, err := storage.Scan(context.Background(), req2)
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
 		}
@@ -1363,7 +1829,7 @@ func TestInnerStorageScan(t *testing.T) {
 			Filter:         condition.NewCondition(filter),
 		}
 
-		res, err := storage.Scan(req)
+		res, err := storage.Scan(context.Background(), req)
 
 		if err != nil {
 			t.Fatalf("Scan failed: %v", err)
@@ -1377,6 +1843,62 @@ func TestInnerStorageScan(t *testing.T) {
 
 }
 
+func TestInnerStorageScanWithInFilter(t *testing.T) {
+	storage := createTestInnerStorageWithGSI([]core.GlobalSecondaryIndexSetting{})
+	statuses := []core.AttributeValue{
+		{S: aws.String("active")},
+		{S: aws.String("pending")},
+		{S: aws.String("archived")},
+		{S: aws.String("deleted")},
+		{N: aws.String("1")},
+		{SS: &[]string{"active", "pending"}},
+	}
+	for i, status := range statuses {
+		body := make(map[string]core.AttributeValue)
+		body["partitionKey"] = core.AttributeValue{S: aws.String("foo")}
+		body["sortKey"] = core.AttributeValue{S: aws.String(fmt.Sprintf("bar%d", i))}
+		body["status"] = status
+		_, err := storage.Put(&PutRequest{
+			Entry:     &core.Entry{Body: body},
+			TableName: "test",
+		})
+		if err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	filter, err := condition.BuildCondition(
+		"#status IN (:a, :b, :c)",
+		map[string]string{"#status": "status"},
+		map[string]core.AttributeValue{
+			":a": {S: aws.String("active")},
+			":b": {S: aws.String("pending")},
+			":c": {SS: &[]string{"active"}},
+		},
+	)
+	if err != nil {
+		t.Fatalf("BuildCondition failed: %v", err)
+	}
+
+	res, err := storage.Scan(context.Background(), &scan.Request{
+		Limit:          10,
+		ConsistentRead: true,
+		TableName:      "test",
+		Filter:         filter,
+	})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(res.Entries) != 2 {
+		t.Fatalf("Scan failed: expected 2 entries but got %d", len(res.Entries))
+	}
+	for i, expected := range []string{"active", "pending"} {
+		if *res.Entries[i].Body["status"].S != expected {
+			t.Fatalf("Expected status %s, got %v", expected, res.Entries[i].Body["status"])
+		}
+	}
+}
+
 func TestInnerStorageScanWithSegments(t *testing.T) {
 	storage := createTestInnerStorageWithGSI([]core.GlobalSecondaryIndexSetting{})
 	count := 10
@@ -1390,7 +1912,7 @@ func TestInnerStorageScanWithSegments(t *testing.T) {
 		version := "1"
 		body["version"] = core.AttributeValue{N: &version}
 		entry := &core.Entry{Body: body}
-		err := storage.Put(&PutRequest{
+		_, err := storage.Put(&PutRequest{
 			Entry:     entry,
 			TableName: "test",
 		})
@@ -1409,7 +1931,7 @@ func TestInnerStorageScanWithSegments(t *testing.T) {
 			TableName:     "test",
 			Limit:         count,
 		}
-		res, err := storage.Scan(req)
+		res, err := storage.Scan(context.Background(), req)
 		if err != nil {
 			t.Fatalf("Scan failed for segment %d: %v", segment, err)
 		}
@@ -1436,6 +1958,80 @@ func TestInnerStorageScanWithSegments(t *testing.T) {
 	}
 }
 
+func TestBuildShardIdDistribution(t *testing.T) {
+	totalSegments := 8
+	keyCount := 8000
+	counts := make([]int, totalSegments)
+	for i := 0; i < keyCount; i++ {
+		shardId := buildShardId([]byte(fmt.Sprintf("user#%d", i)))
+		if shardId < 0 || shardId >= TOTAL_SEGMENTS {
+			t.Fatalf("Expected shard id in [0, %d), got %d", TOTAL_SEGMENTS, shardId)
+		}
+		counts[int(shardId)%totalSegments] += 1
+	}
+
+	expected := keyCount / totalSegments
+	for segment, count := range counts {
+		// allow 20% deviation from a perfectly even split
+		if count < expected*8/10 || count > expected*12/10 {
+			t.Fatalf("Expected segment %d to get about %d keys, got %d (%v)", segment, expected, count, counts)
+		}
+	}
+}
+
+func TestInnerStorageScanWithCustomShardIdBuilder(t *testing.T) {
+	storage := NewInnerStorage()
+	// every partition key goes to shard 1
+	storage.ShardIdBuilder = func(partitionKey []byte) int32 {
+		return 1
+	}
+	err := storage.CreateTable(&core.TableMetaData{
+		Name: "test",
+		PartitionKeySchema: &core.KeySchema{
+			AttributeName: "partitionKey",
+		},
+		BillingMode: core.BILLING_MODE_PAY_PER_REQUEST,
+	})
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	count := 5
+	for i := 0; i < count; i++ {
+		body := make(map[string]core.AttributeValue)
+		body["partitionKey"] = core.AttributeValue{S: aws.String(fmt.Sprintf("foo%d", i))}
+		_, err := storage.Put(&PutRequest{
+			Entry:     &core.Entry{Body: body},
+			TableName: "test",
+		})
+		if err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	totalSegments := int32(2)
+	for segment := int32(0); segment < totalSegments; segment++ {
+		res, err := storage.Scan(context.Background(), &scan.Request{
+			TotalSegments:  &totalSegments,
+			Segment:        &segment,
+			TableName:      "test",
+			Limit:          count,
+			ConsistentRead: true,
+		})
+		if err != nil {
+			t.Fatalf("Scan failed for segment %d: %v", segment, err)
+		}
+
+		expected := 0
+		if segment == 1 {
+			expected = count
+		}
+		if len(res.Entries) != expected {
+			t.Fatalf("Expected %d entries in segment %d, got %d", expected, segment, len(res.Entries))
+		}
+	}
+}
+
 func TestInnerStorageScanGsi(t *testing.T) {
 	gsiName := "gsi1"
 	gsiPartitionKeyName := "gsi1PartitionKey"
@@ -1470,7 +2066,7 @@ func TestInnerStorageScanGsi(t *testing.T) {
 			Body: body,
 		}
 
-		err := storage.Put(&PutRequest{
+		_, err := storage.Put(&PutRequest{
 			Entry:     entry,
 			TableName: "test",
 		})
@@ -1489,7 +2085,7 @@ func TestInnerStorageScanGsi(t *testing.T) {
 			TableName: "test",
 			IndexName: &gsiName,
 		}
-		res, err := storage.Scan(req)
+		res, err := storage.Scan(context.Background(), req)
 		if err != nil {
 			t.Fatalf("Query failed: %v", err)
 		}
@@ -1507,7 +2103,7 @@ func TestInnerStorageScanGsi(t *testing.T) {
 			TableName: "test",
 			IndexName: &gsiName,
 		}
-		res, err := storage.Scan(req)
+		res, err := storage.Scan(context.Background(), req)
 		if err != nil {
 			t.Fatalf("Scan failed: %v", err)
 		}
@@ -1520,3 +2116,394 @@ func TestInnerStorageScanGsi(t *testing.T) {
 
 	}
 }
+
+func TestInnerStorageScanGsiKeysOnly(t *testing.T) {
+	gsiName := "gsi1"
+	gsiSettings := []core.GlobalSecondaryIndexSetting{
+		{
+			IndexName: &gsiName,
+			PartitionKeySchema: &core.KeySchema{
+				AttributeName: "gsi1PartitionKey",
+				AttributeType: core.ScalarAttributeTypeS,
+			},
+			SortKeySchema: &core.KeySchema{
+				AttributeName: "gsi1SortKey",
+				AttributeType: core.ScalarAttributeTypeS,
+			},
+			ProjectionType: core.PROJECTION_TYPE_KEYS_ONLY,
+		},
+	}
+	storage := createTestInnerStorageWithGSI(gsiSettings)
+
+	body := make(map[string]core.AttributeValue)
+	body["partitionKey"] = core.AttributeValue{S: aws.String("foo")}
+	body["sortKey"] = core.AttributeValue{S: aws.String("bar")}
+	body["gsi1PartitionKey"] = core.AttributeValue{S: aws.String("gsiFoo")}
+	body["gsi1SortKey"] = core.AttributeValue{S: aws.String("gsiBar")}
+	body["message"] = core.AttributeValue{S: aws.String("hola")}
+	body["version"] = core.AttributeValue{N: aws.String("1")}
+	entry := &core.Entry{
+		Body: body,
+	}
+	_, err := storage.Put(&PutRequest{
+		Entry:     entry,
+		TableName: "test",
+	})
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	updateTestTableMetadata(storage, "test", 0, 0, 0)
+	res, err := storage.Scan(context.Background(), &scan.Request{
+		Limit:     10,
+		TableName: "test",
+		IndexName: &gsiName,
+	})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(res.Entries) != 1 {
+		t.Fatalf("Scan failed: expected 1 entry but got %d", len(res.Entries))
+	}
+
+	expectedEntry := &core.Entry{
+		Body: make(map[string]core.AttributeValue),
+	}
+	for _, attributeName := range []string{"partitionKey", "sortKey", "gsi1PartitionKey", "gsi1SortKey"} {
+		expectedEntry.Body[attributeName] = entry.Body[attributeName]
+	}
+	assertEntry(res.Entries[0], expectedEntry, t)
+}
+
+func TestInnerStorageScanGsiWithIndexDelaySeconds(t *testing.T) {
+	gsiSettings := make([]core.GlobalSecondaryIndexSetting, 0)
+	for _, gsiName := range []string{"gsi1", "gsi2"} {
+		gsiSettings = append(gsiSettings, core.GlobalSecondaryIndexSetting{
+			IndexName: aws.String(gsiName),
+			PartitionKeySchema: &core.KeySchema{
+				AttributeName: gsiName + "PartitionKey",
+				AttributeType: core.ScalarAttributeTypeS,
+			},
+			ProjectionType: core.PROJECTION_TYPE_ALL,
+		})
+	}
+	storage := createTestInnerStorageWithGSI(gsiSettings)
+
+	putMetadata := func(indexDelaySeconds map[string]core.AttributeValue) error {
+		_, err := storage.Put(&PutRequest{
+			Entry: &core.Entry{
+				Body: map[string]core.AttributeValue{
+					"tableName":         {S: aws.String("test")},
+					"tableDelaySeconds": {N: aws.String("0")},
+					"gsiDelaySeconds":   {N: aws.String("100")},
+					"indexDelaySeconds": {M: &indexDelaySeconds},
+				},
+			},
+			TableName: METADATA_TABLE_NAME,
+		})
+		return err
+	}
+	// gsi1 lags behind the table by gsiDelaySeconds, gsi2 reflects writes immediately
+	if err := putMetadata(map[string]core.AttributeValue{"gsi2": {N: aws.String("0")}}); err != nil {
+		t.Fatalf("Put metadata failed: %v", err)
+	}
+
+	_, err := storage.Put(&PutRequest{
+		Entry: &core.Entry{
+			Body: map[string]core.AttributeValue{
+				"partitionKey":     {S: aws.String("foo")},
+				"sortKey":          {S: aws.String("bar")},
+				"gsi1PartitionKey": {S: aws.String("gsiFoo")},
+				"gsi2PartitionKey": {S: aws.String("gsiFoo")},
+			},
+		},
+		TableName: "test",
+	})
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	scanCount := func(gsiName string) int {
+		t.Helper()
+		res, err := storage.Scan(context.Background(), &scan.Request{
+			Limit:     10,
+			TableName: "test",
+			IndexName: aws.String(gsiName),
+		})
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		return len(res.Entries)
+	}
+	if n := scanCount("gsi1"); n != 0 {
+		t.Fatalf("Expected no entry in gsi1 within its delay, got %d", n)
+	}
+	if n := scanCount("gsi2"); n != 1 {
+		t.Fatalf("Expected 1 entry in gsi2 without delay, got %d", n)
+	}
+
+	// the delay survives cloning the table metadata, e.g. by UpdateTable
+	storage.TableMetaDatas["test"] = storage.TableMetaDatas["test"].Clone()
+	if n := scanCount("gsi2"); n != 1 {
+		t.Fatalf("Expected 1 entry in gsi2 after cloning, got %d", n)
+	}
+
+	// a GSI left out of indexDelaySeconds goes back to gsiDelaySeconds
+	if err := putMetadata(map[string]core.AttributeValue{"gsi1": {N: aws.String("0")}}); err != nil {
+		t.Fatalf("Put metadata failed: %v", err)
+	}
+	if n := scanCount("gsi1"); n != 1 {
+		t.Fatalf("Expected 1 entry in gsi1 without delay, got %d", n)
+	}
+	if n := scanCount("gsi2"); n != 0 {
+		t.Fatalf("Expected no entry in gsi2 within gsiDelaySeconds, got %d", n)
+	}
+
+	err = putMetadata(map[string]core.AttributeValue{"missing": {N: aws.String("0")}})
+	if err == nil || err.Error() != "global secondary index missing not found" {
+		t.Fatalf("Expected global secondary index missing not found, got %v", err)
+	}
+}
+
+func TestInnerStorageKeysOnlyGsiSkipsNonKeyUpdate(t *testing.T) {
+	gsiName := "gsi1"
+	gsiSettings := []core.GlobalSecondaryIndexSetting{
+		{
+			IndexName: &gsiName,
+			PartitionKeySchema: &core.KeySchema{
+				AttributeName: "gsi1PartitionKey",
+				AttributeType: core.ScalarAttributeTypeS,
+			},
+			ProjectionType: core.PROJECTION_TYPE_KEYS_ONLY,
+		},
+	}
+	storage := createTestInnerStorageWithGSI(gsiSettings)
+
+	body := make(map[string]core.AttributeValue)
+	body["partitionKey"] = core.AttributeValue{S: aws.String("foo")}
+	body["sortKey"] = core.AttributeValue{S: aws.String("bar")}
+	body["gsi1PartitionKey"] = core.AttributeValue{S: aws.String("gsiFoo")}
+	body["message"] = core.AttributeValue{S: aws.String("hola")}
+	_, err := storage.Put(&PutRequest{
+		Entry:     &core.Entry{Body: body},
+		TableName: "test",
+	})
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	readGsiTuple := func() *Tuple {
+		txn, err := storage.db.Begin()
+		if err != nil {
+			t.Fatalf("Begin failed: %v", err)
+		}
+		defer txn.Rollback()
+
+		primaryKey := &PrimaryKey{
+			PartitionKey: []byte("foo"),
+			SortKey:      []byte("bar"),
+		}
+		gsiTableName := storage.TableMetaDatas["test"].GlobalSecondaryIndexSettings[gsiName].IndexTableName
+		tuple, err := storage.getTuple(primaryKey.Bytes(), gsiTableName, txn)
+		if err != nil {
+			t.Fatalf("getTuple failed: %v", err)
+		}
+		if tuple == nil {
+			t.Fatalf("expected gsi tuple to exist")
+		}
+		return tuple
+	}
+	before := readGsiTuple()
+
+	updatedBody := make(map[string]core.AttributeValue)
+	for k, v := range body {
+		updatedBody[k] = v
+	}
+	updatedBody["message"] = core.AttributeValue{S: aws.String("hello")}
+	_, err = storage.Put(&PutRequest{
+		Entry:     &core.Entry{Body: updatedBody},
+		TableName: "test",
+	})
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	after := readGsiTuple()
+	if len(after.Entries) != len(before.Entries) {
+		t.Fatalf("expected gsi tuple to keep %d entries, got %d", len(before.Entries), len(after.Entries))
+	}
+	if !after.Entries[0].CreatedAt.Equal(before.Entries[0].CreatedAt) {
+		t.Fatalf("expected gsi entry not to be rewritten, CreatedAt changed from %v to %v", before.Entries[0].CreatedAt, after.Entries[0].CreatedAt)
+	}
+	if _, ok := after.Entries[0].Entry.Body["message"]; ok {
+		t.Fatalf("expected non-key attribute not to leak into the gsi")
+	}
+}
+
+func bulkPutTestEntries(count int) []*core.Entry {
+	entries := make([]*core.Entry, count)
+	for i := 0; i < count; i++ {
+		entries[i] = &core.Entry{
+			Body: map[string]core.AttributeValue{
+				"partitionKey":     {S: aws.String("foo")},
+				"sortKey":          {S: aws.String(fmt.Sprintf("bar%05d", i))},
+				"gsi1PartitionKey": {S: aws.String("gsiFoo")},
+				"gsi1SortKey":      {S: aws.String(fmt.Sprintf("gsiBar%05d", count-i))},
+				"version":          {N: aws.String("1")},
+			},
+		}
+	}
+	return entries
+}
+
+func bulkPutTestGsiSettings(gsiName string) []core.GlobalSecondaryIndexSetting {
+	return []core.GlobalSecondaryIndexSetting{
+		{
+			IndexName: &gsiName,
+			PartitionKeySchema: &core.KeySchema{
+				AttributeName: "gsi1PartitionKey",
+				AttributeType: core.ScalarAttributeTypeS,
+			},
+			SortKeySchema: &core.KeySchema{
+				AttributeName: "gsi1SortKey",
+				AttributeType: core.ScalarAttributeTypeS,
+			},
+			ProjectionType: core.PROJECTION_TYPE_ALL,
+		},
+	}
+}
+
+func TestInnerStorageBulkPut(t *testing.T) {
+	gsiName := "gsi1"
+	count := 20
+	entries := bulkPutTestEntries(count)
+
+	itemByItemStorage := createTestInnerStorageWithGSI(bulkPutTestGsiSettings(gsiName))
+	for _, entry := range entries {
+		_, err := itemByItemStorage.Put(&PutRequest{
+			Entry:     entry,
+			TableName: "test",
+		})
+		if err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	bulkStorage := createTestInnerStorageWithGSI(bulkPutTestGsiSettings(gsiName))
+	err := bulkStorage.BulkPut("test", entries)
+	if err != nil {
+		t.Fatalf("BulkPut failed: %v", err)
+	}
+
+	tablePartitionKey := []byte("foo")
+	gsiPartitionKey := []byte("gsiFoo")
+	queries := []*query.Query{
+		{
+			PartitionKey:     &tablePartitionKey,
+			ScanIndexForward: true,
+			Limit:            count,
+			ConsistentRead:   true,
+			TableName:        "test",
+		},
+		{
+			IndexName:        &gsiName,
+			PartitionKey:     &gsiPartitionKey,
+			ScanIndexForward: true,
+			Limit:            count,
+			TableName:        "test",
+		},
+	}
+	for _, req := range queries {
+		expected, err := itemByItemStorage.Query(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		actual, err := bulkStorage.Query(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(actual.Entries) != count || len(expected.Entries) != count {
+			t.Fatalf("Expected %d entries from both storages, got %d and %d", count, len(actual.Entries), len(expected.Entries))
+		}
+		for i := range expected.Entries {
+			assertEntry(actual.Entries[i], expected.Entries[i], t)
+		}
+	}
+
+	// bulk put entries are visible regardless of the consistency delay
+	updateTestTableMetadata(itemByItemStorage, "test", 10, 10, 0)
+	updateTestTableMetadata(bulkStorage, "test", 10, 10, 0)
+	res, err := itemByItemStorage.Query(context.Background(), queries[1])
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(res.Entries) != 0 {
+		t.Fatalf("Expected 0 entries within the delay, got %d", len(res.Entries))
+	}
+	res, err = bulkStorage.Query(context.Background(), queries[1])
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(res.Entries) != count {
+		t.Fatalf("Expected %d bulk put entries within the delay, got %d", count, len(res.Entries))
+	}
+}
+
+func BenchmarkInnerStorageBulkPut(b *testing.B) {
+	entries := bulkPutTestEntries(50000)
+	for i := 0; i < b.N; i++ {
+		storage := createTestInnerStorageWithGSI(bulkPutTestGsiSettings("gsi1"))
+		if err := storage.BulkPut("test", entries); err != nil {
+			b.Fatalf("BulkPut failed: %v", err)
+		}
+	}
+}
+
+func TestInnerStorageDropTable(t *testing.T) {
+	gsiName := "gsi1"
+	storage := createTestInnerStorageWithGSI(bulkPutTestGsiSettings(gsiName))
+	if err := storage.BulkPut("test", bulkPutTestEntries(3)); err != nil {
+		t.Fatalf("BulkPut failed: %v", err)
+	}
+	tableMetadata := storage.TableMetaDatas["test"]
+	sqlTableNames := []string{tableMetadata.Name, tableMetadata.GlobalSecondaryIndexSettings[gsiName].IndexTableName}
+
+	if err := storage.DropTable("test"); err != nil {
+		t.Fatalf("DropTable failed: %v", err)
+	}
+
+	for _, sqlTableName := range sqlTableNames {
+		var count int
+		err := storage.db.QueryRow("select count(*) from sqlite_master where type = 'table' and name = ?", sqlTableName).Scan(&count)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if count != 0 {
+			t.Fatalf("Expected table %s to be dropped", sqlTableName)
+		}
+	}
+	if _, err := storage.QueryItemCount("test"); err == nil || err.Error() != "table test not found" {
+		t.Fatalf("Expected table not found error, got %v", err)
+	}
+	if err := storage.DropTable("test"); err == nil {
+		t.Fatalf("Expected dropping a dropped table to fail")
+	}
+
+	err := storage.CreateTable(&core.TableMetaData{
+		Name:                         "test",
+		GlobalSecondaryIndexSettings: bulkPutTestGsiSettings(gsiName),
+		PartitionKeySchema:           &core.KeySchema{AttributeName: "partitionKey"},
+		SortKeySchema:                &core.KeySchema{AttributeName: "sortKey"},
+		BillingMode:                  core.BILLING_MODE_PAY_PER_REQUEST,
+	})
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	count, err := storage.QueryItemCount("test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("Expected the recreated table to be empty, got %d items", count)
+	}
+}
@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/ocowchun/baddb/ddb/core"
+)
+
+// StreamRecord is a change of an item, images are kept according to the StreamViewType of the stream
+type StreamRecord struct {
+	EventID                     string
+	EventName                   string
+	SequenceNumber              string
+	ApproximateCreationDateTime time.Time
+	Keys                        map[string]core.AttributeValue
+	OldImage                    map[string]core.AttributeValue
+	NewImage                    map[string]core.AttributeValue
+	SizeBytes                   int
+}
+
+// Stream keeps every record of a table since the stream is enabled, it has a single shard.
+// A disabled stream can still be read but no longer records writes.
+type Stream struct {
+	Arn            string
+	TableName      string
+	KeySchema      []types.KeySchemaElement
+	StreamViewType types.StreamViewType
+	CreatedAt      time.Time
+	Enabled        bool
+	records        []StreamRecord
+}
+
+const (
+	STREAM_EVENT_INSERT = "INSERT"
+	STREAM_EVENT_MODIFY = "MODIFY"
+	STREAM_EVENT_REMOVE = "REMOVE"
+)
+
+// STREAM_LABEL_LAYOUT formats the creation time of a stream the way DynamoDB labels its streams
+const STREAM_LABEL_LAYOUT = "2006-01-02T15:04:05.000"
+
+// StreamShardId is the id of the only shard of every stream
+const StreamShardId = "shardId-00000000000000000000-00000001"
+
+type pendingStreamRecord struct {
+	stream *Stream
+	record StreamRecord
+}
+
+// EnableStream starts recording the writes of tableName to a new stream created at createdAt
+func (s *InnerStorage) EnableStream(tableName string, streamArn string, viewType types.StreamViewType, keySchema []types.KeySchemaElement, createdAt time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.enableStream(tableName, streamArn, viewType, keySchema, createdAt)
+}
+
+func (s *InnerStorage) enableStream(tableName string, streamArn string, viewType types.StreamViewType, keySchema []types.KeySchemaElement, createdAt time.Time) error {
+	m, ok := s.TableMetaDatas[tableName]
+	if !ok {
+		return fmt.Errorf("table %s not found", tableName)
+	}
+
+	stream := &Stream{
+		Arn:            streamArn,
+		TableName:      tableName,
+		KeySchema:      keySchema,
+		StreamViewType: viewType,
+		CreatedAt:      createdAt,
+		Enabled:        true,
+		records:        make([]StreamRecord, 0),
+	}
+	s.streams[streamArn] = stream
+	m.stream = stream
+
+	return nil
+}
+
+// DisableStream stops recording the writes of tableName, the records of the stream can still be read
+func (s *InnerStorage) DisableStream(tableName string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	m, ok := s.TableMetaDatas[tableName]
+	if !ok {
+		return fmt.Errorf("table %s not found", tableName)
+	}
+	if m.stream != nil {
+		m.stream.Enabled = false
+		m.stream = nil
+	}
+
+	return nil
+}
+
+// RestoreStream undoes enabling or disabling a stream of tableName: streamArn is the enabled stream again, or the
+// table has no enabled stream when streamArn is nil. A stream enabled since is deleted with its records
+func (s *InnerStorage) RestoreStream(tableName string, streamArn *string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	m, ok := s.TableMetaDatas[tableName]
+	if !ok {
+		return fmt.Errorf("table %s not found", tableName)
+	}
+	if m.stream != nil && (streamArn == nil || m.stream.Arn != *streamArn) {
+		delete(s.streams, m.stream.Arn)
+		m.stream = nil
+	}
+	if streamArn == nil {
+		return nil
+	}
+
+	stream, ok := s.streams[*streamArn]
+	if !ok {
+		return ErrStreamNotFound
+	}
+	stream.Enabled = true
+	m.stream = stream
+
+	return nil
+}
+
+// DescribeStream returns the stream without its records and the number of records in it
+func (s *InnerStorage) DescribeStream(streamArn string) (*Stream, int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stream, ok := s.streams[streamArn]
+	if !ok {
+		return nil, 0, ErrStreamNotFound
+	}
+	described := *stream
+	described.records = nil
+
+	return &described, len(stream.records), nil
+}
+
+// StreamRecords returns at most limit records from position and the position after them
+func (s *InnerStorage) StreamRecords(streamArn string, position int, limit int) ([]StreamRecord, int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stream, ok := s.streams[streamArn]
+	if !ok {
+		return nil, 0, ErrStreamNotFound
+	}
+	if position < 0 || position > len(stream.records) {
+		return nil, 0, fmt.Errorf("position %d is out of the stream", position)
+	}
+
+	end := min(position+limit, len(stream.records))
+	records := make([]StreamRecord, end-position)
+	copy(records, stream.records[position:end])
+
+	return records, end, nil
+}
+
+// StreamPosition is the position of the record with sequenceNumber
+func (s *InnerStorage) StreamPosition(streamArn string, sequenceNumber string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stream, ok := s.streams[streamArn]
+	if !ok {
+		return 0, ErrStreamNotFound
+	}
+	for i, record := range stream.records {
+		if record.SequenceNumber == sequenceNumber {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("sequence number %s is not found in the stream", sequenceNumber)
+}
+
+// recordStreamEvent keeps the change of an item in txn, it's added to the stream when txn is committed.
+// Nothing is recorded if the table has no enabled stream or the item isn't changed.
+func (txn *Txn) recordStreamEvent(table *InnerTableMetadata, oldEntry *core.Entry, newEntry *core.Entry) {
+	stream := table.stream
+	if stream == nil {
+		return
+	}
+
+	var eventName string
+	var keyEntry *core.Entry
+	switch {
+	case oldEntry == nil && newEntry == nil:
+		return
+	case oldEntry == nil:
+		eventName = STREAM_EVENT_INSERT
+		keyEntry = newEntry
+	case newEntry == nil:
+		eventName = STREAM_EVENT_REMOVE
+		keyEntry = oldEntry
+	default:
+		if (core.AttributeValue{M: &oldEntry.Body}).Equal(core.AttributeValue{M: &newEntry.Body}) {
+			return
+		}
+		eventName = STREAM_EVENT_MODIFY
+		keyEntry = newEntry
+	}
+
+	keys := make(map[string]core.AttributeValue)
+	keys[table.PartitionKeySchema.AttributeName] = keyEntry.Body[table.PartitionKeySchema.AttributeName]
+	if table.SortKeySchema != nil {
+		keys[table.SortKeySchema.AttributeName] = keyEntry.Body[table.SortKeySchema.AttributeName]
+	}
+	record := StreamRecord{
+		EventID:                     uuid.New().String(),
+		EventName:                   eventName,
+		ApproximateCreationDateTime: time.Now(),
+		Keys:                        keys,
+	}
+	record.SizeBytes = (&core.Entry{Body: keys}).Size()
+
+	if oldEntry != nil && (stream.StreamViewType == types.StreamViewTypeOldImage || stream.StreamViewType == types.StreamViewTypeNewAndOldImages) {
+		record.OldImage = oldEntry.Clone().Body
+		record.SizeBytes += oldEntry.Size()
+	}
+	if newEntry != nil && (stream.StreamViewType == types.StreamViewTypeNewImage || stream.StreamViewType == types.StreamViewTypeNewAndOldImages) {
+		record.NewImage = newEntry.Clone().Body
+		record.SizeBytes += newEntry.Size()
+	}
+
+	txn.streamRecords = append(txn.streamRecords, pendingStreamRecord{stream: stream, record: record})
+}
+
+// appendStreamRecords adds the records of a committed txn to their streams, callers must hold the mutex
+func (s *InnerStorage) appendStreamRecords(records []pendingStreamRecord) {
+	for _, pending := range records {
+		s.streamSequenceNumber++
+		pending.record.SequenceNumber = fmt.Sprintf("%021d", s.streamSequenceNumber)
+		pending.stream.records = append(pending.stream.records, pending.record)
+	}
+}
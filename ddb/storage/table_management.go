@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/ocowchun/baddb/ddb/core"
+	"golang.org/x/time/rate"
 )
 
 const METADATA_TABLE_NAME = "baddb_table_metadata"
@@ -14,6 +15,8 @@ type TableMetadata struct {
 	tableDelaySeconds   int
 	gsiDelaySeconds     int
 	unprocessedRequests uint32
+	// indexDelaySeconds overrides gsiDelaySeconds of the GSIs it names
+	indexDelaySeconds map[string]int
 }
 
 // TODO: ensure update TableMetaDatas is thread safe
@@ -44,6 +47,22 @@ func (s *InnerStorage) extractTableMetadata(entry *core.Entry) (*TableMetadata,
 		}
 	}
 
+	indexDelaySeconds := make(map[string]int)
+	if indexDelayAttr, ok := entry.Body["indexDelaySeconds"]; ok {
+		if indexDelayAttr.M == nil {
+			return nil, fmt.Errorf("indexDelaySeconds should be M, but got %s", indexDelayAttr)
+		}
+		for indexName, delayAttr := range *indexDelayAttr.M {
+			if delayAttr.N == nil {
+				return nil, fmt.Errorf("indexDelaySeconds of %s should be N, but got %s", indexName, delayAttr)
+			}
+			indexDelaySeconds[indexName], err = strconv.Atoi(*delayAttr.N)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	unprocessedRequests := uint32(0)
 	if unprocessedAttr, ok := entry.Body["unprocessedRequests"]; ok {
 		val, err := strconv.Atoi(*unprocessedAttr.N)
@@ -57,6 +76,7 @@ func (s *InnerStorage) extractTableMetadata(entry *core.Entry) (*TableMetadata,
 			tableName:           tableName,
 			tableDelaySeconds:   tableDelaySeconds,
 			gsiDelaySeconds:     gsiDelaySeconds,
+			indexDelaySeconds:   indexDelaySeconds,
 			unprocessedRequests: unprocessedRequests,
 		},
 		nil
@@ -67,10 +87,153 @@ func (s *InnerStorage) updateTableMetadata(tableMetadata *TableMetadata) error {
 	if !ok {
 		return fmt.Errorf("table %s not found", tableMetadata.tableName)
 	}
+	for indexName := range tableMetadata.indexDelaySeconds {
+		if gsi, ok := m.GlobalSecondaryIndexSettings[indexName]; !ok || gsi.isLocal {
+			return fmt.Errorf("global secondary index %s not found", indexName)
+		}
+	}
 
 	m.tableDelaySeconds = tableMetadata.tableDelaySeconds
 	m.gsiDelaySeconds = tableMetadata.gsiDelaySeconds
+	// a GSI left out of indexDelaySeconds goes back to gsiDelaySeconds
+	for indexName, gsi := range m.GlobalSecondaryIndexSettings {
+		gsi.delaySeconds = nil
+		if delaySeconds, ok := tableMetadata.indexDelaySeconds[indexName]; ok {
+			gsi.delaySeconds = &delaySeconds
+		}
+		m.GlobalSecondaryIndexSettings[indexName] = gsi
+	}
 	m.unprocessedRequests.Store(tableMetadata.unprocessedRequests)
 
 	return nil
 }
+
+// UpdateDelaySeconds sets tableDelaySeconds and gsiDelaySeconds of all tableNames at once, clearing the delays of
+// their GSIs set by indexDelaySeconds. None of them is updated when any table is not found
+func (s *InnerStorage) UpdateDelaySeconds(tableNames []string, tableDelaySeconds int, gsiDelaySeconds int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, tableName := range tableNames {
+		if _, ok := s.TableMetaDatas[tableName]; !ok {
+			return fmt.Errorf("table %s not found", tableName)
+		}
+	}
+
+	for _, tableName := range tableNames {
+		m := s.TableMetaDatas[tableName]
+		m.tableDelaySeconds = tableDelaySeconds
+		m.gsiDelaySeconds = gsiDelaySeconds
+		for indexName, gsi := range m.GlobalSecondaryIndexSettings {
+			gsi.delaySeconds = nil
+			m.GlobalSecondaryIndexSettings[indexName] = gsi
+		}
+	}
+
+	return nil
+}
+
+// UpdatePartitionLimit sets the maximum item count and size in bytes of a single partition of all tableNames,
+// none of them is updated when any table is not found
+func (s *InnerStorage) UpdatePartitionLimit(tableNames []string, maxItemCount int, maxSizeBytes int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, tableName := range tableNames {
+		if _, ok := s.TableMetaDatas[tableName]; !ok {
+			return fmt.Errorf("table %s not found", tableName)
+		}
+	}
+
+	for _, tableName := range tableNames {
+		m := s.TableMetaDatas[tableName]
+		m.maxPartitionItemCount = maxItemCount
+		m.maxPartitionSizeBytes = maxSizeBytes
+	}
+
+	return nil
+}
+
+// UpdateTimeToLive sets the TTL attribute of tableName, reads skip the items expired by it. A nil attributeName disables TTL
+func (s *InnerStorage) UpdateTimeToLive(tableName string, attributeName *string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	m, ok := s.TableMetaDatas[tableName]
+	if !ok {
+		return fmt.Errorf("table %s not found", tableName)
+	}
+	m.timeToLiveAttributeName = attributeName
+
+	return nil
+}
+
+// ResetRateLimiters refills the rate limiters of all tableNames and their GSIs to full burst,
+// none of them is reset when any table is not found
+func (s *InnerStorage) ResetRateLimiters(tableNames []string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, tableName := range tableNames {
+		if _, ok := s.TableMetaDatas[tableName]; !ok {
+			return fmt.Errorf("table %s not found", tableName)
+		}
+	}
+
+	for _, tableName := range tableNames {
+		m := s.TableMetaDatas[tableName]
+		m.readRateLimiter = rate.NewLimiter(m.readRateLimiter.Limit(), m.readRateLimiter.Burst())
+		m.writeRateLimiter = rate.NewLimiter(m.writeRateLimiter.Limit(), m.writeRateLimiter.Burst())
+		for indexName, gsi := range m.GlobalSecondaryIndexSettings {
+			if gsi.isLocal {
+				continue
+			}
+			gsi.readRateLimiter = rate.NewLimiter(gsi.readRateLimiter.Limit(), gsi.readRateLimiter.Burst())
+			m.GlobalSecondaryIndexSettings[indexName] = gsi
+		}
+	}
+
+	return nil
+}
+
+// DropTable drops the sqlite tables of tableName and its GSIs and forgets the table,
+// so creating a table with the same name starts from an empty table
+func (s *InnerStorage) DropTable(tableName string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tableMetadata, exists := s.TableMetaDatas[tableName]
+	if !exists {
+		return fmt.Errorf("table %s not found", tableName)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	sqlTableNames := []string{tableMetadata.Name}
+	for _, gsi := range tableMetadata.GlobalSecondaryIndexSettings {
+		sqlTableNames = append(sqlTableNames, gsi.IndexTableName)
+	}
+	for _, sqlTableName := range sqlTableNames {
+		if _, err := tx.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", sqlTableName)); err != nil {
+			return fmt.Errorf("failed to drop table %s: %w", sqlTableName, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit drop table transaction: %w", err)
+	}
+
+	// the streams of a dropped table go with it, so their records don't stay in memory
+	for streamArn, stream := range s.streams {
+		if stream.TableName == tableName {
+			delete(s.streams, streamArn)
+		}
+	}
+	delete(s.TableMetaDatas, tableName)
+
+	return nil
+}
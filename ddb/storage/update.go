@@ -14,6 +14,8 @@ type UpdateRequest struct {
 	UpdateOperation *update.UpdateOperation
 	TableName       string
 	Condition       *condition.Condition
+	// ValidateEntry, when set, checks the updated item before it's written
+	ValidateEntry func(entry *core.Entry) error
 }
 
 type UpdateResponse struct {
@@ -68,20 +70,24 @@ func (s *InnerStorage) UpdateWithTransaction(req *UpdateRequest, txn *Txn) (*Upd
 		return nil, err
 	}
 
+	var currentEntry *core.Entry
 	if entry == nil {
 		entry = &core.Entry{
 			Body: make(map[string]core.AttributeValue),
 		}
+	} else {
+		currentEntry = entry.Clone()
 	}
 	oldEntry := entry.Clone()
 
+	// the condition must see the current value, so it is checked before the update operation is performed
 	if req.Condition != nil {
 		matched, err := req.Condition.Check(entry)
 		if err != nil {
 			return nil, err
 		}
 		if !matched {
-			return nil, &ConditionalCheckFailedException{"The conditional request failed"}
+			return nil, &ConditionalCheckFailedException{Message: "The conditional request failed", Item: currentEntry}
 		}
 	}
 
@@ -96,6 +102,17 @@ func (s *InnerStorage) UpdateWithTransaction(req *UpdateRequest, txn *Txn) (*Upd
 	if err != nil {
 		return nil, err
 	}
+	if entry.Size() > core.MAX_ITEM_SIZE_BYTES {
+		return nil, ErrItemSizeExceeded
+	}
+	if req.ValidateEntry != nil {
+		if err := req.ValidateEntry(entry); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.checkPartitionLimit(entry, tableMetadata, txn); err != nil {
+		return nil, err
+	}
 
 	entryWrapper := &EntryWrapper{
 		Entry:     entry,
@@ -104,7 +121,7 @@ func (s *InnerStorage) UpdateWithTransaction(req *UpdateRequest, txn *Txn) (*Upd
 	}
 
 	// condition checked in above
-	err = s.put(entryWrapper, tableMetadata, nil, txn.tx)
+	_, err = s.put(entryWrapper, tableMetadata, nil, txn)
 	if err != nil {
 		return nil, err
 	}
@@ -1,12 +1,21 @@
 package storage
 
-import "hash/fnv"
+import (
+	"hash/fnv"
 
+	"github.com/ocowchun/baddb/ddb/scan"
+)
+
+// TOTAL_SEGMENTS is the max TotalSegments of a parallel scan, shard ids are in [0, TOTAL_SEGMENTS) and a scan
+// segment contains the rows where shard_id % TotalSegments = Segment
 // https://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_Scan.html#DDB-Scan-request-TotalSegments
-const TOTAL_SEGMENTS = 1000000
+const TOTAL_SEGMENTS = scan.MAX_TOTAL_SEGMENTS
+
+// ShardIdBuilder maps a partition key to its shard id, the result must be in [0, TOTAL_SEGMENTS)
+type ShardIdBuilder func(partitionKey []byte) int32
 
 func buildShardId(bs []byte) int32 {
 	h := fnv.New32a()
 	h.Write(bs)
-	return int32(h.Sum32() % TOTAL_SEGMENTS) // for testing purpose, shard id is 0-999
+	return int32(h.Sum32() % TOTAL_SEGMENTS)
 }
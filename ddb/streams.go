@@ -0,0 +1,350 @@
+package ddb
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ocowchun/baddb/ddb/core"
+	"github.com/ocowchun/baddb/ddb/storage"
+)
+
+const (
+	STREAM_STATUS_ENABLED  = "ENABLED"
+	STREAM_STATUS_DISABLED = "DISABLED"
+)
+
+const (
+	SHARD_ITERATOR_TYPE_TRIM_HORIZON          = "TRIM_HORIZON"
+	SHARD_ITERATOR_TYPE_LATEST                = "LATEST"
+	SHARD_ITERATOR_TYPE_AT_SEQUENCE_NUMBER    = "AT_SEQUENCE_NUMBER"
+	SHARD_ITERATOR_TYPE_AFTER_SEQUENCE_NUMBER = "AFTER_SEQUENCE_NUMBER"
+)
+
+// MAX_GET_RECORDS_LIMIT is the default and the max number of records GetRecords returns at once
+const MAX_GET_RECORDS_LIMIT = 1000
+
+type DescribeStreamInput struct {
+	StreamArn             *string
+	Limit                 *int32
+	ExclusiveStartShardId *string
+}
+
+type SequenceNumberRange struct {
+	StartingSequenceNumber *string
+	// EndingSequenceNumber is nil while the shard is open
+	EndingSequenceNumber *string
+}
+
+type Shard struct {
+	ShardId             *string
+	SequenceNumberRange *SequenceNumberRange
+}
+
+type StreamDescription struct {
+	StreamArn               *string
+	StreamLabel             *string
+	StreamStatus            string
+	StreamViewType          types.StreamViewType
+	CreationRequestDateTime *time.Time
+	TableName               *string
+	KeySchema               []types.KeySchemaElement
+	Shards                  []Shard
+}
+
+type DescribeStreamOutput struct {
+	StreamDescription *StreamDescription
+}
+
+type GetShardIteratorInput struct {
+	StreamArn         *string
+	ShardId           *string
+	ShardIteratorType string
+	// SequenceNumber is required by AT_SEQUENCE_NUMBER and AFTER_SEQUENCE_NUMBER
+	SequenceNumber *string
+}
+
+type GetShardIteratorOutput struct {
+	ShardIterator *string
+}
+
+type GetRecordsInput struct {
+	ShardIterator *string
+	Limit         *int32
+}
+
+type StreamRecord struct {
+	ApproximateCreationDateTime *time.Time
+	Keys                        map[string]core.AttributeValue
+	NewImage                    map[string]core.AttributeValue
+	OldImage                    map[string]core.AttributeValue
+	SequenceNumber              *string
+	SizeBytes                   int64
+	StreamViewType              types.StreamViewType
+}
+
+type Record struct {
+	AwsRegion    string
+	EventID      string
+	EventName    string
+	EventSource  string
+	EventVersion string
+	Dynamodb     *StreamRecord
+}
+
+type GetRecordsOutput struct {
+	Records []Record
+	// NextShardIterator is nil once a disabled stream is fully read
+	NextShardIterator *string
+}
+
+// validateStreamSpecification checks the StreamSpecification of CreateTable and UpdateTable
+func validateStreamSpecification(spec *types.StreamSpecification) error {
+	if spec.StreamEnabled == nil {
+		msg := "1 validation error detected: Value null at 'streamSpecification.streamEnabled' failed to satisfy constraint: Member must not be null"
+		return &ValidationException{Message: msg}
+	}
+
+	if *spec.StreamEnabled {
+		switch spec.StreamViewType {
+		case types.StreamViewTypeKeysOnly, types.StreamViewTypeNewImage, types.StreamViewTypeOldImage, types.StreamViewTypeNewAndOldImages:
+		case "":
+			return &ValidationException{Message: "StreamViewType is required when StreamEnabled is true"}
+		default:
+			msg := fmt.Sprintf("1 validation error detected: Value '%s' at 'streamSpecification.streamViewType' failed to satisfy constraint: Member must satisfy enum value set: [NEW_IMAGE, OLD_IMAGE, NEW_AND_OLD_IMAGES, KEYS_ONLY]", spec.StreamViewType)
+			return &ValidationException{Message: msg}
+		}
+	} else if spec.StreamViewType != "" {
+		return &ValidationException{Message: "StreamViewType can not be specified when StreamEnabled is false"}
+	}
+
+	return nil
+}
+
+// enableStream starts a new stream of table and keeps its ARN and label in table, callers must hold tableLock
+func (svc *Service) enableStream(table *core.TableMetaData, viewType types.StreamViewType, now time.Time) error {
+	label := now.UTC().Format(storage.STREAM_LABEL_LAYOUT)
+	streamArn := fmt.Sprintf("arn:aws:dynamodb:ddblocal:000000000000:table/%s/stream/%s", table.Name, label)
+	if err := svc.storage.EnableStream(table.Name, streamArn, viewType, table.PrimaryKeySchema(), now); err != nil {
+		return err
+	}
+
+	streamEnabled := true
+	table.StreamSpecification = &types.StreamSpecification{
+		StreamEnabled:  &streamEnabled,
+		StreamViewType: viewType,
+	}
+	table.LatestStreamArn = &streamArn
+	table.LatestStreamLabel = &label
+
+	return nil
+}
+
+func streamNotFoundError(streamArn string) error {
+	msg := fmt.Sprintf("Requested resource not found: Stream: %s not found", streamArn)
+	return &types.ResourceNotFoundException{
+		Message: &msg,
+	}
+}
+
+func (svc *Service) DescribeStream(ctx context.Context, input *DescribeStreamInput) (*DescribeStreamOutput, error) {
+	if input.StreamArn == nil {
+		msg := "1 validation error detected: Value null at 'streamArn' failed to satisfy constraint: Member must not be null"
+		return nil, &ValidationException{Message: msg}
+	}
+
+	stream, recordCount, err := svc.storage.DescribeStream(*input.StreamArn)
+	if errors.Is(err, storage.ErrStreamNotFound) {
+		return nil, streamNotFoundError(*input.StreamArn)
+	} else if err != nil {
+		return nil, err
+	}
+
+	status := STREAM_STATUS_ENABLED
+	if !stream.Enabled {
+		status = STREAM_STATUS_DISABLED
+	}
+	label := stream.CreatedAt.UTC().Format(storage.STREAM_LABEL_LAYOUT)
+	createdAt := stream.CreatedAt
+	tableName := stream.TableName
+
+	// the stream has a single shard, it starts with the first record and is closed when the stream is disabled
+	shardId := storage.StreamShardId
+	shard := Shard{
+		ShardId:             &shardId,
+		SequenceNumberRange: &SequenceNumberRange{},
+	}
+	if recordCount > 0 {
+		records, _, err := svc.storage.StreamRecords(stream.Arn, 0, 1)
+		if err != nil {
+			return nil, err
+		}
+		shard.SequenceNumberRange.StartingSequenceNumber = &records[0].SequenceNumber
+
+		if !stream.Enabled {
+			records, _, err := svc.storage.StreamRecords(stream.Arn, recordCount-1, 1)
+			if err != nil {
+				return nil, err
+			}
+			shard.SequenceNumberRange.EndingSequenceNumber = &records[0].SequenceNumber
+		}
+	}
+
+	output := &DescribeStreamOutput{
+		StreamDescription: &StreamDescription{
+			StreamArn:               &stream.Arn,
+			StreamLabel:             &label,
+			StreamStatus:            status,
+			StreamViewType:          stream.StreamViewType,
+			CreationRequestDateTime: &createdAt,
+			TableName:               &tableName,
+			KeySchema:               stream.KeySchema,
+			Shards:                  []Shard{shard},
+		},
+	}
+
+	return output, nil
+}
+
+func (svc *Service) GetShardIterator(ctx context.Context, input *GetShardIteratorInput) (*GetShardIteratorOutput, error) {
+	if input.StreamArn == nil || input.ShardId == nil {
+		msg := "1 validation error detected: StreamArn and ShardId must not be null"
+		return nil, &ValidationException{Message: msg}
+	}
+
+	_, recordCount, err := svc.storage.DescribeStream(*input.StreamArn)
+	if errors.Is(err, storage.ErrStreamNotFound) {
+		return nil, streamNotFoundError(*input.StreamArn)
+	} else if err != nil {
+		return nil, err
+	}
+	if *input.ShardId != storage.StreamShardId {
+		msg := fmt.Sprintf("Requested resource not found: Shard does not exist: %s", *input.ShardId)
+		return nil, &types.ResourceNotFoundException{
+			Message: &msg,
+		}
+	}
+
+	var position int
+	switch input.ShardIteratorType {
+	case SHARD_ITERATOR_TYPE_TRIM_HORIZON:
+		position = 0
+	case SHARD_ITERATOR_TYPE_LATEST:
+		position = recordCount
+	case SHARD_ITERATOR_TYPE_AT_SEQUENCE_NUMBER, SHARD_ITERATOR_TYPE_AFTER_SEQUENCE_NUMBER:
+		if input.SequenceNumber == nil {
+			msg := fmt.Sprintf("SequenceNumber is required when ShardIteratorType is %s", input.ShardIteratorType)
+			return nil, &ValidationException{Message: msg}
+		}
+		position, err = svc.storage.StreamPosition(*input.StreamArn, *input.SequenceNumber)
+		if err != nil {
+			msg := fmt.Sprintf("Invalid SequenceNumber: %s", *input.SequenceNumber)
+			return nil, &ValidationException{Message: msg}
+		}
+		if input.ShardIteratorType == SHARD_ITERATOR_TYPE_AFTER_SEQUENCE_NUMBER {
+			position++
+		}
+	default:
+		msg := fmt.Sprintf("1 validation error detected: Value '%s' at 'shardIteratorType' failed to satisfy constraint: Member must satisfy enum value set: [AFTER_SEQUENCE_NUMBER, LATEST, AT_SEQUENCE_NUMBER, TRIM_HORIZON]", input.ShardIteratorType)
+		return nil, &ValidationException{Message: msg}
+	}
+
+	shardIterator := encodeShardIterator(*input.StreamArn, position)
+	output := &GetShardIteratorOutput{
+		ShardIterator: &shardIterator,
+	}
+	return output, nil
+}
+
+func (svc *Service) GetRecords(ctx context.Context, input *GetRecordsInput) (*GetRecordsOutput, error) {
+	if input.ShardIterator == nil {
+		msg := "1 validation error detected: Value null at 'shardIterator' failed to satisfy constraint: Member must not be null"
+		return nil, &ValidationException{Message: msg}
+	}
+
+	limit := MAX_GET_RECORDS_LIMIT
+	if input.Limit != nil {
+		if *input.Limit < 1 || *input.Limit > MAX_GET_RECORDS_LIMIT {
+			msg := fmt.Sprintf("1 validation error detected: Value '%d' at 'limit' failed to satisfy constraint: Member must have value less than or equal to %d and greater than or equal to 1", *input.Limit, MAX_GET_RECORDS_LIMIT)
+			return nil, &ValidationException{Message: msg}
+		}
+		limit = int(*input.Limit)
+	}
+
+	streamArn, position, err := decodeShardIterator(*input.ShardIterator)
+	if err != nil {
+		return nil, &ValidationException{Message: "Invalid ShardIterator"}
+	}
+
+	stream, recordCount, err := svc.storage.DescribeStream(streamArn)
+	if errors.Is(err, storage.ErrStreamNotFound) {
+		return nil, streamNotFoundError(streamArn)
+	} else if err != nil {
+		return nil, err
+	}
+
+	storageRecords, nextPosition, err := svc.storage.StreamRecords(streamArn, position, limit)
+	if err != nil {
+		return nil, &ValidationException{Message: "Invalid ShardIterator"}
+	}
+
+	records := make([]Record, len(storageRecords))
+	for i, r := range storageRecords {
+		records[i] = Record{
+			AwsRegion:    "ddblocal",
+			EventID:      r.EventID,
+			EventName:    r.EventName,
+			EventSource:  "aws:dynamodb",
+			EventVersion: "1.1",
+			Dynamodb: &StreamRecord{
+				ApproximateCreationDateTime: &r.ApproximateCreationDateTime,
+				Keys:                        r.Keys,
+				NewImage:                    r.NewImage,
+				OldImage:                    r.OldImage,
+				SequenceNumber:              &r.SequenceNumber,
+				SizeBytes:                   int64(r.SizeBytes),
+				StreamViewType:              stream.StreamViewType,
+			},
+		}
+	}
+
+	output := &GetRecordsOutput{
+		Records: records,
+	}
+	// a disabled stream gets no more records, so the shard is closed once it's fully read
+	if stream.Enabled || nextPosition < recordCount {
+		nextShardIterator := encodeShardIterator(streamArn, nextPosition)
+		output.NextShardIterator = &nextShardIterator
+	}
+
+	return output, nil
+}
+
+// encodeShardIterator hides the position of a shard iterator, it's opaque to clients like the one of DynamoDB
+func encodeShardIterator(streamArn string, position int) string {
+	raw := fmt.Sprintf("%s|%s|%d", streamArn, storage.StreamShardId, position)
+	return base64.StdEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeShardIterator(shardIterator string) (string, int, error) {
+	bs, err := base64.StdEncoding.DecodeString(shardIterator)
+	if err != nil {
+		return "", 0, err
+	}
+
+	parts := strings.Split(string(bs), "|")
+	if len(parts) != 3 || parts[1] != storage.StreamShardId {
+		return "", 0, errors.New("invalid shard iterator")
+	}
+	position, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return "", 0, err
+	}
+
+	return parts[0], position, nil
+}
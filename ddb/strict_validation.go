@@ -0,0 +1,60 @@
+package ddb
+
+import (
+	"fmt"
+
+	"github.com/ocowchun/baddb/ddb/core"
+	"github.com/ocowchun/baddb/ddb/request"
+)
+
+// MAX_NESTING_DEPTH is how deep lists and maps can be nested in an item
+const MAX_NESTING_DEPTH = 32
+
+// validateStrictItem runs the validations DynamoDB does on a written item but baddb skips unless Config.Strict is set:
+// empty string or binary GSI and LSI key attributes and the nesting depth of lists and maps. Empty table key
+// attributes are always rejected by the request builders
+func (svc *Service) validateStrictItem(entry *core.Entry, table *core.TableMetaData) error {
+	if !svc.config.Strict || table.PartitionKeySchema == nil {
+		return nil
+	}
+
+	indexes := append(append([]core.GlobalSecondaryIndexSetting{}, table.GlobalSecondaryIndexSettings...), table.LocalSecondaryIndexSettings...)
+	for _, gsi := range indexes {
+		for _, keySchema := range []*core.KeySchema{gsi.PartitionKeySchema, gsi.SortKeySchema} {
+			if keySchema == nil {
+				continue
+			}
+			if emptyValue, ok := request.EmptyKeyValue(entry, keySchema.AttributeName); ok {
+				return &ValidationException{
+					Message: fmt.Sprintf("One or more parameter values are not valid. A value specified for a secondary index key is not supported. The AttributeValue for a key attribute cannot contain an empty %s value. IndexName: %s, IndexKey: %s", emptyValue, *gsi.IndexName, keySchema.AttributeName),
+				}
+			}
+		}
+	}
+
+	for _, val := range entry.Body {
+		if nestingDepth(val) > MAX_NESTING_DEPTH {
+			return &ValidationException{
+				Message: "Nesting Levels have exceeded supported limits",
+			}
+		}
+	}
+
+	return nil
+}
+
+func nestingDepth(val core.AttributeValue) int {
+	depth := 0
+	if val.L != nil {
+		for _, v := range *val.L {
+			depth = max(depth, nestingDepth(v))
+		}
+		return depth + 1
+	} else if val.M != nil {
+		for _, v := range *val.M {
+			depth = max(depth, nestingDepth(v))
+		}
+		return depth + 1
+	}
+	return depth
+}
@@ -65,6 +65,41 @@ func (o *UpdateOperation) Perform(entry *core.Entry) error {
 	return nil
 }
 
+// Paths returns the paths updated by the SET, REMOVE, ADD and DELETE clauses
+func (o *UpdateOperation) Paths() ([]core.PathOperand, error) {
+	astPaths := make([]ast.PathOperand, 0)
+	if o.updateExpression.Set != nil {
+		for _, action := range o.updateExpression.Set.Actions {
+			astPaths = append(astPaths, action.Path)
+		}
+	}
+	if o.updateExpression.Remove != nil {
+		for _, path := range o.updateExpression.Remove.Paths {
+			astPaths = append(astPaths, path)
+		}
+	}
+	if o.updateExpression.Add != nil {
+		for _, action := range o.updateExpression.Add.Actions {
+			astPaths = append(astPaths, action.Path)
+		}
+	}
+	if o.updateExpression.Delete != nil {
+		for _, action := range o.updateExpression.Delete.Actions {
+			astPaths = append(astPaths, action.Path)
+		}
+	}
+
+	paths := make([]core.PathOperand, len(astPaths))
+	for i, astPath := range astPaths {
+		path, err := o.buildPath(astPath)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = path
+	}
+	return paths, nil
+}
+
 func (o *UpdateOperation) performSetClause(entry *core.Entry) error {
 	for _, action := range o.updateExpression.Set.Actions {
 		path, err := o.buildPath(action.Path)
@@ -1,6 +1,7 @@
 package update
 
 import (
+	"errors"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/ocowchun/baddb/ddb/core"
 	"testing"
@@ -523,6 +524,68 @@ func TestPerformAddClause(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "Add to number set attribute",
+			entry: &core.Entry{
+				Body: map[string]core.AttributeValue{
+					"scores": {NS: &[]string{"1", "2"}},
+				},
+			},
+			updateExpressionContent: "ADD scores :scores",
+			expressionAttributeValues: map[string]core.AttributeValue{
+				":scores": {NS: &[]string{"2", "3"}},
+			},
+			expected: map[string]core.AttributeValue{
+				"scores": {NS: &[]string{"1", "2", "3"}},
+			},
+			expectError: false,
+		},
+		{
+			name: "Add to binary set attribute",
+			entry: &core.Entry{
+				Body: map[string]core.AttributeValue{
+					"blobs": {BS: &[][]byte{{1}, {2}}},
+				},
+			},
+			updateExpressionContent: "ADD blobs :blobs",
+			expressionAttributeValues: map[string]core.AttributeValue{
+				":blobs": {BS: &[][]byte{{2}, {3}}},
+			},
+			expected: map[string]core.AttributeValue{
+				"blobs": {BS: &[][]byte{{1}, {2}, {3}}},
+			},
+			expectError: false,
+		},
+		{
+			name: "Add set to non-existent attribute",
+			entry: &core.Entry{
+				Body: map[string]core.AttributeValue{},
+			},
+			updateExpressionContent: "ADD blobs :blobs",
+			expressionAttributeValues: map[string]core.AttributeValue{
+				":blobs": {BS: &[][]byte{{1}}},
+			},
+			expected: map[string]core.AttributeValue{
+				"blobs": {BS: &[][]byte{{1}}},
+			},
+			expectError: false,
+		},
+		{
+			name: "Add to set attribute of another type",
+			entry: &core.Entry{
+				Body: map[string]core.AttributeValue{
+					"tags": {SS: &[]string{"1"}},
+				},
+			},
+			updateExpressionContent: "ADD tags :newTags",
+			expressionAttributeValues: map[string]core.AttributeValue{
+				":newTags": {NS: &[]string{"1"}},
+			},
+			expected: map[string]core.AttributeValue{
+				"tags": {SS: &[]string{"1"}},
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -540,6 +603,9 @@ func TestPerformAddClause(t *testing.T) {
 			if (err != nil) != tt.expectError {
 				t.Fatalf("Expected error: %v, got: %v", tt.expectError, err)
 			}
+			if tt.expectError && !errors.Is(err, core.ErrIncorrectOperandType) {
+				t.Fatalf("Expected ErrIncorrectOperandType, got: %v", err)
+			}
 
 			if err == nil {
 				for key, expectedValue := range tt.expected {
@@ -560,6 +626,7 @@ func TestPerformDeleteClause(t *testing.T) {
 		expressionAttributeNames  map[string]string
 		expressionAttributeValues map[string]core.AttributeValue
 		expected                  map[string]core.AttributeValue
+		removed                   []string
 		expectError               bool
 	}{
 		{
@@ -578,6 +645,75 @@ func TestPerformDeleteClause(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name: "Delete many elements from set attribute",
+			entry: &core.Entry{
+				Body: map[string]core.AttributeValue{
+					"tags": {SS: &[]string{"tag1", "tag2", "tag3"}},
+				},
+			},
+			updateExpressionContent: "DELETE tags :removeTags",
+			expressionAttributeValues: map[string]core.AttributeValue{
+				":removeTags": {SS: &[]string{"tag1", "tag3"}},
+			},
+			expected: map[string]core.AttributeValue{
+				"tags": {SS: &[]string{"tag2"}},
+			},
+			expectError: false,
+		},
+		{
+			name: "Delete every element from set attribute",
+			entry: &core.Entry{
+				Body: map[string]core.AttributeValue{
+					"tags":   {SS: &[]string{"tag1", "tag2"}},
+					"scores": {NS: &[]string{"1"}},
+					"blobs":  {BS: &[][]byte{{1}, {2}}},
+				},
+			},
+			updateExpressionContent: "DELETE tags :removeTags, scores :removeScores, blobs :removeBlobs",
+			expressionAttributeValues: map[string]core.AttributeValue{
+				":removeTags":   {SS: &[]string{"tag1", "tag2", "tag3"}},
+				":removeScores": {NS: &[]string{"1"}},
+				":removeBlobs":  {BS: &[][]byte{{1}, {2}}},
+			},
+			removed:     []string{"tags", "scores", "blobs"},
+			expectError: false,
+		},
+		{
+			name: "Delete from binary set attribute",
+			entry: &core.Entry{
+				Body: map[string]core.AttributeValue{
+					"blobs": {BS: &[][]byte{{1}, {2}, {3}}},
+				},
+			},
+			updateExpressionContent: "DELETE blobs :removeBlobs",
+			expressionAttributeValues: map[string]core.AttributeValue{
+				":removeBlobs": {BS: &[][]byte{{2}}},
+			},
+			expected: map[string]core.AttributeValue{
+				"blobs": {BS: &[][]byte{{1}, {3}}},
+			},
+			expectError: false,
+		},
+		{
+			name: "Delete set from number attribute",
+			entry: &core.Entry{
+				Body: map[string]core.AttributeValue{
+					"views": {N: aws.String("100")},
+				},
+			},
+			updateExpressionContent: "DELETE #views :removeViews",
+			expressionAttributeNames: map[string]string{
+				"#views": "views",
+			},
+			expressionAttributeValues: map[string]core.AttributeValue{
+				":removeViews": {NS: &[]string{"100"}},
+			},
+			expected: map[string]core.AttributeValue{
+				"views": {N: aws.String("100")},
+			},
+			expectError: true,
+		},
 		{
 			name: "Delete non-existent element from set",
 			entry: &core.Entry{
@@ -653,7 +789,43 @@ func TestPerformDeleteClause(t *testing.T) {
 						t.Fatalf("Expected %v for key %s, got %v", expectedValue, key, val)
 					}
 				}
+				for _, key := range tt.removed {
+					if val, ok := tt.entry.Body[key]; ok {
+						t.Fatalf("Expected key %s to be removed, got %v", key, val)
+					}
+				}
 			}
 		})
 	}
 }
+
+func TestUpdateOperationPaths(t *testing.T) {
+	operation, err := BuildUpdateOperation(
+		"SET #info.rating = :rating, actors[1] = :actor REMOVE plot ADD viewCount :one DELETE tags :tags",
+		map[string]string{"#info": "info"},
+		map[string]core.AttributeValue{
+			":rating": {N: aws.String("9")},
+			":actor":  {S: aws.String("actor")},
+			":one":    {N: aws.String("1")},
+			":tags":   {SS: &[]string{"tag"}},
+		},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v, when build operation", err)
+	}
+
+	paths, err := operation.Paths()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"info.rating", "actors[1]", "plot", "viewCount", "tags"}
+	if len(paths) != len(expected) {
+		t.Fatalf("Expected %d paths, got %v", len(expected), paths)
+	}
+	for i, path := range paths {
+		if path.String() != expected[i] {
+			t.Fatalf("Expected path %s, got %s", expected[i], path.String())
+		}
+	}
+}
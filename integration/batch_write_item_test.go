@@ -283,6 +283,59 @@ func TestBatchWriteItem_EmptyRequest(t *testing.T) {
 	}
 }
 
+func TestBatchWriteItem_DuplicateKeys(t *testing.T) {
+	testContext := setupTest(t)
+	ddbLocal := testContext.ddbLocal
+	baddb := testContext.baddb
+	defer testContext.shutdown()
+
+	key := map[string]types.AttributeValue{
+		"year":  &types.AttributeValueMemberN{Value: "2024"},
+		"title": &types.AttributeValueMemberS{Value: "Test Movie"},
+	}
+	writeRequests := []types.WriteRequest{
+		{
+			PutRequest: &types.PutRequest{
+				Item: map[string]types.AttributeValue{
+					"year":     &types.AttributeValueMemberN{Value: "2024"},
+					"title":    &types.AttributeValueMemberS{Value: "Test Movie"},
+					"language": &types.AttributeValueMemberS{Value: "English"},
+				},
+			},
+		},
+		{
+			DeleteRequest: &types.DeleteRequest{
+				Key: key,
+			},
+		},
+	}
+
+	ddbOut, ddbErr := batchWriteItem(ddbLocal, writeRequests)
+	baddbOut, baddbErr := batchWriteItem(baddb, writeRequests)
+
+	if ddbOut != nil || baddbOut != nil {
+		t.Fatalf("Expected nil outputs for duplicate keys, got ddbOut=%v, baddbOut=%v", ddbOut, baddbOut)
+	}
+
+	if ddbErr == nil || baddbErr == nil {
+		t.Fatalf("Expected errors for duplicate keys, got ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+	}
+
+	if !compareWithoutRequestID(ddbErr.Error(), baddbErr.Error()) {
+		t.Fatalf("BatchWriteItem errors differ: ddbErr=%s, baddbErr=%s", ddbErr.Error(), baddbErr.Error())
+	}
+
+	// the batch is rejected as a whole, the put isn't made
+	ddbGetOut, ddbErr := getItem(ddbLocal, key)
+	baddbGetOut, baddbErr := getItem(baddb, key)
+	if ddbErr != nil || baddbErr != nil {
+		t.Fatalf("unexpected error: ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+	}
+	if len(ddbGetOut.Item) != 0 || len(baddbGetOut.Item) != 0 {
+		t.Fatalf("expected no item, got ddbLocal=%v, baddb=%v", ddbGetOut.Item, baddbGetOut.Item)
+	}
+}
+
 func batchWriteItem(client *dynamodb.Client, writeRequests []types.WriteRequest) (*dynamodb.BatchWriteItemOutput, error) {
 	input := &dynamodb.BatchWriteItemInput{
 		RequestItems: map[string][]types.WriteRequest{
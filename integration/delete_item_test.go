@@ -0,0 +1,153 @@
+package integration
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"testing"
+)
+
+func TestDeleteItem_NestedAttributeCondition(t *testing.T) {
+	key := map[string]types.AttributeValue{
+		"year":  &types.AttributeValueMemberN{Value: "1994"},
+		"title": &types.AttributeValueMemberS{Value: "The Shawshank Redemption"},
+	}
+	existsItem := map[string]types.AttributeValue{
+		"year":  &types.AttributeValueMemberN{Value: "1994"},
+		"title": &types.AttributeValueMemberS{Value: "The Shawshank Redemption"},
+		"info": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"status": &types.AttributeValueMemberS{Value: "archived"},
+		}},
+		"language": &types.AttributeValueMemberS{Value: "English"},
+	}
+
+	tests := []struct {
+		name        string
+		status      string
+		expectErr   bool
+		expectFound bool
+	}{
+		{
+			name:        "nested attribute matches",
+			status:      "archived",
+			expectErr:   false,
+			expectFound: false,
+		},
+		{
+			name:        "nested attribute doesn't match",
+			status:      "active",
+			expectErr:   true,
+			expectFound: true,
+		},
+	}
+
+	for _, tt := range tests {
+		testContext := setupTest(t)
+		ddbLocal := testContext.ddbLocal
+		baddb := testContext.baddb
+
+		input := &dynamodb.PutItemInput{
+			TableName: aws.String(TestTableName),
+			Item:      existsItem,
+		}
+		_, err := putItem(ddbLocal, input)
+		if err != nil {
+			t.Fatalf("failed to put existing item in ddbLocal: %v", err)
+		}
+		_, err = putItem(baddb, input)
+		if err != nil {
+			t.Fatalf("failed to put existing item in baddb: %v", err)
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			defer testContext.shutdown()
+			deleteInput := &dynamodb.DeleteItemInput{
+				TableName:           aws.String(TestTableName),
+				Key:                 key,
+				ConditionExpression: aws.String("info.#status = :status"),
+				ExpressionAttributeNames: map[string]string{
+					"#status": "status",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":status": &types.AttributeValueMemberS{Value: tt.status},
+				},
+			}
+
+			_, ddbErr := deleteItem(ddbLocal, deleteInput)
+			_, baddbErr := deleteItem(baddb, deleteInput)
+
+			if (ddbErr != nil) != tt.expectErr {
+				t.Errorf("ddbLocal: expected error=%v, got %v", tt.expectErr, ddbErr)
+			}
+			if (baddbErr != nil) != tt.expectErr {
+				t.Errorf("baddb: expected error=%v, got %v", tt.expectErr, baddbErr)
+			}
+			if tt.expectErr && ddbErr != nil && baddbErr != nil {
+				if !compareWithoutRequestID(ddbErr.Error(), baddbErr.Error()) {
+					t.Errorf("expected errors to match, ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+				}
+			}
+
+			ddbOut, ddbErr := getItem(ddbLocal, key)
+			baddbOut, baddbErr := getItem(baddb, key)
+			if ddbErr != nil || baddbErr != nil {
+				t.Fatalf("unexpected error: ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+			}
+			if (len(ddbOut.Item) != 0) != tt.expectFound || (len(baddbOut.Item) != 0) != tt.expectFound {
+				t.Errorf("expected item found=%v, got ddbLocal=%v, baddb=%v", tt.expectFound, ddbOut.Item, baddbOut.Item)
+			}
+			if tt.expectFound {
+				compareGetItemOutput(ddbOut, baddbOut, t)
+			}
+		})
+	}
+}
+
+func TestDeleteItem_KeyTypeMismatch(t *testing.T) {
+	tests := []struct {
+		name string
+		key  map[string]types.AttributeValue
+	}{
+		{
+			name: "partition key type mismatch",
+			key: map[string]types.AttributeValue{
+				"year":  &types.AttributeValueMemberS{Value: "1994"},
+				"title": &types.AttributeValueMemberS{Value: "The Shawshank Redemption"},
+			},
+		},
+		{
+			name: "sort key type mismatch",
+			key: map[string]types.AttributeValue{
+				"year":  &types.AttributeValueMemberN{Value: "1994"},
+				"title": &types.AttributeValueMemberN{Value: "1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testContext := setupTest(t)
+			ddbLocal := testContext.ddbLocal
+			baddb := testContext.baddb
+			defer testContext.shutdown()
+
+			input := &dynamodb.DeleteItemInput{
+				TableName: aws.String(TestTableName),
+				Key:       tt.key,
+			}
+			_, ddbErr := deleteItem(ddbLocal, input)
+			_, baddbErr := deleteItem(baddb, input)
+
+			if ddbErr == nil || baddbErr == nil {
+				t.Fatalf("expected error for key type mismatch, got ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+			}
+			if !compareWithoutRequestID(ddbErr.Error(), baddbErr.Error()) {
+				t.Errorf("expected errors to match, ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+			}
+		})
+	}
+}
+
+func deleteItem(client *dynamodb.Client, input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	return client.DeleteItem(context.TODO(), input)
+}
@@ -0,0 +1,76 @@
+package integration
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"testing"
+)
+
+func TestExecuteStatement_SelectNestedPaths(t *testing.T) {
+	item := map[string]types.AttributeValue{
+		"year":  &types.AttributeValueMemberN{Value: "1994"},
+		"title": &types.AttributeValueMemberS{Value: "The Shawshank Redemption"},
+		"info": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"rating": &types.AttributeValueMemberN{Value: "9.3"},
+			"actors": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberS{Value: "Tim Robbins"},
+				&types.AttributeValueMemberS{Value: "Morgan Freeman"},
+			}},
+		}},
+	}
+
+	tests := []struct {
+		name      string
+		statement string
+	}{
+		{
+			name:      "nested attribute",
+			statement: `SELECT info.rating FROM movie WHERE "year" = 1994`,
+		},
+		{
+			name:      "list element",
+			statement: `SELECT title, info.actors[1] FROM movie WHERE "year" = 1994`,
+		},
+		{
+			name:      "missing nested attribute",
+			statement: `SELECT title, info.director FROM movie WHERE "year" = 1994`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testContext := setupTest(t)
+			ddbLocal := testContext.ddbLocal
+			baddb := testContext.baddb
+			defer testContext.shutdown()
+
+			input := &dynamodb.PutItemInput{
+				TableName: aws.String(TestTableName),
+				Item:      item,
+			}
+			if _, err := putItem(ddbLocal, input); err != nil {
+				t.Fatalf("failed to put item in ddbLocal: %v", err)
+			}
+			if _, err := putItem(baddb, input); err != nil {
+				t.Fatalf("failed to put item in baddb: %v", err)
+			}
+
+			statementInput := &dynamodb.ExecuteStatementInput{
+				Statement:      aws.String(tt.statement),
+				ConsistentRead: aws.Bool(true),
+			}
+			ddbOut, ddbErr := executeStatement(ddbLocal, statementInput)
+			baddbOut, baddbErr := executeStatement(baddb, statementInput)
+			if ddbErr != nil || baddbErr != nil {
+				t.Fatalf("unexpected error: ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+			}
+			compareItems(ddbOut.Items, baddbOut.Items, t)
+		})
+	}
+}
+
+func executeStatement(client *dynamodb.Client, input *dynamodb.ExecuteStatementInput) (*dynamodb.ExecuteStatementOutput, error) {
+	return client.ExecuteStatement(context.TODO(), input)
+}
@@ -448,6 +448,126 @@ func TestPutItemBehavior(t *testing.T) {
 				"language": &types.AttributeValueMemberS{Value: "English"},
 			},
 		},
+		// Comparison operators on name (string), ordered by UTF-8 bytes
+		{
+			name:      "name less than success",
+			condition: aws.String("info.#name < :name"),
+			expectErr: false,
+			existsItem: map[string]types.AttributeValue{
+				"year":     &types.AttributeValueMemberN{Value: "1994"},
+				"title":    &types.AttributeValueMemberS{Value: "The Shawshank Redemption"},
+				"info":     &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"name": &types.AttributeValueMemberS{Value: "apple"}}},
+				"language": &types.AttributeValueMemberS{Value: "English"},
+			},
+			expressionAttributeNames: map[string]string{
+				"#name": "name",
+			},
+			expressionAttributeValues: map[string]types.AttributeValue{
+				":name": &types.AttributeValueMemberS{Value: "banana"},
+			},
+		},
+		{
+			name:      "name less than failure",
+			condition: aws.String("info.#name < :name"),
+			expectErr: true,
+			existsItem: map[string]types.AttributeValue{
+				"year":     &types.AttributeValueMemberN{Value: "1994"},
+				"title":    &types.AttributeValueMemberS{Value: "The Shawshank Redemption"},
+				"info":     &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"name": &types.AttributeValueMemberS{Value: "banana"}}},
+				"language": &types.AttributeValueMemberS{Value: "English"},
+			},
+			expressionAttributeNames: map[string]string{
+				"#name": "name",
+			},
+			expressionAttributeValues: map[string]types.AttributeValue{
+				":name": &types.AttributeValueMemberS{Value: "apple"},
+			},
+		},
+		{
+			name:      "name greater than success",
+			condition: aws.String("info.#name > :name"),
+			expectErr: false,
+			existsItem: map[string]types.AttributeValue{
+				"year":     &types.AttributeValueMemberN{Value: "1994"},
+				"title":    &types.AttributeValueMemberS{Value: "The Shawshank Redemption"},
+				"info":     &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"name": &types.AttributeValueMemberS{Value: "banana"}}},
+				"language": &types.AttributeValueMemberS{Value: "English"},
+			},
+			expressionAttributeNames: map[string]string{
+				"#name": "name",
+			},
+			expressionAttributeValues: map[string]types.AttributeValue{
+				":name": &types.AttributeValueMemberS{Value: "apple"},
+			},
+		},
+		{
+			name:      "name greater than failure",
+			condition: aws.String("info.#name > :name"),
+			expectErr: true,
+			existsItem: map[string]types.AttributeValue{
+				"year":     &types.AttributeValueMemberN{Value: "1994"},
+				"title":    &types.AttributeValueMemberS{Value: "The Shawshank Redemption"},
+				"info":     &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"name": &types.AttributeValueMemberS{Value: "apple"}}},
+				"language": &types.AttributeValueMemberS{Value: "English"},
+			},
+			expressionAttributeNames: map[string]string{
+				"#name": "name",
+			},
+			expressionAttributeValues: map[string]types.AttributeValue{
+				":name": &types.AttributeValueMemberS{Value: "applesauce"},
+			},
+		},
+		{
+			name:      "name uppercase before lowercase",
+			condition: aws.String("info.#name < :name"),
+			expectErr: false,
+			existsItem: map[string]types.AttributeValue{
+				"year":     &types.AttributeValueMemberN{Value: "1994"},
+				"title":    &types.AttributeValueMemberS{Value: "The Shawshank Redemption"},
+				"info":     &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"name": &types.AttributeValueMemberS{Value: "Zebra"}}},
+				"language": &types.AttributeValueMemberS{Value: "English"},
+			},
+			expressionAttributeNames: map[string]string{
+				"#name": "name",
+			},
+			expressionAttributeValues: map[string]types.AttributeValue{
+				":name": &types.AttributeValueMemberS{Value: "apple"},
+			},
+		},
+		{
+			name:      "name multibyte after ascii",
+			condition: aws.String("info.#name > :name"),
+			expectErr: false,
+			existsItem: map[string]types.AttributeValue{
+				"year":     &types.AttributeValueMemberN{Value: "1994"},
+				"title":    &types.AttributeValueMemberS{Value: "The Shawshank Redemption"},
+				"info":     &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"name": &types.AttributeValueMemberS{Value: "élan"}}},
+				"language": &types.AttributeValueMemberS{Value: "English"},
+			},
+			expressionAttributeNames: map[string]string{
+				"#name": "name",
+			},
+			expressionAttributeValues: map[string]types.AttributeValue{
+				":name": &types.AttributeValueMemberS{Value: "zebra"},
+			},
+		},
+		{
+			name:      "name ordered by utf-8 bytes",
+			condition: aws.String("info.#name < :name"),
+			expectErr: false,
+			existsItem: map[string]types.AttributeValue{
+				"year":     &types.AttributeValueMemberN{Value: "1994"},
+				"title":    &types.AttributeValueMemberS{Value: "The Shawshank Redemption"},
+				"info":     &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"name": &types.AttributeValueMemberS{Value: "\uFF5E"}}},
+				"language": &types.AttributeValueMemberS{Value: "English"},
+			},
+			expressionAttributeNames: map[string]string{
+				"#name": "name",
+			},
+			expressionAttributeValues: map[string]types.AttributeValue{
+				":name": &types.AttributeValueMemberS{Value: "\U0001F600"},
+			},
+		},
 		// Complex expressions with parentheses
 		{
 			name:      "complex expression success",
@@ -546,6 +666,50 @@ func TestPutItemBehavior(t *testing.T) {
 	}
 }
 
+func TestPutItem_KeyTypeMismatch(t *testing.T) {
+	tests := []struct {
+		name string
+		item map[string]types.AttributeValue
+	}{
+		{
+			name: "partition key type mismatch",
+			item: map[string]types.AttributeValue{
+				"year":  &types.AttributeValueMemberS{Value: "1994"},
+				"title": &types.AttributeValueMemberS{Value: "The Shawshank Redemption"},
+			},
+		},
+		{
+			name: "sort key type mismatch",
+			item: map[string]types.AttributeValue{
+				"year":  &types.AttributeValueMemberN{Value: "1994"},
+				"title": &types.AttributeValueMemberN{Value: "1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testContext := setupTest(t)
+			ddbLocal := testContext.ddbLocal
+			baddb := testContext.baddb
+			defer testContext.shutdown()
+
+			input := &dynamodb.PutItemInput{
+				TableName: aws.String(TestTableName),
+				Item:      tt.item,
+			}
+			_, ddbErr := putItem(ddbLocal, input)
+			_, baddbErr := putItem(baddb, input)
+
+			if ddbErr == nil || baddbErr == nil {
+				t.Fatalf("expected error for key type mismatch, got ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+			}
+			if !compareWithoutRequestID(ddbErr.Error(), baddbErr.Error()) {
+				t.Errorf("expected errors to match, ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+			}
+		})
+	}
+}
+
 func putItem(client *dynamodb.Client, input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
 	return client.PutItem(context.TODO(), input)
 }
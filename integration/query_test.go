@@ -2,6 +2,7 @@ package integration
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -336,6 +337,75 @@ func TestQueryWithReservedWord(t *testing.T) {
 	shutdown()
 }
 
+func TestQueryWithReservedWordKeysAndBetween(t *testing.T) {
+	ddbLocal := newDdbLocalClient()
+	baddb := newBaddbClient()
+	shutdown := startServer()
+	defer shutdown()
+
+	// both key attributes are reserved words
+	tableName := "event"
+	_, _ = deleteTable(ddbLocal, tableName)
+	createTableInput := &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("date"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("timestamp"), AttributeType: types.ScalarAttributeTypeN},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("date"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("timestamp"), KeyType: types.KeyTypeRange},
+		},
+		BillingMode: types.BillingModePayPerRequest,
+	}
+	_, ddbErr := ddbLocal.CreateTable(context.TODO(), createTableInput)
+	_, baddbErr := baddb.CreateTable(context.TODO(), createTableInput)
+	if ddbErr != nil || baddbErr != nil {
+		t.Fatalf("failed to create table: ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+	}
+	defer deleteTable(ddbLocal, tableName)
+
+	for _, date := range []string{"2025-01-01", "2025-01-02"} {
+		for _, timestamp := range []string{"5", "10", "15", "20", "100"} {
+			item := map[string]types.AttributeValue{
+				"date":      &types.AttributeValueMemberS{Value: date},
+				"timestamp": &types.AttributeValueMemberN{Value: timestamp},
+			}
+			_, ddbErr := ddbLocal.PutItem(context.TODO(), &dynamodb.PutItemInput{TableName: aws.String(tableName), Item: item})
+			_, baddbErr := baddb.PutItem(context.TODO(), &dynamodb.PutItemInput{TableName: aws.String(tableName), Item: item})
+			if ddbErr != nil || baddbErr != nil {
+				t.Fatalf("failed to put item: ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+			}
+		}
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(tableName),
+		KeyConditionExpression: aws.String("#d = :d AND #ts BETWEEN :a AND :b"),
+		ExpressionAttributeNames: map[string]string{
+			"#d":  "date",
+			"#ts": "timestamp",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":d": &types.AttributeValueMemberS{Value: "2025-01-01"},
+			":a": &types.AttributeValueMemberN{Value: "10"},
+			":b": &types.AttributeValueMemberN{Value: "20"},
+		},
+	}
+	ddbOut, ddbErr := queryAllPages(ddbLocal, input)
+	baddbOut, baddbErr := queryAllPages(baddb, input)
+	if ddbErr != nil || baddbErr != nil {
+		t.Fatalf("unexpected error: ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+	}
+
+	if len(baddbOut) != 3 || len(ddbOut) != len(baddbOut) {
+		t.Fatalf("expected 3 items, got ddbLocal=%d, baddb=%d", len(ddbOut), len(baddbOut))
+	}
+	for i := range ddbOut {
+		compareItem(ddbOut[i], baddbOut[i], t)
+	}
+}
+
 func TestQueryGSI(t *testing.T) {
 	ddbLocal := newDdbLocalClient()
 	baddb := newBaddbClient()
@@ -381,6 +451,55 @@ func TestQueryGSI(t *testing.T) {
 	shutdown()
 }
 
+func TestConsistentReadOnGSI(t *testing.T) {
+	ddbLocal := newDdbLocalClient()
+	baddb := newBaddbClient()
+	cleanDdbLocal(ddbLocal)
+	shutdown := startServer()
+
+	_, ddbErr := createTable(ddbLocal)
+	_, baddbErr := createTable(baddb)
+	if ddbErr != nil || baddbErr != nil {
+		t.Fatalf("failed to create table: ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+	}
+
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String("movie"),
+		IndexName:              aws.String("gsiLanguage"),
+		KeyConditionExpression: aws.String("#lang = :lang"),
+		ExpressionAttributeNames: map[string]string{
+			"#lang": "language",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":lang": &types.AttributeValueMemberS{Value: "English"},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+	_, ddbErr = ddbLocal.Query(context.Background(), queryInput)
+	_, baddbErr = baddb.Query(context.Background(), queryInput)
+	if ddbErr == nil || baddbErr == nil {
+		t.Fatalf("expected errors: ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+	}
+	if !compareWithoutRequestID(ddbErr.Error(), baddbErr.Error()) {
+		t.Fatalf("Query errors differ: ddbErr=%s, baddbErr=%s", ddbErr.Error(), baddbErr.Error())
+	}
+
+	scanInput := &dynamodb.ScanInput{
+		TableName:      aws.String("movie"),
+		IndexName:      aws.String("gsiLanguage"),
+		ConsistentRead: aws.Bool(true),
+	}
+	_, ddbErr = ddbLocal.Scan(context.Background(), scanInput)
+	_, baddbErr = baddb.Scan(context.Background(), scanInput)
+	if ddbErr == nil || baddbErr == nil {
+		t.Fatalf("expected errors: ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+	}
+	if !compareWithoutRequestID(ddbErr.Error(), baddbErr.Error()) {
+		t.Fatalf("Scan errors differ: ddbErr=%s, baddbErr=%s", ddbErr.Error(), baddbErr.Error())
+	}
+	shutdown()
+}
+
 func TestQueryPartitionKeyAndSortKey(t *testing.T) {
 	ddbLocal := newDdbLocalClient()
 	baddb := newBaddbClient()
@@ -636,6 +755,70 @@ func TestQueryInvalidCases(t *testing.T) {
 	}
 }
 
+func TestQueryPagingWithLastEvaluatedKey(t *testing.T) {
+	ddbLocal := newDdbLocalClient()
+	baddb := newBaddbClient()
+	cleanDdbLocal(ddbLocal)
+	shutdown := startServer()
+	defer shutdown()
+
+	_, ddbErr := createTable(ddbLocal)
+	_, baddbErr := createTable(baddb)
+	if ddbErr != nil || baddbErr != nil {
+		t.Fatalf("failed to create table: ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+	}
+
+	items := queryTestItems()
+	for i := 0; i < 5; i++ {
+		items = append(items, map[string]types.AttributeValue{
+			"year":     &types.AttributeValueMemberN{Value: "1994"},
+			"title":    &types.AttributeValueMemberS{Value: fmt.Sprintf("Movie %d", i)},
+			"language": &types.AttributeValueMemberS{Value: "English"},
+		})
+	}
+	for _, item := range items {
+		_, _ = putItemRaw(ddbLocal, item)
+		_, _ = putItemRaw(baddb, item)
+	}
+
+	for _, scanIndexForward := range []bool{true, false} {
+		for _, limit := range []int32{1, 2, 3} {
+			input := &dynamodb.QueryInput{
+				TableName:              aws.String("movie"),
+				KeyConditionExpression: aws.String("#year = :year"),
+				ExpressionAttributeNames: map[string]string{
+					"#year": "year",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":year": &types.AttributeValueMemberN{Value: "1994"},
+				},
+				ScanIndexForward: aws.Bool(scanIndexForward),
+			}
+			baddbAll, err := queryAllPages(baddb, input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			input.Limit = aws.Int32(limit)
+			ddbPages, ddbErr := queryAllPages(ddbLocal, input)
+			baddbPages, baddbErr := queryAllPages(baddb, input)
+			if ddbErr != nil || baddbErr != nil {
+				t.Fatalf("unexpected error: ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+			}
+
+			// pages must be concatenated in order, without gaps or repeats
+			if len(baddbPages) != len(baddbAll) || len(ddbPages) != len(baddbPages) {
+				t.Fatalf("item count differ with limit %d: ddbLocal=%d, baddb=%d, baddb without limit=%d",
+					limit, len(ddbPages), len(baddbPages), len(baddbAll))
+			}
+			for i := range baddbPages {
+				compareItem(baddbAll[i], baddbPages[i], t)
+				compareItem(ddbPages[i], baddbPages[i], t)
+			}
+		}
+	}
+}
+
 // Helper to query all pages and collect items
 func queryAllPages(client *dynamodb.Client, baseInput *dynamodb.QueryInput) ([]map[string]types.AttributeValue, error) {
 	var allItems []map[string]types.AttributeValue
@@ -649,6 +832,7 @@ func queryAllPages(client *dynamodb.Client, baseInput *dynamodb.QueryInput) ([]m
 			ExpressionAttributeNames:  baseInput.ExpressionAttributeNames,
 			ExpressionAttributeValues: baseInput.ExpressionAttributeValues,
 			Limit:                     baseInput.Limit,
+			ScanIndexForward:          baseInput.ScanIndexForward,
 			ExclusiveStartKey:         baseInput.ExclusiveStartKey,
 		}
 		if lastKey != nil {
@@ -140,6 +140,55 @@ func TestScanBehaviorWithFilter(t *testing.T) {
 	shutdown()
 }
 
+func TestScanBehaviorWithSizeComparison(t *testing.T) {
+	ddbLocal := newDdbLocalClient()
+	baddb := newBaddbClient()
+	cleanDdbLocal(ddbLocal)
+	shutdown := startServer()
+
+	_, ddbErr := createTable(ddbLocal)
+	_, baddbErr := createTable(baddb)
+	if ddbErr != nil || baddbErr != nil {
+		t.Fatalf("failed to create table: ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+	}
+
+	for _, item := range scanTestItems() {
+		_, err := putItemRaw(ddbLocal, item)
+		if err != nil {
+			t.Fatalf("failed to request item in ddbLocal: %v", err)
+		}
+		_, err = putItemRaw(baddb, item)
+		if err != nil {
+			t.Fatalf("failed to request item in baddb: %v", err)
+		}
+	}
+
+	// both operands are the size of an attribute, and info.actors doesn't exist
+	for _, filterExpression := range []string{
+		"size(title) > size(#lang)",
+		"size(#lang) >= size(title)",
+		"size(title) > size(info.actors)",
+	} {
+		baseScanInput := &dynamodb.ScanInput{
+			TableName:        aws.String("movie"),
+			FilterExpression: aws.String(filterExpression),
+			ExpressionAttributeNames: map[string]string{
+				"#lang": "language",
+			},
+		}
+		ddbItems, ddbErr := scanAllPages(ddbLocal, baseScanInput)
+		baddbItems, baddbErr := scanAllPages(baddb, baseScanInput)
+
+		if ddbErr != nil || baddbErr != nil {
+			t.Errorf("unexpected error: ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+		}
+
+		compareItems(ddbItems, baddbItems, t)
+	}
+
+	shutdown()
+}
+
 func TestScanBehaviorWithReservedWord(t *testing.T) {
 	ddbLocal := newDdbLocalClient()
 	baddb := newBaddbClient()
@@ -274,6 +323,91 @@ func TestScanBehaviorWithSegments(t *testing.T) {
 	shutdown()
 }
 
+func TestScanInvalidSegments(t *testing.T) {
+	tests := []struct {
+		name          string
+		segment       *int32
+		totalSegments *int32
+	}{
+		{name: "segment without total segments", segment: aws.Int32(0)},
+		{name: "total segments without segment", totalSegments: aws.Int32(2)},
+		{name: "segment out of range", segment: aws.Int32(2), totalSegments: aws.Int32(2)},
+		{name: "negative segment", segment: aws.Int32(-1), totalSegments: aws.Int32(2)},
+		{name: "too many total segments", segment: aws.Int32(0), totalSegments: aws.Int32(1000001)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testContext := setupTest(t)
+			ddbLocal := testContext.ddbLocal
+			baddb := testContext.baddb
+			defer testContext.shutdown()
+
+			input := &dynamodb.ScanInput{
+				TableName:     aws.String("movie"),
+				Segment:       tt.segment,
+				TotalSegments: tt.totalSegments,
+			}
+			_, ddbErr := ddbLocal.Scan(context.TODO(), input)
+			_, baddbErr := baddb.Scan(context.TODO(), input)
+
+			if ddbErr == nil || baddbErr == nil {
+				t.Fatalf("expected errors for invalid segments, got ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+			}
+			if !compareWithoutRequestID(ddbErr.Error(), baddbErr.Error()) {
+				t.Errorf("expected errors to match, ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+			}
+		})
+	}
+}
+
+func TestScanDeeplyNestedMapFilter(t *testing.T) {
+	testContext := setupTest(t)
+	ddbLocal := testContext.ddbLocal
+	baddb := testContext.baddb
+	defer testContext.shutdown()
+
+	for i, d := range []string{"deep", "shallow"} {
+		item := map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: d},
+			"a": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"b": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+					"c": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+						"d": &types.AttributeValueMemberS{Value: d},
+					}},
+				}},
+			}},
+		}
+		if i == 1 {
+			// c isn't a map, so a.b.c.d doesn't resolve
+			item["a"].(*types.AttributeValueMemberM).Value["b"].(*types.AttributeValueMemberM).Value["c"] = &types.AttributeValueMemberS{Value: d}
+		}
+		if _, err := putItemRaw(ddbLocal, item); err != nil {
+			t.Fatalf("failed to put item in ddbLocal: %v", err)
+		}
+		if _, err := putItemRaw(baddb, item); err != nil {
+			t.Fatalf("failed to put item in baddb: %v", err)
+		}
+	}
+
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String("movie"),
+		FilterExpression: aws.String("a.b.c.d = :v"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":v": &types.AttributeValueMemberS{Value: "deep"},
+		},
+	}
+	ddbItems, ddbErr := scanAllPages(ddbLocal, input)
+	baddbItems, baddbErr := scanAllPages(baddb, input)
+	if ddbErr != nil || baddbErr != nil {
+		t.Fatalf("unexpected error: ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+	}
+	if len(baddbItems) != 1 {
+		t.Errorf("expected 1 item from baddb, got %v", baddbItems)
+	}
+	compareItems(ddbItems, baddbItems, t)
+}
+
 // Helper to insert a raw item
 func putItemRaw(client *dynamodb.Client, item map[string]types.AttributeValue) (*dynamodb.PutItemOutput, error) {
 	input := &dynamodb.PutItemInput{
@@ -135,3 +135,79 @@ func updateItemWithCondition(client *dynamodb.Client, condition *string) (*dynam
 func compareUpdateItemOutput(ddbOutput *dynamodb.UpdateItemOutput, baddbOutput *dynamodb.UpdateItemOutput, t *testing.T) {
 	compareItem(ddbOutput.Attributes, baddbOutput.Attributes, t)
 }
+
+func TestUpdateItemReturnValues(t *testing.T) {
+	tests := []struct {
+		name             string
+		returnValues     types.ReturnValue
+		updateExpression string
+		existsItem       bool
+	}{
+		{name: "NONE", returnValues: types.ReturnValueNone, updateExpression: "SET info.rating = :rating REMOVE #L ADD viewCount :one", existsItem: true},
+		{name: "ALL_OLD", returnValues: types.ReturnValueAllOld, updateExpression: "SET info.rating = :rating REMOVE #L ADD viewCount :one", existsItem: true},
+		{name: "UPDATED_OLD", returnValues: types.ReturnValueUpdatedOld, updateExpression: "SET info.rating = :rating REMOVE #L ADD viewCount :one", existsItem: true},
+		{name: "ALL_NEW", returnValues: types.ReturnValueAllNew, updateExpression: "SET info.rating = :rating REMOVE #L ADD viewCount :one", existsItem: true},
+		{name: "UPDATED_NEW", returnValues: types.ReturnValueUpdatedNew, updateExpression: "SET info.rating = :rating REMOVE #L ADD viewCount :one", existsItem: true},
+		{name: "ALL_OLD when item does not exist", returnValues: types.ReturnValueAllOld, updateExpression: "SET #L = :lang ADD viewCount :one", existsItem: false},
+		{name: "UPDATED_NEW when item does not exist", returnValues: types.ReturnValueUpdatedNew, updateExpression: "SET #L = :lang ADD viewCount :one", existsItem: false},
+	}
+
+	for _, tt := range tests {
+		ddbLocal := newDdbLocalClient()
+		baddb := newBaddbClient()
+		cleanDdbLocal(ddbLocal)
+		shutdown := startServer()
+
+		_, ddbErr := createTable(ddbLocal)
+		_, baddbErr := createTable(baddb)
+		if ddbErr != nil || baddbErr != nil {
+			t.Fatalf("failed to create table: ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.existsItem {
+				input := &dynamodb.PutItemInput{
+					TableName: aws.String(TestTableName),
+					Item: map[string]types.AttributeValue{
+						"year":     &types.AttributeValueMemberN{Value: "2024"},
+						"title":    &types.AttributeValueMemberS{Value: "The Shawshank Redemption"},
+						"info":     &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"rating": &types.AttributeValueMemberN{Value: "9.3"}, "plot": &types.AttributeValueMemberS{Value: "prison"}}},
+						"language": &types.AttributeValueMemberS{Value: "English"},
+					},
+				}
+				_, _ = putItem(ddbLocal, input)
+				_, _ = putItem(baddb, input)
+			}
+
+			values := map[string]types.AttributeValue{
+				":one": &types.AttributeValueMemberN{Value: "1"},
+			}
+			if tt.existsItem {
+				values[":rating"] = &types.AttributeValueMemberN{Value: "9.5"}
+			} else {
+				values[":lang"] = &types.AttributeValueMemberS{Value: "French"}
+			}
+			input := &dynamodb.UpdateItemInput{
+				TableName: aws.String(TestTableName),
+				Key: map[string]types.AttributeValue{
+					"year":  &types.AttributeValueMemberN{Value: "2024"},
+					"title": &types.AttributeValueMemberS{Value: "The Shawshank Redemption"},
+				},
+				UpdateExpression:          aws.String(tt.updateExpression),
+				ExpressionAttributeNames:  map[string]string{"#L": "language"},
+				ExpressionAttributeValues: values,
+				ReturnValues:              tt.returnValues,
+			}
+
+			ddbOut, ddbErr := ddbLocal.UpdateItem(context.TODO(), input)
+			baddbOut, baddbErr := baddb.UpdateItem(context.TODO(), input)
+			if ddbErr != nil || baddbErr != nil {
+				t.Fatalf("expected no error, ddbErr=%v, baddbErr=%v", ddbErr, baddbErr)
+			}
+
+			compareUpdateItemOutput(ddbOut, baddbOut, t)
+		})
+
+		shutdown()
+	}
+}
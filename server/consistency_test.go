@@ -0,0 +1,139 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ocowchun/baddb/ddb"
+)
+
+func updateConsistencyDelay(t *testing.T, body string) (int, []byte) {
+	res, err := http.Post("http://localhost:8080"+CONSISTENCY_DELAY_PATH, "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer res.Body.Close()
+
+	bs, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return res.StatusCode, bs
+}
+
+func TestUpdateConsistencyDelay(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	client := newDdbClient()
+	_, err := createTable(client, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, err = putItem(client, 2025, "Hello World", "message", "1", "US")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	getItemInput := &dynamodb.GetItemInput{
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World"},
+		},
+		TableName: aws.String("movie"),
+	}
+	getItemOutput, err := client.GetItem(context.Background(), getItemInput)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(getItemOutput.Item) != 0 {
+		t.Fatalf("Expected no item with delay, got %v", getItemOutput.Item)
+	}
+
+	statusCode, body := updateConsistencyDelay(t, `{"TableDelaySeconds": 0, "GsiDelaySeconds": 0}`)
+	if statusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", statusCode, body)
+	}
+	var output ddb.UpdateConsistencyDelayOutput
+	if err := json.Unmarshal(body, &output); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(output.Tables) != 1 || output.Tables[0].TableName != "movie" || output.Tables[0].TableDelaySeconds != 0 {
+		t.Fatalf("Expected movie with TableDelaySeconds 0, got %v", output.Tables)
+	}
+
+	getItemOutput, err = client.GetItem(context.Background(), getItemInput)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(getItemOutput.Item) == 0 {
+		t.Fatalf("Expected item without delay, got none")
+	}
+	queryOutput, err := client.Query(context.Background(), &dynamodb.QueryInput{
+		TableName:              aws.String("movie"),
+		IndexName:              aws.String("regionGSI"),
+		KeyConditionExpression: aws.String("regionCode = :regionCode"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":regionCode": &types.AttributeValueMemberS{Value: "1"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(queryOutput.Items) != 1 {
+		t.Fatalf("Expected 1 item from GSI without delay, got %d", len(queryOutput.Items))
+	}
+
+	statusCode, body = updateConsistencyDelay(t, `{"TableNames": ["movie"], "TableDelaySeconds": 60, "GsiDelaySeconds": 60}`)
+	if statusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", statusCode, body)
+	}
+
+	getItemOutput, err = client.GetItem(context.Background(), getItemInput)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(getItemOutput.Item) != 0 {
+		t.Fatalf("Expected no item with delay, got %v", getItemOutput.Item)
+	}
+}
+
+func TestUpdateConsistencyDelay_NonExistentTable(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	client := newDdbClient()
+	_, err := createTable(client, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	statusCode, body := updateConsistencyDelay(t, `{"TableNames": ["movie", "unknown"], "TableDelaySeconds": 0}`)
+	if statusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d: %s", statusCode, body)
+	}
+
+	// movie keeps its delay since the update is rejected as a whole
+	_, err = putItem(client, 2025, "Hello World", "message", "1", "US")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	getItemOutput, err := client.GetItem(context.Background(), &dynamodb.GetItemInput{
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World"},
+		},
+		TableName: aws.String("movie"),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(getItemOutput.Item) != 0 {
+		t.Fatalf("Expected no item with delay, got %v", getItemOutput.Item)
+	}
+}
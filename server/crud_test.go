@@ -1,6 +1,7 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -8,7 +9,10 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
 	"log"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 )
@@ -254,6 +258,552 @@ func TestPutWithCondition(t *testing.T) {
 	}
 }
 
+func TestPutWithReservedKeywordCondition(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 5, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = putItem(ddb, 2025, "Hello World", "your magic is mine", "1", "US")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	putItemInput := &dynamodb.PutItemInput{
+		Item: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World"},
+		},
+		TableName:           aws.String("movie"),
+		ConditionExpression: aws.String("year = :y"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":y": &types.AttributeValueMemberN{Value: "2025"},
+		},
+	}
+	_, err = ddb.PutItem(context.Background(), putItemInput)
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+		t.Fatalf("Expected ValidationException, got %v", err)
+	}
+	if apiErr.ErrorMessage() != "Invalid ConditionExpression: Attribute name is a reserved keyword; reserved keyword: year" {
+		t.Fatalf("error message is unexpected, got %s", apiErr.ErrorMessage())
+	}
+
+	putItemInput.ConditionExpression = aws.String("#y = :y")
+	putItemInput.ExpressionAttributeNames = map[string]string{"#y": "year"}
+	_, err = ddb.PutItem(context.Background(), putItemInput)
+	if err != nil {
+		t.Fatalf("Expected no error with an aliased reserved word, got %v", err)
+	}
+}
+
+func TestPutWithNestedOrCondition(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	item := map[string]types.AttributeValue{
+		"year":    &types.AttributeValueMemberN{Value: "2025"},
+		"title":   &types.AttributeValueMemberS{Value: "Hello World"},
+		"message": &types.AttributeValueMemberS{Value: "your magic is mine"},
+		"status":  &types.AttributeValueMemberS{Value: "active"},
+		"version": &types.AttributeValueMemberN{Value: "1"},
+	}
+	_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String("movie"),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	condition := "attribute_not_exists(title) OR (#s = :s AND (version < :v OR begins_with(message, :prefix)))"
+	tests := []struct {
+		name      string
+		title     string
+		condition string
+		status    string
+		version   string
+		prefix    string
+		passed    bool
+	}{
+		{"new item", "Hello World 2", condition, "inactive", "0", "jobs", true},
+		{"status and version match", "Hello World", condition, "active", "2", "jobs", true},
+		{"status does not match", "Hello World", condition, "inactive", "2", "your", false},
+		{"neither version nor prefix match", "Hello World", condition, "active", "1", "jobs", false},
+		{"prefix matches", "Hello World", condition, "active", "0", "your", true},
+		// AND binds tighter than OR, grouped left to right the condition would be false
+		{"AND before OR", "Hello World", "#s = :s OR attribute_exists(title) AND version < :v", "active", "0", "", true},
+		{"AND before OR fails", "Hello World", "#s = :s OR attribute_exists(title) AND version < :v", "inactive", "0", "", false},
+	}
+
+	for _, tt := range tests {
+		values := map[string]types.AttributeValue{
+			":s": &types.AttributeValueMemberS{Value: tt.status},
+			":v": &types.AttributeValueMemberN{Value: tt.version},
+		}
+		if tt.prefix != "" {
+			values[":prefix"] = &types.AttributeValueMemberS{Value: tt.prefix}
+		}
+		newItem := make(map[string]types.AttributeValue)
+		for k, v := range item {
+			newItem[k] = v
+		}
+		newItem["title"] = &types.AttributeValueMemberS{Value: tt.title}
+
+		_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+			Item:                      newItem,
+			TableName:                 aws.String("movie"),
+			ConditionExpression:       aws.String(tt.condition),
+			ExpressionAttributeNames:  map[string]string{"#s": "status"},
+			ExpressionAttributeValues: values,
+		})
+		if tt.passed {
+			if err != nil {
+				t.Fatalf("%s: expected no error, got %v", tt.name, err)
+			}
+		} else {
+			var conditionalCheckFailedException *types.ConditionalCheckFailedException
+			if !errors.As(err, &conditionalCheckFailedException) {
+				t.Fatalf("%s: expected ConditionalCheckFailedException, got %v", tt.name, err)
+			}
+		}
+	}
+}
+
+func TestUpdateWithSoftDeleteCondition(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	items := map[string]map[string]types.AttributeValue{
+		"active": {
+			"status": &types.AttributeValueMemberS{Value: "active"},
+		},
+		"deleted": {
+			"status":    &types.AttributeValueMemberS{Value: "active"},
+			"deletedAt": &types.AttributeValueMemberN{Value: "1700000000"},
+		},
+		"archived": {
+			"status": &types.AttributeValueMemberS{Value: "archived"},
+		},
+	}
+	for title, info := range items {
+		_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+			Item: map[string]types.AttributeValue{
+				"year":  &types.AttributeValueMemberN{Value: "2025"},
+				"title": &types.AttributeValueMemberS{Value: title},
+				"info":  &types.AttributeValueMemberM{Value: info},
+			},
+			TableName: aws.String("movie"),
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	tests := []struct {
+		title  string
+		passed bool
+	}{
+		{"active", true},
+		{"deleted", false},
+		{"archived", false},
+		// the condition of a missing item reads info.status of nothing
+		{"missing", false},
+	}
+	for _, tt := range tests {
+		_, err = ddb.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+			TableName: aws.String("movie"),
+			Key: map[string]types.AttributeValue{
+				"year":  &types.AttributeValueMemberN{Value: "2025"},
+				"title": &types.AttributeValueMemberS{Value: tt.title},
+			},
+			UpdateExpression:         aws.String("SET info.deletedAt = :now"),
+			ConditionExpression:      aws.String("attribute_not_exists(info.deletedAt) AND info.#status = :active"),
+			ExpressionAttributeNames: map[string]string{"#status": "status"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":now":    &types.AttributeValueMemberN{Value: "1800000000"},
+				":active": &types.AttributeValueMemberS{Value: "active"},
+			},
+		})
+		if tt.passed {
+			if err != nil {
+				t.Fatalf("%s: expected no error, got %v", tt.title, err)
+			}
+		} else {
+			var conditionalCheckFailedException *types.ConditionalCheckFailedException
+			if !errors.As(err, &conditionalCheckFailedException) {
+				t.Fatalf("%s: expected ConditionalCheckFailedException, got %v", tt.title, err)
+			}
+		}
+	}
+
+	output, err := ddb.GetItem(context.Background(), &dynamodb.GetItemInput{
+		TableName: aws.String("movie"),
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "active"},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	info := output.Item["info"].(*types.AttributeValueMemberM).Value
+	if info["deletedAt"].(*types.AttributeValueMemberN).Value != "1800000000" {
+		t.Fatalf("Expected the active item to be soft deleted, got %v", info)
+	}
+}
+
+func TestUpdateWithConditionOnIncrementedAttribute(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, err = putItem(ddb, 2025, "Hello World", "Initial message", "1", "US")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	increment := func() error {
+		_, err := ddb.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+			TableName: aws.String("movie"),
+			Key: map[string]types.AttributeValue{
+				"year":  &types.AttributeValueMemberN{Value: "2025"},
+				"title": &types.AttributeValueMemberS{Value: "Hello World"},
+			},
+			// the condition reads the count before the update increments it
+			UpdateExpression:         aws.String("SET #count = if_not_exists(#count, :zero) + :one"),
+			ConditionExpression:      aws.String("attribute_not_exists(#count) OR #count < :max"),
+			ExpressionAttributeNames: map[string]string{"#count": "count"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":zero": &types.AttributeValueMemberN{Value: "0"},
+				":one":  &types.AttributeValueMemberN{Value: "1"},
+				":max":  &types.AttributeValueMemberN{Value: "3"},
+			},
+		})
+		return err
+	}
+	for i := 0; i < 3; i++ {
+		if err := increment(); err != nil {
+			t.Fatalf("Expected increment %d to succeed, got %v", i+1, err)
+		}
+	}
+	var conditionalCheckFailedException *types.ConditionalCheckFailedException
+	if err := increment(); !errors.As(err, &conditionalCheckFailedException) {
+		t.Fatalf("Expected ConditionalCheckFailedException incrementing past the cap, got %v", err)
+	}
+
+	output, err := ddb.GetItem(context.Background(), &dynamodb.GetItemInput{
+		TableName: aws.String("movie"),
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World"},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if count := output.Item["count"].(*types.AttributeValueMemberN).Value; count != "3" {
+		t.Fatalf("Expected count to stop at 3, got %s", count)
+	}
+}
+
+func TestPutWithAttributeTypeConditionOnKey(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	item, err := putItem(ddb, 2025, "Hello World", "Initial message", "1", "US")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		keyType string
+		passed  bool
+	}{
+		{"partition key is a number", "year", "N", true},
+		{"partition key isn't a string", "year", "S", false},
+		{"sort key is a string", "title", "S", true},
+		{"sort key isn't a number", "title", "N", false},
+	}
+	for _, tt := range tests {
+		_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+			TableName:                aws.String("movie"),
+			Item:                     item,
+			ConditionExpression:      aws.String("attribute_type(#key, :type)"),
+			ExpressionAttributeNames: map[string]string{"#key": tt.key},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":type": &types.AttributeValueMemberS{Value: tt.keyType},
+			},
+		})
+		if tt.passed {
+			if err != nil {
+				t.Fatalf("%s: expected no error, got %v", tt.name, err)
+			}
+		} else {
+			var conditionalCheckFailedException *types.ConditionalCheckFailedException
+			if !errors.As(err, &conditionalCheckFailedException) {
+				t.Fatalf("%s: expected ConditionalCheckFailedException, got %v", tt.name, err)
+			}
+		}
+	}
+}
+
+func TestPutWithDeeplyNestedPathCondition(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	item := map[string]types.AttributeValue{
+		"year":  &types.AttributeValueMemberN{Value: "2025"},
+		"title": &types.AttributeValueMemberS{Value: "Hello World"},
+		"info": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"b": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberS{Value: "first"},
+				&types.AttributeValueMemberS{Value: "second"},
+				&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+					"c": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+						&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+							"d": &types.AttributeValueMemberS{Value: "deep"},
+						}},
+					}},
+				}},
+			}},
+		}},
+	}
+	_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String("movie"), Item: item})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		value  string
+		passed bool
+	}{
+		{"deep", true},
+		{"shallow", false},
+	}
+	for _, tt := range tests {
+		_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+			TableName:           aws.String("movie"),
+			Item:                item,
+			ConditionExpression: aws.String("info.b[2].c[0].d = :d"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":d": &types.AttributeValueMemberS{Value: tt.value},
+			},
+		})
+		if tt.passed {
+			if err != nil {
+				t.Fatalf("%s: expected no error, got %v", tt.value, err)
+			}
+		} else {
+			var conditionalCheckFailedException *types.ConditionalCheckFailedException
+			if !errors.As(err, &conditionalCheckFailedException) {
+				t.Fatalf("%s: expected ConditionalCheckFailedException, got %v", tt.value, err)
+			}
+		}
+	}
+}
+
+func TestPutWithBinaryBeginsWithCondition(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 5, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	item := map[string]types.AttributeValue{
+		"year":    &types.AttributeValueMemberN{Value: "2025"},
+		"title":   &types.AttributeValueMemberS{Value: "Hello World"},
+		"payload": &types.AttributeValueMemberB{Value: []byte{0x01, 0x02, 0x03}},
+	}
+	_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String("movie"),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Try to put the item with a binary prefix that doesn't match
+	_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+		Item:                item,
+		TableName:           aws.String("movie"),
+		ConditionExpression: aws.String("begins_with(payload, :prefix)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":prefix": &types.AttributeValueMemberB{Value: []byte{0x02}},
+		},
+	})
+	var conditionalCheckFailedException *types.ConditionalCheckFailedException
+	if !errors.As(err, &conditionalCheckFailedException) {
+		t.Fatalf("Expected ConditionalCheckFailedException, got %v", err)
+	}
+
+	// Try to put the item with a matching binary prefix
+	_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+		Item:                item,
+		TableName:           aws.String("movie"),
+		ConditionExpression: aws.String("begins_with(payload, :prefix)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":prefix": &types.AttributeValueMemberB{Value: []byte{0x01, 0x02}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestPutWithConditionOnEmptyListAndMap(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 5, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	item := map[string]types.AttributeValue{
+		"year":    &types.AttributeValueMemberN{Value: "2025"},
+		"title":   &types.AttributeValueMemberS{Value: "Hello World"},
+		"tags":    &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+		"profile": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{}},
+	}
+	_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String("movie"),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// empty list and map are stored values, not absent attributes
+	_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+		Item:                item,
+		TableName:           aws.String("movie"),
+		ConditionExpression: aws.String("size(tags) = :zero AND attribute_exists(profile) AND size(profile) = :zero"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":zero": &types.AttributeValueMemberN{Value: "0"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+		Item:                item,
+		TableName:           aws.String("movie"),
+		ConditionExpression: aws.String("attribute_not_exists(tags)"),
+	})
+	var conditionalCheckFailedException *types.ConditionalCheckFailedException
+	if !errors.As(err, &conditionalCheckFailedException) {
+		t.Fatalf("Expected ConditionalCheckFailedException, got %v", err)
+	}
+}
+
+func TestPutWithConditionOnBinarySet(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 5, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	item := map[string]types.AttributeValue{
+		"year":     &types.AttributeValueMemberN{Value: "2025"},
+		"title":    &types.AttributeValueMemberS{Value: "Hello World"},
+		"checksum": &types.AttributeValueMemberBS{Value: [][]byte{{0x01, 0x02}, {0x03}}},
+	}
+	_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String("movie"),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// sets are equal regardless of the order of their elements
+	_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+		Item:                item,
+		TableName:           aws.String("movie"),
+		ConditionExpression: aws.String("contains(checksum, :b) AND checksum = :checksum AND size(checksum) = :two AND attribute_type(checksum, :bs)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":b":        &types.AttributeValueMemberB{Value: []byte{0x03}},
+			":checksum": &types.AttributeValueMemberBS{Value: [][]byte{{0x03}, {0x01, 0x02}}},
+			":two":      &types.AttributeValueMemberN{Value: "2"},
+			":bs":       &types.AttributeValueMemberS{Value: "BS"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	conditions := []struct {
+		expression string
+		value      types.AttributeValue
+	}{
+		{"contains(checksum, :v)", &types.AttributeValueMemberB{Value: []byte{0x01}}},
+		{"checksum = :v", &types.AttributeValueMemberBS{Value: [][]byte{{0x01, 0x02}}}},
+	}
+	for _, condition := range conditions {
+		_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+			Item:                item,
+			TableName:           aws.String("movie"),
+			ConditionExpression: aws.String(condition.expression),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":v": condition.value,
+			},
+		})
+		var conditionalCheckFailedException *types.ConditionalCheckFailedException
+		if !errors.As(err, &conditionalCheckFailedException) {
+			t.Fatalf("Expected ConditionalCheckFailedException for %s, got %v", condition.expression, err)
+		}
+	}
+
+	output, err := ddb.GetItem(context.Background(), &dynamodb.GetItemInput{
+		Key: map[string]types.AttributeValue{
+			"year":  item["year"],
+			"title": item["title"],
+		},
+		TableName: aws.String("movie"),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	checksum, ok := output.Item["checksum"].(*types.AttributeValueMemberBS)
+	if !ok || len(checksum.Value) != 2 || !bytes.Equal(checksum.Value[0], []byte{0x01, 0x02}) {
+		t.Fatalf("Expected checksum to be returned as a binary set, got %v", output.Item["checksum"])
+	}
+}
+
 func TestDelete_ProvisionedThroughputExceededException(t *testing.T) {
 	shutdown := startServer()
 	defer shutdown()
@@ -422,6 +972,381 @@ func TestUpdateItem(t *testing.T) {
 	}
 }
 
+func TestUpdateItemReturnValues(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		returnValues    types.ReturnValue
+		expectedKeys    []string
+		expectedMessage string
+	}{
+		{
+			returnValues: types.ReturnValueNone,
+			expectedKeys: []string{},
+		},
+		{
+			returnValues:    types.ReturnValueAllOld,
+			expectedKeys:    []string{"countryCode", "message", "regionCode", "title", "year"},
+			expectedMessage: "Initial message",
+		},
+		{
+			returnValues:    types.ReturnValueUpdatedOld,
+			expectedKeys:    []string{"countryCode", "message"},
+			expectedMessage: "Initial message",
+		},
+		{
+			returnValues:    types.ReturnValueAllNew,
+			expectedKeys:    []string{"message", "regionCode", "title", "viewCount", "year"},
+			expectedMessage: "Updated message",
+		},
+		{
+			returnValues:    types.ReturnValueUpdatedNew,
+			expectedKeys:    []string{"message", "viewCount"},
+			expectedMessage: "Updated message",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err = putItem(ddb, 2025, "Hello World", "Initial message", "1", "US")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		output, err := ddb.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+			Key: map[string]types.AttributeValue{
+				"year":  &types.AttributeValueMemberN{Value: "2025"},
+				"title": &types.AttributeValueMemberS{Value: "Hello World"},
+			},
+			TableName:        aws.String("movie"),
+			UpdateExpression: aws.String("SET message = :newMessage REMOVE countryCode ADD viewCount :one"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":newMessage": &types.AttributeValueMemberS{Value: "Updated message"},
+				":one":        &types.AttributeValueMemberN{Value: "1"},
+			},
+			ReturnValues: tt.returnValues,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		keys := make([]string, 0, len(output.Attributes))
+		for key := range output.Attributes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if strings.Join(keys, ",") != strings.Join(tt.expectedKeys, ",") {
+			t.Fatalf("Expected %s to return %v, got %v", tt.returnValues, tt.expectedKeys, keys)
+		}
+		if tt.expectedMessage != "" {
+			if message := output.Attributes["message"].(*types.AttributeValueMemberS).Value; message != tt.expectedMessage {
+				t.Fatalf("Expected %s to return message %s, got %s", tt.returnValues, tt.expectedMessage, message)
+			}
+		}
+	}
+
+	_, err = ddb.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World"},
+		},
+		TableName:        aws.String("movie"),
+		UpdateExpression: aws.String("SET message = :newMessage"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":newMessage": &types.AttributeValueMemberS{Value: "Updated message"},
+		},
+		ReturnValues: "ALL",
+	})
+	if err == nil || !strings.Contains(err.Error(), "Value 'ALL' at 'returnValues' failed to satisfy constraint") {
+		t.Fatalf("Expected invalid ReturnValues error, got %v", err)
+	}
+}
+
+func TestUpdateItemWithAttributeUpdates(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 5, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = putItem(ddb, 2025, "Hello World", "Initial message", "1", "US")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	key := map[string]types.AttributeValue{
+		"year":  &types.AttributeValueMemberN{Value: "2025"},
+		"title": &types.AttributeValueMemberS{Value: "Hello World"},
+	}
+	_, err = ddb.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+		Key:       key,
+		TableName: aws.String("movie"),
+		AttributeUpdates: map[string]types.AttributeValueUpdate{
+			"message": {
+				Action: types.AttributeActionPut,
+				Value:  &types.AttributeValueMemberS{Value: "Updated message"},
+			},
+			"views": {
+				Action: types.AttributeActionAdd,
+				Value:  &types.AttributeValueMemberN{Value: "3"},
+			},
+			"countryCode": {
+				Action: types.AttributeActionDelete,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	getItemOutput, err := ddb.GetItem(context.Background(), &dynamodb.GetItemInput{
+		Key:            key,
+		TableName:      aws.String("movie"),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if val, ok := getItemOutput.Item["message"].(*types.AttributeValueMemberS); !ok || val.Value != "Updated message" {
+		t.Fatalf("Expected message to be 'Updated message', got %v", getItemOutput.Item["message"])
+	}
+	if val, ok := getItemOutput.Item["views"].(*types.AttributeValueMemberN); !ok || val.Value != "3" {
+		t.Fatalf("Expected views to be 3, got %v", getItemOutput.Item["views"])
+	}
+	if _, ok := getItemOutput.Item["countryCode"]; ok {
+		t.Fatalf("Expected countryCode to be removed, got %v", getItemOutput.Item["countryCode"])
+	}
+}
+
+func TestUpdateItemWithAttributeUpdatesAndUpdateExpression(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 5, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = ddb.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World"},
+		},
+		TableName:        aws.String("movie"),
+		UpdateExpression: aws.String("SET message = :newMessage"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":newMessage": &types.AttributeValueMemberS{Value: "Updated message"},
+		},
+		AttributeUpdates: map[string]types.AttributeValueUpdate{
+			"message": {
+				Action: types.AttributeActionPut,
+				Value:  &types.AttributeValueMemberS{Value: "Updated message"},
+			},
+		},
+	})
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+		t.Fatalf("Expected ValidationException, got %v", err)
+	}
+	expected := "Can not use both expression and non-expression parameters in the same request: Non-expression parameters: {AttributeUpdates} Expression parameters: {UpdateExpression}"
+	if apiErr.ErrorMessage() != expected {
+		t.Fatalf("Expected message %q, got %q", expected, apiErr.ErrorMessage())
+	}
+}
+
+func TestUpdateItemWithEmptyUpdateExpression(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 5, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, updateExpression := range []string{"", "  "} {
+		_, err = ddb.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+			Key: map[string]types.AttributeValue{
+				"year":  &types.AttributeValueMemberN{Value: "2025"},
+				"title": &types.AttributeValueMemberS{Value: "Hello World"},
+			},
+			TableName:        aws.String("movie"),
+			UpdateExpression: aws.String(updateExpression),
+		})
+		var apiErr smithy.APIError
+		if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+			t.Fatalf("Expected ValidationException, got %v", err)
+		}
+		expected := "Invalid UpdateExpression: The expression can not be empty;"
+		if apiErr.ErrorMessage() != expected {
+			t.Fatalf("Expected message %q, got %q", expected, apiErr.ErrorMessage())
+		}
+	}
+
+	output, err := ddb.GetItem(context.Background(), &dynamodb.GetItemInput{
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World"},
+		},
+		TableName:      aws.String("movie"),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if output.Item != nil {
+		t.Fatalf("Expected the rejected update not to create an item, got %v", output.Item)
+	}
+}
+
+func TestUpdateItemWithAddClause(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	key := map[string]types.AttributeValue{
+		"year":  &types.AttributeValueMemberN{Value: "2025"},
+		"title": &types.AttributeValueMemberS{Value: "Hello World"},
+	}
+
+	add := func(values map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+		output, err := ddb.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+			Key:                       key,
+			TableName:                 aws.String("movie"),
+			UpdateExpression:          aws.String("ADD viewCount :views, tags :tags"),
+			ExpressionAttributeValues: values,
+			ReturnValues:              types.ReturnValueAllNew,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return output.Attributes, nil
+	}
+
+	// a missing number counts as 0 and a missing set as the empty set
+	attributes, err := add(map[string]types.AttributeValue{
+		":views": &types.AttributeValueMemberN{Value: "1"},
+		":tags":  &types.AttributeValueMemberSS{Value: []string{"comedy", "drama"}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if views := attributes["viewCount"].(*types.AttributeValueMemberN).Value; views != "1" {
+		t.Fatalf("Expected viewCount 1, got %s", views)
+	}
+
+	attributes, err = add(map[string]types.AttributeValue{
+		":views": &types.AttributeValueMemberN{Value: "2"},
+		":tags":  &types.AttributeValueMemberSS{Value: []string{"drama", "thriller"}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if views := attributes["viewCount"].(*types.AttributeValueMemberN).Value; views != "3" {
+		t.Fatalf("Expected viewCount 3, got %s", views)
+	}
+	tags := attributes["tags"].(*types.AttributeValueMemberSS).Value
+	if !reflect.DeepEqual(tags, []string{"comedy", "drama", "thriller"}) {
+		t.Fatalf("Expected tags comedy, drama and thriller, got %v", tags)
+	}
+
+	// tags is a string set, adding a number set to it is rejected
+	_, err = add(map[string]types.AttributeValue{
+		":views": &types.AttributeValueMemberN{Value: "1"},
+		":tags":  &types.AttributeValueMemberNS{Value: []string{"1"}},
+	})
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+		t.Fatalf("Expected ValidationException, got %v", err)
+	}
+	expected := "An operand in the update expression has an incorrect data type"
+	if apiErr.ErrorMessage() != expected {
+		t.Fatalf("Expected message %q, got %q", expected, apiErr.ErrorMessage())
+	}
+}
+
+func TestUpdateItemWithDeleteClause(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	key := map[string]types.AttributeValue{
+		"year":  &types.AttributeValueMemberN{Value: "2025"},
+		"title": &types.AttributeValueMemberS{Value: "Hello World"},
+	}
+	_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String("movie"),
+		Item: map[string]types.AttributeValue{
+			"year":      key["year"],
+			"title":     key["title"],
+			"viewCount": &types.AttributeValueMemberN{Value: "1"},
+			"tags":      &types.AttributeValueMemberSS{Value: []string{"comedy", "drama", "thriller"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	deleteFrom := func(attributeName string, subset types.AttributeValue) (map[string]types.AttributeValue, error) {
+		output, err := ddb.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+			Key:                       key,
+			TableName:                 aws.String("movie"),
+			UpdateExpression:          aws.String("DELETE " + attributeName + " :subset"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":subset": subset},
+			ReturnValues:              types.ReturnValueAllNew,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return output.Attributes, nil
+	}
+
+	attributes, err := deleteFrom("tags", &types.AttributeValueMemberSS{Value: []string{"comedy", "thriller"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	tags := attributes["tags"].(*types.AttributeValueMemberSS).Value
+	if !reflect.DeepEqual(tags, []string{"drama"}) {
+		t.Fatalf("Expected tags drama, got %v", tags)
+	}
+
+	// the set can't be empty, deleting its last element removes the attribute
+	attributes, err = deleteFrom("tags", &types.AttributeValueMemberSS{Value: []string{"drama"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if tags, ok := attributes["tags"]; ok {
+		t.Fatalf("Expected tags to be removed, got %v", tags)
+	}
+
+	// deleting from a missing attribute does nothing
+	if _, err := deleteFrom("tags", &types.AttributeValueMemberSS{Value: []string{"drama"}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = deleteFrom("viewCount", &types.AttributeValueMemberNS{Value: []string{"1"}})
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+		t.Fatalf("Expected ValidationException, got %v", err)
+	}
+	expected := "An operand in the update expression has an incorrect data type"
+	if apiErr.ErrorMessage() != expected {
+		t.Fatalf("Expected message %q, got %q", expected, apiErr.ErrorMessage())
+	}
+}
+
 func TestUpdate_ProvisionedThroughputExceededException(t *testing.T) {
 	shutdown := startServer()
 	defer shutdown()
@@ -5,6 +5,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/smithy-go/middleware"
+	"github.com/ocowchun/baddb/ddb"
 	"github.com/ocowchun/baddb/ddb/core"
 	"io"
 	"log"
@@ -15,13 +16,17 @@ import (
 type timestamp time.Time
 
 func newTimestamp(t *time.Time) *timestamp {
+	if t == nil {
+		return nil
+	}
 	ts := timestamp(*t)
 	return &ts
 }
 
+// MarshalJSON encodes the timestamp as epoch seconds with millisecond precision, e.g. 1696089600.123
 func (t timestamp) MarshalJSON() ([]byte, error) {
 	ts := time.Time(t)
-	return []byte(strconv.FormatInt(ts.Unix(), 10)), nil
+	return []byte(strconv.FormatFloat(float64(ts.UnixMilli())/1000, 'f', 3, 64)), nil
 }
 
 type tableDescription struct {
@@ -31,7 +36,7 @@ type tableDescription struct {
 
 	AttributeDefinitions []types.AttributeDefinition
 
-	BillingModeSummary *types.BillingModeSummary
+	BillingModeSummary *billingModeSummary
 
 	CreationDateTime *timestamp
 
@@ -79,11 +84,27 @@ type tableDescription struct {
 	WarmThroughput *types.TableWarmThroughputDescription
 }
 
+type billingModeSummary struct {
+	BillingMode types.BillingMode
+
+	LastUpdateToPayPerRequestDateTime *timestamp
+}
+
+func newBillingModeSummary(summary *types.BillingModeSummary) *billingModeSummary {
+	if summary == nil {
+		return nil
+	}
+	return &billingModeSummary{
+		BillingMode:                       summary.BillingMode,
+		LastUpdateToPayPerRequestDateTime: newTimestamp(summary.LastUpdateToPayPerRequestDateTime),
+	}
+}
+
 func newTableDescription(description *types.TableDescription) *tableDescription {
 	return &tableDescription{
 		ArchivalSummary:           description.ArchivalSummary,
 		AttributeDefinitions:      description.AttributeDefinitions,
-		BillingModeSummary:        description.BillingModeSummary,
+		BillingModeSummary:        newBillingModeSummary(description.BillingModeSummary),
 		CreationDateTime:          newTimestamp(description.CreationDateTime),
 		DeletionProtectionEnabled: description.DeletionProtectionEnabled,
 		GlobalSecondaryIndexes:    description.GlobalSecondaryIndexes,
@@ -120,7 +141,8 @@ type KeysAndAttributes struct {
 }
 
 type batchGetItemInput struct {
-	RequestItems map[string]KeysAndAttributes
+	RequestItems           map[string]KeysAndAttributes
+	ReturnConsumedCapacity types.ReturnConsumedCapacity
 }
 
 func DecodeBatchGetItemInput(reader io.ReadCloser) (*dynamodb.BatchGetItemInput, error) {
@@ -153,15 +175,17 @@ func DecodeBatchGetItemInput(reader io.ReadCloser) (*dynamodb.BatchGetItemInput,
 		}
 	}
 	input := &dynamodb.BatchGetItemInput{
-		RequestItems: requestItems,
+		RequestItems:           requestItems,
+		ReturnConsumedCapacity: input2.ReturnConsumedCapacity,
 	}
 
 	return input, err
 }
 
 type batchGetItemOutput struct {
-	Responses       map[string][]map[string]core.AttributeValue
-	UnprocessedKeys map[string]KeysAndAttributes
+	ConsumedCapacity []types.ConsumedCapacity `json:",omitempty"`
+	Responses        map[string][]map[string]core.AttributeValue
+	UnprocessedKeys  map[string]KeysAndAttributes
 }
 
 func EncodeBatchGetItemOutput(output *dynamodb.BatchGetItemOutput) ([]byte, error) {
@@ -198,8 +222,9 @@ func EncodeBatchGetItemOutput(output *dynamodb.BatchGetItemOutput) ([]byte, erro
 	}
 
 	output2 := batchGetItemOutput{
-		Responses:       responses,
-		UnprocessedKeys: unprocessedKeys,
+		ConsumedCapacity: output.ConsumedCapacity,
+		Responses:        responses,
+		UnprocessedKeys:  unprocessedKeys,
 	}
 
 	bs, err := json.Marshal(output2)
@@ -219,7 +244,8 @@ type WriteRequest struct {
 }
 
 type batchWriteItemInput struct {
-	RequestItems map[string][]WriteRequest
+	RequestItems           map[string][]WriteRequest
+	ReturnConsumedCapacity types.ReturnConsumedCapacity
 }
 
 func DecodeBatchWriteItemInput(reader io.ReadCloser) (*dynamodb.BatchWriteItemInput, error) {
@@ -261,12 +287,14 @@ func DecodeBatchWriteItemInput(reader io.ReadCloser) (*dynamodb.BatchWriteItemIn
 		requestItems[tableName] = requests
 	}
 	input := &dynamodb.BatchWriteItemInput{
-		RequestItems: requestItems,
+		RequestItems:           requestItems,
+		ReturnConsumedCapacity: input2.ReturnConsumedCapacity,
 	}
 	return input, nil
 }
 
 type batchWriteItemOutput struct {
+	ConsumedCapacity []types.ConsumedCapacity `json:",omitempty"`
 	UnprocessedItems map[string][]WriteRequest
 }
 
@@ -302,6 +330,7 @@ func EncodeBatchWriteItemOutput(output *dynamodb.BatchWriteItemOutput) ([]byte,
 	}
 
 	output2 := batchWriteItemOutput{
+		ConsumedCapacity: output.ConsumedCapacity,
 		UnprocessedItems: unprocessedItems,
 	}
 
@@ -416,14 +445,56 @@ func DecodePutItemInput(reader io.ReadCloser) (*dynamodb.PutItemInput, error) {
 	return &input, nil
 }
 
+type itemCollectionMetrics struct {
+	ItemCollectionKey   map[string]core.AttributeValue
+	SizeEstimateRangeGB []float64
+}
+
+func newItemCollectionMetrics(metrics *types.ItemCollectionMetrics) (*itemCollectionMetrics, error) {
+	if metrics == nil {
+		return nil, nil
+	}
+	key, err := core.TransformAttributeValueMap(metrics.ItemCollectionKey)
+	if err != nil {
+		return nil, err
+	}
+	return &itemCollectionMetrics{
+		ItemCollectionKey:   key,
+		SizeEstimateRangeGB: metrics.SizeEstimateRangeGB,
+	}, nil
+}
+
+type putItemOutput struct {
+	ConsumedCapacity      *types.ConsumedCapacity
+	ItemCollectionMetrics *itemCollectionMetrics `json:",omitempty"`
+
+	ResultMetadata middleware.Metadata
+}
+
 func EncodePutItemOutput(output *dynamodb.PutItemOutput) ([]byte, error) {
-	bs, err := json.Marshal(output)
+	metrics, err := newItemCollectionMetrics(output.ItemCollectionMetrics)
+	if err != nil {
+		return nil, err
+	}
+	output2 := putItemOutput{
+		ConsumedCapacity:      output.ConsumedCapacity,
+		ItemCollectionMetrics: metrics,
+		ResultMetadata:        output.ResultMetadata,
+	}
+
+	bs, err := json.Marshal(output2)
 	return bs, err
 }
 
+type attributeValueUpdate struct {
+	Action types.AttributeAction
+	Value  *core.AttributeValue
+}
+
 type updateItemInput struct {
 	Key                                 map[string]core.AttributeValue
 	TableName                           *string
+	AttributeUpdates                    map[string]attributeValueUpdate
 	ConditionExpression                 *string
 	ConditionalOperator                 types.ConditionalOperator
 	Expected                            map[string]types.ExpectedAttributeValue
@@ -466,24 +537,45 @@ func DecodeUpdateItemInput(reader io.ReadCloser) (*dynamodb.UpdateItemInput, err
 		ReturnValuesOnConditionCheckFailure: input2.ReturnValuesOnConditionCheckFailure,
 		UpdateExpression:                    input2.UpdateExpression,
 	}
+	if len(input2.AttributeUpdates) > 0 {
+		input.AttributeUpdates = make(map[string]types.AttributeValueUpdate)
+		for name, attributeUpdate := range input2.AttributeUpdates {
+			update := types.AttributeValueUpdate{
+				Action: attributeUpdate.Action,
+			}
+			if attributeUpdate.Value != nil {
+				update.Value = attributeUpdate.Value.ToDdbAttributeValue()
+			}
+			input.AttributeUpdates[name] = update
+		}
+	}
 
 	return &input, nil
 }
 
 type updateItemOutput struct {
-	ConsumedCapacity *types.ConsumedCapacity
+	ConsumedCapacity      *types.ConsumedCapacity
+	ItemCollectionMetrics *itemCollectionMetrics `json:",omitempty"`
 
-	Attributes map[string]core.AttributeValue
+	Attributes map[string]core.AttributeValue `json:",omitempty"`
 
 	ResultMetadata middleware.Metadata
 }
 
 func EncodeUpdateItemOutput(output *dynamodb.UpdateItemOutput) ([]byte, error) {
 	attrs, err := core.TransformAttributeValueMap(output.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := newItemCollectionMetrics(output.ItemCollectionMetrics)
+	if err != nil {
+		return nil, err
+	}
 	output2 := updateItemOutput{
-		ConsumedCapacity: output.ConsumedCapacity,
-		Attributes:       attrs,
-		ResultMetadata:   output.ResultMetadata,
+		ConsumedCapacity:      output.ConsumedCapacity,
+		ItemCollectionMetrics: metrics,
+		Attributes:            attrs,
+		ResultMetadata:        output.ResultMetadata,
 	}
 
 	bs, err := json.Marshal(output2)
@@ -558,6 +650,9 @@ type queryInput struct {
 	IndexName                 *string
 	ScanIndexForward          *bool
 	KeyConditionExpression    *string
+	ProjectionExpression      *string
+	ReturnConsumedCapacity    types.ReturnConsumedCapacity
+	Select                    types.Select
 }
 
 func DecodeQueryInput(reader io.ReadCloser) (*dynamodb.QueryInput, error) {
@@ -586,22 +681,29 @@ func DecodeQueryInput(reader io.ReadCloser) (*dynamodb.QueryInput, error) {
 		IndexName:                 input2.IndexName,
 		ScanIndexForward:          input2.ScanIndexForward,
 		KeyConditionExpression:    input2.KeyConditionExpression,
+		ProjectionExpression:      input2.ProjectionExpression,
+		ReturnConsumedCapacity:    input2.ReturnConsumedCapacity,
+		Select:                    input2.Select,
 	}
 
 	return &input, nil
 }
 
 type queryOutput struct {
-	//ConsumedCapacity *types.ConsumedCapacity
+	ConsumedCapacity *types.ConsumedCapacity `json:",omitempty"`
 	Count            int32
 	Items            []map[string]core.AttributeValue
-	LastEvaluatedKey map[string]core.AttributeValue
+	LastEvaluatedKey map[string]core.AttributeValue `json:",omitempty"`
 	ScannedCount     int32
 	//ResultMetadata   middleware.Metadata
 }
 
 func EncodeQueryOutput(output *dynamodb.QueryOutput) ([]byte, error) {
-	items := make([]map[string]core.AttributeValue, len(output.Items))
+	// Items is null when Select is COUNT
+	var items []map[string]core.AttributeValue
+	if output.Items != nil {
+		items = make([]map[string]core.AttributeValue, len(output.Items))
+	}
 	for i, item := range output.Items {
 		m, err := core.TransformAttributeValueMap(item)
 		if err != nil {
@@ -615,10 +717,13 @@ func EncodeQueryOutput(output *dynamodb.QueryOutput) ([]byte, error) {
 		return nil, err
 	}
 
+	// encoding/json sorts map keys, so attributes are encoded in a stable order
 	output2 := queryOutput{
+		ConsumedCapacity: output.ConsumedCapacity,
 		Count:            output.Count,
 		Items:            items,
 		LastEvaluatedKey: lastKey,
+		ScannedCount:     output.ScannedCount,
 	}
 	bs, err := json.Marshal(output2)
 	return bs, err
@@ -703,11 +808,13 @@ func EncodeDescribeTableOutput(output *dynamodb.DescribeTableOutput) ([]byte, er
 }
 
 type deleteItemInput struct {
-	Key                       map[string]core.AttributeValue
-	TableName                 *string
-	ConditionExpression       *string
-	ExpressionAttributeNames  map[string]string
-	ExpressionAttributeValues map[string]core.AttributeValue
+	Key                         map[string]core.AttributeValue
+	TableName                   *string
+	ConditionExpression         *string
+	ExpressionAttributeNames    map[string]string
+	ExpressionAttributeValues   map[string]core.AttributeValue
+	ReturnConsumedCapacity      types.ReturnConsumedCapacity
+	ReturnItemCollectionMetrics types.ReturnItemCollectionMetrics
 }
 
 func DecodeDeleteItemInput(reader io.ReadCloser) (*dynamodb.DeleteItemInput, error) {
@@ -725,18 +832,22 @@ func DecodeDeleteItemInput(reader io.ReadCloser) (*dynamodb.DeleteItemInput, err
 	err = json.Unmarshal(body, &input2)
 
 	input := &dynamodb.DeleteItemInput{
-		TableName:                 input2.TableName,
-		Key:                       transformToDdbMap(input2.Key),
-		ConditionExpression:       input2.ConditionExpression,
-		ExpressionAttributeNames:  input2.ExpressionAttributeNames,
-		ExpressionAttributeValues: transformToDdbMap(input2.ExpressionAttributeValues),
+		TableName:                   input2.TableName,
+		Key:                         transformToDdbMap(input2.Key),
+		ConditionExpression:         input2.ConditionExpression,
+		ExpressionAttributeNames:    input2.ExpressionAttributeNames,
+		ExpressionAttributeValues:   transformToDdbMap(input2.ExpressionAttributeValues),
+		ReturnConsumedCapacity:      input2.ReturnConsumedCapacity,
+		ReturnItemCollectionMetrics: input2.ReturnItemCollectionMetrics,
 	}
 
 	return input, nil
 }
 
 type deleteItemOutput struct {
-	Attributes map[string]core.AttributeValue
+	ConsumedCapacity      *types.ConsumedCapacity `json:",omitempty"`
+	ItemCollectionMetrics *itemCollectionMetrics  `json:",omitempty"`
+	Attributes            map[string]core.AttributeValue
 }
 
 func EncodeDeleteItemOutput(output *dynamodb.DeleteItemOutput) ([]byte, error) {
@@ -745,8 +856,14 @@ func EncodeDeleteItemOutput(output *dynamodb.DeleteItemOutput) ([]byte, error) {
 		return nil, err
 	}
 
+	metrics, err := newItemCollectionMetrics(output.ItemCollectionMetrics)
+	if err != nil {
+		return nil, err
+	}
 	output2 := deleteItemOutput{
-		Attributes: attrs,
+		ConsumedCapacity:      output.ConsumedCapacity,
+		ItemCollectionMetrics: metrics,
+		Attributes:            attrs,
 	}
 
 	bs, err := json.Marshal(output2)
@@ -795,7 +912,9 @@ type TransactWriteItem struct {
 }
 
 type transactWriteItemsInput struct {
-	TransactItems []TransactWriteItem
+	TransactItems          []TransactWriteItem
+	ReturnConsumedCapacity types.ReturnConsumedCapacity
+	ClientRequestToken     *string
 }
 
 func DecodeTransactWriteItemsInput(reader io.ReadCloser) (*dynamodb.TransactWriteItemsInput, error) {
@@ -860,7 +979,9 @@ func DecodeTransactWriteItemsInput(reader io.ReadCloser) (*dynamodb.TransactWrit
 	}
 
 	input := &dynamodb.TransactWriteItemsInput{
-		TransactItems: transactItems,
+		TransactItems:          transactItems,
+		ReturnConsumedCapacity: input2.ReturnConsumedCapacity,
+		ClientRequestToken:     input2.ClientRequestToken,
 	}
 	return input, nil
 }
@@ -870,8 +991,15 @@ func EncodeTransactWriteItemsOutput(output *dynamodb.TransactWriteItemsOutput) (
 	return bs, err
 }
 
+// legacyCondition is types.Condition with AttributeValueList decodable from JSON
+type legacyCondition struct {
+	AttributeValueList []core.AttributeValue
+	ComparisonOperator types.ComparisonOperator
+}
+
 type scanInput struct {
 	TableName                 *string
+	ConditionalOperator       types.ConditionalOperator
 	ConsistentRead            *bool
 	ExclusiveStartKey         map[string]core.AttributeValue
 	ExpressionAttributeNames  map[string]string
@@ -881,6 +1009,7 @@ type scanInput struct {
 	Limit                     *int32
 	ProjectionExpression      *string
 	ReturnConsumedCapacity    types.ReturnConsumedCapacity
+	ScanFilter                map[string]legacyCondition
 	Segment                   *int32
 	Select                    types.Select
 	TotalSegments             *int32
@@ -900,8 +1029,24 @@ func DecodeScanInput(reader io.ReadCloser) (*dynamodb.ScanInput, error) {
 	}
 	err = json.Unmarshal(body, &input2)
 
+	var scanFilter map[string]types.Condition
+	if input2.ScanFilter != nil {
+		scanFilter = make(map[string]types.Condition, len(input2.ScanFilter))
+		for attributeName, cond := range input2.ScanFilter {
+			attributeValueList := make([]types.AttributeValue, len(cond.AttributeValueList))
+			for i, val := range cond.AttributeValueList {
+				attributeValueList[i] = val.ToDdbAttributeValue()
+			}
+			scanFilter[attributeName] = types.Condition{
+				AttributeValueList: attributeValueList,
+				ComparisonOperator: cond.ComparisonOperator,
+			}
+		}
+	}
+
 	input := &dynamodb.ScanInput{
 		TableName:                 input2.TableName,
+		ConditionalOperator:       input2.ConditionalOperator,
 		ConsistentRead:            input2.ConsistentRead,
 		ExclusiveStartKey:         transformToDdbMap(input2.ExclusiveStartKey),
 		ExpressionAttributeNames:  input2.ExpressionAttributeNames,
@@ -911,6 +1056,7 @@ func DecodeScanInput(reader io.ReadCloser) (*dynamodb.ScanInput, error) {
 		Limit:                     input2.Limit,
 		ProjectionExpression:      input2.ProjectionExpression,
 		ReturnConsumedCapacity:    input2.ReturnConsumedCapacity,
+		ScanFilter:                scanFilter,
 		Segment:                   input2.Segment,
 		Select:                    input2.Select,
 		TotalSegments:             input2.TotalSegments,
@@ -920,14 +1066,19 @@ func DecodeScanInput(reader io.ReadCloser) (*dynamodb.ScanInput, error) {
 }
 
 type scanOutput struct {
+	ConsumedCapacity *types.ConsumedCapacity `json:",omitempty"`
 	Count            int32
 	Items            []map[string]core.AttributeValue
-	LastEvaluatedKey map[string]core.AttributeValue
+	LastEvaluatedKey map[string]core.AttributeValue `json:",omitempty"`
 	ScannedCount     int32
 }
 
 func EncodeScanOutput(output *dynamodb.ScanOutput) ([]byte, error) {
-	items := make([]map[string]core.AttributeValue, len(output.Items))
+	// Items is null when Select is COUNT
+	var items []map[string]core.AttributeValue
+	if output.Items != nil {
+		items = make([]map[string]core.AttributeValue, len(output.Items))
+	}
 	for i, item := range output.Items {
 		m, err := core.TransformAttributeValueMap(item)
 		if err != nil {
@@ -941,9 +1092,11 @@ func EncodeScanOutput(output *dynamodb.ScanOutput) ([]byte, error) {
 		return nil, err
 	}
 	output2 := scanOutput{
+		ConsumedCapacity: output.ConsumedCapacity,
 		Count:            output.Count,
 		Items:            items,
 		LastEvaluatedKey: lastKey,
+		ScannedCount:     output.ScannedCount,
 	}
 	bs, err := json.Marshal(output2)
 	return bs, err
@@ -982,3 +1135,429 @@ func EncodeUpdateTableOutput(output *dynamodb.UpdateTableOutput) ([]byte, error)
 	bs, err := json.Marshal(output2)
 	return bs, err
 }
+
+func DecodeUpdateTimeToLiveInput(reader io.ReadCloser) (*dynamodb.UpdateTimeToLiveInput, error) {
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("Error closing request body: %v", err)
+		}
+	}()
+
+	var input dynamodb.UpdateTimeToLiveInput
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(body, &input)
+
+	return &input, err
+}
+
+type updateTimeToLiveOutput struct {
+	TimeToLiveSpecification *types.TimeToLiveSpecification
+}
+
+func EncodeUpdateTimeToLiveOutput(output *dynamodb.UpdateTimeToLiveOutput) ([]byte, error) {
+	output2 := updateTimeToLiveOutput{
+		TimeToLiveSpecification: output.TimeToLiveSpecification,
+	}
+
+	bs, err := json.Marshal(output2)
+	return bs, err
+}
+
+func DecodeDescribeTimeToLiveInput(reader io.ReadCloser) (*dynamodb.DescribeTimeToLiveInput, error) {
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("Error closing request body: %v", err)
+		}
+	}()
+
+	var input dynamodb.DescribeTimeToLiveInput
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(body, &input)
+
+	return &input, err
+}
+
+type describeTimeToLiveOutput struct {
+	TimeToLiveDescription *types.TimeToLiveDescription
+}
+
+func EncodeDescribeTimeToLiveOutput(output *dynamodb.DescribeTimeToLiveOutput) ([]byte, error) {
+	output2 := describeTimeToLiveOutput{
+		TimeToLiveDescription: output.TimeToLiveDescription,
+	}
+
+	bs, err := json.Marshal(output2)
+	return bs, err
+}
+
+func DecodeDescribeEndpointsInput(reader io.ReadCloser) (*dynamodb.DescribeEndpointsInput, error) {
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("Error closing request body: %v", err)
+		}
+	}()
+
+	var input dynamodb.DescribeEndpointsInput
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(body, &input)
+
+	return &input, err
+}
+
+type describeEndpointsOutput struct {
+	Endpoints []types.Endpoint
+}
+
+func EncodeDescribeEndpointsOutput(output *dynamodb.DescribeEndpointsOutput) ([]byte, error) {
+	output2 := describeEndpointsOutput{
+		Endpoints: output.Endpoints,
+	}
+
+	bs, err := json.Marshal(output2)
+	return bs, err
+}
+
+type executeStatementInput struct {
+	Statement      *string
+	Parameters     []core.AttributeValue
+	ConsistentRead *bool
+	Limit          *int32
+	NextToken      *string
+}
+
+func DecodeExecuteStatementInput(reader io.ReadCloser) (*dynamodb.ExecuteStatementInput, error) {
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("Error closing request body: %v", err)
+		}
+	}()
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var input2 executeStatementInput
+	err = json.Unmarshal(body, &input2)
+	if err != nil {
+		return nil, err
+	}
+
+	var parameters []types.AttributeValue
+	if input2.Parameters != nil {
+		parameters = make([]types.AttributeValue, len(input2.Parameters))
+		for i, parameter := range input2.Parameters {
+			parameters[i] = parameter.ToDdbAttributeValue()
+		}
+	}
+	input := dynamodb.ExecuteStatementInput{
+		Statement:      input2.Statement,
+		Parameters:     parameters,
+		ConsistentRead: input2.ConsistentRead,
+		Limit:          input2.Limit,
+		NextToken:      input2.NextToken,
+	}
+
+	return &input, nil
+}
+
+type executeStatementOutput struct {
+	Items            []map[string]core.AttributeValue
+	LastEvaluatedKey map[string]core.AttributeValue `json:",omitempty"`
+	NextToken        *string                        `json:",omitempty"`
+}
+
+func EncodeExecuteStatementOutput(output *dynamodb.ExecuteStatementOutput) ([]byte, error) {
+	items := make([]map[string]core.AttributeValue, len(output.Items))
+	for i, item := range output.Items {
+		m, err := core.TransformAttributeValueMap(item)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = m
+	}
+
+	lastKey, err := core.TransformAttributeValueMap(output.LastEvaluatedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	output2 := executeStatementOutput{
+		Items:            items,
+		LastEvaluatedKey: lastKey,
+		NextToken:        output.NextToken,
+	}
+	bs, err := json.Marshal(output2)
+	return bs, err
+}
+
+func DecodeUpdateConsistencyDelayInput(reader io.ReadCloser) (*ddb.UpdateConsistencyDelayInput, error) {
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("Error closing request body: %v", err)
+		}
+	}()
+
+	var input ddb.UpdateConsistencyDelayInput
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(body, &input)
+
+	return &input, err
+}
+
+func EncodeUpdateConsistencyDelayOutput(output *ddb.UpdateConsistencyDelayOutput) ([]byte, error) {
+	bs, err := json.Marshal(output)
+	return bs, err
+}
+
+func DecodeUpdatePartitionLimitInput(reader io.ReadCloser) (*ddb.UpdatePartitionLimitInput, error) {
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("Error closing request body: %v", err)
+		}
+	}()
+
+	var input ddb.UpdatePartitionLimitInput
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(body, &input)
+
+	return &input, err
+}
+
+func EncodeUpdatePartitionLimitOutput(output *ddb.UpdatePartitionLimitOutput) ([]byte, error) {
+	bs, err := json.Marshal(output)
+	return bs, err
+}
+
+func DecodeResetRateLimitersInput(reader io.ReadCloser) (*ddb.ResetRateLimitersInput, error) {
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("Error closing request body: %v", err)
+		}
+	}()
+
+	var input ddb.ResetRateLimitersInput
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(body, &input)
+
+	return &input, err
+}
+
+func EncodeResetRateLimitersOutput(output *ddb.ResetRateLimitersOutput) ([]byte, error) {
+	bs, err := json.Marshal(output)
+	return bs, err
+}
+
+func DecodeImportItemsInput(reader io.ReadCloser) (*ddb.ImportItemsInput, error) {
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("Error closing request body: %v", err)
+		}
+	}()
+
+	var input ddb.ImportItemsInput
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(body, &input)
+
+	return &input, err
+}
+
+func EncodeImportItemsOutput(output *ddb.ImportItemsOutput) ([]byte, error) {
+	bs, err := json.Marshal(output)
+	return bs, err
+}
+
+func DecodeDescribeStreamInput(reader io.ReadCloser) (*ddb.DescribeStreamInput, error) {
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("Error closing request body: %v", err)
+		}
+	}()
+
+	var input ddb.DescribeStreamInput
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(body, &input)
+
+	return &input, err
+}
+
+type sequenceNumberRange struct {
+	StartingSequenceNumber *string `json:",omitempty"`
+	EndingSequenceNumber   *string `json:",omitempty"`
+}
+
+type shard struct {
+	ShardId             *string
+	SequenceNumberRange *sequenceNumberRange
+}
+
+type streamDescription struct {
+	StreamArn               *string
+	StreamLabel             *string
+	StreamStatus            string
+	StreamViewType          types.StreamViewType
+	CreationRequestDateTime *timestamp
+	TableName               *string
+	KeySchema               []types.KeySchemaElement
+	Shards                  []shard
+}
+
+type describeStreamOutput struct {
+	StreamDescription *streamDescription
+}
+
+func EncodeDescribeStreamOutput(output *ddb.DescribeStreamOutput) ([]byte, error) {
+	description := output.StreamDescription
+	shards := make([]shard, len(description.Shards))
+	for i, s := range description.Shards {
+		shards[i] = shard{
+			ShardId: s.ShardId,
+			SequenceNumberRange: &sequenceNumberRange{
+				StartingSequenceNumber: s.SequenceNumberRange.StartingSequenceNumber,
+				EndingSequenceNumber:   s.SequenceNumberRange.EndingSequenceNumber,
+			},
+		}
+	}
+
+	output2 := describeStreamOutput{
+		StreamDescription: &streamDescription{
+			StreamArn:               description.StreamArn,
+			StreamLabel:             description.StreamLabel,
+			StreamStatus:            description.StreamStatus,
+			StreamViewType:          description.StreamViewType,
+			CreationRequestDateTime: newTimestamp(description.CreationRequestDateTime),
+			TableName:               description.TableName,
+			KeySchema:               description.KeySchema,
+			Shards:                  shards,
+		},
+	}
+	bs, err := json.Marshal(output2)
+	return bs, err
+}
+
+func DecodeGetShardIteratorInput(reader io.ReadCloser) (*ddb.GetShardIteratorInput, error) {
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("Error closing request body: %v", err)
+		}
+	}()
+
+	var input ddb.GetShardIteratorInput
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(body, &input)
+
+	return &input, err
+}
+
+func EncodeGetShardIteratorOutput(output *ddb.GetShardIteratorOutput) ([]byte, error) {
+	bs, err := json.Marshal(output)
+	return bs, err
+}
+
+func DecodeGetRecordsInput(reader io.ReadCloser) (*ddb.GetRecordsInput, error) {
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("Error closing request body: %v", err)
+		}
+	}()
+
+	var input ddb.GetRecordsInput
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(body, &input)
+
+	return &input, err
+}
+
+type streamRecord struct {
+	ApproximateCreationDateTime *timestamp
+	Keys                        map[string]core.AttributeValue
+	NewImage                    map[string]core.AttributeValue `json:",omitempty"`
+	OldImage                    map[string]core.AttributeValue `json:",omitempty"`
+	SequenceNumber              *string
+	SizeBytes                   int64
+	StreamViewType              types.StreamViewType
+}
+
+// record follows the field names of DynamoDB Streams, they are lower camel case unlike the other shapes
+type record struct {
+	AwsRegion    string        `json:"awsRegion"`
+	EventID      string        `json:"eventID"`
+	EventName    string        `json:"eventName"`
+	EventSource  string        `json:"eventSource"`
+	EventVersion string        `json:"eventVersion"`
+	Dynamodb     *streamRecord `json:"dynamodb"`
+}
+
+type getRecordsOutput struct {
+	Records           []record
+	NextShardIterator *string `json:",omitempty"`
+}
+
+func EncodeGetRecordsOutput(output *ddb.GetRecordsOutput) ([]byte, error) {
+	records := make([]record, len(output.Records))
+	for i, r := range output.Records {
+		records[i] = record{
+			AwsRegion:    r.AwsRegion,
+			EventID:      r.EventID,
+			EventName:    r.EventName,
+			EventSource:  r.EventSource,
+			EventVersion: r.EventVersion,
+			Dynamodb: &streamRecord{
+				ApproximateCreationDateTime: newTimestamp(r.Dynamodb.ApproximateCreationDateTime),
+				Keys:                        r.Dynamodb.Keys,
+				NewImage:                    r.Dynamodb.NewImage,
+				OldImage:                    r.Dynamodb.OldImage,
+				SequenceNumber:              r.Dynamodb.SequenceNumber,
+				SizeBytes:                   r.Dynamodb.SizeBytes,
+				StreamViewType:              r.Dynamodb.StreamViewType,
+			},
+		}
+	}
+
+	output2 := getRecordsOutput{
+		Records:           records,
+		NextShardIterator: output.NextShardIterator,
+	}
+	bs, err := json.Marshal(output2)
+	return bs, err
+}
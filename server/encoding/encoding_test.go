@@ -0,0 +1,138 @@
+package encoding
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestDecodeReturnConsumedCapacity(t *testing.T) {
+	key := `{"year": {"N": "2025"}, "title": {"S": "Hello World"}}`
+	tests := []struct {
+		name   string
+		body   string
+		decode func(reader io.ReadCloser) (types.ReturnConsumedCapacity, error)
+	}{
+		{
+			name: "Query",
+			body: `{"TableName": "movie", "KeyConditionExpression": "#year = :year", "ReturnConsumedCapacity": "TOTAL"}`,
+			decode: func(reader io.ReadCloser) (types.ReturnConsumedCapacity, error) {
+				input, err := DecodeQueryInput(reader)
+				if err != nil {
+					return "", err
+				}
+				return input.ReturnConsumedCapacity, nil
+			},
+		},
+		{
+			name: "Scan",
+			body: `{"TableName": "movie", "ReturnConsumedCapacity": "TOTAL"}`,
+			decode: func(reader io.ReadCloser) (types.ReturnConsumedCapacity, error) {
+				input, err := DecodeScanInput(reader)
+				if err != nil {
+					return "", err
+				}
+				return input.ReturnConsumedCapacity, nil
+			},
+		},
+		{
+			name: "DeleteItem",
+			body: `{"TableName": "movie", "Key": ` + key + `, "ReturnConsumedCapacity": "TOTAL"}`,
+			decode: func(reader io.ReadCloser) (types.ReturnConsumedCapacity, error) {
+				input, err := DecodeDeleteItemInput(reader)
+				if err != nil {
+					return "", err
+				}
+				return input.ReturnConsumedCapacity, nil
+			},
+		},
+		{
+			name: "BatchGetItem",
+			body: `{"RequestItems": {"movie": {"Keys": [` + key + `]}}, "ReturnConsumedCapacity": "TOTAL"}`,
+			decode: func(reader io.ReadCloser) (types.ReturnConsumedCapacity, error) {
+				input, err := DecodeBatchGetItemInput(reader)
+				if err != nil {
+					return "", err
+				}
+				return input.ReturnConsumedCapacity, nil
+			},
+		},
+		{
+			name: "BatchWriteItem",
+			body: `{"RequestItems": {"movie": [{"DeleteRequest": {"Key": ` + key + `}}]}, "ReturnConsumedCapacity": "TOTAL"}`,
+			decode: func(reader io.ReadCloser) (types.ReturnConsumedCapacity, error) {
+				input, err := DecodeBatchWriteItemInput(reader)
+				if err != nil {
+					return "", err
+				}
+				return input.ReturnConsumedCapacity, nil
+			},
+		},
+		{
+			name: "TransactWriteItems",
+			body: `{"TransactItems": [{"Delete": {"TableName": "movie", "Key": ` + key + `}}], "ReturnConsumedCapacity": "TOTAL"}`,
+			decode: func(reader io.ReadCloser) (types.ReturnConsumedCapacity, error) {
+				input, err := DecodeTransactWriteItemsInput(reader)
+				if err != nil {
+					return "", err
+				}
+				return input.ReturnConsumedCapacity, nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		returnConsumedCapacity, err := tt.decode(io.NopCloser(strings.NewReader(tt.body)))
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", tt.name, err)
+		}
+		if returnConsumedCapacity != types.ReturnConsumedCapacityTotal {
+			t.Fatalf("%s: expected ReturnConsumedCapacity to be TOTAL, got %q", tt.name, returnConsumedCapacity)
+		}
+	}
+}
+
+func TestEncodeQueryOutputSortsAttributes(t *testing.T) {
+	newItem := func(names []string) map[string]types.AttributeValue {
+		info := make(map[string]types.AttributeValue)
+		item := make(map[string]types.AttributeValue)
+		for _, name := range names {
+			info[name] = &types.AttributeValueMemberS{Value: name}
+			item[name] = &types.AttributeValueMemberN{Value: "1"}
+		}
+		item["info"] = &types.AttributeValueMemberM{Value: info}
+		return item
+	}
+	names := []string{"year", "title", "rating", "actors", "plot", "director", "genre", "studio"}
+	reversed := make([]string, len(names))
+	for i, name := range names {
+		reversed[len(names)-1-i] = name
+	}
+
+	var expected string
+	for i := 0; i < 20; i++ {
+		item := newItem(names)
+		if i%2 == 1 {
+			item = newItem(reversed)
+		}
+		bs, err := EncodeQueryOutput(&dynamodb.QueryOutput{
+			Count: 1,
+			Items: []map[string]types.AttributeValue{item},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if i == 0 {
+			expected = string(bs)
+		} else if string(bs) != expected {
+			t.Fatalf("expected identical items to be encoded to %s, got %s", expected, bs)
+		}
+	}
+
+	if !strings.Contains(expected, `"info":{"M":{"actors":{"S":"actors"},"director":{"S":"director"}`) {
+		t.Fatalf("expected attributes to be sorted by name, got %s", expected)
+	}
+}
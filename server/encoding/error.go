@@ -0,0 +1,119 @@
+package encoding
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ocowchun/baddb/ddb"
+	"github.com/ocowchun/baddb/ddb/storage"
+)
+
+// DDB_ERROR_TYPE_PREFIX is the namespace of the __type of DynamoDB errors, the SDKs deserialize an error by the part
+// after #. ValidationException and UnknownOperationException are in the namespaces of the service framework instead
+const DDB_ERROR_TYPE_PREFIX = "com.amazonaws.dynamodb.v20120810#"
+
+// ErrorResponse is the JSON body of an error response
+type ErrorResponse struct {
+	Type                string                   `json:"__type"`
+	Message             string                   `json:"message"`
+	CancellationReasons []ddb.CancellationReason `json:",omitempty"`
+}
+
+// UnknownOperationException is returned for an X-Amz-Target baddb doesn't implement, the message lists
+// SupportedTargets to tell a typo from an operation baddb doesn't have
+type UnknownOperationException struct {
+	Target           string
+	SupportedTargets []string
+}
+
+func (e *UnknownOperationException) Error() string {
+	if len(e.SupportedTargets) == 0 {
+		return fmt.Sprintf("Unknown operation %s", e.Target)
+	}
+	return fmt.Sprintf("Unknown operation %s, supported operations: %s", e.Target, strings.Join(e.SupportedTargets, ", "))
+}
+
+// EncodeError maps err to the HTTP status and the body DynamoDB responds with, an error
+// that isn't a DynamoDB error is an InternalServerError
+func EncodeError(outputErr error) (int, []byte, error) {
+	status, errResponse := buildErrorResponse(outputErr)
+	bs, err := json.Marshal(errResponse)
+	return status, bs, err
+}
+
+func buildErrorResponse(outputErr error) (int, ErrorResponse) {
+	var resourceInUseException *types.ResourceInUseException
+	var resourceNotFoundException *types.ResourceNotFoundException
+	var validationException *ddb.ValidationException
+	var provisionedThroughputExceededException *types.ProvisionedThroughputExceededException
+	var conditionalCheckFailedException *storage.ConditionalCheckFailedException
+	var transactionCanceledException *ddb.TransactionCanceledException
+	var duplicateItemException *types.DuplicateItemException
+	var idempotentParameterMismatchException *types.IdempotentParameterMismatchException
+	var unknownOperationException *UnknownOperationException
+
+	switch {
+	case errors.Is(outputErr, context.Canceled) || errors.Is(outputErr, context.DeadlineExceeded):
+		// the client gave up on the request, e.g. its request timeout is reached
+		return http.StatusRequestTimeout, ErrorResponse{
+			Type:    DDB_ERROR_TYPE_PREFIX + "RequestTimeoutException",
+			Message: outputErr.Error(),
+		}
+	case errors.As(outputErr, &resourceInUseException):
+		return http.StatusBadRequest, ErrorResponse{
+			Type:    DDB_ERROR_TYPE_PREFIX + "ResourceInUseException",
+			Message: resourceInUseException.ErrorMessage(),
+		}
+	case errors.As(outputErr, &resourceNotFoundException):
+		return http.StatusBadRequest, ErrorResponse{
+			Type:    DDB_ERROR_TYPE_PREFIX + "ResourceNotFoundException",
+			Message: resourceNotFoundException.ErrorMessage(),
+		}
+	case errors.As(outputErr, &provisionedThroughputExceededException):
+		return http.StatusBadRequest, ErrorResponse{
+			Type:    DDB_ERROR_TYPE_PREFIX + "ProvisionedThroughputExceededException",
+			Message: provisionedThroughputExceededException.ErrorMessage(),
+		}
+	case errors.As(outputErr, &unknownOperationException):
+		return http.StatusBadRequest, ErrorResponse{
+			Type:    "com.amazon.coral.service#UnknownOperationException",
+			Message: unknownOperationException.Error(),
+		}
+	case errors.As(outputErr, &validationException):
+		return http.StatusBadRequest, ErrorResponse{
+			Type:    "com.amazon.coral.validate#ValidationException",
+			Message: validationException.Error(),
+		}
+	case errors.As(outputErr, &conditionalCheckFailedException):
+		return http.StatusBadRequest, ErrorResponse{
+			Type:    DDB_ERROR_TYPE_PREFIX + "ConditionalCheckFailedException",
+			Message: conditionalCheckFailedException.Message,
+		}
+	case errors.As(outputErr, &duplicateItemException):
+		return http.StatusBadRequest, ErrorResponse{
+			Type:    DDB_ERROR_TYPE_PREFIX + "DuplicateItemException",
+			Message: duplicateItemException.ErrorMessage(),
+		}
+	case errors.As(outputErr, &idempotentParameterMismatchException):
+		return http.StatusBadRequest, ErrorResponse{
+			Type:    DDB_ERROR_TYPE_PREFIX + "IdempotentParameterMismatchException",
+			Message: idempotentParameterMismatchException.ErrorMessage(),
+		}
+	case errors.As(outputErr, &transactionCanceledException):
+		return http.StatusBadRequest, ErrorResponse{
+			Type:                DDB_ERROR_TYPE_PREFIX + "TransactionCanceledException",
+			Message:             transactionCanceledException.Error(),
+			CancellationReasons: transactionCanceledException.CancellationReasons,
+		}
+	default:
+		return http.StatusInternalServerError, ErrorResponse{
+			Type:    DDB_ERROR_TYPE_PREFIX + "InternalServerError",
+			Message: outputErr.Error(),
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package encoding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ocowchun/baddb/ddb"
+	"github.com/ocowchun/baddb/ddb/storage"
+)
+
+func TestEncodeError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "ValidationException",
+			err:            &ddb.ValidationException{Message: "The table does not have the specified index: missing"},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"__type":"com.amazon.coral.validate#ValidationException","message":"The table does not have the specified index: missing"}`,
+		},
+		{
+			name:           "wrapped ValidationException",
+			err:            fmt.Errorf("put: %w", &ddb.ValidationException{Message: "invalid"}),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"__type":"com.amazon.coral.validate#ValidationException","message":"invalid"}`,
+		},
+		{
+			name:           "ResourceNotFoundException",
+			err:            &types.ResourceNotFoundException{Message: aws.String("Cannot do operations on a non-existent table")},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"__type":"com.amazonaws.dynamodb.v20120810#ResourceNotFoundException","message":"Cannot do operations on a non-existent table"}`,
+		},
+		{
+			name:           "ResourceInUseException",
+			err:            &types.ResourceInUseException{Message: aws.String("Table already exists: movie")},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"__type":"com.amazonaws.dynamodb.v20120810#ResourceInUseException","message":"Table already exists: movie"}`,
+		},
+		{
+			name:           "ProvisionedThroughputExceededException",
+			err:            &types.ProvisionedThroughputExceededException{Message: aws.String("exceeded")},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"__type":"com.amazonaws.dynamodb.v20120810#ProvisionedThroughputExceededException","message":"exceeded"}`,
+		},
+		{
+			name:           "ConditionalCheckFailedException",
+			err:            &storage.ConditionalCheckFailedException{Message: "The conditional request failed"},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"__type":"com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException","message":"The conditional request failed"}`,
+		},
+		{
+			name: "TransactionCanceledException",
+			err: &ddb.TransactionCanceledException{CancellationReasons: []ddb.CancellationReason{
+				{Code: "None"},
+				{Code: "ConditionalCheckFailed", Message: "The conditional request failed"},
+			}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"__type":"com.amazonaws.dynamodb.v20120810#TransactionCanceledException","message":"Transaction cancelled, please refer cancellation reasons for specific reasons [None, ConditionalCheckFailed]","CancellationReasons":[{"Code":"None"},{"Code":"ConditionalCheckFailed","Message":"The conditional request failed"}]}`,
+		},
+		{
+			name:           "UnknownOperationException",
+			err:            &UnknownOperationException{Target: "DynamoDB_20120810.DropEverything"},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"__type":"com.amazon.coral.service#UnknownOperationException","message":"Unknown operation DynamoDB_20120810.DropEverything"}`,
+		},
+		{
+			name:           "canceled request",
+			err:            context.Canceled,
+			expectedStatus: http.StatusRequestTimeout,
+			expectedBody:   `{"__type":"com.amazonaws.dynamodb.v20120810#RequestTimeoutException","message":"context canceled"}`,
+		},
+		{
+			name:           "internal error",
+			err:            errors.New("disk I/O error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"__type":"com.amazonaws.dynamodb.v20120810#InternalServerError","message":"disk I/O error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		status, bs, err := EncodeError(tt.err)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if status != tt.expectedStatus {
+			t.Errorf("%s: expected status %d but got %d", tt.name, tt.expectedStatus, status)
+		}
+		if string(bs) != tt.expectedBody {
+			t.Errorf("%s: expected body %s but got %s", tt.name, tt.expectedBody, bs)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func seedMovies(t *testing.T, client *dynamodb.Client) {
+	t.Helper()
+	_, err := createTable(client, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := putItem(client, 2025, fmt.Sprintf("Hello World %d", i), fmt.Sprintf("message %d", i), "1", "US"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if _, err := putItem(client, 2024, "Hello World 0", "message", "2", "TW"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestExecuteStatementSelect(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	client := newDdbClient()
+	seedMovies(t, client)
+
+	// with the partition key, SELECT is a query
+	output, err := client.ExecuteStatement(context.Background(), &dynamodb.ExecuteStatementInput{
+		Statement: aws.String(`SELECT title, message FROM "movie" WHERE "year" = ? AND title >= 'Hello World 3'`),
+		Parameters: []types.AttributeValue{
+			&types.AttributeValueMemberN{Value: "2025"},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(output.Items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(output.Items))
+	}
+	if len(output.Items[0]) != 2 || output.Items[0]["title"].(*types.AttributeValueMemberS).Value != "Hello World 3" {
+		t.Fatalf("Unexpected item %v", output.Items[0])
+	}
+	if output.NextToken != nil {
+		t.Fatalf("Expected no NextToken without Limit")
+	}
+
+	// without the partition key, SELECT is a scan
+	output, err = client.ExecuteStatement(context.Background(), &dynamodb.ExecuteStatementInput{
+		Statement:      aws.String(`SELECT * FROM movie WHERE title = 'Hello World 0'`),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(output.Items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(output.Items))
+	}
+
+	// pages of a SELECT are chained by NextToken
+	titles := make([]string, 0)
+	var nextToken *string
+	for page := 0; page < 10; page++ {
+		output, err = client.ExecuteStatement(context.Background(), &dynamodb.ExecuteStatementInput{
+			Statement:      aws.String(`SELECT * FROM movie WHERE "year" = 2025`),
+			Limit:          aws.Int32(2),
+			NextToken:      nextToken,
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, item := range output.Items {
+			titles = append(titles, item["title"].(*types.AttributeValueMemberS).Value)
+		}
+		nextToken = output.NextToken
+		if nextToken == nil {
+			break
+		}
+	}
+	if len(titles) != 5 || titles[0] != "Hello World 0" || titles[4] != "Hello World 4" {
+		t.Fatalf("Expected 5 titles in order, got %v", titles)
+	}
+
+	_, err = client.ExecuteStatement(context.Background(), &dynamodb.ExecuteStatementInput{
+		Statement: aws.String(`SELECT * FROM movie WHERE "year" = ?`),
+	})
+	var validationErr interface{ ErrorCode() string }
+	if !errors.As(err, &validationErr) || validationErr.ErrorCode() != "ValidationException" {
+		t.Fatalf("Expected ValidationException for missing parameters, got %v", err)
+	}
+}
+
+func TestExecuteStatementSelectPagesThroughManyItems(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	client := newDdbClient()
+	_, err := createTable(client, 1000, 1000)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	count := 150
+	for i := 0; i < count; i++ {
+		if _, err := putItem(client, 2025, fmt.Sprintf("Hello World %03d", i), "message", "1", "US"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	// a page has at most 100 items, with or without Limit
+	for _, limit := range []*int32{nil, aws.Int32(200)} {
+		for _, statement := range []string{`SELECT * FROM movie WHERE "year" = 2025`, `SELECT * FROM movie`} {
+			titles := make(map[string]bool)
+			pages := 0
+			var nextToken *string
+			for {
+				output, err := client.ExecuteStatement(context.Background(), &dynamodb.ExecuteStatementInput{
+					Statement:      aws.String(statement),
+					Limit:          limit,
+					NextToken:      nextToken,
+					ConsistentRead: aws.Bool(true),
+				})
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				pages++
+				if len(output.Items) > 100 {
+					t.Fatalf("Expected at most 100 items in a page, got %d", len(output.Items))
+				}
+				for _, item := range output.Items {
+					titles[item["title"].(*types.AttributeValueMemberS).Value] = true
+				}
+				if output.NextToken == nil {
+					break
+				}
+				nextToken = output.NextToken
+			}
+			if len(titles) != count || pages != 2 {
+				t.Fatalf("Expected %d items in 2 pages for %s, got %d items in %d pages", count, statement, len(titles), pages)
+			}
+		}
+	}
+}
+
+func TestExecuteStatementSelectNestedPaths(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	client := newDdbClient()
+	_, err := createTable(client, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, err = client.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String("movie"),
+		Item: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "1994"},
+			"title": &types.AttributeValueMemberS{Value: "The Shawshank Redemption"},
+			"info": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"rating": &types.AttributeValueMemberN{Value: "9.3"},
+				"actors": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "Tim Robbins"},
+					&types.AttributeValueMemberS{Value: "Morgan Freeman"},
+				}},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// a projected path is named after its last attribute name, paths not in the item are skipped
+	output, err := client.ExecuteStatement(context.Background(), &dynamodb.ExecuteStatementInput{
+		Statement:      aws.String(`SELECT title, info.rating, info.actors[1], info.director FROM movie WHERE "year" = 1994`),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(output.Items) != 1 {
+		t.Fatalf("Expected 1 item, got %d", len(output.Items))
+	}
+	item := output.Items[0]
+	if len(item) != 3 {
+		t.Fatalf("Expected title, rating and actors, got %v", item)
+	}
+	if rating, ok := item["rating"].(*types.AttributeValueMemberN); !ok || rating.Value != "9.3" {
+		t.Fatalf("Expected rating 9.3, got %v", item["rating"])
+	}
+	if actor, ok := item["actors"].(*types.AttributeValueMemberS); !ok || actor.Value != "Morgan Freeman" {
+		t.Fatalf("Expected the second actor, got %v", item["actors"])
+	}
+}
+
+func TestExecuteStatementWrites(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	client := newDdbClient()
+	seedMovies(t, client)
+	ctx := context.Background()
+
+	_, err := client.ExecuteStatement(ctx, &dynamodb.ExecuteStatementInput{
+		Statement: aws.String(`INSERT INTO movie VALUE {'year': 2026, 'title': ?, 'message': 'new', 'tags': <<'a', 'b'>>}`),
+		Parameters: []types.AttributeValue{
+			&types.AttributeValueMemberS{Value: "Inserted"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	item := getMovie(t, client, "2026", "Inserted")
+	if item["message"].(*types.AttributeValueMemberS).Value != "new" {
+		t.Fatalf("Unexpected item %v", item)
+	}
+
+	// INSERT doesn't replace an item
+	_, err = client.ExecuteStatement(ctx, &dynamodb.ExecuteStatementInput{
+		Statement: aws.String(`INSERT INTO movie VALUE {'year': 2026, 'title': 'Inserted'}`),
+	})
+	var duplicateItemException *types.DuplicateItemException
+	if !errors.As(err, &duplicateItemException) {
+		t.Fatalf("Expected DuplicateItemException, got %v", err)
+	}
+
+	_, err = client.ExecuteStatement(ctx, &dynamodb.ExecuteStatementInput{
+		Statement: aws.String(`UPDATE movie SET message = ? REMOVE tags WHERE "year" = 2026 AND title = 'Inserted'`),
+		Parameters: []types.AttributeValue{
+			&types.AttributeValueMemberS{Value: "updated"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	item = getMovie(t, client, "2026", "Inserted")
+	if item["message"].(*types.AttributeValueMemberS).Value != "updated" || item["tags"] != nil {
+		t.Fatalf("Unexpected item %v", item)
+	}
+
+	// UPDATE doesn't create an item
+	_, err = client.ExecuteStatement(ctx, &dynamodb.ExecuteStatementInput{
+		Statement: aws.String(`UPDATE movie SET message = 'x' WHERE "year" = 2027 AND title = 'Missing'`),
+	})
+	var conditionalCheckFailedException *types.ConditionalCheckFailedException
+	if !errors.As(err, &conditionalCheckFailedException) {
+		t.Fatalf("Expected ConditionalCheckFailedException, got %v", err)
+	}
+
+	_, err = client.ExecuteStatement(ctx, &dynamodb.ExecuteStatementInput{
+		Statement: aws.String(`UPDATE movie SET message = 'x' WHERE "year" = 2026`),
+	})
+	if err == nil {
+		t.Fatalf("Expected UPDATE without the sort key to fail")
+	}
+
+	// the rest of the WHERE clause is a condition of DELETE
+	_, err = client.ExecuteStatement(ctx, &dynamodb.ExecuteStatementInput{
+		Statement: aws.String(`DELETE FROM movie WHERE "year" = 2026 AND title = 'Inserted' AND message = 'new'`),
+	})
+	if !errors.As(err, &conditionalCheckFailedException) {
+		t.Fatalf("Expected ConditionalCheckFailedException, got %v", err)
+	}
+	_, err = client.ExecuteStatement(ctx, &dynamodb.ExecuteStatementInput{
+		Statement: aws.String(`DELETE FROM movie WHERE "year" = 2026 AND title = 'Inserted'`),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if item := getMovie(t, client, "2026", "Inserted"); item != nil {
+		t.Fatalf("Expected the item to be deleted, got %v", item)
+	}
+}
+
+func getMovie(t *testing.T, client *dynamodb.Client, year string, title string) map[string]types.AttributeValue {
+	t.Helper()
+	output, err := client.GetItem(context.Background(), &dynamodb.GetItemInput{
+		TableName: aws.String("movie"),
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: year},
+			"title": &types.AttributeValueMemberS{Value: title},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return output.Item
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ocowchun/baddb/ddb"
+)
+
+func TestImportItems(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	client := newDdbClient()
+	_, err := createTable(client, 100, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	body := `{"TableName": "movie", "Items": [
+		{"year": {"N": "2025"}, "title": {"S": "Hello World 1"}, "regionCode": {"S": "1"}, "countryCode": {"S": "US"}},
+		{"year": {"N": "2025"}, "title": {"S": "Hello World 2"}, "regionCode": {"S": "1"}, "countryCode": {"S": "US"}},
+		{"year": {"N": "2025"}, "title": {"S": "Hello World 3"}, "regionCode": {"S": "1"}, "countryCode": {"S": "US"}}
+	]}`
+	res, err := http.Post("http://localhost:8080"+IMPORT_ITEMS_PATH, "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer res.Body.Close()
+	bs, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", res.StatusCode, bs)
+	}
+	var output ddb.ImportItemsOutput
+	if err := json.Unmarshal(bs, &output); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if output.ImportedItemCount != 3 {
+		t.Fatalf("Expected 3 imported items, got %d", output.ImportedItemCount)
+	}
+
+	// imported items skip the write capacity of 1 and the 60 seconds GSI delay createTable configures
+	queryOutput, err := client.Query(context.Background(), &dynamodb.QueryInput{
+		TableName:              aws.String("movie"),
+		IndexName:              aws.String("regionGSI"),
+		KeyConditionExpression: aws.String("regionCode = :regionCode"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":regionCode": &types.AttributeValueMemberS{Value: "1"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(queryOutput.Items) != 3 {
+		t.Fatalf("Expected 3 items from GSI, got %d", len(queryOutput.Items))
+	}
+}
+
+func TestImportItems_ReportsInvalidItems(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	client := newDdbClient()
+	_, err := createTable(client, 100, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	body := `{"TableName": "movie", "Items": [
+		{"year": {"N": "2025"}, "title": {"S": "Hello World 1"}, "regionCode": {"S": "1"}, "countryCode": {"S": "US"}},
+		{"year": {"N": "2025"}, "regionCode": {"S": "1"}, "countryCode": {"S": "US"}},
+		{"year": {"S": "2025"}, "title": {"S": "Hello World 3"}},
+		{"year": {"N": "2025"}, "title": {"S": "Hello World 4"}, "regionCode": {"N": "1"}},
+		{"year": {"N": "2025"}, "title": {"S": "Hello World 5"}},
+		{"year": {"N": "2025"}, "title": {"S": ""}},
+		{"year": {"N": "2025"}, "title": {"S": "Hello World 7"}, "tags": {"SS": []}}
+	]}`
+	res, err := http.Post("http://localhost:8080"+IMPORT_ITEMS_PATH, "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer res.Body.Close()
+	bs, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", res.StatusCode, bs)
+	}
+	var output ddb.ImportItemsOutput
+	if err := json.Unmarshal(bs, &output); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// an item without the GSI keys is still valid
+	if output.ImportedItemCount != 2 {
+		t.Fatalf("Expected 2 imported items, got %d", output.ImportedItemCount)
+	}
+	expectedMessages := map[int]string{
+		1: "One or more parameter values were invalid: Missing the key title in the item",
+		2: "One or more parameter values were invalid: Type mismatch for key year expected: N actual: S",
+		3: "One or more parameter values were invalid: Type mismatch for Index Key regionCode Expected: S Actual: N IndexName: regionGSI",
+		5: "One or more parameter values are not valid. The AttributeValue for a key attribute cannot contain an empty string value. Key: title",
+		6: "One or more parameter values were invalid: An string set  may not be empty",
+	}
+	if len(output.FailedItems) != len(expectedMessages) {
+		t.Fatalf("Expected %d failed items, got %v", len(expectedMessages), output.FailedItems)
+	}
+	for _, failedItem := range output.FailedItems {
+		if expectedMessages[failedItem.Index] != failedItem.Message {
+			t.Fatalf("Expected item %d to fail with %q, got %q", failedItem.Index, expectedMessages[failedItem.Index], failedItem.Message)
+		}
+	}
+
+	for title, expected := range map[string]bool{"Hello World 1": true, "Hello World 3": false, "Hello World 5": true} {
+		item := getMovie(t, client, "2025", title)
+		if (item != nil) != expected {
+			t.Fatalf("Expected %s to be imported: %v, got %v", title, expected, item)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+type countingRoundTripper struct {
+	count *atomic.Int32
+}
+
+func (rt countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.count.Add(1)
+	return nil, net.ErrClosed
+}
+
+// TestNoDefaultTransportOrResolverUse serves requests in process and asserts baddb makes no request through
+// http.DefaultTransport and no lookup through net.DefaultResolver. Connections made with another transport or
+// dialer aren't caught
+func TestNoDefaultTransportOrResolverUse(t *testing.T) {
+	var calls atomic.Int32
+	defaultTransport := http.DefaultTransport
+	defaultResolver := net.DefaultResolver
+	http.DefaultTransport = countingRoundTripper{count: &calls}
+	net.DefaultResolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			calls.Add(1)
+			return nil, net.ErrClosed
+		},
+	}
+	defer func() {
+		http.DefaultTransport = defaultTransport
+		net.DefaultResolver = defaultResolver
+	}()
+
+	svr := NewDdbServer()
+	defer svr.Close()
+
+	requests := []struct {
+		target string
+		body   string
+	}{
+		{"DynamoDB_20120810.CreateTable", `{"TableName": "movie", "BillingMode": "PAY_PER_REQUEST",
+			"AttributeDefinitions": [{"AttributeName": "year", "AttributeType": "N"}, {"AttributeName": "title", "AttributeType": "S"}],
+			"KeySchema": [{"AttributeName": "year", "KeyType": "HASH"}, {"AttributeName": "title", "KeyType": "RANGE"}]}`},
+		{"DynamoDB_20120810.ListTables", `{}`},
+		{"DynamoDB_20120810.DescribeTable", `{"TableName": "movie"}`},
+		{"DynamoDB_20120810.PutItem", `{"TableName": "movie", "Item": {"year": {"N": "2025"}, "title": {"S": "Hello World"}}}`},
+		{"DynamoDB_20120810.GetItem", `{"TableName": "movie", "Key": {"year": {"N": "2025"}, "title": {"S": "Hello World"}}, "ConsistentRead": true}`},
+		{"DynamoDB_20120810.UpdateItem", `{"TableName": "movie", "Key": {"year": {"N": "2025"}, "title": {"S": "Hello World"}},
+			"UpdateExpression": "SET rating = :rating", "ExpressionAttributeValues": {":rating": {"N": "9"}}}`},
+		{"DynamoDB_20120810.Query", `{"TableName": "movie", "KeyConditionExpression": "#year = :year",
+			"ExpressionAttributeNames": {"#year": "year"}, "ExpressionAttributeValues": {":year": {"N": "2025"}}, "ConsistentRead": true}`},
+		{"DynamoDB_20120810.Scan", `{"TableName": "movie", "ConsistentRead": true}`},
+		{"DynamoDB_20120810.DeleteItem", `{"TableName": "movie", "Key": {"year": {"N": "2025"}, "title": {"S": "Hello World"}}}`},
+		{"DynamoDB_20120810.DeleteTable", `{"TableName": "movie"}`},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(r.body))
+		req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+		req.Header.Set("X-Amz-Target", r.target)
+		res := httptest.NewRecorder()
+		svr.Handler(res, req)
+		if res.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, got %d %s", r.target, res.Code, res.Body.String())
+		}
+	}
+
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("Expected no use of the default transport or resolver, got %d", n)
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func updatePartitionLimit(t *testing.T, body string) {
+	t.Helper()
+	res, err := http.Post("http://localhost:8080"+PARTITION_LIMIT_PATH, "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer res.Body.Close()
+	bs, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", res.StatusCode, bs)
+	}
+}
+
+func TestPartitionLimit(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	client := newDdbClient()
+	_, err := createTable(client, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	updatePartitionLimit(t, `{"TableNames": ["movie"], "MaxItemCount": 3}`)
+	for i := 0; i < 3; i++ {
+		if _, err := putItem(client, 2025, fmt.Sprintf("Hello World %d", i), "message", "1", "US"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	var provisionedThroughputExceededException *types.ProvisionedThroughputExceededException
+	_, err = putItem(client, 2025, "Hello World 3", "message", "1", "US")
+	if !errors.As(err, &provisionedThroughputExceededException) {
+		t.Fatalf("Expected ProvisionedThroughputExceededException, got %v", err)
+	}
+	_, err = client.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+		TableName: aws.String("movie"),
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World 3"},
+		},
+		UpdateExpression: aws.String("SET message = :message"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":message": &types.AttributeValueMemberS{Value: "updated"},
+		},
+	})
+	if !errors.As(err, &provisionedThroughputExceededException) {
+		t.Fatalf("Expected ProvisionedThroughputExceededException, got %v", err)
+	}
+
+	// replacing an item doesn't add an item to the partition, and other partitions aren't affected
+	if _, err := putItem(client, 2025, "Hello World 0", "updated", "1", "US"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := putItem(client, 2024, "Hello World 3", "message", "1", "US"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// a deleted item leaves room for another item
+	_, err = client.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{
+		TableName: aws.String("movie"),
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World 1"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := putItem(client, 2025, "Hello World 3", "message", "1", "US"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	updatePartitionLimit(t, `{"MaxSizeBytes": 1024}`)
+	_, err = putItem(client, 2024, "Hello World 4", strings.Repeat("a", 1024), "1", "US")
+	if !errors.As(err, &provisionedThroughputExceededException) {
+		t.Fatalf("Expected ProvisionedThroughputExceededException, got %v", err)
+	}
+
+	updatePartitionLimit(t, `{"MaxItemCount": 0, "MaxSizeBytes": 0}`)
+	for i := 4; i < 6; i++ {
+		if _, err := putItem(client, 2025, fmt.Sprintf("Hello World %d", i), strings.Repeat("a", 1024), "1", "US"); err != nil {
+			t.Fatalf("Expected no error after disabling the limit, got %v", err)
+		}
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
 )
 
 func TestQuery(t *testing.T) {
@@ -121,6 +123,76 @@ func TestQuery(t *testing.T) {
 
 // th
 
+func TestQuery_ScanIndexForwardPaging(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	items := make([]map[string]types.AttributeValue, 0)
+	for i := 0; i < 5; i++ {
+		item, err := putItem(ddb, 2025, fmt.Sprintf("Hello World %d", i), "message", "1", fmt.Sprintf("code%d", i))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		items = append(items, item)
+	}
+
+	queryPages := func(scanIndexForward *bool) []map[string]types.AttributeValue {
+		results := make([]map[string]types.AttributeValue, 0)
+		var exclusiveStartKey map[string]types.AttributeValue
+		for page := 0; page < 10; page++ {
+			queryOutput, err := ddb.Query(context.Background(), &dynamodb.QueryInput{
+				TableName:              aws.String("movie"),
+				KeyConditionExpression: aws.String("#year = :year"),
+				ExpressionAttributeNames: map[string]string{
+					"#year": "year",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":year": &types.AttributeValueMemberN{Value: "2025"},
+				},
+				ScanIndexForward:  scanIndexForward,
+				Limit:             aws.Int32(2),
+				ConsistentRead:    aws.Bool(true),
+				ExclusiveStartKey: exclusiveStartKey,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(queryOutput.Items) > 2 {
+				t.Fatalf("Expected at most 2 items per page, got %d", len(queryOutput.Items))
+			}
+			results = append(results, queryOutput.Items...)
+			if len(queryOutput.LastEvaluatedKey) == 0 {
+				return results
+			}
+			exclusiveStartKey = queryOutput.LastEvaluatedKey
+		}
+		t.Fatalf("Expected paging to end within 10 pages")
+		return nil
+	}
+
+	// ScanIndexForward defaults to true
+	results := queryPages(nil)
+	if len(results) != len(items) {
+		t.Fatalf("Expected %d items, got %d", len(items), len(results))
+	}
+	for i, item := range results {
+		assertPrimaryKey(item, items[i], t)
+	}
+
+	results = queryPages(aws.Bool(false))
+	if len(results) != len(items) {
+		t.Fatalf("Expected %d items, got %d", len(items), len(results))
+	}
+	for i, item := range results {
+		assertPrimaryKey(item, items[len(items)-1-i], t)
+	}
+}
+
 func TestQuery_ProvisionedThroughputExceededException(t *testing.T) {
 	shutdown := startServer()
 	defer shutdown()
@@ -290,6 +362,110 @@ func TestQueryWithGsi(t *testing.T) {
 }
 
 // TODO: check GSI's billing mode is PROVISIONED
+func TestQueryWithGsi_LastEvaluatedKeyHasOnlyKeys(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 5, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	updateTestTableMetadata(ddb, 0, 0, 0)
+
+	for i := 0; i < 3; i++ {
+		_, err := putItem(ddb, 2025, fmt.Sprintf("Hello World %d", i), "message", "1", fmt.Sprintf("code%d", i))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	assertLastEvaluatedKey := func(lastEvaluatedKey map[string]types.AttributeValue) {
+		t.Helper()
+		expected := map[string]string{"year": "2025", "title": "Hello World 1", "regionCode": "1", "countryCode": "code1"}
+		if len(lastEvaluatedKey) != len(expected) {
+			t.Fatalf("Expected LastEvaluatedKey to have %d attributes, got %v", len(expected), lastEvaluatedKey)
+		}
+		for name, value := range expected {
+			var actual string
+			switch v := lastEvaluatedKey[name].(type) {
+			case *types.AttributeValueMemberS:
+				actual = v.Value
+			case *types.AttributeValueMemberN:
+				actual = v.Value
+			}
+			if actual != value {
+				t.Fatalf("Expected %s to be %s in LastEvaluatedKey, got %v", name, value, lastEvaluatedKey[name])
+			}
+		}
+	}
+
+	queryOutput, err := ddb.Query(context.Background(), &dynamodb.QueryInput{
+		TableName:              aws.String("movie"),
+		KeyConditionExpression: aws.String("regionCode = :regionCode"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":regionCode": &types.AttributeValueMemberS{Value: "1"},
+		},
+		Limit:     aws.Int32(2),
+		IndexName: aws.String("regionGSI"),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	assertLastEvaluatedKey(queryOutput.LastEvaluatedKey)
+
+	scanOutput, err := ddb.Scan(context.Background(), &dynamodb.ScanInput{
+		TableName: aws.String("movie"),
+		Limit:     aws.Int32(2),
+		IndexName: aws.String("regionGSI"),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	assertLastEvaluatedKey(scanOutput.LastEvaluatedKey)
+}
+
+func TestQueryWithGsi_RejectsConsistentRead(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 5, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = ddb.Query(context.Background(), &dynamodb.QueryInput{
+		TableName:              aws.String("movie"),
+		KeyConditionExpression: aws.String("regionCode = :regionCode"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":regionCode": &types.AttributeValueMemberS{Value: "1"},
+		},
+		IndexName:      aws.String("regionGSI"),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err == nil || !strings.Contains(err.Error(), "Consistent reads are not supported on global secondary indexes") {
+		t.Fatalf("Expected consistent reads on GSI to be rejected, got %v", err)
+	}
+
+	_, err = ddb.Scan(context.Background(), &dynamodb.ScanInput{
+		TableName:      aws.String("movie"),
+		IndexName:      aws.String("regionGSI"),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err == nil || !strings.Contains(err.Error(), "Consistent reads are not supported on global secondary indexes") {
+		t.Fatalf("Expected consistent reads on GSI to be rejected, got %v", err)
+	}
+
+	// eventually consistent reads on GSI are fine
+	_, err = ddb.Scan(context.Background(), &dynamodb.ScanInput{
+		TableName:      aws.String("movie"),
+		IndexName:      aws.String("regionGSI"),
+		ConsistentRead: aws.Bool(false),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
 func TestQueryWithGsi_ProvisionedThroughputExceededException(t *testing.T) {
 	shutdown := startServer()
 	defer shutdown()
@@ -334,3 +510,603 @@ func TestQueryWithGsi_ProvisionedThroughputExceededException(t *testing.T) {
 
 	}
 }
+
+func TestQueryWithGsi_ThrottlesIndependentlyOfTable(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := ddb.CreateTable(context.Background(), &dynamodb.CreateTableInput{
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("year"), AttributeType: types.ScalarAttributeTypeN},
+			{AttributeName: aws.String("title"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("regionCode"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("countryCode"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("year"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("title"), KeyType: types.KeyTypeRange},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{{
+			IndexName: aws.String("regionGSI"),
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("regionCode"), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("countryCode"), KeyType: types.KeyTypeRange},
+			},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			// two eventually consistent reads in a burst
+			ProvisionedThroughput: &types.ProvisionedThroughput{
+				ReadCapacityUnits:  aws.Int64(1),
+				WriteCapacityUnits: aws.Int64(1),
+			},
+		}},
+		TableName:   aws.String("movie"),
+		BillingMode: types.BillingModeProvisioned,
+		ProvisionedThroughput: &types.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(100),
+			WriteCapacityUnits: aws.Int64(2),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	updateTestTableMetadata(ddb, 0, 0, 0)
+
+	// syncing the GSI doesn't consume the table's write capacity again, so both puts fit into the 2 WCUs
+	for i := 0; i < 2; i++ {
+		_, err := putItem(ddb, 2025, fmt.Sprintf("Hello World %d", i), "message", "1", fmt.Sprintf("code%d", i))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	queryGsi := func() error {
+		_, err := ddb.Query(context.Background(), &dynamodb.QueryInput{
+			TableName:              aws.String("movie"),
+			IndexName:              aws.String("regionGSI"),
+			KeyConditionExpression: aws.String("regionCode = :regionCode"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":regionCode": &types.AttributeValueMemberS{Value: "1"},
+			},
+		})
+		return err
+	}
+	if err := queryGsi(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	err = queryGsi()
+	var provisionedThroughputExceededException *types.ProvisionedThroughputExceededException
+	if !errors.As(err, &provisionedThroughputExceededException) {
+		t.Fatalf("Expected ProvisionedThroughputExceededException once the GSI's read capacity is used up, got %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		queryOutput, err := ddb.Query(context.Background(), &dynamodb.QueryInput{
+			TableName:              aws.String("movie"),
+			KeyConditionExpression: aws.String("#year = :year"),
+			ExpressionAttributeNames: map[string]string{
+				"#year": "year",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":year": &types.AttributeValueMemberN{Value: "2025"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Expected the table's reads not to be throttled by the GSI, got %v", err)
+		}
+		if len(queryOutput.Items) != 2 {
+			t.Fatalf("Expected 2 items, got %d", len(queryOutput.Items))
+		}
+	}
+}
+
+func TestQueryWithProjectionExpressionAndSelect(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	updateTestTableMetadata(ddb, 0, 0, 0)
+
+	for i := 0; i < 3; i++ {
+		_, err := putItem(ddb, 2025, fmt.Sprintf("Hello World %d", i), fmt.Sprintf("message %d", i), "1", fmt.Sprintf("code%d", i))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	newQueryInput := func() *dynamodb.QueryInput {
+		return &dynamodb.QueryInput{
+			TableName:              aws.String("movie"),
+			KeyConditionExpression: aws.String("#year = :year"),
+			ExpressionAttributeNames: map[string]string{
+				"#year": "year",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":year": &types.AttributeValueMemberN{Value: "2025"},
+			},
+		}
+	}
+
+	// ProjectionExpression implies SPECIFIC_ATTRIBUTES
+	{
+		queryInput := newQueryInput()
+		queryInput.ProjectionExpression = aws.String("title, #message")
+		queryInput.ExpressionAttributeNames["#message"] = "message"
+		queryOutput, err := ddb.Query(context.Background(), queryInput)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(queryOutput.Items) != 3 {
+			t.Fatalf("Expected 3 items, got %d", len(queryOutput.Items))
+		}
+		for i, item := range queryOutput.Items {
+			if len(item) != 2 {
+				t.Fatalf("Expected only title and message, got %v", item)
+			}
+			if message := item["message"].(*types.AttributeValueMemberS).Value; message != fmt.Sprintf("message %d", i) {
+				t.Fatalf("Expected message %d, got %s", i, message)
+			}
+		}
+	}
+
+	// COUNT returns counts only
+	{
+		queryInput := newQueryInput()
+		queryInput.Select = types.SelectCount
+		queryInput.FilterExpression = aws.String("countryCode <> :countryCode")
+		queryInput.ExpressionAttributeValues[":countryCode"] = &types.AttributeValueMemberS{Value: "code0"}
+		queryOutput, err := ddb.Query(context.Background(), queryInput)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if queryOutput.Items != nil {
+			t.Fatalf("Expected no items, got %v", queryOutput.Items)
+		}
+		if queryOutput.Count != 2 || queryOutput.ScannedCount != 3 {
+			t.Fatalf("Expected Count 2 and ScannedCount 3, got %d and %d", queryOutput.Count, queryOutput.ScannedCount)
+		}
+	}
+
+	// GSI queries return projected attributes by default
+	{
+		queryOutput, err := ddb.Query(context.Background(), &dynamodb.QueryInput{
+			TableName:              aws.String("movie"),
+			IndexName:              aws.String("regionGSI"),
+			KeyConditionExpression: aws.String("regionCode = :regionCode"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":regionCode": &types.AttributeValueMemberS{Value: "1"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(queryOutput.Items) != 3 || len(queryOutput.Items[0]) != 5 {
+			t.Fatalf("Expected 3 items with every attribute, got %v", queryOutput.Items)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		update   func(input *dynamodb.QueryInput)
+		expected string
+	}{
+		{
+			name: "SPECIFIC_ATTRIBUTES without ProjectionExpression",
+			update: func(input *dynamodb.QueryInput) {
+				input.Select = types.SelectSpecificAttributes
+			},
+			expected: "One or more parameter values were invalid: Select type SPECIFIC_ATTRIBUTES requires either AttributesToGet or ProjectionExpression",
+		},
+		{
+			name: "ProjectionExpression with ALL_ATTRIBUTES",
+			update: func(input *dynamodb.QueryInput) {
+				input.Select = types.SelectAllAttributes
+				input.ProjectionExpression = aws.String("title")
+			},
+			expected: "Cannot specify the ProjectionExpression when choosing to get ALL_ATTRIBUTES",
+		},
+		{
+			name: "ALL_PROJECTED_ATTRIBUTES without IndexName",
+			update: func(input *dynamodb.QueryInput) {
+				input.Select = types.SelectAllProjectedAttributes
+			},
+			expected: "One or more parameter values were invalid: ALL_PROJECTED_ATTRIBUTES can be used only when Querying using an IndexName",
+		},
+		{
+			name: "overlapping paths",
+			update: func(input *dynamodb.QueryInput) {
+				input.ProjectionExpression = aws.String("info, info.rating")
+			},
+			expected: "Invalid ProjectionExpression: Two document paths overlap with each other; must remove or rewrite one of these paths; path one: [info], path two: [info, rating]",
+		},
+	}
+	for _, tt := range tests {
+		queryInput := newQueryInput()
+		tt.update(queryInput)
+		_, err := ddb.Query(context.Background(), queryInput)
+		var apiErr smithy.APIError
+		if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+			t.Fatalf("Expected ValidationException for %s, got %v", tt.name, err)
+		}
+		if apiErr.ErrorMessage() != tt.expected {
+			t.Fatalf("Expected %s for %s, got %s", tt.expected, tt.name, apiErr.ErrorMessage())
+		}
+	}
+}
+
+func TestQueryWithKeysOnlyGsi_RejectsAllAttributes(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := ddb.CreateTable(context.Background(), &dynamodb.CreateTableInput{
+		TableName: aws.String("ticket"),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("id"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("status"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("id"), KeyType: types.KeyTypeHash},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String("statusGSI"),
+				KeySchema: []types.KeySchemaElement{
+					{AttributeName: aws.String("status"), KeyType: types.KeyTypeHash},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly},
+			},
+		},
+		BillingMode: types.BillingModePayPerRequest,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String("ticket"),
+		IndexName:              aws.String("statusGSI"),
+		KeyConditionExpression: aws.String("#status = :status"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status": &types.AttributeValueMemberS{Value: "open"},
+		},
+		Select: types.SelectAllAttributes,
+	}
+	_, err = ddb.Query(context.Background(), queryInput)
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+		t.Fatalf("Expected ValidationException, got %v", err)
+	}
+	expected := "One or more parameter values were invalid: Select type ALL_ATTRIBUTES is not supported for global secondary index statusGSI because its projection type is not ALL"
+	if apiErr.ErrorMessage() != expected {
+		t.Fatalf("Expected %s, got %s", expected, apiErr.ErrorMessage())
+	}
+
+	queryInput.Select = types.SelectAllProjectedAttributes
+	if _, err := ddb.Query(context.Background(), queryInput); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestQueryOrdersNumericSortKeys(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := ddb.CreateTable(context.Background(), &dynamodb.CreateTableInput{
+		TableName: aws.String("scores"),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("player"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("score"), AttributeType: types.ScalarAttributeTypeN},
+			{AttributeName: aws.String("game"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("player"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("score"), KeyType: types.KeyTypeRange},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String("gameScoreGSI"),
+				KeySchema: []types.KeySchemaElement{
+					{AttributeName: aws.String("game"), KeyType: types.KeyTypeHash},
+					{AttributeName: aws.String("score"), KeyType: types.KeyTypeRange},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			},
+		},
+		BillingMode: types.BillingModePayPerRequest,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// put in an order that differs from both the numeric and the lexicographic one
+	for _, score := range []string{"20", "1", "100", "10", "2"} {
+		_, err := ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+			TableName: aws.String("scores"),
+			Item: map[string]types.AttributeValue{
+				"player": &types.AttributeValueMemberS{Value: "alice"},
+				"score":  &types.AttributeValueMemberN{Value: score},
+				"game":   &types.AttributeValueMemberS{Value: "chess"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String("baddb_table_metadata"),
+		Item: map[string]types.AttributeValue{
+			"tableName":         &types.AttributeValueMemberS{Value: "scores"},
+			"tableDelaySeconds": &types.AttributeValueMemberN{Value: "0"},
+			"gsiDelaySeconds":   &types.AttributeValueMemberN{Value: "0"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	queryScores := func(input *dynamodb.QueryInput) []string {
+		t.Helper()
+		scores := make([]string, 0)
+		paginator := dynamodb.NewQueryPaginator(ddb, input)
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(context.Background())
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			for _, item := range output.Items {
+				scores = append(scores, item["score"].(*types.AttributeValueMemberN).Value)
+			}
+		}
+		return scores
+	}
+
+	tests := []struct {
+		name      string
+		indexName *string
+		keyCond   string
+		forward   bool
+		expected  []string
+	}{
+		{"ascending", nil, "player = :pk", true, []string{"1", "2", "10", "20", "100"}},
+		{"descending", nil, "player = :pk", false, []string{"100", "20", "10", "2", "1"}},
+		{"between", nil, "player = :pk AND score BETWEEN :low AND :high", true, []string{"2", "10", "20"}},
+		{"greater than", nil, "player = :pk AND score > :high", true, []string{"100"}},
+		{"GSI ascending", aws.String("gameScoreGSI"), "game = :game", true, []string{"1", "2", "10", "20", "100"}},
+		{"GSI descending", aws.String("gameScoreGSI"), "game = :game", false, []string{"100", "20", "10", "2", "1"}},
+	}
+	for _, tt := range tests {
+		values := map[string]types.AttributeValue{
+			":low":  &types.AttributeValueMemberN{Value: "2"},
+			":high": &types.AttributeValueMemberN{Value: "20"},
+		}
+		if tt.indexName == nil {
+			values[":pk"] = &types.AttributeValueMemberS{Value: "alice"}
+		} else {
+			values[":game"] = &types.AttributeValueMemberS{Value: "chess"}
+		}
+		if !strings.Contains(tt.keyCond, ":low") {
+			delete(values, ":low")
+		}
+		if !strings.Contains(tt.keyCond, ":high") {
+			delete(values, ":high")
+		}
+
+		// a page of 2 items checks ExclusiveStartKey follows the same order
+		scores := queryScores(&dynamodb.QueryInput{
+			TableName:                 aws.String("scores"),
+			IndexName:                 tt.indexName,
+			KeyConditionExpression:    aws.String(tt.keyCond),
+			ExpressionAttributeValues: values,
+			ScanIndexForward:          aws.Bool(tt.forward),
+			Limit:                     aws.Int32(2),
+		})
+		if strings.Join(scores, ",") != strings.Join(tt.expected, ",") {
+			t.Fatalf("%s: expected scores %v, got %v", tt.name, tt.expected, scores)
+		}
+	}
+}
+
+func TestQueryWithLsi(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	newCreateTableInput := func(lsiHashKey string) *dynamodb.CreateTableInput {
+		return &dynamodb.CreateTableInput{
+			TableName: aws.String("scores"),
+			AttributeDefinitions: []types.AttributeDefinition{
+				{AttributeName: aws.String("player"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("game"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("score"), AttributeType: types.ScalarAttributeTypeN},
+			},
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("player"), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("game"), KeyType: types.KeyTypeRange},
+			},
+			LocalSecondaryIndexes: []types.LocalSecondaryIndex{
+				{
+					IndexName: aws.String("playerScoreLSI"),
+					KeySchema: []types.KeySchemaElement{
+						{AttributeName: aws.String(lsiHashKey), KeyType: types.KeyTypeHash},
+						{AttributeName: aws.String("score"), KeyType: types.KeyTypeRange},
+					},
+					Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+				},
+			},
+			BillingMode: types.BillingModePayPerRequest,
+		}
+	}
+
+	// an LSI shares the partition key of the table
+	_, err := ddb.CreateTable(context.Background(), newCreateTableInput("game"))
+	var validationErr smithy.APIError
+	if !errors.As(err, &validationErr) || validationErr.ErrorCode() != "ValidationException" {
+		t.Fatalf("Expected ValidationException, got %v", err)
+	}
+
+	output, err := ddb.CreateTable(context.Background(), newCreateTableInput("player"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(output.TableDescription.LocalSecondaryIndexes) != 1 {
+		t.Fatalf("Expected 1 LocalSecondaryIndex, got %v", output.TableDescription.LocalSecondaryIndexes)
+	}
+
+	for game, score := range map[string]string{"chess": "20", "go": "1", "poker": "100", "shogi": "10"} {
+		_, err := ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+			TableName: aws.String("scores"),
+			Item: map[string]types.AttributeValue{
+				"player": &types.AttributeValueMemberS{Value: "alice"},
+				"game":   &types.AttributeValueMemberS{Value: game},
+				"score":  &types.AttributeValueMemberN{Value: score},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	// a consistent read on an LSI reflects the writes immediately
+	queryOutput, err := ddb.Query(context.Background(), &dynamodb.QueryInput{
+		TableName:              aws.String("scores"),
+		IndexName:              aws.String("playerScoreLSI"),
+		KeyConditionExpression: aws.String("player = :player AND score >= :score"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":player": &types.AttributeValueMemberS{Value: "alice"},
+			":score":  &types.AttributeValueMemberN{Value: "10"},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	games := make([]string, 0)
+	for _, item := range queryOutput.Items {
+		games = append(games, item["game"].(*types.AttributeValueMemberS).Value)
+	}
+	if strings.Join(games, ",") != "shogi,chess,poker" {
+		t.Fatalf("Expected games ordered by score, got %v", games)
+	}
+
+	_, err = ddb.Query(context.Background(), &dynamodb.QueryInput{
+		TableName:              aws.String("scores"),
+		IndexName:              aws.String("missingLSI"),
+		KeyConditionExpression: aws.String("player = :player"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":player": &types.AttributeValueMemberS{Value: "alice"},
+		},
+	})
+	if !errors.As(err, &validationErr) || validationErr.ErrorCode() != "ValidationException" {
+		t.Fatalf("Expected ValidationException for a missing index, got %v", err)
+	}
+}
+
+func TestQuery_RejectsUnusedExpressionAttributes(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	newQueryInput := func() *dynamodb.QueryInput {
+		return &dynamodb.QueryInput{
+			TableName:              aws.String("movie"),
+			KeyConditionExpression: aws.String("#year = :year"),
+			FilterExpression:       aws.String("#message = :message"),
+			ExpressionAttributeNames: map[string]string{
+				"#year":    "year",
+				"#message": "message",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":year":    &types.AttributeValueMemberN{Value: "2025"},
+				":message": &types.AttributeValueMemberS{Value: "Hello"},
+			},
+			ConsistentRead: aws.Bool(true),
+		}
+	}
+
+	// placeholders are used by either the key condition or the filter
+	if _, err := ddb.Query(context.Background(), newQueryInput()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	input := newQueryInput()
+	input.ExpressionAttributeNames["#title"] = "title"
+	_, err = ddb.Query(context.Background(), input)
+	if err == nil || !strings.Contains(err.Error(), "Value provided in ExpressionAttributeNames unused in expressions: keys: {#title}") {
+		t.Fatalf("Expected the unused name to be rejected, got %v", err)
+	}
+
+	input = newQueryInput()
+	input.ExpressionAttributeValues[":title"] = &types.AttributeValueMemberS{Value: "Hello World"}
+	_, err = ddb.Query(context.Background(), input)
+	if err == nil || !strings.Contains(err.Error(), "Value provided in ExpressionAttributeValues unused in expressions: keys: {:title}") {
+		t.Fatalf("Expected the unused value to be rejected, got %v", err)
+	}
+}
+
+func TestQueryWithDeeplyNestedPathFilter(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for i, d := range []string{"deep", "shallow", "deep"} {
+		_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+			TableName: aws.String("movie"),
+			Item: map[string]types.AttributeValue{
+				"year":  &types.AttributeValueMemberN{Value: "2025"},
+				"title": &types.AttributeValueMemberS{Value: fmt.Sprintf("Hello World %d", i)},
+				"info": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+					"b": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+						&types.AttributeValueMemberS{Value: "first"},
+						&types.AttributeValueMemberS{Value: "second"},
+						&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+							"c": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+								&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+									"d": &types.AttributeValueMemberS{Value: d},
+								}},
+							}},
+						}},
+					}},
+				}},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	output, err := ddb.Query(context.Background(), &dynamodb.QueryInput{
+		TableName:              aws.String("movie"),
+		KeyConditionExpression: aws.String("#year = :year"),
+		FilterExpression:       aws.String("info.b[2].c[0].d = :d"),
+		ExpressionAttributeNames: map[string]string{
+			"#year": "year",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":year": &types.AttributeValueMemberN{Value: "2025"},
+			":d":    &types.AttributeValueMemberS{Value: "deep"},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if output.Count != 2 || output.ScannedCount != 3 {
+		t.Fatalf("Expected 2 of 3 items, got %d of %d", output.Count, output.ScannedCount)
+	}
+	for _, item := range output.Items {
+		if title := item["title"].(*types.AttributeValueMemberS).Value; title == "Hello World 1" {
+			t.Fatalf("Expected %s to be filtered out", title)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ocowchun/baddb/ddb"
+)
+
+func TestResetRateLimiters(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	client := newDdbClient()
+	_, err := createTable(client, 100, 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// the write capacity of 3 is exhausted by the first 3 puts
+	putUntilThrottled := func() int {
+		for i := 0; i < 10; i++ {
+			_, err := putItem(client, 2025, fmt.Sprintf("Hello World %d", i), "message", "1", "US")
+			var provisionedThroughputExceededException *types.ProvisionedThroughputExceededException
+			if errors.As(err, &provisionedThroughputExceededException) {
+				return i
+			} else if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}
+		return 10
+	}
+	if n := putUntilThrottled(); n != 3 {
+		t.Fatalf("Expected to be throttled after 3 puts, got %d", n)
+	}
+
+	res, err := http.Post("http://localhost:8080"+RESET_PATH, "application/json", bytes.NewBufferString(`{}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer res.Body.Close()
+	bs, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", res.StatusCode, bs)
+	}
+	var output ddb.ResetRateLimitersOutput
+	if err := json.Unmarshal(bs, &output); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(output.TableNames) != 1 || output.TableNames[0] != "movie" {
+		t.Fatalf("Expected movie to be reset, got %v", output.TableNames)
+	}
+
+	if n := putUntilThrottled(); n != 3 {
+		t.Fatalf("Expected full write capacity after reset, got %d puts", n)
+	}
+
+	res2, err := http.Post("http://localhost:8080"+RESET_PATH, "application/json", bytes.NewBufferString(`{"TableNames": ["unknown"]}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer res2.Body.Close()
+	if res2.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for a non-existent table, got %d", res2.StatusCode)
+	}
+}
@@ -0,0 +1,554 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+func TestScanConsistentRead(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := putItem(ddb, 2025, fmt.Sprintf("Hello World %d", i), "message", "1", fmt.Sprintf("code%d", i))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	// createTable sets a 60 seconds delay, so eventually consistent scans can't see the new items yet
+	{
+		scanOutput, err := ddb.Scan(context.Background(), &dynamodb.ScanInput{
+			TableName: aws.String("movie"),
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(scanOutput.Items) != 0 {
+			t.Fatalf("Expected 0 items when ConsistentRead is not set, got %d", len(scanOutput.Items))
+		}
+	}
+
+	{
+		scanOutput, err := ddb.Scan(context.Background(), &dynamodb.ScanInput{
+			TableName:      aws.String("movie"),
+			ConsistentRead: aws.Bool(false),
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(scanOutput.Items) != 0 {
+			t.Fatalf("Expected 0 items when ConsistentRead is false, got %d", len(scanOutput.Items))
+		}
+	}
+
+	{
+		scanOutput, err := ddb.Scan(context.Background(), &dynamodb.ScanInput{
+			TableName:      aws.String("movie"),
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(scanOutput.Items) != 3 {
+			t.Fatalf("Expected 3 items when ConsistentRead is true, got %d", len(scanOutput.Items))
+		}
+	}
+
+	{
+		_, err := ddb.Scan(context.Background(), &dynamodb.ScanInput{
+			TableName:      aws.String("movie"),
+			IndexName:      aws.String("regionGSI"),
+			ConsistentRead: aws.Bool(true),
+		})
+		var apiErr smithy.APIError
+		if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+			t.Fatalf("Expected ValidationException, got %v", err)
+		}
+	}
+}
+
+func TestScanConsistentReadWithoutDelay(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	updateTestTableMetadata(ddb, 0, 0, 0)
+
+	_, err = putItem(ddb, 2025, "Hello World", "message", "1", "code")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	scanOutput, err := ddb.Scan(context.Background(), &dynamodb.ScanInput{
+		TableName: aws.String("movie"),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(scanOutput.Items) != 1 {
+		t.Fatalf("Expected 1 item, got %d", len(scanOutput.Items))
+	}
+	if _, ok := scanOutput.Items[0]["message"].(*types.AttributeValueMemberS); !ok {
+		t.Fatalf("Expected message attribute, got %v", scanOutput.Items[0])
+	}
+}
+
+func TestMetadataTableOnlySupportsPutItem(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	updateTestTableMetadata(ddb, 0, 0, 0)
+
+	_, err = ddb.Scan(context.Background(), &dynamodb.ScanInput{
+		TableName: aws.String("baddb_table_metadata"),
+	})
+	if err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected smithy.APIError, got %v", err)
+	}
+	if apiErr.ErrorCode() != "ValidationException" {
+		t.Fatalf("Expected ValidationException, got %s", apiErr.ErrorCode())
+	}
+	expectedMessage := "Scan is not supported on baddb_table_metadata, only PutItem is supported"
+	if apiErr.ErrorMessage() != expectedMessage {
+		t.Fatalf("Expected %q, got %q", expectedMessage, apiErr.ErrorMessage())
+	}
+
+	// the metadata table has no key schema, a transaction on it is rejected without building its keys
+	_, err = ddb.TransactWriteItems(context.Background(), &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{{
+			Delete: &types.Delete{
+				TableName: aws.String("baddb_table_metadata"),
+				Key: map[string]types.AttributeValue{
+					"tableName": &types.AttributeValueMemberS{Value: "movie"},
+				},
+			},
+		}},
+	})
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+		t.Fatalf("Expected ValidationException, got %v", err)
+	}
+	expectedMessage = "TransactWriteItems is not supported on baddb_table_metadata, only PutItem is supported"
+	if apiErr.ErrorMessage() != expectedMessage {
+		t.Fatalf("Expected %q, got %q", expectedMessage, apiErr.ErrorMessage())
+	}
+}
+
+func TestScanWithLegacyScanFilter(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	updateTestTableMetadata(ddb, 0, 0, 0)
+
+	titles := []string{"Hello World 0", "Goodbye World 1", "Hello World 2", "Hello Again 3"}
+	for i, title := range titles {
+		_, err := putItem(ddb, 2025, title, "message", fmt.Sprintf("%d", i%2), fmt.Sprintf("code%d", i))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	scanTitles := func(input *dynamodb.ScanInput) []string {
+		input.TableName = aws.String("movie")
+		scanOutput, err := ddb.Scan(context.Background(), input)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		titles := make([]string, len(scanOutput.Items))
+		for i, item := range scanOutput.Items {
+			titles[i] = item["title"].(*types.AttributeValueMemberS).Value
+		}
+		return titles
+	}
+
+	legacyTitles := scanTitles(&dynamodb.ScanInput{
+		ScanFilter: map[string]types.Condition{
+			"title": {
+				ComparisonOperator: types.ComparisonOperatorBeginsWith,
+				AttributeValueList: []types.AttributeValue{&types.AttributeValueMemberS{Value: "Hello World"}},
+			},
+		},
+	})
+	expressionTitles := scanTitles(&dynamodb.ScanInput{
+		FilterExpression: aws.String("begins_with(title, :prefix)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":prefix": &types.AttributeValueMemberS{Value: "Hello World"},
+		},
+	})
+	if fmt.Sprint(legacyTitles) != "[Hello World 0 Hello World 2]" {
+		t.Fatalf("Expected the titles beginning with Hello World, got %v", legacyTitles)
+	}
+	if fmt.Sprint(legacyTitles) != fmt.Sprint(expressionTitles) {
+		t.Fatalf("Expected ScanFilter to match FilterExpression %v, got %v", expressionTitles, legacyTitles)
+	}
+
+	orTitles := scanTitles(&dynamodb.ScanInput{
+		ScanFilter: map[string]types.Condition{
+			"title": {
+				ComparisonOperator: types.ComparisonOperatorBeginsWith,
+				AttributeValueList: []types.AttributeValue{&types.AttributeValueMemberS{Value: "Goodbye"}},
+			},
+			"regionCode": {
+				ComparisonOperator: types.ComparisonOperatorEq,
+				AttributeValueList: []types.AttributeValue{&types.AttributeValueMemberS{Value: "0"}},
+			},
+		},
+		ConditionalOperator: types.ConditionalOperatorOr,
+	})
+	if fmt.Sprint(orTitles) != "[Goodbye World 1 Hello World 0 Hello World 2]" {
+		t.Fatalf("Expected titles matching either condition, got %v", orTitles)
+	}
+
+	_, err = ddb.Scan(context.Background(), &dynamodb.ScanInput{
+		TableName: aws.String("movie"),
+		ScanFilter: map[string]types.Condition{
+			"title": {
+				ComparisonOperator: types.ComparisonOperatorBeginsWith,
+				AttributeValueList: []types.AttributeValue{&types.AttributeValueMemberS{Value: "Hello"}},
+			},
+		},
+		FilterExpression: aws.String("begins_with(title, :prefix)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":prefix": &types.AttributeValueMemberS{Value: "Hello"},
+		},
+	})
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+		t.Fatalf("Expected ValidationException using both ScanFilter and FilterExpression, got %v", err)
+	}
+
+	_, err = ddb.Scan(context.Background(), &dynamodb.ScanInput{
+		TableName: aws.String("movie"),
+		ScanFilter: map[string]types.Condition{
+			"title": {
+				ComparisonOperator: types.ComparisonOperatorBetween,
+				AttributeValueList: []types.AttributeValue{&types.AttributeValueMemberS{Value: "A"}},
+			},
+		},
+	})
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+		t.Fatalf("Expected ValidationException for BETWEEN with one value, got %v", err)
+	}
+}
+
+func TestScanWithProjectionExpressionAndSelect(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	updateTestTableMetadata(ddb, 0, 0, 0)
+
+	_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String("movie"),
+		Item: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World"},
+			"info": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"rating": &types.AttributeValueMemberN{Value: "8"},
+				"plot":   &types.AttributeValueMemberS{Value: "nothing happens"},
+			}},
+			"actors": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberS{Value: "Alice"},
+				&types.AttributeValueMemberS{Value: "Bob"},
+				&types.AttributeValueMemberS{Value: "Carol"},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, err = putItem(ddb, 2024, "Goodbye World", "bye", "1", "code1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// nested paths keep their parent map, list elements are compacted
+	scanOutput, err := ddb.Scan(context.Background(), &dynamodb.ScanInput{
+		TableName:            aws.String("movie"),
+		ProjectionExpression: aws.String("title, info.rating, actors[2]"),
+		FilterExpression:     aws.String("attribute_exists(info)"),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(scanOutput.Items) != 1 {
+		t.Fatalf("Expected 1 item, got %d", len(scanOutput.Items))
+	}
+	item := scanOutput.Items[0]
+	if len(item) != 3 {
+		t.Fatalf("Expected title, info and actors, got %v", item)
+	}
+	info := item["info"].(*types.AttributeValueMemberM).Value
+	if len(info) != 1 || info["rating"].(*types.AttributeValueMemberN).Value != "8" {
+		t.Fatalf("Expected info to only contain rating, got %v", info)
+	}
+	actors := item["actors"].(*types.AttributeValueMemberL).Value
+	if len(actors) != 1 || actors[0].(*types.AttributeValueMemberS).Value != "Carol" {
+		t.Fatalf("Expected actors to only contain Carol, got %v", actors)
+	}
+
+	scanOutput, err = ddb.Scan(context.Background(), &dynamodb.ScanInput{
+		TableName:        aws.String("movie"),
+		Select:           types.SelectCount,
+		FilterExpression: aws.String("attribute_exists(info)"),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if scanOutput.Items != nil {
+		t.Fatalf("Expected no items, got %v", scanOutput.Items)
+	}
+	if scanOutput.Count != 1 || scanOutput.ScannedCount != 2 {
+		t.Fatalf("Expected Count 1 and ScannedCount 2, got %d and %d", scanOutput.Count, scanOutput.ScannedCount)
+	}
+
+	_, err = ddb.Scan(context.Background(), &dynamodb.ScanInput{
+		TableName:            aws.String("movie"),
+		ProjectionExpression: aws.String("#rating"),
+	})
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+		t.Fatalf("Expected ValidationException, got %v", err)
+	}
+	expected := "Invalid ProjectionExpression: An expression attribute name used in the document path is not defined; attribute name: #rating"
+	if apiErr.ErrorMessage() != expected {
+		t.Fatalf("Expected %s, got %s", expected, apiErr.ErrorMessage())
+	}
+}
+
+func TestFilterComparingSizes(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := putItem(ddb, 2025, "Long title", "short", "1", "US"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := putItem(ddb, 2025, "Short", "a longer message", "1", "US"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// size of a missing attribute doesn't satisfy the comparison
+	_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String("movie"),
+		Item: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Without message"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	{
+		scanOutput, err := ddb.Scan(context.Background(), &dynamodb.ScanInput{
+			TableName:        aws.String("movie"),
+			FilterExpression: aws.String("size(title) > size(message)"),
+			ConsistentRead:   aws.Bool(true),
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(scanOutput.Items) != 1 || scanOutput.Items[0]["title"].(*types.AttributeValueMemberS).Value != "Long title" {
+			t.Fatalf("Expected only Long title, got %v", scanOutput.Items)
+		}
+		if scanOutput.ScannedCount != 3 {
+			t.Fatalf("Expected ScannedCount 3, got %d", scanOutput.ScannedCount)
+		}
+	}
+
+	{
+		queryOutput, err := ddb.Query(context.Background(), &dynamodb.QueryInput{
+			TableName:              aws.String("movie"),
+			KeyConditionExpression: aws.String("#year = :year"),
+			FilterExpression:       aws.String("size(#title) <= size(#message)"),
+			ExpressionAttributeNames: map[string]string{
+				"#year":    "year",
+				"#title":   "title",
+				"#message": "message",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":year": &types.AttributeValueMemberN{Value: "2025"},
+			},
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(queryOutput.Items) != 1 || queryOutput.Items[0]["title"].(*types.AttributeValueMemberS).Value != "Short" {
+			t.Fatalf("Expected only Short, got %v", queryOutput.Items)
+		}
+	}
+}
+
+func TestScanWithSegments(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		message := "odd"
+		if i%2 == 0 {
+			message = "even"
+		}
+		_, err := putItem(ddb, 2000+i, fmt.Sprintf("Hello World %d", i), message, "1", fmt.Sprintf("code%d", i))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	scan := func(segment *int32, totalSegments *int32) *dynamodb.ScanOutput {
+		t.Helper()
+		output, err := ddb.Scan(context.Background(), &dynamodb.ScanInput{
+			TableName:                 aws.String("movie"),
+			ConsistentRead:            aws.Bool(true),
+			FilterExpression:          aws.String("message = :message"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":message": &types.AttributeValueMemberS{Value: "even"}},
+			Segment:                   segment,
+			TotalSegments:             totalSegments,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return output
+	}
+
+	fullScan := scan(nil, nil)
+	if fullScan.ScannedCount != 20 || fullScan.Count != 10 {
+		t.Fatalf("Expected ScannedCount 20 and Count 10, got %d and %d", fullScan.ScannedCount, fullScan.Count)
+	}
+	totalSegments := int32(4)
+	scannedCount, count := int32(0), int32(0)
+	for segment := int32(0); segment < totalSegments; segment++ {
+		output := scan(aws.Int32(segment), aws.Int32(totalSegments))
+		if output.ScannedCount == fullScan.ScannedCount {
+			t.Fatalf("Expected segment %d to scan a part of the table, got ScannedCount %d", segment, output.ScannedCount)
+		}
+		scannedCount += output.ScannedCount
+		count += output.Count
+	}
+	if scannedCount != fullScan.ScannedCount || count != fullScan.Count {
+		t.Fatalf("Expected the segments to sum to ScannedCount %d and Count %d, got %d and %d", fullScan.ScannedCount, fullScan.Count, scannedCount, count)
+	}
+
+	tests := []struct {
+		segment       *int32
+		totalSegments *int32
+		expected      string
+	}{
+		{aws.Int32(0), nil, "The TotalSegments parameter is required but was not present in the request when Segment parameter is present"},
+		{nil, aws.Int32(2), "The Segment parameter is required but was not present in the request when parameter TotalSegments is present"},
+		{aws.Int32(2), aws.Int32(2), "The Segment parameter is zero-based and must be less than parameter TotalSegments: Segment: 2 is out of bounds for TotalSegments: 2"},
+		{aws.Int32(-1), aws.Int32(2), "1 validation error detected: Value '-1' at 'segment' failed to satisfy constraint: Member must have value greater than or equal to 0"},
+		{aws.Int32(0), aws.Int32(0), "1 validation error detected: Value '0' at 'totalSegments' failed to satisfy constraint: Member must have value greater than or equal to 1"},
+		{aws.Int32(0), aws.Int32(1000001), "1 validation error detected: Value '1000001' at 'totalSegments' failed to satisfy constraint: Member must have value less than or equal to 1000000"},
+	}
+	for _, tt := range tests {
+		_, err := ddb.Scan(context.Background(), &dynamodb.ScanInput{
+			TableName:     aws.String("movie"),
+			Segment:       tt.segment,
+			TotalSegments: tt.totalSegments,
+		})
+		var apiErr smithy.APIError
+		if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" || apiErr.ErrorMessage() != tt.expected {
+			t.Fatalf("Expected ValidationException %q, got %v", tt.expected, err)
+		}
+	}
+}
+
+func TestScanWithDeeplyNestedMapFilter(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// nested wraps val in depth maps, each keyed by name
+	nested := func(name string, depth int, val types.AttributeValue) types.AttributeValue {
+		for i := 0; i < depth; i++ {
+			val = &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{name: val}}
+		}
+		return val
+	}
+	items := []struct {
+		title string
+		a     types.AttributeValue
+		deep  types.AttributeValue
+	}{
+		{"Match", nested("d", 1, &types.AttributeValueMemberS{Value: "deep"}), &types.AttributeValueMemberS{Value: "deep"}},
+		{"Different value", nested("d", 1, &types.AttributeValueMemberS{Value: "shallow"}), &types.AttributeValueMemberS{Value: "shallow"}},
+		{"Missing d", &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{}}, &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{}}},
+	}
+	for _, item := range items {
+		_, err := ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+			TableName: aws.String("movie"),
+			Item: map[string]types.AttributeValue{
+				"year":  &types.AttributeValueMemberN{Value: "2025"},
+				"title": &types.AttributeValueMemberS{Value: item.title},
+				"a": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+					"b": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"c": item.a}},
+				}},
+				"n": nested("n", 31, item.deep),
+			},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	deepPath := strings.TrimSuffix(strings.Repeat("n.", 32), ".")
+	for _, filter := range []string{"a.b.c.d = :v", deepPath + " = :v"} {
+		output, err := ddb.Scan(context.Background(), &dynamodb.ScanInput{
+			TableName:                 aws.String("movie"),
+			FilterExpression:          aws.String(filter),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":v": &types.AttributeValueMemberS{Value: "deep"}},
+			ConsistentRead:            aws.Bool(true),
+		})
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", filter, err)
+		}
+		if len(output.Items) != 1 || output.Items[0]["title"].(*types.AttributeValueMemberS).Value != "Match" {
+			t.Fatalf("Expected only Match for %s, got %v", filter, output.Items)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"log"
+	"os"
+)
+
+// CreateTablesFromSchemaFile creates the tables of a JSON file containing an array of CreateTableInput,
+// it stops at the first invalid table. Tables that already exist, e.g. restored from dbPath, are skipped.
+func (svr *DdbServer) CreateTablesFromSchemaFile(path string) error {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read schema file %s: %w", path, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	var inputs []*dynamodb.CreateTableInput
+	if err := decoder.Decode(&inputs); err != nil {
+		return fmt.Errorf("failed to parse schema file %s: %w", path, err)
+	}
+
+	for i, input := range inputs {
+		if input == nil || input.TableName == nil {
+			return fmt.Errorf("table %d of schema file %s has no TableName", i, path)
+		}
+
+		_, err := svr.inner.CreateTable(context.Background(), input)
+		var resourceInUseErr *types.ResourceInUseException
+		if errors.As(err, &resourceInUseErr) {
+			log.Printf("table %s already exists, skip creating it from schema file\n", *input.TableName)
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to create table %s from schema file %s: %w", *input.TableName, path, err)
+		}
+	}
+
+	return nil
+}
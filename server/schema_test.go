@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateTablesFromSchemaFile(t *testing.T) {
+	schemaPath := filepath.Join(t.TempDir(), "schema.json")
+	schema := `[
+  {
+    "TableName": "movie",
+    "AttributeDefinitions": [
+      {"AttributeName": "year", "AttributeType": "N"},
+      {"AttributeName": "title", "AttributeType": "S"}
+    ],
+    "KeySchema": [
+      {"AttributeName": "year", "KeyType": "HASH"},
+      {"AttributeName": "title", "KeyType": "RANGE"}
+    ],
+    "BillingMode": "PAY_PER_REQUEST"
+  },
+  {
+    "TableName": "user",
+    "AttributeDefinitions": [
+      {"AttributeName": "id", "AttributeType": "S"},
+      {"AttributeName": "email", "AttributeType": "S"}
+    ],
+    "KeySchema": [
+      {"AttributeName": "id", "KeyType": "HASH"}
+    ],
+    "GlobalSecondaryIndexes": [
+      {
+        "IndexName": "emailGSI",
+        "KeySchema": [{"AttributeName": "email", "KeyType": "HASH"}],
+        "Projection": {"ProjectionType": "ALL"},
+        "ProvisionedThroughput": {"ReadCapacityUnits": 5, "WriteCapacityUnits": 5}
+      }
+    ],
+    "ProvisionedThroughput": {"ReadCapacityUnits": 5, "WriteCapacityUnits": 5}
+  }
+]`
+	if err := os.WriteFile(schemaPath, []byte(schema), 0644); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	svr := NewDdbServer()
+	if err := svr.CreateTablesFromSchemaFile(schemaPath); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// tables that already exist are skipped
+	if err := svr.CreateTablesFromSchemaFile(schemaPath); err != nil {
+		t.Fatalf("Expected no error loading the schema twice, got %v", err)
+	}
+	shutdown := startDdbServer(svr)
+	defer shutdown()
+	ddb := newDdbClient()
+
+	movie, err := ddb.DescribeTable(context.Background(), &dynamodb.DescribeTableInput{
+		TableName: aws.String("movie"),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(movie.Table.KeySchema) != 2 || *movie.Table.KeySchema[1].AttributeName != "title" {
+		t.Fatalf("Expected movie to have year and title keys, got %v", movie.Table.KeySchema)
+	}
+
+	user, err := ddb.DescribeTable(context.Background(), &dynamodb.DescribeTableInput{
+		TableName: aws.String("user"),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(user.Table.GlobalSecondaryIndexes) != 1 || *user.Table.GlobalSecondaryIndexes[0].IndexName != "emailGSI" {
+		t.Fatalf("Expected user to have emailGSI, got %v", user.Table.GlobalSecondaryIndexes)
+	}
+	if *user.Table.ProvisionedThroughput.ReadCapacityUnits != 5 {
+		t.Fatalf("Expected user to have 5 RCUs, got %d", *user.Table.ProvisionedThroughput.ReadCapacityUnits)
+	}
+	if user.Table.TableStatus != types.TableStatusActive {
+		t.Fatalf("Expected user to be ACTIVE, got %s", user.Table.TableStatus)
+	}
+}
+
+func TestCreateTablesFromSchemaFile_InvalidSchema(t *testing.T) {
+	tests := []struct {
+		schema   string
+		expected string
+	}{
+		{
+			schema:   `{"TableName": "movie"}`,
+			expected: "failed to parse schema file",
+		},
+		{
+			schema:   `[{"TableName": "movie", "KeySchemas": []}]`,
+			expected: "unknown field \"KeySchemas\"",
+		},
+		{
+			schema:   `[{"AttributeDefinitions": []}]`,
+			expected: "table 0 of schema file",
+		},
+		{
+			schema:   `[{"TableName": "movie", "AttributeDefinitions": [{"AttributeName": "year", "AttributeType": "N"}], "KeySchema": [{"AttributeName": "title", "KeyType": "HASH"}], "BillingMode": "PAY_PER_REQUEST"}]`,
+			expected: "failed to create table movie",
+		},
+	}
+
+	for _, tt := range tests {
+		schemaPath := filepath.Join(t.TempDir(), "schema.json")
+		if err := os.WriteFile(schemaPath, []byte(tt.schema), 0644); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		err := NewDdbServer().CreateTablesFromSchemaFile(schemaPath)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Fatalf("Expected error containing %s for schema %s, got %v", tt.expected, tt.schema, err)
+		}
+	}
+}
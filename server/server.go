@@ -2,13 +2,9 @@ package server
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/google/uuid"
 	"github.com/ocowchun/baddb/ddb"
-	"github.com/ocowchun/baddb/ddb/storage"
 	"github.com/ocowchun/baddb/server/encoding"
 	"hash/crc32"
 	"io"
@@ -18,168 +14,19 @@ import (
 	"strings"
 )
 
-type ErrorResponse struct {
-	Type    string `json:"__type"`
-	Message string `json:"Message"`
-}
-
-type TransactionCanceledErrorResponse struct {
-	Type                string                   `json:"__type"`
-	Message             string                   `json:"Message"`
-	CancellationReasons []ddb.CancellationReason `json:"CancellationReasons"`
-}
-
 func handleDdbError(w http.ResponseWriter, outputErr error) {
-	var resourceInUseException *types.ResourceInUseException
-	var resourceNotFoundException *types.ResourceNotFoundException
-	var validationException *ddb.ValidationException
-	var provisionedThroughputExceededException *types.ProvisionedThroughputExceededException
-	var conditionalCheckFailedException *storage.ConditionalCheckFailedException
-	var transactionCanceledException *ddb.TransactionCanceledException
 	log.Println("handle err", outputErr)
-	switch {
-
-	case errors.As(outputErr, &resourceInUseException):
-		w.WriteHeader(http.StatusBadRequest)
-		errResponse := ErrorResponse{
-			Type:    "ResourceInUseException",
-			Message: resourceInUseException.ErrorMessage(),
-		}
-
-		bs, err := json.Marshal(errResponse)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			// TODO describe reason
-			return
-		}
-		_, err = w.Write(bs)
-		if err != nil {
-			log.Printf("Error writing response: %v", err)
-			return
-		}
-
-	case errors.As(outputErr, &resourceNotFoundException):
-		w.WriteHeader(http.StatusBadRequest)
-		errResponse := ErrorResponse{
-			Type:    "ResourceNotFoundException",
-			Message: resourceNotFoundException.ErrorMessage(),
-		}
-
-		bs, err := json.Marshal(errResponse)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			// TODO describe reason
-			return
-		}
-		_, err = w.Write(bs)
-		if err != nil {
-			log.Printf("Error writing response: %v", err)
-			return
-		}
-
-		return
-
-	case errors.As(outputErr, &provisionedThroughputExceededException):
-		w.WriteHeader(http.StatusBadRequest)
-		errResponse := ErrorResponse{
-			Type:    "ProvisionedThroughputExceededException",
-			Message: provisionedThroughputExceededException.ErrorMessage(),
-		}
-
-		bs, err := json.Marshal(errResponse)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		_, err = w.Write(bs)
-		if err != nil {
-			log.Printf("Error writing response: %v", err)
-			return
-		}
-
-		return
-	case errors.As(outputErr, &validationException):
-		w.WriteHeader(http.StatusBadRequest)
-		errResponse := ErrorResponse{
-			Type:    "com.amazon.coral.validate#ValidationException",
-			Message: validationException.Error(),
-		}
-
-		bs, err := json.Marshal(errResponse)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		_, err = w.Write(bs)
-		if err != nil {
-			log.Printf("Error writing response: %v", err)
-			return
-		}
-
-		return
-	case errors.As(outputErr, &conditionalCheckFailedException):
-		w.WriteHeader(http.StatusBadRequest)
-
-		errResponse := ErrorResponse{
-			Type:    "ConditionalCheckFailedException",
-			Message: conditionalCheckFailedException.Message,
-		}
-
-		bs, err := json.Marshal(errResponse)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		_, err = w.Write(bs)
-		if err != nil {
-			log.Printf("Error writing response: %v", err)
-			return
-		}
-
-		return
-	case errors.As(outputErr, &transactionCanceledException):
-		w.WriteHeader(http.StatusBadRequest)
-
-		errResponse := TransactionCanceledErrorResponse{
-			Type:                "TransactionCanceledException",
-			Message:             transactionCanceledException.Error(),
-			CancellationReasons: transactionCanceledException.CancellationReasons,
-		}
-
-		bs, err := json.Marshal(errResponse)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		_, err = w.Write(bs)
-		if err != nil {
-			log.Printf("Error writing response: %v", err)
-			return
-		}
-
-		return
-
-	default:
+	status, bs, err := encoding.EncodeError(outputErr)
+	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-
-		errResponse := ErrorResponse{
-			Type:    "InternalServerError",
-			Message: outputErr.Error(),
-		}
-
-		bs, err := json.Marshal(errResponse)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		_, err = w.Write(bs)
-		if err != nil {
-			log.Printf("Error writing response: %v", err)
-			return
-		}
-
 		return
+	}
 
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.WriteHeader(status)
+	_, err = w.Write(bs)
+	if err != nil {
+		log.Printf("Error writing response: %v", err)
 	}
 }
 
@@ -198,7 +45,7 @@ func genericHandler(
 		return
 	}
 
-	output, err := handle(context.Background(), input)
+	output, err := handle(req.Context(), input)
 	if err != nil {
 		handleDdbError(w, err)
 		return
@@ -226,15 +73,97 @@ func writeResHeaders(bs []byte, w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
 }
 
+// CONSISTENCY_DELAY_PATH is the admin endpoint updating tableDelaySeconds and gsiDelaySeconds of many tables at once
+const CONSISTENCY_DELAY_PATH = "/_baddb/consistency"
+
+// IMPORT_ITEMS_PATH is the admin endpoint seeding a table with many items at once
+const IMPORT_ITEMS_PATH = "/_baddb/import"
+
+// RESET_PATH is the admin endpoint refilling the rate limiters of many tables at once
+const RESET_PATH = "/_baddb/reset"
+
+// PARTITION_LIMIT_PATH is the admin endpoint capping the item count and size of a single partition of many tables
+const PARTITION_LIMIT_PATH = "/_baddb/partition-limit"
+
+// DDB_TARGET_PREFIX is the X-Amz-Target prefix of DynamoDB requests, e.g. DynamoDB_20120810.PutItem
+const DDB_TARGET_PREFIX = "DynamoDB_20120810."
+
+// STREAMS_TARGET_PREFIX is the X-Amz-Target prefix of DynamoDB Streams requests, they are served by the same endpoint
+const STREAMS_TARGET_PREFIX = "DynamoDBStreams_20120810."
+
+// ddbOperations and streamsOperations are the operations Handler serves, keep them in sync with its switches
+var ddbOperations = []string{
+	"BatchGetItem",
+	"BatchWriteItem",
+	"CreateTable",
+	"DeleteItem",
+	"DeleteTable",
+	"DescribeEndpoints",
+	"DescribeTable",
+	"DescribeTimeToLive",
+	"ExecuteStatement",
+	"GetItem",
+	"ListTables",
+	"PutItem",
+	"Query",
+	"Scan",
+	"TransactWriteItems",
+	"UpdateItem",
+	"UpdateTable",
+	"UpdateTimeToLive",
+}
+
+var streamsOperations = []string{
+	"DescribeStream",
+	"GetRecords",
+	"GetShardIterator",
+}
+
+func unknownOperation(target string) error {
+	targets := make([]string, 0, len(ddbOperations)+len(streamsOperations))
+	for _, operation := range ddbOperations {
+		targets = append(targets, DDB_TARGET_PREFIX+operation)
+	}
+	for _, operation := range streamsOperations {
+		targets = append(targets, STREAMS_TARGET_PREFIX+operation)
+	}
+	return &encoding.UnknownOperationException{Target: target, SupportedTargets: targets}
+}
+
 func (svr *DdbServer) Handler(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == CONSISTENCY_DELAY_PATH {
+		svr.consistencyDelayHandler(w, req)
+		return
+	}
+	if req.URL.Path == IMPORT_ITEMS_PATH {
+		svr.importItemsHandler(w, req)
+		return
+	}
+	if req.URL.Path == RESET_PATH {
+		svr.resetHandler(w, req)
+		return
+	}
+	if req.URL.Path == PARTITION_LIMIT_PATH {
+		svr.partitionLimitHandler(w, req)
+		return
+	}
+
 	targetActions := req.Header["X-Amz-Target"]
 	if len(targetActions) != 1 {
-		w.WriteHeader(http.StatusBadRequest)
-		// TODO: describe invalid target
+		handleDdbError(w, unknownOperation(strings.Join(targetActions, ",")))
+		return
+	}
+
+	if strings.HasPrefix(targetActions[0], STREAMS_TARGET_PREFIX) {
+		svr.streamsHandler(w, req, strings.TrimPrefix(targetActions[0], STREAMS_TARGET_PREFIX))
 		return
 	}
 
-	targetAction := strings.Replace(targetActions[0], "DynamoDB_20120810.", "", -1)
+	if !strings.HasPrefix(targetActions[0], DDB_TARGET_PREFIX) {
+		handleDdbError(w, unknownOperation(targetActions[0]))
+		return
+	}
+	targetAction := strings.TrimPrefix(targetActions[0], DDB_TARGET_PREFIX)
 
 	id := uuid.New()
 	w.Header().Set("X-Amzn-Requestid", id.String())
@@ -311,6 +240,34 @@ func (svr *DdbServer) Handler(w http.ResponseWriter, req *http.Request) {
 				return encoding.EncodeDescribeTableOutput(i.(*dynamodb.DescribeTableOutput))
 			},
 		)
+	case "UpdateTimeToLive":
+		genericHandler(
+			w,
+			req,
+			func(bs io.ReadCloser) (interface{}, error) {
+				return encoding.DecodeUpdateTimeToLiveInput(bs)
+			},
+			func(ctx context.Context, input interface{}) (interface{}, error) {
+				return svr.inner.UpdateTimeToLive(ctx, input.(*dynamodb.UpdateTimeToLiveInput))
+			},
+			func(i interface{}) ([]byte, error) {
+				return encoding.EncodeUpdateTimeToLiveOutput(i.(*dynamodb.UpdateTimeToLiveOutput))
+			},
+		)
+	case "DescribeTimeToLive":
+		genericHandler(
+			w,
+			req,
+			func(bs io.ReadCloser) (interface{}, error) {
+				return encoding.DecodeDescribeTimeToLiveInput(bs)
+			},
+			func(ctx context.Context, input interface{}) (interface{}, error) {
+				return svr.inner.DescribeTimeToLive(ctx, input.(*dynamodb.DescribeTimeToLiveInput))
+			},
+			func(i interface{}) ([]byte, error) {
+				return encoding.EncodeDescribeTimeToLiveOutput(i.(*dynamodb.DescribeTimeToLiveOutput))
+			},
+		)
 	case "UpdateTable":
 		genericHandler(
 			w,
@@ -438,10 +395,177 @@ func (svr *DdbServer) Handler(w http.ResponseWriter, req *http.Request) {
 				return encoding.EncodeScanOutput(i.(*dynamodb.ScanOutput))
 			},
 		)
+	case "DescribeEndpoints":
+		genericHandler(
+			w,
+			req,
+			func(bs io.ReadCloser) (interface{}, error) {
+				return encoding.DecodeDescribeEndpointsInput(bs)
+			},
+			func(ctx context.Context, input interface{}) (interface{}, error) {
+				return svr.inner.DescribeEndpoints(ctx, input.(*dynamodb.DescribeEndpointsInput), req.Host)
+			},
+			func(i interface{}) ([]byte, error) {
+				return encoding.EncodeDescribeEndpointsOutput(i.(*dynamodb.DescribeEndpointsOutput))
+			},
+		)
+	case "ExecuteStatement":
+		genericHandler(
+			w,
+			req,
+			func(bs io.ReadCloser) (interface{}, error) {
+				return encoding.DecodeExecuteStatementInput(bs)
+			},
+			func(ctx context.Context, input interface{}) (interface{}, error) {
+				return svr.inner.ExecuteStatement(ctx, input.(*dynamodb.ExecuteStatementInput))
+			},
+			func(i interface{}) ([]byte, error) {
+				return encoding.EncodeExecuteStatementOutput(i.(*dynamodb.ExecuteStatementOutput))
+			},
+		)
+	default:
+		handleDdbError(w, unknownOperation(targetActions[0]))
+	}
+}
+
+func (svr *DdbServer) streamsHandler(w http.ResponseWriter, req *http.Request, targetAction string) {
+	id := uuid.New()
+	w.Header().Set("X-Amzn-Requestid", id.String())
+	log.Printf("received %s request, requestId=%s\n", targetAction, id.String())
+	switch targetAction {
+	case "DescribeStream":
+		genericHandler(
+			w,
+			req,
+			func(bs io.ReadCloser) (interface{}, error) {
+				return encoding.DecodeDescribeStreamInput(bs)
+			},
+			func(ctx context.Context, input interface{}) (interface{}, error) {
+				return svr.inner.DescribeStream(ctx, input.(*ddb.DescribeStreamInput))
+			},
+			func(i interface{}) ([]byte, error) {
+				return encoding.EncodeDescribeStreamOutput(i.(*ddb.DescribeStreamOutput))
+			},
+		)
+	case "GetShardIterator":
+		genericHandler(
+			w,
+			req,
+			func(bs io.ReadCloser) (interface{}, error) {
+				return encoding.DecodeGetShardIteratorInput(bs)
+			},
+			func(ctx context.Context, input interface{}) (interface{}, error) {
+				return svr.inner.GetShardIterator(ctx, input.(*ddb.GetShardIteratorInput))
+			},
+			func(i interface{}) ([]byte, error) {
+				return encoding.EncodeGetShardIteratorOutput(i.(*ddb.GetShardIteratorOutput))
+			},
+		)
+	case "GetRecords":
+		genericHandler(
+			w,
+			req,
+			func(bs io.ReadCloser) (interface{}, error) {
+				return encoding.DecodeGetRecordsInput(bs)
+			},
+			func(ctx context.Context, input interface{}) (interface{}, error) {
+				return svr.inner.GetRecords(ctx, input.(*ddb.GetRecordsInput))
+			},
+			func(i interface{}) ([]byte, error) {
+				return encoding.EncodeGetRecordsOutput(i.(*ddb.GetRecordsOutput))
+			},
+		)
 	default:
-		w.WriteHeader(http.StatusBadRequest)
+		handleDdbError(w, unknownOperation(STREAMS_TARGET_PREFIX+targetAction))
+	}
+}
+
+func (svr *DdbServer) consistencyDelayHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Printf("received UpdateConsistencyDelay request\n")
+	genericHandler(
+		w,
+		req,
+		func(bs io.ReadCloser) (interface{}, error) {
+			return encoding.DecodeUpdateConsistencyDelayInput(bs)
+		},
+		func(ctx context.Context, input interface{}) (interface{}, error) {
+			return svr.inner.UpdateConsistencyDelay(ctx, input.(*ddb.UpdateConsistencyDelayInput))
+		},
+		func(i interface{}) ([]byte, error) {
+			return encoding.EncodeUpdateConsistencyDelayOutput(i.(*ddb.UpdateConsistencyDelayOutput))
+		},
+	)
+}
+
+func (svr *DdbServer) importItemsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
+
+	log.Printf("received ImportItems request\n")
+	genericHandler(
+		w,
+		req,
+		func(bs io.ReadCloser) (interface{}, error) {
+			return encoding.DecodeImportItemsInput(bs)
+		},
+		func(ctx context.Context, input interface{}) (interface{}, error) {
+			return svr.inner.ImportItems(ctx, input.(*ddb.ImportItemsInput))
+		},
+		func(i interface{}) ([]byte, error) {
+			return encoding.EncodeImportItemsOutput(i.(*ddb.ImportItemsOutput))
+		},
+	)
+}
+
+func (svr *DdbServer) resetHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Printf("received ResetRateLimiters request\n")
+	genericHandler(
+		w,
+		req,
+		func(bs io.ReadCloser) (interface{}, error) {
+			return encoding.DecodeResetRateLimitersInput(bs)
+		},
+		func(ctx context.Context, input interface{}) (interface{}, error) {
+			return svr.inner.ResetRateLimiters(ctx, input.(*ddb.ResetRateLimitersInput))
+		},
+		func(i interface{}) ([]byte, error) {
+			return encoding.EncodeResetRateLimitersOutput(i.(*ddb.ResetRateLimitersOutput))
+		},
+	)
+}
+
+func (svr *DdbServer) partitionLimitHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Printf("received UpdatePartitionLimit request\n")
+	genericHandler(
+		w,
+		req,
+		func(bs io.ReadCloser) (interface{}, error) {
+			return encoding.DecodeUpdatePartitionLimitInput(bs)
+		},
+		func(ctx context.Context, input interface{}) (interface{}, error) {
+			return svr.inner.UpdatePartitionLimit(ctx, input.(*ddb.UpdatePartitionLimitInput))
+		},
+		func(i interface{}) ([]byte, error) {
+			return encoding.EncodeUpdatePartitionLimitOutput(i.(*ddb.UpdatePartitionLimitOutput))
+		},
+	)
 }
 
 type DdbServer struct {
@@ -453,3 +577,18 @@ func NewDdbServer() *DdbServer {
 		inner: ddb.NewDdbService(),
 	}
 }
+
+func NewDdbServerWithConfig(config ddb.Config) (*DdbServer, error) {
+	inner, err := ddb.NewDdbServiceWithConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DdbServer{
+		inner: inner,
+	}, nil
+}
+
+func (s *DdbServer) Close() error {
+	return s.inner.Close()
+}
@@ -9,10 +9,12 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
 	"log"
 	"net/http"
-	"sort"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCreateAndDeleteTable(t *testing.T) {
@@ -36,14 +38,11 @@ func TestCreateAndDeleteTable(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
-	if len(listTablesOutput.TableNames) != 2 {
-		t.Fatalf("Expected 2 table, got %d", len(listTablesOutput.TableNames))
+	// baddb_table_metadata is internal, so it's not listed
+	if len(listTablesOutput.TableNames) != 1 {
+		t.Fatalf("Expected 1 table, got %d", len(listTablesOutput.TableNames))
 	}
-	sort.Strings(listTablesOutput.TableNames)
-	if listTablesOutput.TableNames[0] != "baddb_table_metadata" {
-		t.Fatalf("Expected table name %s, got %s", "baddb_table_metadata", listTablesOutput.TableNames[0])
-	}
-	if listTablesOutput.TableNames[1] != "movie" {
+	if listTablesOutput.TableNames[0] != "movie" {
 		t.Fatalf("Expected table name %s, got %s", "movie", listTablesOutput.TableNames[0])
 	}
 
@@ -77,6 +76,128 @@ func TestCreateAndDeleteTable(t *testing.T) {
 	}
 }
 
+func TestTableCreationDateTime(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+
+	ddb := newDdbClient()
+
+	before := time.Now().Add(-1 * time.Second)
+	res, err := createTable(ddb, 5, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	after := time.Now()
+
+	createdAt := res.TableDescription.CreationDateTime
+	if createdAt == nil || createdAt.IsZero() {
+		t.Fatalf("Expected CreationDateTime to be populated, got %v", createdAt)
+	}
+	if createdAt.Before(before) || createdAt.After(after) {
+		t.Fatalf("Expected CreationDateTime between %v and %v, got %v", before, after, createdAt)
+	}
+
+	describeTableInput := &dynamodb.DescribeTableInput{
+		TableName: aws.String("movie"),
+	}
+	describeTableOutput, err := ddb.DescribeTable(context.Background(), describeTableInput)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !describeTableOutput.Table.CreationDateTime.Equal(*createdAt) {
+		t.Fatalf("Expected CreationDateTime %v, got %v", createdAt, describeTableOutput.Table.CreationDateTime)
+	}
+
+	err = updateProvisionedThroughput(ddb, 10, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	describeTableOutput, err = ddb.DescribeTable(context.Background(), describeTableInput)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !describeTableOutput.Table.CreationDateTime.Equal(*createdAt) {
+		t.Fatalf("Expected CreationDateTime %v after UpdateTable, got %v", createdAt, describeTableOutput.Table.CreationDateTime)
+	}
+}
+
+func TestDescribeEndpoints(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+
+	output, err := ddb.DescribeEndpoints(context.Background(), &dynamodb.DescribeEndpointsInput{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(output.Endpoints) != 1 {
+		t.Fatalf("Expected 1 endpoint, got %d", len(output.Endpoints))
+	}
+	endpoint := output.Endpoints[0]
+	if endpoint.Address == nil || *endpoint.Address != "localhost:8080" {
+		t.Fatalf("Expected endpoint localhost:8080, got %v", endpoint.Address)
+	}
+	if endpoint.CachePeriodInMinutes != 1440 {
+		t.Fatalf("Expected cache period of 1440 minutes, got %d", endpoint.CachePeriodInMinutes)
+	}
+}
+
+func TestListTablesPaging(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+
+	tableCount := 150
+	expectedTableNames := make([]string, tableCount)
+	for i := 0; i < tableCount; i++ {
+		expectedTableNames[i] = fmt.Sprintf("table%03d", i)
+		_, err := ddb.CreateTable(context.Background(), &dynamodb.CreateTableInput{
+			TableName:   aws.String(expectedTableNames[i]),
+			BillingMode: types.BillingModePayPerRequest,
+			AttributeDefinitions: []types.AttributeDefinition{
+				{AttributeName: aws.String("pk"), AttributeType: types.ScalarAttributeTypeS},
+			},
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	for _, limit := range []*int32{nil, aws.Int32(40), aws.Int32(50)} {
+		tableNames := make([]string, 0)
+		pageCount := 0
+		paginator := dynamodb.NewListTablesPaginator(ddb, &dynamodb.ListTablesInput{Limit: limit})
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(context.Background())
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			pageCount++
+			tableNames = append(tableNames, output.TableNames...)
+		}
+
+		expectedPageCount := 2
+		if limit != nil {
+			expectedPageCount = (tableCount + int(*limit) - 1) / int(*limit)
+		}
+		if pageCount != expectedPageCount {
+			t.Fatalf("Expected %d pages with limit %v, got %d", expectedPageCount, limit, pageCount)
+		}
+		if strings.Join(tableNames, ",") != strings.Join(expectedTableNames, ",") {
+			t.Fatalf("Expected every table listed once in order, got %v", tableNames)
+		}
+	}
+
+	_, err := ddb.ListTables(context.Background(), &dynamodb.ListTablesInput{Limit: aws.Int32(101)})
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+		t.Fatalf("Expected ValidationException for a limit over 100, got %v", err)
+	}
+}
+
 func TestBatchGetItem(t *testing.T) {
 	shutdown := startServer()
 	defer shutdown()
@@ -173,6 +294,58 @@ func TestBatchGetItem(t *testing.T) {
 
 }
 
+func TestBatchGetItemResponseSizeLimit(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// 50 items of ~390KB are more than the 16MB a BatchGetItem response can hold
+	itemCount := 50
+	message := strings.Repeat("a", 390*1024)
+	keys := make([]map[string]types.AttributeValue, 0)
+	for i := 0; i < itemCount; i++ {
+		item, err := putItem(ddb, 2025, fmt.Sprintf("Hello World %d", i), message, "1", fmt.Sprintf("code%d", i))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		keys = append(keys, map[string]types.AttributeValue{
+			"year":  item["year"],
+			"title": item["title"],
+		})
+	}
+
+	output, err := ddb.BatchGetItem(context.Background(), &dynamodb.BatchGetItemInput{
+		RequestItems: map[string]types.KeysAndAttributes{
+			"movie": {
+				Keys:           keys,
+				ConsistentRead: aws.Bool(true),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	responseSize := 0
+	for _, item := range output.Responses["movie"] {
+		responseSize += len(item["message"].(*types.AttributeValueMemberS).Value)
+	}
+	if responseSize > 16*1024*1024 {
+		t.Fatalf("Expected response under 16MB, got %d bytes", responseSize)
+	}
+
+	unprocessedCount := len(output.UnprocessedKeys["movie"].Keys)
+	if unprocessedCount == 0 {
+		t.Fatalf("Expected unprocessed keys, got none")
+	}
+	if len(output.Responses["movie"])+unprocessedCount != itemCount {
+		t.Fatalf("Expected %d items and unprocessed keys in total, got %d items and %d unprocessed keys", itemCount, len(output.Responses["movie"]), unprocessedCount)
+	}
+}
 func TestBatchWriteItem(t *testing.T) {
 	shutdown := startServer()
 	defer shutdown()
@@ -234,6 +407,290 @@ func TestBatchWriteItem(t *testing.T) {
 
 }
 
+func TestBatchOperationsReturnConsumedCapacity(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	updateTestTableMetadata(ddb, 0, 0, 0)
+	_, err = ddb.CreateTable(context.Background(), &dynamodb.CreateTableInput{
+		TableName:   aws.String("actor"),
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("name"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("name"), KeyType: types.KeyTypeHash},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	movieKey := func(title string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: title},
+		}
+	}
+	actorKey := func(name string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"name": &types.AttributeValueMemberS{Value: name},
+		}
+	}
+	// about 1.5KB, so writing it costs 2 WCUs
+	actorItem := actorKey("Alice")
+	actorItem["bio"] = &types.AttributeValueMemberS{Value: strings.Repeat("a", 1500)}
+
+	writeOutput, err := ddb.BatchWriteItem(context.Background(), &dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]types.WriteRequest{
+			"movie": {
+				{PutRequest: &types.PutRequest{Item: movieKey("Hello World 1")}},
+				{PutRequest: &types.PutRequest{Item: movieKey("Hello World 2")}},
+				{DeleteRequest: &types.DeleteRequest{Key: movieKey("Hello World 3")}},
+			},
+			"actor": {
+				{PutRequest: &types.PutRequest{Item: actorItem}},
+			},
+		},
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	assertConsumedCapacity(t, writeOutput.ConsumedCapacity, map[string]float64{"actor": 2, "movie": 3})
+
+	getOutput, err := ddb.BatchGetItem(context.Background(), &dynamodb.BatchGetItemInput{
+		RequestItems: map[string]types.KeysAndAttributes{
+			"movie": {
+				Keys:           []map[string]types.AttributeValue{movieKey("Hello World 1"), movieKey("Hello World 2"), movieKey("Hello World 3")},
+				ConsistentRead: aws.Bool(true),
+			},
+			"actor": {
+				Keys: []map[string]types.AttributeValue{actorKey("Alice"), actorKey("Bob")},
+			},
+		},
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// eventually consistent reads cost half, a missing item still costs the minimum
+	assertConsumedCapacity(t, getOutput.ConsumedCapacity, map[string]float64{"actor": 1, "movie": 3})
+
+	getOutput, err = ddb.BatchGetItem(context.Background(), &dynamodb.BatchGetItemInput{
+		RequestItems: map[string]types.KeysAndAttributes{
+			"actor": {
+				Keys: []map[string]types.AttributeValue{actorKey("Alice")},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if getOutput.ConsumedCapacity != nil {
+		t.Fatalf("Expected no ConsumedCapacity when it's not requested, got %v", getOutput.ConsumedCapacity)
+	}
+}
+
+func TestSingleItemOperationsReturnConsumedCapacity(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	updateTestTableMetadata(ddb, 0, 0, 0)
+
+	key := map[string]types.AttributeValue{
+		"year":  &types.AttributeValueMemberN{Value: "2025"},
+		"title": &types.AttributeValueMemberS{Value: "Hello World"},
+	}
+	// about 5KB, so writing it costs 5 WCUs and reading it costs 2 RCUs. regionGSI projects all attributes, so the
+	// writes cost as much again
+	item := map[string]types.AttributeValue{
+		"year":        key["year"],
+		"title":       key["title"],
+		"regionCode":  &types.AttributeValueMemberS{Value: "1"},
+		"countryCode": &types.AttributeValueMemberS{Value: "US"},
+		"message":     &types.AttributeValueMemberS{Value: strings.Repeat("a", 5000)},
+	}
+	expectCapacity := func(consumedCapacity *types.ConsumedCapacity, expected float64) {
+		t.Helper()
+		if consumedCapacity == nil || *consumedCapacity.TableName != "movie" || *consumedCapacity.CapacityUnits != expected {
+			t.Fatalf("Expected %v capacity units of movie, got %+v", expected, consumedCapacity)
+		}
+	}
+
+	putOutput, err := ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName:              aws.String("movie"),
+		Item:                   item,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectCapacity(putOutput.ConsumedCapacity, 10)
+
+	for _, consistentRead := range []bool{true, false} {
+		getOutput, err := ddb.GetItem(context.Background(), &dynamodb.GetItemInput{
+			TableName:              aws.String("movie"),
+			Key:                    key,
+			ConsistentRead:         aws.Bool(consistentRead),
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		expected := 1.0
+		if consistentRead {
+			expected = 2
+		}
+		expectCapacity(getOutput.ConsumedCapacity, expected)
+	}
+
+	queryOutput, err := ddb.Query(context.Background(), &dynamodb.QueryInput{
+		TableName:              aws.String("movie"),
+		KeyConditionExpression: aws.String("#year = :year"),
+		ExpressionAttributeNames: map[string]string{
+			"#year": "year",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":year": key["year"],
+		},
+		ConsistentRead:         aws.Bool(true),
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectCapacity(queryOutput.ConsumedCapacity, 2)
+
+	// the capacity of a read on a GSI is reported as the index's
+	queryOutput, err = ddb.Query(context.Background(), &dynamodb.QueryInput{
+		TableName:              aws.String("movie"),
+		IndexName:              aws.String("regionGSI"),
+		KeyConditionExpression: aws.String("regionCode = :regionCode"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":regionCode": item["regionCode"],
+		},
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectCapacity(queryOutput.ConsumedCapacity, 1)
+	if *queryOutput.ConsumedCapacity.Table.CapacityUnits != 0 || *queryOutput.ConsumedCapacity.GlobalSecondaryIndexes["regionGSI"].CapacityUnits != 1 {
+		t.Fatalf("Expected the capacity to be consumed by regionGSI, got %+v", queryOutput.ConsumedCapacity)
+	}
+
+	scanOutput, err := ddb.Scan(context.Background(), &dynamodb.ScanInput{
+		TableName:              aws.String("movie"),
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectCapacity(scanOutput.ConsumedCapacity, 1)
+
+	updateOutput, err := ddb.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+		TableName:              aws.String("movie"),
+		Key:                    key,
+		UpdateExpression:       aws.String("REMOVE message"),
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// the item before the update is the larger one
+	expectCapacity(updateOutput.ConsumedCapacity, 10)
+
+	if _, err := ddb.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String("movie"), Item: item}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	deleteOutput, err := ddb.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{
+		TableName:              aws.String("movie"),
+		Key:                    key,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// a delete is sized by the deleted item
+	expectCapacity(deleteOutput.ConsumedCapacity, 10)
+	if *deleteOutput.ConsumedCapacity.Table.CapacityUnits != 5 || *deleteOutput.ConsumedCapacity.GlobalSecondaryIndexes["regionGSI"].CapacityUnits != 5 {
+		t.Fatalf("Expected the capacity to be consumed by the table and regionGSI, got %+v", deleteOutput.ConsumedCapacity)
+	}
+
+	deleteOutput, err = ddb.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{
+		TableName:              aws.String("movie"),
+		Key:                    key,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// deleting a missing item costs the minimum and writes no index
+	expectCapacity(deleteOutput.ConsumedCapacity, 1)
+	if deleteOutput.ConsumedCapacity.GlobalSecondaryIndexes != nil {
+		t.Fatalf("Expected no index capacity, got %+v", deleteOutput.ConsumedCapacity.GlobalSecondaryIndexes)
+	}
+
+	if _, err := ddb.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String("movie"), Item: item}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	putOutput, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String("movie"),
+		Item: map[string]types.AttributeValue{
+			"year":        key["year"],
+			"title":       key["title"],
+			"regionCode":  &types.AttributeValueMemberS{Value: "2"},
+			"countryCode": &types.AttributeValueMemberS{Value: "US"},
+		},
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// overwriting the large item with a small one is sized by the replaced item, and the changed regionGSI key deletes
+	// the old index entry and puts the new one
+	expectCapacity(putOutput.ConsumedCapacity, 11)
+	if *putOutput.ConsumedCapacity.Table.CapacityUnits != 5 || *putOutput.ConsumedCapacity.GlobalSecondaryIndexes["regionGSI"].CapacityUnits != 6 {
+		t.Fatalf("Expected 5 units of the table and 6 of regionGSI, got %+v", putOutput.ConsumedCapacity)
+	}
+
+	getOutput, err := ddb.GetItem(context.Background(), &dynamodb.GetItemInput{
+		TableName: aws.String("movie"),
+		Key:       key,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if getOutput.ConsumedCapacity != nil {
+		t.Fatalf("Expected no ConsumedCapacity when it's not requested, got %v", getOutput.ConsumedCapacity)
+	}
+}
+
+func assertConsumedCapacity(t *testing.T, consumedCapacities []types.ConsumedCapacity, expected map[string]float64) {
+	t.Helper()
+	if len(consumedCapacities) != len(expected) {
+		t.Fatalf("Expected %d ConsumedCapacity entries, got %d", len(expected), len(consumedCapacities))
+	}
+	for _, consumedCapacity := range consumedCapacities {
+		units, ok := expected[*consumedCapacity.TableName]
+		if !ok {
+			t.Fatalf("Unexpected ConsumedCapacity of table %s", *consumedCapacity.TableName)
+		}
+		if *consumedCapacity.CapacityUnits != units {
+			t.Fatalf("Expected %v capacity units of table %s, got %v", units, *consumedCapacity.TableName, *consumedCapacity.CapacityUnits)
+		}
+	}
+}
+
 func assertPrimaryKey(actual map[string]types.AttributeValue, expected map[string]types.AttributeValue, t *testing.T) {
 	t.Helper()
 	if actual["year"].(*types.AttributeValueMemberN).Value != expected["year"].(*types.AttributeValueMemberN).Value {
@@ -368,7 +825,10 @@ func newDdbClient() *dynamodb.Client {
 }
 
 func startServer() func() {
-	svr := NewDdbServer()
+	return startDdbServer(NewDdbServer())
+}
+
+func startDdbServer(svr *DdbServer) func() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", svr.Handler)
 
@@ -0,0 +1,289 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// postStreamsRequest calls a DynamoDB Streams operation, the SDK client of DynamoDB can't
+func postStreamsRequest(t *testing.T, action string, input interface{}, output interface{}) int {
+	t.Helper()
+	body, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, "http://localhost:8080", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	req.Header.Set("X-Amz-Target", STREAMS_TARGET_PREFIX+action)
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer res.Body.Close()
+	bs, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if res.StatusCode == http.StatusOK {
+		if err := json.Unmarshal(bs, output); err != nil {
+			t.Fatalf("Expected no error, got %v: %s", err, bs)
+		}
+	}
+	return res.StatusCode
+}
+
+type testShard struct {
+	ShardId             string
+	SequenceNumberRange struct {
+		StartingSequenceNumber string
+		EndingSequenceNumber   string
+	}
+}
+
+type testDescribeStreamOutput struct {
+	StreamDescription struct {
+		StreamArn      string
+		StreamStatus   string
+		StreamViewType string
+		TableName      string
+		Shards         []testShard
+	}
+}
+
+type testGetRecordsOutput struct {
+	Records []struct {
+		EventName string `json:"eventName"`
+		Dynamodb  struct {
+			Keys           map[string]map[string]string
+			NewImage       map[string]map[string]string
+			OldImage       map[string]map[string]string
+			SequenceNumber string
+		} `json:"dynamodb"`
+	}
+	NextShardIterator *string
+}
+
+func TestStreamRecordsWrites(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	client := newDdbClient()
+	_, err := createTable(client, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	updateOutput, err := client.UpdateTable(context.Background(), &dynamodb.UpdateTableInput{
+		TableName: aws.String("movie"),
+		StreamSpecification: &types.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: types.StreamViewTypeNewAndOldImages,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	streamArn := updateOutput.TableDescription.LatestStreamArn
+	if streamArn == nil {
+		t.Fatalf("Expected LatestStreamArn to be set")
+	}
+
+	if _, err := putItem(client, 2025, "Hello World", "first", "1", "US"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := putItem(client, 2025, "Hello World", "second", "1", "US"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, err = client.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{
+		TableName: aws.String("movie"),
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var describeOutput testDescribeStreamOutput
+	status := postStreamsRequest(t, "DescribeStream", map[string]string{"StreamArn": *streamArn}, &describeOutput)
+	if status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	description := describeOutput.StreamDescription
+	if description.StreamStatus != "ENABLED" || description.TableName != "movie" || description.StreamViewType != "NEW_AND_OLD_IMAGES" {
+		t.Fatalf("Unexpected stream description %+v", description)
+	}
+	if len(description.Shards) != 1 {
+		t.Fatalf("Expected 1 shard, got %d", len(description.Shards))
+	}
+
+	var iteratorOutput struct{ ShardIterator string }
+	status = postStreamsRequest(t, "GetShardIterator", map[string]string{
+		"StreamArn":         *streamArn,
+		"ShardId":           description.Shards[0].ShardId,
+		"ShardIteratorType": "TRIM_HORIZON",
+	}, &iteratorOutput)
+	if status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+
+	var recordsOutput testGetRecordsOutput
+	status = postStreamsRequest(t, "GetRecords", map[string]interface{}{
+		"ShardIterator": iteratorOutput.ShardIterator,
+	}, &recordsOutput)
+	if status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	if len(recordsOutput.Records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(recordsOutput.Records))
+	}
+	expectedEventNames := []string{"INSERT", "MODIFY", "REMOVE"}
+	for i, r := range recordsOutput.Records {
+		if r.EventName != expectedEventNames[i] {
+			t.Errorf("Expected record %d to be %s, got %s", i, expectedEventNames[i], r.EventName)
+		}
+		if r.Dynamodb.Keys["title"]["S"] != "Hello World" || len(r.Dynamodb.Keys) != 2 {
+			t.Errorf("Unexpected keys of record %d: %v", i, r.Dynamodb.Keys)
+		}
+		if i > 0 && r.Dynamodb.SequenceNumber <= recordsOutput.Records[i-1].Dynamodb.SequenceNumber {
+			t.Errorf("Expected sequence numbers to increase, got %s after %s", r.Dynamodb.SequenceNumber, recordsOutput.Records[i-1].Dynamodb.SequenceNumber)
+		}
+	}
+	modify := recordsOutput.Records[1].Dynamodb
+	if modify.OldImage["message"]["S"] != "first" || modify.NewImage["message"]["S"] != "second" {
+		t.Errorf("Unexpected images of MODIFY record: old=%v, new=%v", modify.OldImage, modify.NewImage)
+	}
+	if recordsOutput.Records[2].Dynamodb.NewImage != nil {
+		t.Errorf("Expected REMOVE record to have no NewImage, got %v", recordsOutput.Records[2].Dynamodb.NewImage)
+	}
+
+	// the stream is still enabled, so the shard stays open after all records are read
+	if recordsOutput.NextShardIterator == nil {
+		t.Fatalf("Expected NextShardIterator to be set")
+	}
+	var nextOutput testGetRecordsOutput
+	postStreamsRequest(t, "GetRecords", map[string]interface{}{
+		"ShardIterator": *recordsOutput.NextShardIterator,
+	}, &nextOutput)
+	if len(nextOutput.Records) != 0 || nextOutput.NextShardIterator == nil {
+		t.Fatalf("Expected no records and an open shard, got %d records", len(nextOutput.Records))
+	}
+
+	// AFTER_SEQUENCE_NUMBER skips the record of the sequence number
+	status = postStreamsRequest(t, "GetShardIterator", map[string]string{
+		"StreamArn":         *streamArn,
+		"ShardId":           description.Shards[0].ShardId,
+		"ShardIteratorType": "AFTER_SEQUENCE_NUMBER",
+		"SequenceNumber":    recordsOutput.Records[0].Dynamodb.SequenceNumber,
+	}, &iteratorOutput)
+	if status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	var afterOutput testGetRecordsOutput
+	postStreamsRequest(t, "GetRecords", map[string]interface{}{
+		"ShardIterator": iteratorOutput.ShardIterator,
+		"Limit":         1,
+	}, &afterOutput)
+	if len(afterOutput.Records) != 1 || afterOutput.Records[0].EventName != "MODIFY" {
+		t.Fatalf("Expected the MODIFY record, got %+v", afterOutput.Records)
+	}
+}
+
+func TestStreamClosedWhenDisabled(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	client := newDdbClient()
+	_, err := createTable(client, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	enable := &dynamodb.UpdateTableInput{
+		TableName: aws.String("movie"),
+		StreamSpecification: &types.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: types.StreamViewTypeKeysOnly,
+		},
+	}
+	updateOutput, err := client.UpdateTable(context.Background(), enable)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	streamArn := *updateOutput.TableDescription.LatestStreamArn
+
+	_, err = client.UpdateTable(context.Background(), enable)
+	if err == nil {
+		t.Fatalf("Expected enabling an enabled stream to fail")
+	}
+
+	if _, err := putItem(client, 2025, "Hello World", "first", "1", "US"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = client.UpdateTable(context.Background(), &dynamodb.UpdateTableInput{
+		TableName: aws.String("movie"),
+		StreamSpecification: &types.StreamSpecification{
+			StreamEnabled: aws.Bool(false),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// writes after the stream is disabled are not recorded
+	if _, err := putItem(client, 2025, "Hello World 2", "second", "1", "US"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	describeTableOutput, err := client.DescribeTable(context.Background(), &dynamodb.DescribeTableInput{
+		TableName: aws.String("movie"),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if describeTableOutput.Table.LatestStreamArn == nil || *describeTableOutput.Table.LatestStreamArn != streamArn {
+		t.Fatalf("Expected LatestStreamArn to be kept after the stream is disabled")
+	}
+
+	var describeOutput testDescribeStreamOutput
+	postStreamsRequest(t, "DescribeStream", map[string]string{"StreamArn": streamArn}, &describeOutput)
+	if describeOutput.StreamDescription.StreamStatus != "DISABLED" {
+		t.Fatalf("Expected stream to be DISABLED, got %s", describeOutput.StreamDescription.StreamStatus)
+	}
+
+	var iteratorOutput struct{ ShardIterator string }
+	postStreamsRequest(t, "GetShardIterator", map[string]string{
+		"StreamArn":         streamArn,
+		"ShardId":           describeOutput.StreamDescription.Shards[0].ShardId,
+		"ShardIteratorType": "TRIM_HORIZON",
+	}, &iteratorOutput)
+	var recordsOutput testGetRecordsOutput
+	postStreamsRequest(t, "GetRecords", map[string]interface{}{
+		"ShardIterator": iteratorOutput.ShardIterator,
+	}, &recordsOutput)
+	if len(recordsOutput.Records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(recordsOutput.Records))
+	}
+	if recordsOutput.Records[0].Dynamodb.NewImage != nil {
+		t.Errorf("Expected KEYS_ONLY record to have no images")
+	}
+	if recordsOutput.NextShardIterator != nil {
+		t.Errorf("Expected the shard to be closed once a disabled stream is fully read")
+	}
+
+	status := postStreamsRequest(t, "DescribeStream", map[string]string{"StreamArn": streamArn + "-unknown"}, &describeOutput)
+	if status != http.StatusBadRequest {
+		t.Errorf("Expected unknown stream to fail with 400, got %d", status)
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func postTarget(t *testing.T, target string, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, "http://localhost:8080/", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	if target != "" {
+		req.Header.Set("X-Amz-Target", target)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer res.Body.Close()
+
+	bs, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var output map[string]interface{}
+	if err := json.Unmarshal(bs, &output); err != nil {
+		t.Fatalf("Expected a JSON response to %s, got %s", target, bs)
+	}
+	return res.StatusCode, output
+}
+
+func TestHandlerDispatchesByTarget(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	client := newDdbClient()
+	_, err := createTable(client, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	status, output := postTarget(t, "DynamoDB_20120810.ListTables", `{}`)
+	if status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d %v", status, output)
+	}
+	if tableNames, ok := output["TableNames"].([]interface{}); !ok || len(tableNames) != 1 || tableNames[0] != "movie" {
+		t.Fatalf("Expected ListTables output, got %v", output)
+	}
+
+	status, output = postTarget(t, "DynamoDB_20120810.DescribeTable", `{"TableName": "movie"}`)
+	if status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d %v", status, output)
+	}
+	if table, ok := output["Table"].(map[string]interface{}); !ok || table["TableName"] != "movie" {
+		t.Fatalf("Expected DescribeTable output, got %v", output)
+	}
+
+	status, output = postTarget(t, "DynamoDB_20120810.GetItem", `{"TableName": "missing", "Key": {"id": {"S": "1"}}}`)
+	if status != http.StatusBadRequest || output["__type"] != "com.amazonaws.dynamodb.v20120810#ResourceNotFoundException" {
+		t.Fatalf("Expected GetItem to reach the service, got %d %v", status, output)
+	}
+
+	tests := []string{
+		"",
+		"DynamoDB_20120810.DropEverything",
+		"DynamoDB_20120810.",
+		"DynamoDB_20111205.ListTables",
+		"ListTables",
+		"DynamoDBStreams_20120810.ListTables",
+	}
+	for _, target := range tests {
+		status, output := postTarget(t, target, `{}`)
+		if status != http.StatusBadRequest || output["__type"] != "com.amazon.coral.service#UnknownOperationException" {
+			t.Fatalf("Expected UnknownOperationException for target %q, got %d %v", target, status, output)
+		}
+	}
+}
+
+func TestUnknownOperationListsSupportedOperations(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	// the client retries until the server is up
+	if _, err := newDdbClient().ListTables(context.Background(), &dynamodb.ListTablesInput{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	status, output := postTarget(t, "DynamoDB_20120810.PutItems", `{}`)
+	if status != http.StatusBadRequest || output["__type"] != "com.amazon.coral.service#UnknownOperationException" {
+		t.Fatalf("Expected UnknownOperationException, got %d %v", status, output)
+	}
+	message, _ := output["message"].(string)
+	if !strings.HasPrefix(message, "Unknown operation DynamoDB_20120810.PutItems, supported operations: ") {
+		t.Fatalf("Unexpected message: %s", message)
+	}
+
+	// every listed operation is served, whatever the service thinks of a request with only a table name
+	targets := make([]string, 0)
+	for _, operation := range ddbOperations {
+		targets = append(targets, DDB_TARGET_PREFIX+operation)
+	}
+	for _, operation := range streamsOperations {
+		targets = append(targets, STREAMS_TARGET_PREFIX+operation)
+	}
+	for _, target := range targets {
+		if !strings.Contains(message, target) {
+			t.Fatalf("Expected %s in the supported operations, got %s", target, message)
+		}
+		_, output := postTarget(t, target, `{"TableName": "missing"}`)
+		if output["__type"] == "com.amazon.coral.service#UnknownOperationException" {
+			t.Fatalf("Expected %s to be served, got %v", target, output)
+		}
+	}
+}
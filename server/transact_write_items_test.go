@@ -122,6 +122,252 @@ func TestTransactWriteItems(t *testing.T) {
 	}
 }
 
+func TestTransactWriteItems_ConditionalDeleteFailedReturnsItem(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 5, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	item, err := putItem(ddb, 2025, "Hello World", "your magic is mine", "1", "US")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	input := dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					Item: map[string]types.AttributeValue{
+						"year":  &types.AttributeValueMemberN{Value: "2025"},
+						"title": &types.AttributeValueMemberS{Value: "Hello World 0"},
+					},
+					TableName: aws.String("movie"),
+				},
+			},
+			{
+				Delete: &types.Delete{
+					Key: map[string]types.AttributeValue{
+						"year":  &types.AttributeValueMemberN{Value: "2025"},
+						"title": &types.AttributeValueMemberS{Value: "Hello World"},
+					},
+					ConditionExpression: aws.String("message = :message"),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":message": &types.AttributeValueMemberS{Value: "not my message"},
+					},
+					ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+					TableName:                           aws.String("movie"),
+				},
+			},
+		},
+	}
+
+	_, err = ddb.TransactWriteItems(context.Background(), &input)
+	var transactionCanceledException *types.TransactionCanceledException
+	if !errors.As(err, &transactionCanceledException) {
+		t.Fatalf("Expected TransactionCanceledException, got %v", err)
+	}
+
+	reasons := transactionCanceledException.CancellationReasons
+	if len(reasons) != 2 {
+		t.Fatalf("Expected 2 cancellation reasons, got %d", len(reasons))
+	}
+	if *reasons[0].Code != "None" {
+		t.Fatalf("Expected first reason code None, got %s", *reasons[0].Code)
+	}
+	if *reasons[1].Code != "ConditionalCheckFailed" {
+		t.Fatalf("Expected second reason code ConditionalCheckFailed, got %s", *reasons[1].Code)
+	}
+	if len(reasons[1].Item) != len(item) {
+		t.Fatalf("Expected old item with %d attributes, got %v", len(item), reasons[1].Item)
+	}
+	for k, v := range item {
+		actual, ok := reasons[1].Item[k].(*types.AttributeValueMemberS)
+		if expected, isString := v.(*types.AttributeValueMemberS); isString && (!ok || actual.Value != expected.Value) {
+			t.Fatalf("Expected %s to be %v, got %v", k, expected.Value, reasons[1].Item[k])
+		}
+	}
+
+	getItemOutput, err := ddb.GetItem(context.Background(), &dynamodb.GetItemInput{
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World"},
+		},
+		TableName:      aws.String("movie"),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(getItemOutput.Item) == 0 {
+		t.Fatalf("Expected item not to be deleted")
+	}
+}
+
+func TestTransactWriteItems_FailedConditionCheckRollsBackGsi(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 5, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	updateTestTableMetadata(ddb, 0, 0, 0)
+
+	input := dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					Item: map[string]types.AttributeValue{
+						"year":       &types.AttributeValueMemberN{Value: "2025"},
+						"title":      &types.AttributeValueMemberS{Value: "Hello World"},
+						"regionCode": &types.AttributeValueMemberS{Value: "1"},
+					},
+					TableName: aws.String("movie"),
+				},
+			},
+			{
+				ConditionCheck: &types.ConditionCheck{
+					Key: map[string]types.AttributeValue{
+						"year":  &types.AttributeValueMemberN{Value: "2025"},
+						"title": &types.AttributeValueMemberS{Value: "Hello World 2"},
+					},
+					ConditionExpression: aws.String("attribute_exists(title)"),
+					TableName:           aws.String("movie"),
+				},
+			},
+		},
+	}
+
+	_, err = ddb.TransactWriteItems(context.Background(), &input)
+	var transactionCanceledException *types.TransactionCanceledException
+	if !errors.As(err, &transactionCanceledException) {
+		t.Fatalf("Expected TransactionCanceledException, got %v", err)
+	}
+
+	queryOutput, err := ddb.Query(context.Background(), &dynamodb.QueryInput{
+		TableName:              aws.String("movie"),
+		IndexName:              aws.String("regionGSI"),
+		KeyConditionExpression: aws.String("regionCode = :regionCode"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":regionCode": &types.AttributeValueMemberS{Value: "1"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(queryOutput.Items) != 0 {
+		t.Fatalf("Expected GSI entry of the first put to be rolled back, got %v", queryOutput.Items)
+	}
+
+	getItemOutput, err := ddb.GetItem(context.Background(), &dynamodb.GetItemInput{
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World"},
+		},
+		TableName:      aws.String("movie"),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(getItemOutput.Item) != 0 {
+		t.Fatalf("Expected the first put to be rolled back, got %v", getItemOutput.Item)
+	}
+}
+
+func TestTransactWriteItems_ConditionCheckListSize(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 5, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	updateTestTableMetadata(ddb, 0, 0, 0)
+
+	cartKey := map[string]types.AttributeValue{
+		"year":  &types.AttributeValueMemberN{Value: "2025"},
+		"title": &types.AttributeValueMemberS{Value: "cart"},
+	}
+	_, err = ddb.PutItem(context.Background(), &dynamodb.PutItemInput{
+		Item: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "cart"},
+			"items": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberS{Value: "book"},
+				&types.AttributeValueMemberS{Value: "pen"},
+			}},
+		},
+		TableName: aws.String("movie"),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	addToCart := func(title string, max string) error {
+		_, err := ddb.TransactWriteItems(context.Background(), &dynamodb.TransactWriteItemsInput{
+			TransactItems: []types.TransactWriteItem{
+				{
+					Put: &types.Put{
+						Item: map[string]types.AttributeValue{
+							"year":  &types.AttributeValueMemberN{Value: "2025"},
+							"title": &types.AttributeValueMemberS{Value: title},
+						},
+						TableName: aws.String("movie"),
+					},
+				},
+				{
+					ConditionCheck: &types.ConditionCheck{
+						Key:                 cartKey,
+						ConditionExpression: aws.String("size(#items) < :max"),
+						ExpressionAttributeNames: map[string]string{
+							"#items": "items",
+						},
+						ExpressionAttributeValues: map[string]types.AttributeValue{
+							":max": &types.AttributeValueMemberN{Value: max},
+						},
+						TableName: aws.String("movie"),
+					},
+				},
+			},
+		})
+		return err
+	}
+
+	err = addToCart("Hello World", "3")
+	if err != nil {
+		t.Fatalf("Expected no error when the cart is under the limit, got %v", err)
+	}
+
+	err = addToCart("Hello World 2", "2")
+	var transactionCanceledException *types.TransactionCanceledException
+	if !errors.As(err, &transactionCanceledException) {
+		t.Fatalf("Expected TransactionCanceledException when the cart is full, got %v", err)
+	}
+	if len(transactionCanceledException.CancellationReasons) != 2 || *transactionCanceledException.CancellationReasons[1].Code != "ConditionalCheckFailed" {
+		t.Fatalf("Expected the ConditionCheck to fail, got %v", transactionCanceledException.CancellationReasons)
+	}
+
+	for title, expectedExists := range map[string]bool{"Hello World": true, "Hello World 2": false} {
+		getItemOutput, err := ddb.GetItem(context.Background(), &dynamodb.GetItemInput{
+			Key: map[string]types.AttributeValue{
+				"year":  &types.AttributeValueMemberN{Value: "2025"},
+				"title": &types.AttributeValueMemberS{Value: title},
+			},
+			TableName:      aws.String("movie"),
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if exists := len(getItemOutput.Item) != 0; exists != expectedExists {
+			t.Fatalf("Expected %s to exist: %v, got %v", title, expectedExists, getItemOutput.Item)
+		}
+	}
+}
+
 func TestTransactWriteItems_TooManyRequest(t *testing.T) {
 	shutdown := startServer()
 	defer shutdown()
@@ -192,6 +438,65 @@ func TestTransactWriteItems_DuplicatedKey(t *testing.T) {
 	}
 }
 
+func TestTransactWriteItems_UnusedExpressionPlaceholders(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 5, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	put := &types.Put{
+		Item: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello World"},
+		},
+		TableName:                aws.String("movie"),
+		ConditionExpression:      aws.String("attribute_not_exists(#title)"),
+		ExpressionAttributeNames: map[string]string{"#title": "title", "#message": "message"},
+	}
+	_, err = ddb.TransactWriteItems(context.Background(), &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{{Put: put}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "Value provided in ExpressionAttributeNames unused in expressions: keys: {#message}") {
+		t.Fatalf("Expected the unused name to be rejected, got %v", err)
+	}
+
+	// the put is valid on its own, but nothing is written when the update is rejected
+	delete(put.ExpressionAttributeNames, "#message")
+	update := &types.Update{
+		Key: map[string]types.AttributeValue{
+			"year":  &types.AttributeValueMemberN{Value: "2025"},
+			"title": &types.AttributeValueMemberS{Value: "Hello Moon"},
+		},
+		TableName:        aws.String("movie"),
+		UpdateExpression: aws.String("SET message = :message"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":message": &types.AttributeValueMemberS{Value: "Hello"},
+			":region":  &types.AttributeValueMemberS{Value: "us"},
+		},
+	}
+	_, err = ddb.TransactWriteItems(context.Background(), &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{{Put: put}, {Update: update}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "Value provided in ExpressionAttributeValues unused in expressions: keys: {:region}") {
+		t.Fatalf("Expected the unused value to be rejected, got %v", err)
+	}
+
+	output, err := ddb.GetItem(context.Background(), &dynamodb.GetItemInput{
+		Key:            put.Item,
+		TableName:      aws.String("movie"),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if output.Item != nil {
+		t.Fatalf("Expected no item to be written, got %v", output.Item)
+	}
+}
+
 func TestTransactWriteItems_ProvisionedThroughputExceeded(t *testing.T) {
 	shutdown := startServer()
 	defer shutdown()
@@ -228,3 +533,67 @@ func TestTransactWriteItems_ProvisionedThroughputExceeded(t *testing.T) {
 		}
 	}
 }
+
+func TestTransactWriteItems_ClientRequestToken(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	incrementInput := func(token string, increment string) *dynamodb.TransactWriteItemsInput {
+		return &dynamodb.TransactWriteItemsInput{
+			ClientRequestToken: aws.String(token),
+			TransactItems: []types.TransactWriteItem{
+				{
+					Update: &types.Update{
+						TableName: aws.String("movie"),
+						Key: map[string]types.AttributeValue{
+							"year":  &types.AttributeValueMemberN{Value: "2025"},
+							"title": &types.AttributeValueMemberS{Value: "Hello World"},
+						},
+						UpdateExpression: aws.String("ADD #views :increment"),
+						ExpressionAttributeNames: map[string]string{
+							"#views": "views",
+						},
+						ExpressionAttributeValues: map[string]types.AttributeValue{
+							":increment": &types.AttributeValueMemberN{Value: increment},
+						},
+					},
+				},
+			},
+		}
+	}
+	expectViews := func(expected string) {
+		t.Helper()
+		item := getMovie(t, ddb, "2025", "Hello World")
+		if views := item["views"].(*types.AttributeValueMemberN).Value; views != expected {
+			t.Fatalf("Expected %s views, got %s", expected, views)
+		}
+	}
+
+	if _, err := ddb.TransactWriteItems(context.Background(), incrementInput("token-1", "1")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectViews("1")
+
+	// a replay succeeds without writing again
+	if _, err := ddb.TransactWriteItems(context.Background(), incrementInput("token-1", "1")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectViews("1")
+
+	_, err = ddb.TransactWriteItems(context.Background(), incrementInput("token-1", "2"))
+	var idempotentParameterMismatchException *types.IdempotentParameterMismatchException
+	if !errors.As(err, &idempotentParameterMismatchException) {
+		t.Fatalf("Expected IdempotentParameterMismatchException, got %v", err)
+	}
+	expectViews("1")
+
+	if _, err := ddb.TransactWriteItems(context.Background(), incrementInput("token-2", "2")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectViews("3")
+}
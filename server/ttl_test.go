@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestTimeToLive(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	client := newDdbClient()
+	_, err := createTable(client, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	key := map[string]types.AttributeValue{
+		"year":  &types.AttributeValueMemberN{Value: "2025"},
+		"title": &types.AttributeValueMemberS{Value: "Hello World"},
+	}
+	_, err = client.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String("movie"),
+		Item: map[string]types.AttributeValue{
+			"year":     key["year"],
+			"title":    key["title"],
+			"expireAt": &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	updateOutput, err := client.UpdateTimeToLive(context.Background(), &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String("movie"),
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: aws.String("expireAt"),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !*updateOutput.TimeToLiveSpecification.Enabled || *updateOutput.TimeToLiveSpecification.AttributeName != "expireAt" {
+		t.Fatalf("Expected TTL to be enabled on expireAt, got %v", updateOutput.TimeToLiveSpecification)
+	}
+
+	describeOutput, err := client.DescribeTimeToLive(context.Background(), &dynamodb.DescribeTimeToLiveInput{
+		TableName: aws.String("movie"),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if describeOutput.TimeToLiveDescription.TimeToLiveStatus != types.TimeToLiveStatusEnabled {
+		t.Fatalf("Expected TTL to be ENABLED, got %s", describeOutput.TimeToLiveDescription.TimeToLiveStatus)
+	}
+
+	getOutput, err := client.GetItem(context.Background(), &dynamodb.GetItemInput{
+		TableName:      aws.String("movie"),
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if getOutput.Item != nil {
+		t.Fatalf("Expected the expired item to be hidden, got %v", getOutput.Item)
+	}
+}
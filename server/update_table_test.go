@@ -0,0 +1,182 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestUpdateTableBackfillsNewGsi(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 200, 200)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	itemCount := 100
+	for i := 0; i < itemCount; i++ {
+		_, err := putItem(ddb, 2025, fmt.Sprintf("Hello World %03d", i), fmt.Sprintf("message %d", i), "1", fmt.Sprintf("code%d", i))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	_, err = ddb.UpdateTable(context.Background(), &dynamodb.UpdateTableInput{
+		TableName: aws.String("movie"),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("regionCode"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("title"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+			{
+				Create: &types.CreateGlobalSecondaryIndexAction{
+					IndexName: aws.String("regionTitleGSI"),
+					KeySchema: []types.KeySchemaElement{
+						{AttributeName: aws.String("regionCode"), KeyType: types.KeyTypeHash},
+						{AttributeName: aws.String("title"), KeyType: types.KeyTypeRange},
+					},
+					Projection: &types.Projection{
+						ProjectionType:   types.ProjectionTypeInclude,
+						NonKeyAttributes: []string{"message"},
+					},
+					ProvisionedThroughput: &types.ProvisionedThroughput{
+						ReadCapacityUnits:  aws.Int64(200),
+						WriteCapacityUnits: aws.Int64(200),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	updateTestTableMetadata(ddb, 0, 0, 0)
+
+	items := make([]map[string]types.AttributeValue, 0)
+	paginator := dynamodb.NewQueryPaginator(ddb, &dynamodb.QueryInput{
+		TableName:              aws.String("movie"),
+		IndexName:              aws.String("regionTitleGSI"),
+		KeyConditionExpression: aws.String("regionCode = :regionCode"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":regionCode": &types.AttributeValueMemberS{Value: "1"},
+		},
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		items = append(items, output.Items...)
+	}
+
+	if len(items) != itemCount {
+		t.Fatalf("Expected %d items from the backfilled GSI, got %d", itemCount, len(items))
+	}
+	for i, item := range items {
+		expectedTitle := fmt.Sprintf("Hello World %03d", i)
+		if title := item["title"].(*types.AttributeValueMemberS).Value; title != expectedTitle {
+			t.Fatalf("Expected item %d to be %s, got %s", i, expectedTitle, title)
+		}
+		if message := item["message"].(*types.AttributeValueMemberS).Value; message != fmt.Sprintf("message %d", i) {
+			t.Fatalf("Expected projected message of %s, got %s", expectedTitle, message)
+		}
+		if _, ok := item["countryCode"]; ok {
+			t.Fatalf("Expected countryCode not to be projected, got %v", item)
+		}
+	}
+}
+
+func TestBillingModeSummary(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+	_, err := createTable(ddb, 10, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	describeTable := func() *types.TableDescription {
+		output, err := ddb.DescribeTable(context.Background(), &dynamodb.DescribeTableInput{
+			TableName: aws.String("movie"),
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return output.Table
+	}
+	if summary := describeTable().BillingModeSummary; summary != nil {
+		t.Fatalf("Expected no BillingModeSummary for a provisioned table, got %+v", summary)
+	}
+
+	before := time.Now().Add(-time.Second)
+	_, err = ddb.UpdateTable(context.Background(), &dynamodb.UpdateTableInput{
+		TableName:   aws.String("movie"),
+		BillingMode: types.BillingModePayPerRequest,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	summary := describeTable().BillingModeSummary
+	if summary == nil || summary.BillingMode != types.BillingModePayPerRequest {
+		t.Fatalf("Expected BillingModeSummary of PAY_PER_REQUEST, got %+v", summary)
+	}
+	if summary.LastUpdateToPayPerRequestDateTime == nil || summary.LastUpdateToPayPerRequestDateTime.Before(before) {
+		t.Fatalf("Expected LastUpdateToPayPerRequestDateTime after %v, got %v", before, summary.LastUpdateToPayPerRequestDateTime)
+	}
+	switchedAt := *summary.LastUpdateToPayPerRequestDateTime
+
+	// switching back to provisioned keeps the time of the last switch to PAY_PER_REQUEST
+	_, err = ddb.UpdateTable(context.Background(), &dynamodb.UpdateTableInput{
+		TableName:   aws.String("movie"),
+		BillingMode: types.BillingModeProvisioned,
+		ProvisionedThroughput: &types.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(10),
+			WriteCapacityUnits: aws.Int64(10),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	summary = describeTable().BillingModeSummary
+	if summary == nil || summary.BillingMode != types.BillingModeProvisioned {
+		t.Fatalf("Expected BillingModeSummary of PROVISIONED, got %+v", summary)
+	}
+	if summary.LastUpdateToPayPerRequestDateTime == nil || !summary.LastUpdateToPayPerRequestDateTime.Equal(switchedAt) {
+		t.Fatalf("Expected LastUpdateToPayPerRequestDateTime %v, got %v", switchedAt, summary.LastUpdateToPayPerRequestDateTime)
+	}
+}
+
+func TestBillingModeSummaryOfPayPerRequestTable(t *testing.T) {
+	shutdown := startServer()
+	defer shutdown()
+	ddb := newDdbClient()
+
+	before := time.Now().Add(-time.Second)
+	output, err := ddb.CreateTable(context.Background(), &dynamodb.CreateTableInput{
+		TableName: aws.String("onDemand"),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("id"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("id"), KeyType: types.KeyTypeHash},
+		},
+		BillingMode: types.BillingModePayPerRequest,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	summary := output.TableDescription.BillingModeSummary
+	if summary == nil || summary.BillingMode != types.BillingModePayPerRequest {
+		t.Fatalf("Expected BillingModeSummary of PAY_PER_REQUEST, got %+v", summary)
+	}
+	if summary.LastUpdateToPayPerRequestDateTime == nil || summary.LastUpdateToPayPerRequestDateTime.Before(before) {
+		t.Fatalf("Expected LastUpdateToPayPerRequestDateTime after %v, got %v", before, summary.LastUpdateToPayPerRequestDateTime)
+	}
+}